@@ -2,16 +2,19 @@ package main
 
 import (
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/bosskrub9992/fuel-management-backend/config"
+	"github.com/bosskrub9992/fuel-management-backend/internal/bootstraps"
 	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
-	"github.com/bosskrub9992/fuel-management-backend/library/databases"
+	"github.com/bosskrub9992/fuel-management-backend/internal/services"
 	"github.com/bosskrub9992/fuel-management-backend/library/slogger"
 	"github.com/shopspring/decimal"
-	"gorm.io/gorm"
 )
 
+const defaultPassword = "password"
+
 func main() {
 	cfg := config.New()
 	slog.SetDefault(slogger.New(&slogger.Config{
@@ -19,21 +22,17 @@ func main() {
 		MaskingFields:   cfg.Logger.MaskingFields,
 		RemovingFields:  cfg.Logger.RemovingFields,
 	}))
-	sqlDB, err := databases.NewPostgres(&cfg.Database.Postgres)
+	database, err := bootstraps.NewDatabase(cfg)
 	if err != nil {
 		slog.Error(err.Error())
 		return
 	}
 	defer func() {
-		if err := sqlDB.Close(); err != nil {
+		if err := database.Close(); err != nil {
 			slog.Error(err.Error())
 		}
 	}()
-	db, err := databases.NewGormDBPostgres(sqlDB, gorm.Config{})
-	if err != nil {
-		slog.Error(err.Error())
-		return
-	}
+	db := database.DB
 
 	now := time.Now()
 	loc, err := time.LoadLocation("Asia/Bangkok")
@@ -44,10 +43,10 @@ func main() {
 
 	cars := []domains.Car{
 		{
-			Name: "Mazda 2", CreateTime: now, UpdateTime: now,
+			Name: "Mazda 2", FareRule: domains.CarFareRuleNone, CreateTime: now, UpdateTime: now,
 		},
 		{
-			Name: "Ford", CreateTime: now, UpdateTime: now,
+			Name: "Ford", FareRule: domains.CarFareRuleNone, CreateTime: now, UpdateTime: now,
 		},
 	}
 
@@ -122,7 +121,9 @@ func main() {
 			KilometerAfterUse:  700,
 			Description:        "mock",
 			TotalMoney:         decimal.NewFromFloat(100),
-			PayEach:            decimal.NewFromFloat(100).DivRound(decimal.NewFromInt(2), 2),
+			Cost:               domains.FuelUsageCost{FuelCost: decimal.NewFromFloat(100)},
+			SplitMode:          domains.FuelUsageSplitModeEqual,
+			FareRule:           domains.CarFareRuleNone,
 			CreateTime:         now,
 			UpdateTime:         now,
 		},
@@ -134,7 +135,9 @@ func main() {
 			KilometerAfterUse:  800,
 			Description:        "mock2",
 			TotalMoney:         decimal.NewFromFloat(100),
-			PayEach:            decimal.NewFromFloat(100).DivRound(decimal.NewFromInt(2), 2),
+			Cost:               domains.FuelUsageCost{FuelCost: decimal.NewFromFloat(100)},
+			SplitMode:          domains.FuelUsageSplitModeEqual,
+			FareRule:           domains.CarFareRuleNone,
 			CreateTime:         now,
 			UpdateTime:         now,
 		},
@@ -146,7 +149,9 @@ func main() {
 			KilometerAfterUse:  680,
 			Description:        "mock",
 			TotalMoney:         decimal.NewFromFloat(20),
-			PayEach:            decimal.NewFromFloat(20).DivRound(decimal.NewFromInt(1), 2),
+			Cost:               domains.FuelUsageCost{FuelCost: decimal.NewFromFloat(20)},
+			SplitMode:          domains.FuelUsageSplitModeEqual,
+			FareRule:           domains.CarFareRuleNone,
 			CreateTime:         now,
 			UpdateTime:         now,
 		},
@@ -158,7 +163,9 @@ func main() {
 			KilometerAfterUse:  660,
 			Description:        "mock",
 			TotalMoney:         decimal.NewFromFloat(20),
-			PayEach:            decimal.NewFromFloat(20).DivRound(decimal.NewFromInt(1), 2),
+			Cost:               domains.FuelUsageCost{FuelCost: decimal.NewFromFloat(20)},
+			SplitMode:          domains.FuelUsageSplitModeEqual,
+			FareRule:           domains.CarFareRuleNone,
 			CreateTime:         now,
 			UpdateTime:         now,
 		},
@@ -170,7 +177,9 @@ func main() {
 			KilometerAfterUse:  640,
 			Description:        "mock",
 			TotalMoney:         decimal.NewFromFloat(20),
-			PayEach:            decimal.NewFromFloat(20).DivRound(decimal.NewFromInt(1), 2),
+			Cost:               domains.FuelUsageCost{FuelCost: decimal.NewFromFloat(20)},
+			SplitMode:          domains.FuelUsageSplitModeEqual,
+			FareRule:           domains.CarFareRuleNone,
 			CreateTime:         now,
 			UpdateTime:         now,
 		},
@@ -182,7 +191,9 @@ func main() {
 			KilometerAfterUse:  620,
 			Description:        "mock",
 			TotalMoney:         decimal.NewFromFloat(20),
-			PayEach:            decimal.NewFromFloat(20).DivRound(decimal.NewFromInt(1), 2),
+			Cost:               domains.FuelUsageCost{FuelCost: decimal.NewFromFloat(20)},
+			SplitMode:          domains.FuelUsageSplitModeEqual,
+			FareRule:           domains.CarFareRuleNone,
 			CreateTime:         now,
 			UpdateTime:         now,
 		},
@@ -194,7 +205,9 @@ func main() {
 			KilometerAfterUse:  600,
 			Description:        "mock",
 			TotalMoney:         decimal.NewFromFloat(20),
-			PayEach:            decimal.NewFromFloat(20).DivRound(decimal.NewFromInt(1), 2),
+			Cost:               domains.FuelUsageCost{FuelCost: decimal.NewFromFloat(20)},
+			SplitMode:          domains.FuelUsageSplitModeEqual,
+			FareRule:           domains.CarFareRuleNone,
 			CreateTime:         now,
 			UpdateTime:         now,
 		},
@@ -206,7 +219,9 @@ func main() {
 			KilometerAfterUse:  580,
 			Description:        "mock",
 			TotalMoney:         decimal.NewFromFloat(20),
-			PayEach:            decimal.NewFromFloat(20).DivRound(decimal.NewFromInt(1), 2),
+			Cost:               domains.FuelUsageCost{FuelCost: decimal.NewFromFloat(20)},
+			SplitMode:          domains.FuelUsageSplitModeEqual,
+			FareRule:           domains.CarFareRuleNone,
 			CreateTime:         now,
 			UpdateTime:         now,
 		},
@@ -218,7 +233,9 @@ func main() {
 			KilometerAfterUse:  560,
 			Description:        "mock",
 			TotalMoney:         decimal.NewFromFloat(20),
-			PayEach:            decimal.NewFromFloat(20).DivRound(decimal.NewFromInt(1), 2),
+			Cost:               domains.FuelUsageCost{FuelCost: decimal.NewFromFloat(20)},
+			SplitMode:          domains.FuelUsageSplitModeEqual,
+			FareRule:           domains.CarFareRuleNone,
 			CreateTime:         now,
 			UpdateTime:         now,
 		},
@@ -230,7 +247,9 @@ func main() {
 			KilometerAfterUse:  540,
 			Description:        "mock",
 			TotalMoney:         decimal.NewFromFloat(20),
-			PayEach:            decimal.NewFromFloat(20).DivRound(decimal.NewFromInt(1), 2),
+			Cost:               domains.FuelUsageCost{FuelCost: decimal.NewFromFloat(20)},
+			SplitMode:          domains.FuelUsageSplitModeEqual,
+			FareRule:           domains.CarFareRuleNone,
 			CreateTime:         now,
 			UpdateTime:         now,
 		},
@@ -238,43 +257,55 @@ func main() {
 
 	fuelUsageUsers := []domains.FuelUsageUser{
 		{
-			FuelUsageID: 1, UserID: 1, IsPaid: false,
+			FuelUsageID: 1, UserID: 1, IsPaid: false, FareWeight: decimal.NewFromInt(1), Amount: decimal.NewFromFloat(50),
 		},
 		{
-			FuelUsageID: 1, UserID: 2, IsPaid: true,
+			FuelUsageID: 1, UserID: 2, IsPaid: true, FareWeight: decimal.NewFromInt(1), Amount: decimal.NewFromFloat(50),
 		},
 		{
-			FuelUsageID: 2, UserID: 3, IsPaid: false,
+			FuelUsageID: 2, UserID: 3, IsPaid: false, FareWeight: decimal.NewFromInt(1), Amount: decimal.NewFromFloat(50),
 		},
 		{
-			FuelUsageID: 2, UserID: 4, IsPaid: true,
+			FuelUsageID: 2, UserID: 4, IsPaid: true, FareWeight: decimal.NewFromInt(1), Amount: decimal.NewFromFloat(50),
 		},
 		{
-			FuelUsageID: 3, UserID: 1, IsPaid: true,
+			FuelUsageID: 3, UserID: 1, IsPaid: true, FareWeight: decimal.NewFromInt(1), Amount: decimal.NewFromFloat(20),
 		},
 		{
-			FuelUsageID: 4, UserID: 1, IsPaid: true,
+			FuelUsageID: 4, UserID: 1, IsPaid: true, FareWeight: decimal.NewFromInt(1), Amount: decimal.NewFromFloat(20),
 		},
 		{
-			FuelUsageID: 5, UserID: 1, IsPaid: true,
+			FuelUsageID: 5, UserID: 1, IsPaid: true, FareWeight: decimal.NewFromInt(1), Amount: decimal.NewFromFloat(20),
 		},
 		{
-			FuelUsageID: 6, UserID: 1, IsPaid: true,
+			FuelUsageID: 6, UserID: 1, IsPaid: true, FareWeight: decimal.NewFromInt(1), Amount: decimal.NewFromFloat(20),
 		},
 		{
-			FuelUsageID: 7, UserID: 1, IsPaid: true,
+			FuelUsageID: 7, UserID: 1, IsPaid: true, FareWeight: decimal.NewFromInt(1), Amount: decimal.NewFromFloat(20),
 		},
 		{
-			FuelUsageID: 8, UserID: 1, IsPaid: true,
+			FuelUsageID: 8, UserID: 1, IsPaid: true, FareWeight: decimal.NewFromInt(1), Amount: decimal.NewFromFloat(20),
 		},
 		{
-			FuelUsageID: 9, UserID: 1, IsPaid: true,
+			FuelUsageID: 9, UserID: 1, IsPaid: true, FareWeight: decimal.NewFromInt(1), Amount: decimal.NewFromFloat(20),
 		},
 		{
-			FuelUsageID: 10, UserID: 1, IsPaid: true,
+			FuelUsageID: 10, UserID: 1, IsPaid: true, FareWeight: decimal.NewFromInt(1), Amount: decimal.NewFromFloat(20),
 		},
 	}
 
+	// the seeded paid records are paid in full
+	for i := range fuelRefills {
+		if fuelRefills[i].IsPaid {
+			fuelRefills[i].PaidAmount = fuelRefills[i].TotalMoney
+		}
+	}
+	for i := range fuelUsageUsers {
+		if fuelUsageUsers[i].IsPaid {
+			fuelUsageUsers[i].PaidAmount = fuelUsageUsers[i].Amount
+		}
+	}
+
 	if err := db.Create(&cars).Error; err != nil {
 		slog.Error(err.Error())
 		return
@@ -285,6 +316,29 @@ func main() {
 		return
 	}
 
+	// every seeded user logs in with lowercase nickname and defaultPassword
+	passwordHash, err := services.HashPassword(defaultPassword)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
+	var userCredentials []domains.UserCredential
+	for _, user := range users {
+		userCredentials = append(userCredentials, domains.UserCredential{
+			UserID:       user.ID,
+			Username:     strings.ToLower(user.Nickname),
+			PasswordHash: passwordHash,
+			CreateTime:   now,
+			UpdateTime:   now,
+		})
+	}
+
+	if err := db.Create(&userCredentials).Error; err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
 	if err := db.Create(&fuelRefills).Error; err != nil {
 		slog.Error(err.Error())
 		return
@@ -300,5 +354,30 @@ func main() {
 		return
 	}
 
+	fuelUsageIDToFuelUsageUsers := make(map[int64][]domains.FuelUsageUser)
+	for _, fuelUsageUser := range fuelUsageUsers {
+		fuelUsageIDToFuelUsageUsers[fuelUsageUser.FuelUsageID] = append(
+			fuelUsageIDToFuelUsageUsers[fuelUsageUser.FuelUsageID],
+			fuelUsageUser,
+		)
+	}
+
+	var ledgerEntries []domains.LedgerEntry
+	for _, fuelRefill := range fuelRefills {
+		ledgerEntries = append(ledgerEntries, services.FuelRefillLedgerEntries(fuelRefill, now)...)
+	}
+	for _, fuelUsage := range fuelUsages {
+		ledgerEntries = append(ledgerEntries, services.FuelUsageLedgerEntries(
+			fuelUsage,
+			fuelUsageIDToFuelUsageUsers[fuelUsage.ID],
+			now,
+		)...)
+	}
+
+	if err := db.Create(&ledgerEntries).Error; err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
 	slog.Info("successfully init data")
 }
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/config"
+	"github.com/bosskrub9992/fuel-management-backend/internal/bootstraps"
+	"github.com/bosskrub9992/fuel-management-backend/internal/migrations"
+	"github.com/bosskrub9992/fuel-management-backend/library/slogger"
+)
+
+const usage = `usage: go run ./cmd/migrate [--dry-run] <command>
+
+commands:
+  status      list applied and pending migrations
+  up [all]    apply the next pending migration, or all of them
+  down [all]  revert the last applied migration, or all of them
+  goto <id>   migrate up or down until <id> is the last applied migration, 0 reverts all
+  redo        revert and apply again the last applied migration
+
+--dry-run prints the SQL each up and down would execute and rolls it back`
+
+func main() {
+	var dryRun bool
+	var args []string
+	for _, arg := range os.Args[1:] {
+		if arg == "--dry-run" || arg == "-dry-run" {
+			dryRun = true
+			continue
+		}
+		args = append(args, arg)
+	}
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command := args[0]
+	all := len(args) > 1 && args[1] == "all"
+
+	cfg := config.New()
+	ctx := context.Background()
+	slog.SetDefault(slogger.New(&slogger.Config{
+		IsProductionEnv: cfg.Logger.IsProductionEnv,
+		MaskingFields:   cfg.Logger.MaskingFields,
+		RemovingFields:  cfg.Logger.RemovingFields,
+	}))
+
+	database, err := bootstraps.NewDatabase(cfg)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	if err := run(ctx, database, command, args[1:], all, dryRun); err != nil {
+		slog.Error(err.Error())
+		_ = database.Close()
+		os.Exit(1)
+	}
+	if err := database.Close(); err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+func run(ctx context.Context, database *bootstraps.Database, command string, args []string, all, dryRun bool) error {
+	migrator, err := migrations.NewMigrator(database.DB, database.Migrations, dryRun, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return printStatuses(statuses)
+	case "up":
+		n := 1
+		if all {
+			n = 0
+		}
+		migratedCount, err := migrator.Up(ctx, n)
+		if err != nil {
+			return err
+		}
+		if migratedCount == 0 {
+			slog.Info("no up migrations to migrate")
+		} else if all {
+			slog.Info("successfully migrated up all pending migrations")
+		}
+	case "down":
+		n := 1
+		if all {
+			n = 0
+		}
+		migratedCount, err := migrator.Down(ctx, n)
+		if err != nil {
+			return err
+		}
+		if migratedCount == 0 {
+			slog.Info("no down migrations to migrate")
+		} else if all {
+			slog.Info("successfully migrated down all migrations")
+		}
+	case "goto":
+		if len(args) == 0 {
+			return fmt.Errorf("goto needs a migration id\n\n%s", usage)
+		}
+		id, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid migration id %q: %w", args[0], err)
+		}
+		migratedCount, err := migrator.Goto(ctx, uint(id))
+		if err != nil {
+			return err
+		}
+		if migratedCount == 0 {
+			slog.Info(fmt.Sprintf("already at migration id: [%d]", id))
+		}
+	case "redo":
+		id, err := migrator.Redo(ctx)
+		if err != nil {
+			return err
+		}
+		slog.Info(fmt.Sprintf("successfully redid migration id: [%d]", id))
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", command, usage)
+	}
+	return nil
+}
+
+func printStatuses(statuses []migrations.Status) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", "-"
+		if status.IsApplied {
+			state = "applied"
+			appliedAt = status.AppliedTime.Format(time.RFC3339)
+		}
+		if status.IsUnknown {
+			state = "applied (not in code)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.ID, state, appliedAt)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/bosskrub9992/fuel-management-backend/config"
+	"github.com/bosskrub9992/fuel-management-backend/internal/bootstraps"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/internal/services"
+	"github.com/bosskrub9992/fuel-management-backend/library/slogger"
+)
+
+const usage = `usage: go run ./cmd/recompute [--dry-run]
+
+splits every fuel usage again with the configured rounding, then rewrites
+the amounts and the ledger entries of the usages which changed, claimed,
+adjusted and paid usages are skipped
+
+--dry-run only prints the amounts which would change`
+
+func main() {
+	var dryRun bool
+	for _, arg := range os.Args[1:] {
+		if arg != "--dry-run" && arg != "-dry-run" {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+		dryRun = true
+	}
+
+	cfg := config.New()
+	ctx := context.Background()
+	slog.SetDefault(slogger.New(&slogger.Config{
+		IsProductionEnv: cfg.Logger.IsProductionEnv,
+		MaskingFields:   cfg.Logger.MaskingFields,
+		RemovingFields:  cfg.Logger.RemovingFields,
+	}))
+
+	database, err := bootstraps.NewDatabase(cfg)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	service := services.New(cfg, database.Adaptor, nil, nil)
+
+	recomputed, skipped, err := service.RecomputeFuelUsageAmounts(ctx, dryRun)
+	if err != nil {
+		slog.Error(err.Error())
+		_ = database.Close()
+		os.Exit(1)
+	}
+	if err := printRecomputed(recomputed); err != nil {
+		slog.Error(err.Error())
+	}
+	if len(skipped) > 0 {
+		if err := printSkipped(skipped); err != nil {
+			slog.Error(err.Error())
+		}
+		slog.Info(fmt.Sprintf("skipped %d fuel usages", len(skipped)))
+	}
+	if dryRun {
+		slog.Info(fmt.Sprintf("dry run, %d amounts would change", len(recomputed)))
+	} else {
+		slog.Info(fmt.Sprintf("successfully recomputed %d amounts", len(recomputed)))
+	}
+
+	if err := database.Close(); err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+func printRecomputed(recomputed []services.RecomputedFuelUsageUser) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FUEL USAGE ID\tUSER ID\tOLD AMOUNT\tNEW AMOUNT")
+	for _, r := range recomputed {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\n", r.FuelUsageID, r.UserID, r.OldAmount, r.NewAmount)
+	}
+	return w.Flush()
+}
+
+func printSkipped(skipped []models.SkippedFuelUsage) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SKIPPED FUEL USAGE ID\tREASON")
+	for _, r := range skipped {
+		fmt.Fprintf(w, "%d\t%s\n", r.FuelUsageID, r.Reason)
+	}
+	return w.Flush()
+}
@@ -4,6 +4,8 @@ import (
 	"strings"
 
 	"github.com/bosskrub9992/fuel-management-backend/library/databases"
+	"github.com/bosskrub9992/fuel-management-backend/library/jwts"
+	"github.com/bosskrub9992/fuel-management-backend/library/storages"
 	"github.com/spf13/viper"
 )
 
@@ -12,12 +14,25 @@ type Config struct {
 		Port string
 	}
 	Database struct {
-		Use      string
-		Postgres databases.PostgresConfig
-		SQLite   struct {
+		Use              string
+		MigrateOnStartup bool `mapstructure:"migrate_on_startup"`
+		Postgres         databases.PostgresConfig
+		SQLite           struct {
 			FilePath string `mapstructure:"file_path"`
 		}
 	}
+	Storage struct {
+		Use   string
+		Local storages.LocalConfig
+	}
+	Auth struct {
+		JWT jwts.Config
+	}
+	FuelUsage struct {
+		SplitTieBreak          string `mapstructure:"split_tie_break"`
+		KilometerTolerance     int64  `mapstructure:"kilometer_tolerance"`
+		KilometerDiscontinuity string `mapstructure:"kilometer_discontinuity"`
+	} `mapstructure:"fuel_usage"`
 	Logger struct {
 		IsProductionEnv bool     `mapstructure:"is_production_env"`
 		MaskingFields   []string `mapstructure:"masking_fields"`
@@ -28,9 +43,10 @@ type Config struct {
 func init() {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yml")
-	viper.AddConfigPath("./config")     // local
-	viper.AddConfigPath("../../config") // unit test
-	viper.AddConfigPath("/app/config")  // docker
+	viper.AddConfigPath("./config")        // local
+	viper.AddConfigPath("../../config")    // unit test
+	viper.AddConfigPath("../../../config") // unit test
+	viper.AddConfigPath("/app/config")     // docker
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	if err := viper.ReadInConfig(); err != nil {
 		panic(err)
@@ -0,0 +1,1045 @@
+package gormadaptor
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/constants"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/services"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TimeOrder is how the dialect orders by a time column.
+type TimeOrder func(column string) string
+
+// GormAdaptor holds the queries every dialect shares, an adaptor of a
+// dialect embeds it and gives its TimeOrder.
+type GormAdaptor struct {
+	db        *gorm.DB
+	timeOrder TimeOrder
+}
+
+func New(db *gorm.DB, timeOrder TimeOrder) *GormAdaptor {
+	return &GormAdaptor{
+		db:        db,
+		timeOrder: timeOrder,
+	}
+}
+
+func (adt *GormAdaptor) Transaction(ctx context.Context, fn func(ctxTx context.Context) error) error {
+	return adt.db.Transaction(func(tx *gorm.DB) error {
+		ctxTx := context.WithValue(ctx, constants.WithTx, tx)
+		return fn(ctxTx)
+	})
+}
+
+func (adt *GormAdaptor) dbOrTx(ctx context.Context) *gorm.DB {
+	tx, ok := ctx.Value(constants.WithTx).(*gorm.DB)
+	if ok {
+		return tx
+	}
+	return adt.db.WithContext(ctx)
+}
+
+func (adt *GormAdaptor) CreateFuelUsage(ctx context.Context, fuelUsage domains.FuelUsage) (int64, error) {
+	if err := adt.dbOrTx(ctx).Create(&fuelUsage).Error; err != nil {
+		return 0, err
+	}
+	return fuelUsage.ID, nil
+}
+
+func (adt *GormAdaptor) CreateFuelUsageUsers(ctx context.Context, fuelUsageUsers []domains.FuelUsageUser) error {
+	return adt.dbOrTx(ctx).
+		Create(&fuelUsageUsers).
+		Error
+}
+
+func (adt *GormAdaptor) GetFuelUsageInPagination(
+	ctx context.Context,
+	params services.GetFuelUsageInPaginationParams,
+) (
+	[]domains.FuelUsage,
+	int64,
+	error,
+) {
+	var totalCount int64
+	stmt := adt.dbOrTx(ctx).
+		Model(&domains.FuelUsage{}).
+		Where(domains.FuelUsage{
+			CarID: params.CarID,
+		})
+
+	if err := stmt.Count(&totalCount).Error; err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, 0, err
+	}
+
+	pageIndex := params.PageIndex
+	if pageIndex <= 0 {
+		pageIndex = 1
+	}
+	pageSize := params.PageSize
+	if pageSize <= 0 {
+		pageSize = 0
+	}
+	offset := (pageIndex - 1) * pageSize
+
+	var fuelUsages []domains.FuelUsage
+	err := stmt.Order(adt.timeOrder("fuel_use_time") + " DESC, id DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&fuelUsages).Error
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, 0, err
+	}
+
+	return fuelUsages, totalCount, nil
+}
+
+func (adt *GormAdaptor) GetFuelUsageUsersByFuelUsageIDs(ctx context.Context, fuelUsageIDs []int64) ([]services.FuelUsageUser, error) {
+	var fuelUsageUsers []services.FuelUsageUser
+	err := adt.dbOrTx(ctx).
+		Table("fuel_usage_users").
+		Select("fuel_usage_users.*, users.nickname").
+		Joins("INNER JOIN users ON users.id = fuel_usage_users.user_id").
+		Where("fuel_usage_users.fuel_usage_id IN ?", fuelUsageIDs).
+		Find(&fuelUsageUsers).Error
+	if err != nil {
+		return nil, err
+	}
+	return fuelUsageUsers, nil
+}
+
+func (adt *GormAdaptor) GetAllUsers(ctx context.Context) ([]domains.User, error) {
+	var users []domains.User
+	err := adt.dbOrTx(ctx).
+		Model(&domains.User{}).
+		Order("nickname ASC").
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (adt *GormAdaptor) GetLatestFuelRefillByCarID(ctx context.Context, carID int64) (*domains.FuelRefill, error) {
+	var fuelRefill domains.FuelRefill
+	err := adt.dbOrTx(ctx).
+		Model(&fuelRefill).
+		Where(domains.FuelRefill{
+			CarID: carID,
+		}).
+		Order(adt.timeOrder("refill_time") + " DESC, id DESC").
+		First(&fuelRefill).Error
+	if err != nil {
+		return nil, err
+	}
+	return &fuelRefill, nil
+}
+
+func (adt *GormAdaptor) GetUsersByDefaultCarID(ctx context.Context, carID int64) ([]domains.User, error) {
+	var users []domains.User
+	err := adt.dbOrTx(ctx).
+		Model(&domains.User{}).
+		Where("default_car_id = ? AND is_deactivated = ?", carID, false).
+		Order("nickname ASC").
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (adt *GormAdaptor) CreateUser(ctx context.Context, user domains.User) (int64, error) {
+	if err := adt.dbOrTx(ctx).Create(&user).Error; err != nil {
+		return 0, err
+	}
+	return user.ID, nil
+}
+
+func (adt *GormAdaptor) UpdateUser(ctx context.Context, user domains.User) error {
+	return adt.dbOrTx(ctx).
+		Save(&user).
+		Error
+}
+
+func (adt *GormAdaptor) CreateUserCredential(ctx context.Context, userCredential domains.UserCredential) error {
+	return adt.dbOrTx(ctx).
+		Create(&userCredential).
+		Error
+}
+
+func (adt *GormAdaptor) GetAllCars(ctx context.Context) ([]domains.Car, error) {
+	var cars []domains.Car
+	err := adt.dbOrTx(ctx).
+		Model(&domains.Car{}).
+		Find(&cars).Error
+	if err != nil {
+		return nil, err
+	}
+	return cars, nil
+}
+
+func (adt *GormAdaptor) GetCarByID(ctx context.Context, carID int64) (*domains.Car, error) {
+	var car domains.Car
+	err := adt.dbOrTx(ctx).
+		Model(&domains.Car{}).
+		Where(domains.Car{
+			ID: carID,
+		}).
+		First(&car).Error
+	if err != nil {
+		return nil, err
+	}
+	return &car, nil
+}
+
+func (adt *GormAdaptor) CreateCar(ctx context.Context, car domains.Car) (int64, error) {
+	if err := adt.dbOrTx(ctx).Create(&car).Error; err != nil {
+		return 0, err
+	}
+	return car.ID, nil
+}
+
+func (adt *GormAdaptor) UpdateCar(ctx context.Context, car domains.Car) error {
+	return adt.dbOrTx(ctx).
+		Save(&car).
+		Error
+}
+
+func (adt *GormAdaptor) GetFuelUsageByID(ctx context.Context, id int64) (*domains.FuelUsage, error) {
+	var fuelUsage domains.FuelUsage
+	err := forUpdate(ctx, adt.dbOrTx(ctx), "fuel_usages").
+		Model(&fuelUsage).
+		Where(domains.FuelUsage{
+			ID: id,
+		}).
+		First(&fuelUsage).Error
+	if err != nil {
+		return nil, err
+	}
+	return &fuelUsage, nil
+}
+
+func (adt *GormAdaptor) GetFuelUsageUsersByFuelUsageID(ctx context.Context, fuelUsageID int64) ([]services.FuelUsageUser, error) {
+	var fuelUsageUsers []services.FuelUsageUser
+	err := forUpdate(ctx, adt.dbOrTx(ctx), "fuel_usage_users").
+		Table("fuel_usage_users").
+		Select("fuel_usage_users.*, users.nickname").
+		Joins("INNER JOIN users ON users.id = fuel_usage_users.user_id").
+		Where("fuel_usage_users.fuel_usage_id = ?", fuelUsageID).
+		Find(&fuelUsageUsers).Error
+	if err != nil {
+		return nil, err
+	}
+	return fuelUsageUsers, nil
+}
+
+func (adt *GormAdaptor) UpdateFuelUsage(ctx context.Context, fuelUsage domains.FuelUsage) error {
+	return adt.dbOrTx(ctx).
+		Save(&fuelUsage).
+		Error
+}
+
+func (adt *GormAdaptor) DeleteFuelUsageUsersByFuelUsageID(ctx context.Context, fuelUsageID int64) error {
+	return adt.dbOrTx(ctx).
+		Where("fuel_usage_id = ?", fuelUsageID).
+		Delete(&domains.FuelUsageUser{}).
+		Error
+}
+
+func (adt *GormAdaptor) DeleteFuelUsageByID(ctx context.Context, id int64) error {
+	return adt.dbOrTx(ctx).
+		Delete(&domains.FuelUsage{}, id).
+		Error
+}
+
+func (adt *GormAdaptor) GetFuelRefillPagination(ctx context.Context, params services.GetFuelRefillPaginationParams) ([]domains.FuelRefill, int, error) {
+	stmt := adt.dbOrTx(ctx).
+		Model(&domains.FuelRefill{}).
+		Where("car_id = ?", params.CarID)
+
+	var totalCount int64
+	if err := stmt.Count(&totalCount).Error; err != nil {
+		return nil, 0, err
+	}
+
+	pageIndex := params.PageIndex
+	if pageIndex <= 0 {
+		pageIndex = 1
+	}
+	pageSize := params.PageSize
+	if pageSize <= 0 {
+		pageSize = 0
+	}
+	offset := (pageIndex - 1) * pageSize
+
+	var fuelRefills []domains.FuelRefill
+	err := stmt.Order(adt.timeOrder("refill_time") + " DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&fuelRefills).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return fuelRefills, int(totalCount), nil
+}
+
+func (adt *GormAdaptor) CreateFuelRefill(ctx context.Context, fr domains.FuelRefill) (int64, error) {
+	if err := adt.dbOrTx(ctx).Create(&fr).Error; err != nil {
+		return 0, err
+	}
+	return fr.ID, nil
+}
+
+func (adt *GormAdaptor) GetFuelRefillByID(ctx context.Context, fuelRefillID int64) (*domains.FuelRefill, error) {
+	var fr domains.FuelRefill
+	err := forUpdate(ctx, adt.dbOrTx(ctx), "fuel_refills").
+		Model(&domains.FuelRefill{}).
+		Where(domains.FuelRefill{
+			ID: fuelRefillID,
+		}).
+		First(&fr).Error
+	if err != nil {
+		return nil, err
+	}
+	return &fr, nil
+}
+
+func (adt *GormAdaptor) DeleteFuelRefillByID(ctx context.Context, fuelRefillID int64) error {
+	return adt.dbOrTx(ctx).
+		Delete(&domains.FuelRefill{}, fuelRefillID).
+		Error
+}
+
+func (adt *GormAdaptor) UpdateFuelRefill(ctx context.Context, fr domains.FuelRefill) error {
+	return adt.dbOrTx(ctx).
+		Save(&fr).
+		Error
+}
+
+func (adt *GormAdaptor) GetLatestFuelUsageByCarID(ctx context.Context, carID int64) (*domains.FuelUsage, error) {
+	var fuelUsage domains.FuelUsage
+	err := adt.dbOrTx(ctx).
+		Model(&fuelUsage).
+		Where(domains.FuelUsage{
+			CarID: carID,
+		}).
+		Order(adt.timeOrder("fuel_use_time") + " DESC, id DESC").
+		First(&fuelUsage).Error
+	if err != nil {
+		return nil, err
+	}
+	return &fuelUsage, nil
+}
+
+func (adt *GormAdaptor) GetUserFuelUsagesByPaidStatus(
+	ctx context.Context,
+	userID int64,
+	isPaid bool,
+	carID int64,
+) (
+	[]services.FuelUsageUserWithFuelUsage,
+	error,
+) {
+	var data []services.FuelUsageUserWithFuelUsage
+	q := adt.dbOrTx(ctx).
+		Select(`fuu.*,
+			fu.fuel_use_time,
+			fu.description,
+			fu.driver_user_id,
+			fu.fare_rule,
+			fu.is_paid_from_wallet,
+			cars.id AS car_id,
+			cars.name AS car_name,
+			COALESCE(NULLIF(fr.refill_by, 0), cars.owner_user_id, 0) AS creditor_user_id`).
+		Table("fuel_usages AS fu").
+		Joins("INNER JOIN fuel_usage_users AS fuu ON fu.id = fuu.fuel_usage_id").
+		Joins("INNER JOIN cars ON cars.id = fu.car_id").
+		Joins("LEFT JOIN fuel_refills AS fr ON fr.id = fu.fuel_refill_id").
+		Where("fuu.user_id = ? AND fuu.is_paid = ?",
+			userID,
+			isPaid,
+		)
+
+	if carID != 0 {
+		q = q.Where("cars.id = ?", carID)
+	}
+
+	q = forUpdate(ctx, q, "fuu")
+	if err := q.Order(adt.timeOrder("fu.fuel_use_time") + ", fu.id ASC").Find(&data).Error; err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (adt *GormAdaptor) UpdateUserFuelUsagePaymentStatus(ctx context.Context, userFuelUsage domains.FuelUsageUser) error {
+	return adt.dbOrTx(ctx).
+		Model(&domains.FuelUsageUser{}).
+		Where(domains.FuelUsageUser{
+			ID: userFuelUsage.ID,
+		}).
+		Updates(map[string]any{
+			"is_paid":     userFuelUsage.IsPaid,
+			"paid_amount": paidAmountExpr(userFuelUsage.IsPaid, "amount"),
+		}).
+		Error
+}
+
+func (adt *GormAdaptor) UpdateFuelUsageUserAmount(ctx context.Context, fuelUsageUserID int64, amount decimal.Decimal) error {
+	return adt.dbOrTx(ctx).
+		Model(&domains.FuelUsageUser{}).
+		Where(domains.FuelUsageUser{
+			ID: fuelUsageUserID,
+		}).
+		Updates(map[string]any{
+			"amount":  amount,
+			"is_paid": gorm.Expr("paid_amount >= ?", amount),
+		}).
+		Error
+}
+
+func (adt *GormAdaptor) GetAllFuelUsages(ctx context.Context) ([]domains.FuelUsage, error) {
+	var fuelUsages []domains.FuelUsage
+	err := adt.dbOrTx(ctx).
+		Model(&domains.FuelUsage{}).
+		Order("id ASC").
+		Find(&fuelUsages).Error
+	if err != nil {
+		return nil, err
+	}
+	return fuelUsages, nil
+}
+
+func (adt *GormAdaptor) GetFuelUsagesByCarID(ctx context.Context, carID int64) ([]domains.FuelUsage, error) {
+	var fuelUsages []domains.FuelUsage
+	err := adt.dbOrTx(ctx).
+		Model(&domains.FuelUsage{}).
+		Where(domains.FuelUsage{
+			CarID: carID,
+		}).
+		Order(adt.timeOrder("fuel_use_time") + " ASC, id ASC").
+		Find(&fuelUsages).Error
+	if err != nil {
+		return nil, err
+	}
+	return fuelUsages, nil
+}
+
+func (adt *GormAdaptor) GetFuelRefillsByCarID(ctx context.Context, carID int64) ([]domains.FuelRefill, error) {
+	var fuelRefills []domains.FuelRefill
+	err := adt.dbOrTx(ctx).
+		Model(&domains.FuelRefill{}).
+		Where(domains.FuelRefill{
+			CarID: carID,
+		}).
+		Order(adt.timeOrder("refill_time") + " ASC, id ASC").
+		Find(&fuelRefills).Error
+	if err != nil {
+		return nil, err
+	}
+	return fuelRefills, nil
+}
+
+func (adt *GormAdaptor) GetFuelUsagesByFuelRefillID(ctx context.Context, fuelRefillID int64) ([]domains.FuelUsage, error) {
+	var fuelUsages []domains.FuelUsage
+	err := adt.dbOrTx(ctx).
+		Model(&domains.FuelUsage{}).
+		Where("fuel_refill_id = ?", fuelRefillID).
+		Order("id ASC").
+		Find(&fuelUsages).Error
+	if err != nil {
+		return nil, err
+	}
+	return fuelUsages, nil
+}
+
+func (adt *GormAdaptor) UnsetFuelUsagesFuelRefillID(ctx context.Context, fuelRefillID int64) error {
+	return adt.dbOrTx(ctx).
+		Model(&domains.FuelUsage{}).
+		Where("fuel_refill_id = ?", fuelRefillID).
+		Update("fuel_refill_id", 0).
+		Error
+}
+
+func (adt *GormAdaptor) GetUserFuelUsageByUserID(ctx context.Context, userID int64) ([]domains.FuelUsageUser, error) {
+	var userFuelUsages []domains.FuelUsageUser
+	err := adt.dbOrTx(ctx).
+		Model(&domains.FuelUsageUser{}).
+		Where(domains.FuelUsageUser{
+			UserID: userID,
+		}).
+		Find(&userFuelUsages).Error
+	if err != nil {
+		return nil, err
+	}
+	return userFuelUsages, nil
+}
+
+func (adt *GormAdaptor) GetUserUnpaidFuelRefills(ctx context.Context, userID int64, carID int64) ([]domains.FuelRefill, error) {
+	var unpaidFuelRefills []domains.FuelRefill
+	err := forUpdate(ctx, adt.dbOrTx(ctx), "fuel_refills").
+		Model(&domains.FuelRefill{}).
+		Where("fuel_refills.is_paid = false").
+		Where("fuel_refills.refill_by = ?", userID).
+		Where("fuel_refills.car_id = ?", carID).
+		Order(adt.timeOrder("refill_time") + " ASC").
+		Find(&unpaidFuelRefills).Error
+	if err != nil {
+		return nil, err
+	}
+	return unpaidFuelRefills, nil
+}
+
+func (adt *GormAdaptor) PayFuelRefills(ctx context.Context, fuelRefillIDs []int64) error {
+	err := adt.dbOrTx(ctx).
+		Model(&domains.FuelRefill{}).
+		Where("id IN ?", fuelRefillIDs).
+		Updates(map[string]any{
+			"is_paid":     true,
+			"paid_amount": paidAmountExpr(true, "total_money"),
+		}).
+		Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (adt *GormAdaptor) PayFuelUsageUsers(ctx context.Context, fuelUsageUserIds []int64) error {
+	err := adt.dbOrTx(ctx).
+		Model(&domains.FuelUsageUser{}).
+		Where("id IN ?", fuelUsageUserIds).
+		Updates(map[string]any{
+			"is_paid":     true,
+			"paid_amount": paidAmountExpr(true, "amount"),
+		}).
+		Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (adt *GormAdaptor) IsUserOwnAllFuelRefills(ctx context.Context, userID int64, carID int64, fuelRefillIDs []int64) (bool, error) {
+	uniqueFuelRefillIDs := uniqueIDs(fuelRefillIDs)
+	if len(uniqueFuelRefillIDs) == 0 {
+		return true, nil
+	}
+	var count int64
+	err := adt.dbOrTx(ctx).
+		Model(&domains.FuelRefill{}).
+		Where("id IN ?", uniqueFuelRefillIDs).
+		Where("refill_by = ?", userID).
+		Where("car_id = ?", carID).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	if int(count) == len(uniqueFuelRefillIDs) {
+		return true, nil
+	}
+	return false, nil
+}
+
+func (adt *GormAdaptor) IsUserOwnAllFuelUsageUser(ctx context.Context, userID int64, carID int64, fuelUsageUserIds []int64) (bool, error) {
+	uniqueFuelUsageUserIDs := uniqueIDs(fuelUsageUserIds)
+	if len(uniqueFuelUsageUserIDs) == 0 {
+		return true, nil
+	}
+	var count int64
+	err := adt.dbOrTx(ctx).
+		Table("fuel_usage_users AS fuu").
+		Joins("INNER JOIN fuel_usages AS fu ON fu.id = fuu.fuel_usage_id").
+		Where("fuu.id IN ?", uniqueFuelUsageUserIDs).
+		Where("fuu.user_id = ?", userID).
+		Where("fu.car_id = ?", carID).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	if int(count) == len(uniqueFuelUsageUserIDs) {
+		return true, nil
+	}
+	return false, nil
+}
+
+func (adt *GormAdaptor) GetUserByID(ctx context.Context, userID int64) (*domains.User, error) {
+	var user domains.User
+	err := adt.dbOrTx(ctx).
+		Model(&domains.User{}).
+		Where(domains.User{
+			ID: userID,
+		}).
+		First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (adt *GormAdaptor) GetUserCredentialByUsername(ctx context.Context, username string) (*domains.UserCredential, error) {
+	var credential domains.UserCredential
+	err := adt.dbOrTx(ctx).
+		Model(&domains.UserCredential{}).
+		Where(domains.UserCredential{
+			Username: username,
+		}).
+		First(&credential).Error
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (adt *GormAdaptor) CreateSettlement(ctx context.Context, settlement domains.Settlement) (int64, error) {
+	if err := adt.dbOrTx(ctx).Create(&settlement).Error; err != nil {
+		return 0, err
+	}
+	return settlement.ID, nil
+}
+
+func (adt *GormAdaptor) CreateSettlementItems(ctx context.Context, settlementItems []domains.SettlementItem) error {
+	return adt.dbOrTx(ctx).
+		Create(&settlementItems).
+		Error
+}
+
+func (adt *GormAdaptor) GetFuelUsageUsersWithFuelUsageByIDs(ctx context.Context, fuelUsageUserIDs []int64) ([]services.FuelUsageUserWithFuelUsage, error) {
+	var data []services.FuelUsageUserWithFuelUsage
+	err := forUpdate(ctx, adt.dbOrTx(ctx), "fuu").
+		Select(`fuu.*,
+			fu.fuel_use_time,
+			fu.description,
+			fu.driver_user_id,
+			fu.fare_rule,
+			fu.is_paid_from_wallet,
+			cars.id AS car_id,
+			cars.name AS car_name,
+			COALESCE(NULLIF(fr.refill_by, 0), cars.owner_user_id, 0) AS creditor_user_id`).
+		Table("fuel_usages AS fu").
+		Joins("INNER JOIN fuel_usage_users AS fuu ON fu.id = fuu.fuel_usage_id").
+		Joins("INNER JOIN cars ON cars.id = fu.car_id").
+		Joins("LEFT JOIN fuel_refills AS fr ON fr.id = fu.fuel_refill_id").
+		Where("fuu.id IN ?", fuelUsageUserIDs).
+		Order(adt.timeOrder("fu.fuel_use_time") + ", fu.id ASC").
+		Find(&data).Error
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (adt *GormAdaptor) GetFuelRefillsByIDs(ctx context.Context, fuelRefillIDs []int64) ([]domains.FuelRefill, error) {
+	var fuelRefills []domains.FuelRefill
+	err := forUpdate(ctx, adt.dbOrTx(ctx), "fuel_refills").
+		Model(&domains.FuelRefill{}).
+		Where("id IN ?", fuelRefillIDs).
+		Order(adt.timeOrder("refill_time") + " ASC").
+		Find(&fuelRefills).Error
+	if err != nil {
+		return nil, err
+	}
+	return fuelRefills, nil
+}
+
+func (adt *GormAdaptor) CreateLedgerEntries(ctx context.Context, ledgerEntries []domains.LedgerEntry) error {
+	return adt.dbOrTx(ctx).
+		Create(&ledgerEntries).
+		Error
+}
+
+func (adt *GormAdaptor) GetLedgerNetAmountsByReference(ctx context.Context, referenceType string, referenceID int64) ([]services.LedgerNetAmount, error) {
+	var netAmounts []services.LedgerNetAmount
+	err := adt.dbOrTx(ctx).
+		Model(&domains.LedgerEntry{}).
+		Select("user_id, car_id, SUM(credit) - SUM(debit) AS net_amount").
+		Where(domains.LedgerEntry{
+			ReferenceType: referenceType,
+			ReferenceID:   referenceID,
+		}).
+		Group("user_id, car_id").
+		Find(&netAmounts).Error
+	if err != nil {
+		return nil, err
+	}
+	return netAmounts, nil
+}
+
+func (adt *GormAdaptor) GetUserLedgerBalances(ctx context.Context, userID int64) ([]services.UserCarLedgerBalance, error) {
+	var balances []services.UserCarLedgerBalance
+	err := adt.dbOrTx(ctx).
+		Table("ledger_entries AS le").
+		Select(`cars.id AS car_id,
+			cars.name AS car_name,
+			SUM(le.debit) AS total_debit,
+			SUM(le.credit) AS total_credit`).
+		Joins("INNER JOIN cars ON cars.id = le.car_id").
+		Where("le.user_id = ?", userID).
+		Group("cars.id, cars.name").
+		Order("cars.name ASC").
+		Find(&balances).Error
+	if err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
+
+// GetUnpaidFuelUsageUsers returns the unpaid shares of every user,
+// carID 0 means every car.
+func (adt *GormAdaptor) GetUnpaidFuelUsageUsers(ctx context.Context, carID int64) ([]services.FuelUsageUserWithFuelUsage, error) {
+	var data []services.FuelUsageUserWithFuelUsage
+	q := adt.dbOrTx(ctx).
+		Select(`fuu.*,
+			fu.fuel_use_time,
+			fu.description,
+			fu.driver_user_id,
+			fu.fare_rule,
+			fu.is_paid_from_wallet,
+			cars.id AS car_id,
+			cars.name AS car_name,
+			COALESCE(NULLIF(fr.refill_by, 0), cars.owner_user_id, 0) AS creditor_user_id`).
+		Table("fuel_usages AS fu").
+		Joins("INNER JOIN fuel_usage_users AS fuu ON fu.id = fuu.fuel_usage_id").
+		Joins("INNER JOIN cars ON cars.id = fu.car_id").
+		Joins("LEFT JOIN fuel_refills AS fr ON fr.id = fu.fuel_refill_id").
+		Where("fuu.is_paid = ?", false)
+
+	if carID != 0 {
+		q = q.Where("cars.id = ?", carID)
+	}
+
+	q = forUpdate(ctx, q, "fuu")
+	if err := q.Order(adt.timeOrder("fu.fuel_use_time") + ", fu.id ASC").Find(&data).Error; err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// GetUnpaidFuelRefills returns the unpaid refills of every user,
+// carID 0 means every car.
+func (adt *GormAdaptor) GetUnpaidFuelRefills(ctx context.Context, carID int64) ([]domains.FuelRefill, error) {
+	var unpaidFuelRefills []domains.FuelRefill
+	q := adt.dbOrTx(ctx).
+		Model(&domains.FuelRefill{}).
+		Where("fuel_refills.is_paid = false")
+
+	if carID != 0 {
+		q = q.Where("fuel_refills.car_id = ?", carID)
+	}
+
+	q = forUpdate(ctx, q, "fuel_refills")
+	if err := q.Order(adt.timeOrder("refill_time") + " ASC").Find(&unpaidFuelRefills).Error; err != nil {
+		return nil, err
+	}
+
+	return unpaidFuelRefills, nil
+}
+
+// GetUserCarIDs returns the cars the user owns or has a fuel usage share or
+// a refill on.
+func (adt *GormAdaptor) GetUserCarIDs(ctx context.Context, userID int64) ([]int64, error) {
+	var carIDs []int64
+	err := adt.dbOrTx(ctx).
+		Raw(`SELECT id FROM cars WHERE owner_user_id = ?
+			UNION
+			SELECT fu.car_id FROM fuel_usages AS fu
+			INNER JOIN fuel_usage_users AS fuu ON fu.id = fuu.fuel_usage_id
+			WHERE fuu.user_id = ?
+			UNION
+			SELECT car_id FROM fuel_refills WHERE refill_by = ?
+			ORDER BY 1`,
+			userID,
+			userID,
+			userID,
+		).
+		Scan(&carIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return carIDs, nil
+}
+
+func (adt *GormAdaptor) CreateDebtSettlement(ctx context.Context, debtSettlement domains.DebtSettlement) (int64, error) {
+	if err := adt.dbOrTx(ctx).Create(&debtSettlement).Error; err != nil {
+		return 0, err
+	}
+	return debtSettlement.ID, nil
+}
+
+func (adt *GormAdaptor) CreateDebtSettlementTransfers(ctx context.Context, transfers []domains.DebtSettlementTransfer) error {
+	return adt.dbOrTx(ctx).
+		Create(&transfers).
+		Error
+}
+
+func (adt *GormAdaptor) CreatePayment(ctx context.Context, payment domains.Payment) (int64, error) {
+	if err := adt.dbOrTx(ctx).Create(&payment).Error; err != nil {
+		return 0, err
+	}
+	return payment.ID, nil
+}
+
+func (adt *GormAdaptor) CreatePaymentAllocations(ctx context.Context, paymentAllocations []domains.PaymentAllocation) error {
+	return adt.dbOrTx(ctx).
+		Create(&paymentAllocations).
+		Error
+}
+
+// GetUserCarPayments returns the payments the user paid or received for
+// the car, the latest first.
+func (adt *GormAdaptor) GetUserCarPayments(ctx context.Context, userID int64, carID int64) ([]domains.Payment, error) {
+	var payments []domains.Payment
+	err := adt.dbOrTx(ctx).
+		Model(&domains.Payment{}).
+		Where("payer_user_id = ? OR payee_user_id = ?", userID, userID).
+		Where("car_id = ?", carID).
+		Order(adt.timeOrder("pay_time") + " DESC, id DESC").
+		Find(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+func (adt *GormAdaptor) GetPaymentAllocationsByPaymentIDs(ctx context.Context, paymentIDs []int64) ([]domains.PaymentAllocation, error) {
+	var paymentAllocations []domains.PaymentAllocation
+	err := adt.dbOrTx(ctx).
+		Model(&domains.PaymentAllocation{}).
+		Where("payment_id IN ?", paymentIDs).
+		Order("id ASC").
+		Find(&paymentAllocations).Error
+	if err != nil {
+		return nil, err
+	}
+	return paymentAllocations, nil
+}
+
+// AddFuelUsageUserPaidAmount adds to the money paid on the share, the share
+// becomes paid once it is covered. It is false when the share would be paid
+// or claimed more than it owes.
+func (adt *GormAdaptor) AddFuelUsageUserPaidAmount(ctx context.Context, fuelUsageUserID int64, amount decimal.Decimal) (bool, error) {
+	result := adt.dbOrTx(ctx).
+		Model(&domains.FuelUsageUser{}).
+		Where(domains.FuelUsageUser{
+			ID: fuelUsageUserID,
+		}).
+		Where(withinAmount("amount"), amount).
+		Updates(map[string]any{
+			"paid_amount": gorm.Expr("paid_amount + ?", amount),
+			"is_paid":     gorm.Expr("paid_amount + ? >= amount", amount),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// AddFuelRefillPaidAmount adds to the money reimbursed on the refill, the
+// refill becomes paid once it is covered. It is false when the refill would
+// be reimbursed or claimed more than it costs.
+func (adt *GormAdaptor) AddFuelRefillPaidAmount(ctx context.Context, fuelRefillID int64, amount decimal.Decimal) (bool, error) {
+	result := adt.dbOrTx(ctx).
+		Model(&domains.FuelRefill{}).
+		Where(domains.FuelRefill{
+			ID: fuelRefillID,
+		}).
+		Where(withinAmount("total_money"), amount).
+		Updates(map[string]any{
+			"paid_amount": gorm.Expr("paid_amount + ?", amount),
+			"is_paid":     gorm.Expr("paid_amount + ? >= total_money", amount),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// AddFuelUsageUserClaimedAmount adds to the money claimed paid on the
+// share, a negative amount takes the claim back. It is false when the share
+// would be paid or claimed more than it owes.
+func (adt *GormAdaptor) AddFuelUsageUserClaimedAmount(ctx context.Context, fuelUsageUserID int64, amount decimal.Decimal) (bool, error) {
+	result := adt.dbOrTx(ctx).
+		Model(&domains.FuelUsageUser{}).
+		Where(domains.FuelUsageUser{
+			ID: fuelUsageUserID,
+		}).
+		Where(withinAmount("amount"), amount).
+		Update("claimed_amount", gorm.Expr("claimed_amount + ?", amount))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// AddFuelRefillClaimedAmount adds to the money claimed reimbursed on the
+// refill, a negative amount takes the claim back. It is false when the
+// refill would be reimbursed or claimed more than it costs.
+func (adt *GormAdaptor) AddFuelRefillClaimedAmount(ctx context.Context, fuelRefillID int64, amount decimal.Decimal) (bool, error) {
+	result := adt.dbOrTx(ctx).
+		Model(&domains.FuelRefill{}).
+		Where(domains.FuelRefill{
+			ID: fuelRefillID,
+		}).
+		Where(withinAmount("total_money"), amount).
+		Update("claimed_amount", gorm.Expr("claimed_amount + ?", amount))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// withinAmount keeps what is paid and claimed within the amount column once
+// the given amount is added. It is rounded to the scale of the columns,
+// sqlite sums them as floats.
+func withinAmount(amountColumn string) string {
+	return "ROUND(paid_amount + claimed_amount + ?, 3) <= " + amountColumn
+}
+
+// forUpdate locks the rows of the table it reads until the transaction
+// ends, so the amounts read are still the ones updated. Outside of a
+// transaction there is nothing to hold the lock, and sqlite has no row
+// locks.
+func forUpdate(ctx context.Context, db *gorm.DB, table string) *gorm.DB {
+	if _, ok := ctx.Value(constants.WithTx).(*gorm.DB); !ok {
+		return db
+	}
+	return db.Clauses(clause.Locking{
+		Strength: clause.LockingStrengthUpdate,
+		Table:    clause.Table{Name: table},
+	})
+}
+
+func (adt *GormAdaptor) GetPaymentByID(ctx context.Context, paymentID int64) (*domains.Payment, error) {
+	var payment domains.Payment
+	err := adt.dbOrTx(ctx).
+		Model(&payment).
+		Where(domains.Payment{
+			ID: paymentID,
+		}).
+		First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// ReviewPendingPayment saves the review of the payment only while it is still
+// pending, false when someone has reviewed it already.
+func (adt *GormAdaptor) ReviewPendingPayment(ctx context.Context, payment domains.Payment) (bool, error) {
+	result := adt.dbOrTx(ctx).
+		Model(&domains.Payment{}).
+		Where("id = ? AND status = ?", payment.ID, domains.PaymentConfirmStatusPending).
+		Updates(map[string]any{
+			"status":        payment.Status,
+			"reject_reason": payment.RejectReason,
+			"review_by":     payment.ReviewBy,
+			"review_time":   payment.ReviewTime,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// GetUserPendingPayments returns the pending payments the user should
+// confirm or has made, the oldest first.
+func (adt *GormAdaptor) GetUserPendingPayments(ctx context.Context, userID int64) ([]domains.Payment, error) {
+	var payments []domains.Payment
+	err := adt.dbOrTx(ctx).
+		Model(&domains.Payment{}).
+		Where("confirm_user_id = ? OR create_by = ?", userID, userID).
+		Where("status = ?", domains.PaymentConfirmStatusPending).
+		Order(adt.timeOrder("pay_time") + " ASC, id ASC").
+		Find(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+func (adt *GormAdaptor) CreateWalletTransactions(ctx context.Context, walletTransactions []domains.WalletTransaction) error {
+	return adt.dbOrTx(ctx).
+		Create(&walletTransactions).
+		Error
+}
+
+func (adt *GormAdaptor) GetWalletNetAmountsByReference(ctx context.Context, referenceType string, referenceID int64) ([]services.WalletNetAmount, error) {
+	var netAmounts []services.WalletNetAmount
+	err := adt.dbOrTx(ctx).
+		Model(&domains.WalletTransaction{}).
+		Select("user_id, car_id, transaction_type, SUM(amount) AS net_amount").
+		Where(domains.WalletTransaction{
+			ReferenceType: referenceType,
+			ReferenceID:   referenceID,
+		}).
+		Group("user_id, car_id, transaction_type").
+		Find(&netAmounts).Error
+	if err != nil {
+		return nil, err
+	}
+	return netAmounts, nil
+}
+
+// GetCarWalletTransactions returns the transactions of the wallet of the
+// car, the oldest first.
+func (adt *GormAdaptor) GetCarWalletTransactions(ctx context.Context, carID int64) ([]domains.WalletTransaction, error) {
+	var walletTransactions []domains.WalletTransaction
+	err := adt.dbOrTx(ctx).
+		Model(&domains.WalletTransaction{}).
+		Where(domains.WalletTransaction{
+			CarID: carID,
+		}).
+		Order(adt.timeOrder("create_time") + " ASC, id ASC").
+		Find(&walletTransactions).Error
+	if err != nil {
+		return nil, err
+	}
+	return walletTransactions, nil
+}
+
+func (adt *GormAdaptor) CreateAdjustments(ctx context.Context, adjustments []domains.Adjustment) error {
+	return adt.dbOrTx(ctx).
+		Create(&adjustments).
+		Error
+}
+
+// GetAdjustmentsByReference returns the adjustments of a fuel usage or a
+// refill in the order they were made.
+func (adt *GormAdaptor) GetAdjustmentsByReference(ctx context.Context, referenceType string, referenceID int64) ([]domains.Adjustment, error) {
+	var adjustments []domains.Adjustment
+	err := adt.dbOrTx(ctx).
+		Model(&domains.Adjustment{}).
+		Where(domains.Adjustment{
+			ReferenceType: referenceType,
+			ReferenceID:   referenceID,
+		}).
+		Order("id ASC").
+		Find(&adjustments).Error
+	if err != nil {
+		return nil, err
+	}
+	return adjustments, nil
+}
+
+// paidAmountExpr pays the column in full or clears what was paid.
+func paidAmountExpr(isPaid bool, amountColumn string) clause.Expr {
+	if !isPaid {
+		return gorm.Expr("0")
+	}
+	return gorm.Expr(amountColumn)
+}
+
+func uniqueIDs(ids []int64) []int64 {
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	return slices.Compact(sorted)
+}
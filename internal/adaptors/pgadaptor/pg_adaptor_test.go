@@ -0,0 +1,148 @@
+package pgadaptor
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestAdaptor runs the adaptor against an in-memory database holding
+// two cars, two users and their fuel usage shares and refills.
+func newTestAdaptor(t *testing.T) *PostgresAdaptor {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlStatements := []string{
+		`CREATE TABLE fuel_usages (id INTEGER PRIMARY KEY, car_id BIGINT NOT NULL);`,
+		`CREATE TABLE fuel_usage_users (id INTEGER PRIMARY KEY, fuel_usage_id BIGINT NOT NULL, user_id BIGINT NOT NULL, is_paid BOOL);`,
+		`CREATE TABLE fuel_refills (id INTEGER PRIMARY KEY, car_id BIGINT NOT NULL, refill_by BIGINT, is_paid BOOL);`,
+		`INSERT INTO fuel_usages (id, car_id) VALUES (1, 1), (2, 2);`,
+		`INSERT INTO fuel_usage_users (id, fuel_usage_id, user_id, is_paid) VALUES
+			(1, 1, 1, false),
+			(2, 1, 2, false),
+			(3, 2, 1, false);`,
+		`INSERT INTO fuel_refills (id, car_id, refill_by, is_paid) VALUES
+			(1, 1, 1, false),
+			(2, 1, 2, false),
+			(3, 2, 1, false);`,
+	}
+	for _, sqlStatement := range sqlStatements {
+		if err := db.Exec(sqlStatement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewPostgresAdaptor(db)
+}
+
+func TestPostgresAdaptor_IsUserOwnAllFuelUsageUser(t *testing.T) {
+	adt := newTestAdaptor(t)
+	tests := []struct {
+		name             string
+		userID           int64
+		carID            int64
+		fuelUsageUserIDs []int64
+		want             bool
+	}{
+		{
+			name:             "own share",
+			userID:           1,
+			carID:            1,
+			fuelUsageUserIDs: []int64{1},
+			want:             true,
+		},
+		{
+			name:             "duplicate ids of own share",
+			userID:           1,
+			carID:            1,
+			fuelUsageUserIDs: []int64{1, 1},
+			want:             true,
+		},
+		{
+			name:             "no share",
+			userID:           1,
+			carID:            1,
+			fuelUsageUserIDs: nil,
+			want:             true,
+		},
+		{
+			name:             "share of another user",
+			userID:           1,
+			carID:            1,
+			fuelUsageUserIDs: []int64{1, 2},
+			want:             false,
+		},
+		{
+			name:             "own share of another car",
+			userID:           1,
+			carID:            1,
+			fuelUsageUserIDs: []int64{1, 3},
+			want:             false,
+		},
+		{
+			name:             "not exist share",
+			userID:           1,
+			carID:            1,
+			fuelUsageUserIDs: []int64{99},
+			want:             false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := adt.IsUserOwnAllFuelUsageUser(context.Background(), tt.userID, tt.carID, tt.fuelUsageUserIDs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("IsUserOwnAllFuelUsageUser() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresAdaptor_IsUserOwnAllFuelRefills(t *testing.T) {
+	adt := newTestAdaptor(t)
+	tests := []struct {
+		name          string
+		userID        int64
+		carID         int64
+		fuelRefillIDs []int64
+		want          bool
+	}{
+		{
+			name:          "own refill",
+			userID:        1,
+			carID:         1,
+			fuelRefillIDs: []int64{1},
+			want:          true,
+		},
+		{
+			name:          "refill of another user",
+			userID:        1,
+			carID:         1,
+			fuelRefillIDs: []int64{1, 2},
+			want:          false,
+		},
+		{
+			name:          "own refill of another car",
+			userID:        1,
+			carID:         1,
+			fuelRefillIDs: []int64{3},
+			want:          false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := adt.IsUserOwnAllFuelRefills(context.Background(), tt.userID, tt.carID, tt.fuelRefillIDs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("IsUserOwnAllFuelRefills() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,477 @@
+package sqliteadaptor
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestAdaptor runs the adaptor against the database of newTestDB.
+func newTestAdaptor(t *testing.T) *SQLiteAdaptor {
+	t.Helper()
+	return NewSQLiteAdaptor(newTestDB(t))
+}
+
+// newTestDB is an in-memory database holding two cars, two users and their
+// fuel usage shares and refills.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlStatements := []string{
+		`CREATE TABLE cars (id INTEGER PRIMARY KEY, name VARCHAR(255), plate_number VARCHAR(50), fuel_type VARCHAR(50), tank_capacity DECIMAL(10,3), owner_user_id BIGINT, extra_per_km DECIMAL(10,3), trip_fee DECIMAL(10,3), minimum_charge DECIMAL(10,3), is_rounded_up BOOL NOT NULL DEFAULT false, fare_rule VARCHAR(20) NOT NULL DEFAULT 'NONE', is_wallet_enabled BOOL NOT NULL DEFAULT false, is_archived BOOL NOT NULL DEFAULT false, create_time DATETIME, update_time DATETIME);`,
+		`CREATE TABLE fuel_usages (id INTEGER PRIMARY KEY, car_id BIGINT NOT NULL, fuel_use_time DATETIME, description VARCHAR(255), driver_user_id BIGINT NOT NULL DEFAULT 0, fare_rule VARCHAR(20) NOT NULL DEFAULT 'NONE', fuel_refill_id BIGINT NOT NULL DEFAULT 0, is_paid_from_wallet BOOL NOT NULL DEFAULT false);`,
+		`CREATE TABLE fuel_usage_users (id INTEGER PRIMARY KEY, fuel_usage_id BIGINT NOT NULL, user_id BIGINT NOT NULL, is_paid BOOL, split_value DECIMAL(10,3) NOT NULL DEFAULT 0, fare_weight DECIMAL(10,3) NOT NULL DEFAULT 1, amount DECIMAL(10,3) NOT NULL DEFAULT 0, paid_amount DECIMAL(10,3) NOT NULL DEFAULT 0, claimed_amount DECIMAL(10,3) NOT NULL DEFAULT 0);`,
+		`CREATE TABLE fuel_refills (id INTEGER PRIMARY KEY, car_id BIGINT NOT NULL, refill_time DATETIME, refill_by BIGINT, total_money DECIMAL(10,3) NOT NULL DEFAULT 0, is_paid BOOL, paid_amount DECIMAL(10,3) NOT NULL DEFAULT 0, claimed_amount DECIMAL(10,3) NOT NULL DEFAULT 0, is_paid_from_wallet BOOL NOT NULL DEFAULT false);`,
+		`CREATE TABLE wallet_transactions (id INTEGER PRIMARY KEY, car_id BIGINT NOT NULL, user_id BIGINT NOT NULL DEFAULT 0, transaction_type VARCHAR(20) NOT NULL, reference_type VARCHAR(20) NOT NULL DEFAULT '', reference_id BIGINT NOT NULL DEFAULT 0, amount DECIMAL(10,3) NOT NULL, description VARCHAR(255) NOT NULL DEFAULT '', create_time DATETIME);`,
+		`CREATE TABLE adjustments (id INTEGER PRIMARY KEY, car_id BIGINT NOT NULL, reference_type VARCHAR(20) NOT NULL, reference_id BIGINT NOT NULL, user_id BIGINT NOT NULL, old_amount DECIMAL(10,3) NOT NULL, new_amount DECIMAL(10,3) NOT NULL, reason VARCHAR(255) NOT NULL, create_by BIGINT NOT NULL, create_time DATETIME);`,
+		`CREATE TABLE payments (id INTEGER PRIMARY KEY, car_id BIGINT NOT NULL, payer_user_id BIGINT NOT NULL DEFAULT 0, payee_user_id BIGINT NOT NULL DEFAULT 0, amount DECIMAL(10,3) NOT NULL, method VARCHAR(20) NOT NULL, pay_time DATETIME, status VARCHAR(20) NOT NULL, confirm_user_id BIGINT NOT NULL DEFAULT 0, reject_reason VARCHAR(255) NOT NULL DEFAULT '', review_by BIGINT NOT NULL DEFAULT 0, review_time DATETIME, create_by BIGINT NOT NULL, create_time DATETIME);`,
+		`INSERT INTO cars (id, name) VALUES (1, 'Mazda 2'), (2, 'Ford');`,
+		`INSERT INTO fuel_usages (id, car_id, fuel_use_time) VALUES
+			(1, 1, '2024-02-02 10:00:00+07:00'),
+			(2, 2, '2024-02-01 10:00:00+07:00');`,
+		`INSERT INTO fuel_usage_users (id, fuel_usage_id, user_id, is_paid, amount) VALUES
+			(1, 1, 1, false, 50),
+			(2, 1, 2, false, 50),
+			(3, 2, 1, false, 20);`,
+		`INSERT INTO fuel_refills (id, car_id, refill_time, refill_by, total_money, is_paid) VALUES
+			(1, 1, '2024-02-03 10:00:00+07:00', 1, 500, false),
+			(2, 1, '2024-02-01 10:00:00+07:00', 2, 600, false),
+			(3, 2, '2024-02-01 10:00:00+07:00', 1, 700, false);`,
+	}
+	for _, sqlStatement := range sqlStatements {
+		if err := db.Exec(sqlStatement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestSQLiteAdaptor_PayFuelUsageUsers(t *testing.T) {
+	adt := newTestAdaptor(t)
+	ctx := context.Background()
+
+	unpaid, err := adt.GetUserFuelUsagesByPaidStatus(ctx, 1, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unpaid) != 2 || unpaid[0].ID != 3 || unpaid[1].ID != 1 {
+		t.Fatalf("unpaid shares should be sorted by fuel use time, got %+v", unpaid)
+	}
+	if unpaid[1].CarName != "Mazda 2" || !unpaid[1].Amount.Equal(decimal.NewFromInt(50)) {
+		t.Errorf("unexpected share %+v", unpaid[1])
+	}
+
+	if err := adt.PayFuelUsageUsers(ctx, []int64{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	unpaid, err = adt.GetUserFuelUsagesByPaidStatus(ctx, 1, false, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unpaid) != 0 {
+		t.Errorf("got %d unpaid shares of car 1, want 0", len(unpaid))
+	}
+}
+
+func TestSQLiteAdaptor_PayFuelRefills(t *testing.T) {
+	adt := newTestAdaptor(t)
+	ctx := context.Background()
+
+	unpaid, err := adt.GetUnpaidFuelRefills(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unpaid) != 2 || unpaid[0].ID != 2 || unpaid[1].ID != 1 {
+		t.Fatalf("unpaid refills should be sorted by refill time, got %+v", unpaid)
+	}
+
+	if err := adt.PayFuelRefills(ctx, []int64{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	userUnpaid, err := adt.GetUserUnpaidFuelRefills(ctx, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(userUnpaid) != 0 {
+		t.Errorf("got %d unpaid refills, want 0", len(userUnpaid))
+	}
+}
+
+func TestSQLiteAdaptor_AddFuelUsageUserPaidAmount(t *testing.T) {
+	adt := newTestAdaptor(t)
+	ctx := context.Background()
+
+	if _, err := adt.AddFuelUsageUserPaidAmount(ctx, 1, decimal.NewFromInt(20)); err != nil {
+		t.Fatal(err)
+	}
+	shares, err := adt.GetFuelUsageUsersWithFuelUsageByIDs(ctx, []int64{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != 1 || shares[0].IsPaid || !shares[0].PaidAmount.Equal(decimal.NewFromInt(20)) {
+		t.Fatalf("share should be paid 20 in part, got %+v", shares)
+	}
+
+	if _, err := adt.AddFuelUsageUserPaidAmount(ctx, 1, decimal.NewFromInt(30)); err != nil {
+		t.Fatal(err)
+	}
+	shares, err = adt.GetFuelUsageUsersWithFuelUsageByIDs(ctx, []int64{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != 1 || !shares[0].IsPaid || !shares[0].PaidAmount.Equal(decimal.NewFromInt(50)) {
+		t.Errorf("share should be paid in full, got %+v", shares)
+	}
+
+	isAdded, err := adt.AddFuelUsageUserPaidAmount(ctx, 1, decimal.RequireFromString("0.01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isAdded {
+		t.Errorf("a paid share should not be paid more")
+	}
+}
+
+func TestSQLiteAdaptor_AddFuelRefillPaidAmount(t *testing.T) {
+	adt := newTestAdaptor(t)
+	ctx := context.Background()
+
+	if _, err := adt.AddFuelRefillPaidAmount(ctx, 1, decimal.NewFromInt(200)); err != nil {
+		t.Fatal(err)
+	}
+	unpaid, err := adt.GetUserUnpaidFuelRefills(ctx, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unpaid) != 1 || !unpaid[0].PaidAmount.Equal(decimal.NewFromInt(200)) {
+		t.Fatalf("refill should be paid 200 in part, got %+v", unpaid)
+	}
+
+	if _, err := adt.AddFuelRefillPaidAmount(ctx, 1, decimal.NewFromInt(300)); err != nil {
+		t.Fatal(err)
+	}
+	unpaid, err = adt.GetUserUnpaidFuelRefills(ctx, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unpaid) != 0 {
+		t.Errorf("got %d unpaid refills, want 0", len(unpaid))
+	}
+}
+
+func TestSQLiteAdaptor_AddFuelUsageUserClaimedAmount(t *testing.T) {
+	adt := newTestAdaptor(t)
+	ctx := context.Background()
+
+	if _, err := adt.AddFuelUsageUserClaimedAmount(ctx, 1, decimal.NewFromInt(50)); err != nil {
+		t.Fatal(err)
+	}
+	shares, err := adt.GetFuelUsageUsersWithFuelUsageByIDs(ctx, []int64{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != 1 || shares[0].IsPaid || !shares[0].ClaimedAmount.Equal(decimal.NewFromInt(50)) {
+		t.Fatalf("share should be claimed but unpaid, got %+v", shares)
+	}
+
+	// what is claimed cannot be paid again
+	isAdded, err := adt.AddFuelUsageUserPaidAmount(ctx, 1, decimal.NewFromInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isAdded {
+		t.Errorf("a claimed share should not be paid over what it owes")
+	}
+
+	if _, err := adt.AddFuelUsageUserClaimedAmount(ctx, 1, decimal.NewFromInt(-50)); err != nil {
+		t.Fatal(err)
+	}
+	shares, err = adt.GetFuelUsageUsersWithFuelUsageByIDs(ctx, []int64{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != 1 || !shares[0].ClaimedAmount.IsZero() {
+		t.Errorf("claim should be taken back, got %+v", shares)
+	}
+}
+
+func TestSQLiteAdaptor_GetFuelUsageUsersWithFuelUsageByIDs_creditor(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	sqlStatements := []string{
+		`UPDATE cars SET owner_user_id = 3 WHERE id = 2;`,
+		`UPDATE fuel_usages SET fuel_refill_id = 2 WHERE id = 1;`,
+	}
+	for _, sqlStatement := range sqlStatements {
+		if err := db.Exec(sqlStatement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	adt := NewSQLiteAdaptor(db)
+
+	shares, err := adt.GetFuelUsageUsersWithFuelUsageByIDs(ctx, []int64{1, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idToCreditorUserID := make(map[int64]int64)
+	for _, share := range shares {
+		idToCreditorUserID[share.ID] = share.CreditorUserID
+	}
+	if idToCreditorUserID[1] != 2 {
+		t.Errorf("creditor of share 1 = %d, want the refiller 2", idToCreditorUserID[1])
+	}
+	if idToCreditorUserID[3] != 3 {
+		t.Errorf("creditor of share 3 = %d, want the owner 3", idToCreditorUserID[3])
+	}
+}
+
+func TestSQLiteAdaptor_WalletTransactions(t *testing.T) {
+	adt := newTestAdaptor(t)
+	ctx := context.Background()
+
+	day := func(d int) time.Time {
+		return time.Date(2024, 2, d, 10, 0, 0, 0, time.Local)
+	}
+	err := adt.CreateWalletTransactions(ctx, []domains.WalletTransaction{
+		{CarID: 1, UserID: 1, TransactionType: domains.WalletTransactionTypeTopUp, Amount: decimal.NewFromInt(500), CreateTime: day(1)},
+		{CarID: 1, UserID: 1, TransactionType: domains.WalletTransactionTypeFuelUsageShare, ReferenceType: domains.WalletReferenceTypeFuelUsage, ReferenceID: 1, Amount: decimal.NewFromInt(50), CreateTime: day(3)},
+		{CarID: 1, UserID: 2, TransactionType: domains.WalletTransactionTypeFuelUsageShare, ReferenceType: domains.WalletReferenceTypeFuelUsage, ReferenceID: 1, Amount: decimal.NewFromInt(50), CreateTime: day(3)},
+		{CarID: 1, UserID: 1, TransactionType: domains.WalletTransactionTypeFuelUsageShare, ReferenceType: domains.WalletReferenceTypeFuelUsage, ReferenceID: 1, Amount: decimal.NewFromInt(-50), CreateTime: day(4)},
+		{CarID: 2, UserID: 1, TransactionType: domains.WalletTransactionTypeTopUp, Amount: decimal.NewFromInt(100), CreateTime: day(2)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	netAmounts, err := adt.GetWalletNetAmountsByReference(ctx, domains.WalletReferenceTypeFuelUsage, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userIDToNetAmount := make(map[int64]decimal.Decimal)
+	for _, netAmount := range netAmounts {
+		userIDToNetAmount[netAmount.UserID] = netAmount.NetAmount
+	}
+	if !userIDToNetAmount[1].IsZero() || !userIDToNetAmount[2].Equal(decimal.NewFromInt(50)) {
+		t.Errorf("net amounts = %+v, want 0 for user 1 and 50 for user 2", netAmounts)
+	}
+
+	walletTransactions, err := adt.GetCarWalletTransactions(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(walletTransactions) != 4 {
+		t.Fatalf("got %d transactions, want the 4 of car 1", len(walletTransactions))
+	}
+	if walletTransactions[0].TransactionType != domains.WalletTransactionTypeTopUp {
+		t.Errorf("first transaction = %+v, want the oldest top up", walletTransactions[0])
+	}
+}
+
+func TestSQLiteAdaptor_Adjustments(t *testing.T) {
+	adt := newTestAdaptor(t)
+	ctx := context.Background()
+
+	adjustments := []domains.Adjustment{
+		{CarID: 1, ReferenceType: domains.AdjustmentReferenceTypeFuelUsage, ReferenceID: 1, UserID: 1, OldAmount: decimal.NewFromInt(50), NewAmount: decimal.NewFromInt(40), Reason: "wrong kilometers", CreateBy: 1, CreateTime: time.Now()},
+		{CarID: 1, ReferenceType: domains.AdjustmentReferenceTypeFuelUsage, ReferenceID: 1, UserID: 1, OldAmount: decimal.NewFromInt(40), NewAmount: decimal.NewFromInt(45), Reason: "wrong kilometers", CreateBy: 1, CreateTime: time.Now()},
+		{CarID: 1, ReferenceType: domains.AdjustmentReferenceTypeFuelRefill, ReferenceID: 1, UserID: 1, OldAmount: decimal.NewFromInt(500), NewAmount: decimal.NewFromInt(550), Reason: "receipt was misread", CreateBy: 1, CreateTime: time.Now()},
+	}
+	if err := adt.CreateAdjustments(ctx, adjustments); err != nil {
+		t.Fatal(err)
+	}
+	if adjustments[2].ID == 0 {
+		t.Fatalf("adjustments = %+v, want their ids", adjustments)
+	}
+
+	got, err := adt.GetAdjustmentsByReference(ctx, domains.AdjustmentReferenceTypeFuelUsage, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !got[1].NewAmount.Equal(decimal.NewFromInt(45)) {
+		t.Errorf("adjustments = %+v, want the 2 of the fuel usage in order", got)
+	}
+}
+
+func TestSQLiteAdaptor_ReviewPendingPayment(t *testing.T) {
+	adt := newTestAdaptor(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	paymentID, err := adt.CreatePayment(ctx, domains.Payment{
+		CarID:         1,
+		PayerUserID:   1,
+		PayeeUserID:   2,
+		Amount:        decimal.NewFromInt(50),
+		Method:        domains.PaymentMethodCash,
+		PayTime:       now,
+		Status:        domains.PaymentConfirmStatusPending,
+		ConfirmUserID: 2,
+		CreateBy:      1,
+		CreateTime:    now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	review := domains.Payment{ID: paymentID, Status: domains.PaymentConfirmStatusConfirmed, ReviewBy: 2, ReviewTime: &now}
+	isReviewed, err := adt.ReviewPendingPayment(ctx, review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isReviewed {
+		t.Fatal("ReviewPendingPayment() = false, want the pending payment reviewed")
+	}
+
+	review.Status = domains.PaymentConfirmStatusRejected
+	isReviewed, err = adt.ReviewPendingPayment(ctx, review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isReviewed {
+		t.Error("ReviewPendingPayment() = true, want a reviewed payment left as it is")
+	}
+
+	payment, err := adt.GetPaymentByID(ctx, paymentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.Status != domains.PaymentConfirmStatusConfirmed || payment.ReviewBy != 2 {
+		t.Errorf("payment = %+v, want confirmed by 2", payment)
+	}
+}
+
+func TestSQLiteAdaptor_IsUserOwnAllFuelUsageUser(t *testing.T) {
+	adt := newTestAdaptor(t)
+	tests := []struct {
+		name             string
+		carID            int64
+		fuelUsageUserIDs []int64
+		want             bool
+	}{
+		{
+			name:             "own share",
+			carID:            1,
+			fuelUsageUserIDs: []int64{1, 1},
+			want:             true,
+		},
+		{
+			name:             "share of another user",
+			carID:            1,
+			fuelUsageUserIDs: []int64{1, 2},
+			want:             false,
+		},
+		{
+			name:             "own share of another car",
+			carID:            1,
+			fuelUsageUserIDs: []int64{3},
+			want:             false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := adt.IsUserOwnAllFuelUsageUser(context.Background(), 1, tt.carID, tt.fuelUsageUserIDs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("IsUserOwnAllFuelUsageUser() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLiteAdaptor_GetUserCarIDs(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.Exec(`UPDATE cars SET owner_user_id = 3 WHERE id = 2`).Error; err != nil {
+		t.Fatal(err)
+	}
+	adt := NewSQLiteAdaptor(db)
+	tests := []struct {
+		name   string
+		userID int64
+		want   []int64
+	}{
+		{
+			name:   "shares and refills on both cars",
+			userID: 1,
+			want:   []int64{1, 2},
+		},
+		{
+			name:   "share and refill on one car",
+			userID: 2,
+			want:   []int64{1},
+		},
+		{
+			name:   "owner only",
+			userID: 3,
+			want:   []int64{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := adt.GetUserCarIDs(context.Background(), tt.userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("GetUserCarIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLiteAdaptor_Car(t *testing.T) {
+	adt := newTestAdaptor(t)
+	ctx := context.Background()
+
+	carID, err := adt.CreateCar(ctx, domains.Car{
+		Name:         "Honda City",
+		FuelType:     domains.CarFuelTypeGasohol95,
+		TankCapacity: decimal.NewFromInt(40),
+		OwnerUserID:  1,
+		PricingPolicy: domains.CarPricingPolicy{
+			ExtraPerKm:  decimal.RequireFromString("0.5"),
+			IsRoundedUp: true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	car, err := adt.GetCarByID(ctx, carID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	car.IsArchived = true
+	if err := adt.UpdateCar(ctx, *car); err != nil {
+		t.Fatal(err)
+	}
+
+	car, err = adt.GetCarByID(ctx, carID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if car.Name != "Honda City" || !car.TankCapacity.Equal(decimal.NewFromInt(40)) || !car.IsArchived {
+		t.Errorf("unexpected car %+v", car)
+	}
+	if !car.PricingPolicy.ExtraPerKm.Equal(decimal.RequireFromString("0.5")) || !car.PricingPolicy.IsRoundedUp {
+		t.Errorf("unexpected pricing policy %+v", car.PricingPolicy)
+	}
+
+	if _, err := adt.GetCarByID(ctx, carID+1); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetCarByID() of missing car err = %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+}
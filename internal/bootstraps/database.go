@@ -0,0 +1,96 @@
+package bootstraps
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bosskrub9992/fuel-management-backend/config"
+	"github.com/bosskrub9992/fuel-management-backend/internal/adaptors/pgadaptor"
+	"github.com/bosskrub9992/fuel-management-backend/internal/adaptors/sqliteadaptor"
+	"github.com/bosskrub9992/fuel-management-backend/internal/migrations"
+	"github.com/bosskrub9992/fuel-management-backend/internal/migrations/mgpostgres"
+	"github.com/bosskrub9992/fuel-management-backend/internal/migrations/mgsqlite"
+	"github.com/bosskrub9992/fuel-management-backend/internal/services"
+	"github.com/bosskrub9992/fuel-management-backend/library/databases"
+	"gorm.io/gorm"
+)
+
+const (
+	DatabasePostgres = "postgres"
+	DatabaseSQLite   = "sqlite"
+)
+
+// Database is the database chosen by config Database.Use together with the
+// adaptor and the migrations written for it, so every entry point targets
+// the same database.
+type Database struct {
+	Use        string
+	DB         *gorm.DB
+	Adaptor    services.DatabaseAdaptor
+	Migrations []migrations.Migration
+}
+
+// NewDatabase connects to the database chosen by cfg.Database.Use and
+// returns an error on an unknown value or an unreachable database.
+func NewDatabase(cfg *config.Config) (*Database, error) {
+	use := strings.ToLower(cfg.Database.Use)
+
+	var database Database
+	switch use {
+	case DatabasePostgres:
+		sqlDB, err := databases.NewPostgres(&cfg.Database.Postgres)
+		if err != nil {
+			return nil, err
+		}
+		gormDB, err := databases.NewGormDBPostgres(sqlDB, gorm.Config{})
+		if err != nil {
+			return nil, err
+		}
+		database = Database{
+			Use:     use,
+			DB:      gormDB,
+			Adaptor: pgadaptor.NewPostgresAdaptor(gormDB),
+		}
+		for _, migration := range mgpostgres.Migrations {
+			database.Migrations = append(database.Migrations, migrations.Migration(migration))
+		}
+	case DatabaseSQLite:
+		gormDB, err := databases.NewGormDBSqlite(cfg.Database.SQLite.FilePath, gorm.Config{})
+		if err != nil {
+			return nil, err
+		}
+		database = Database{
+			Use:     use,
+			DB:      gormDB,
+			Adaptor: sqliteadaptor.NewSQLiteAdaptor(gormDB),
+		}
+		for _, migration := range mgsqlite.Migrations {
+			database.Migrations = append(database.Migrations, migrations.Migration(migration))
+		}
+	default:
+		return nil, fmt.Errorf("unknown database.use %q, should be %q or %q",
+			cfg.Database.Use,
+			DatabasePostgres,
+			DatabaseSQLite,
+		)
+	}
+
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+	if err := sqlDB.Ping(); err != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("cannot connect to %s database: %w", use, err)
+	}
+
+	return &database, nil
+}
+
+func (d *Database) Close() error {
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -0,0 +1,41 @@
+package bootstraps
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bosskrub9992/fuel-management-backend/config"
+	"github.com/bosskrub9992/fuel-management-backend/internal/adaptors/sqliteadaptor"
+)
+
+func TestNewDatabase(t *testing.T) {
+	t.Run("unknown database", func(t *testing.T) {
+		var cfg config.Config
+		cfg.Database.Use = "mysql"
+		if _, err := NewDatabase(&cfg); err == nil {
+			t.Error("expected an error on unknown database.use")
+		}
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		var cfg config.Config
+		cfg.Database.Use = "SQLite"
+		cfg.Database.SQLite.FilePath = filepath.Join(t.TempDir(), "fuel.db")
+
+		database, err := NewDatabase(&cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer database.Close()
+
+		if database.Use != DatabaseSQLite {
+			t.Errorf("Use = %q, want %q", database.Use, DatabaseSQLite)
+		}
+		if _, ok := database.Adaptor.(*sqliteadaptor.SQLiteAdaptor); !ok {
+			t.Errorf("Adaptor = %T, want *sqliteadaptor.SQLiteAdaptor", database.Adaptor)
+		}
+		if len(database.Migrations) == 0 {
+			t.Error("expected the sqlite migrations")
+		}
+	})
+}
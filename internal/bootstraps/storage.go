@@ -0,0 +1,27 @@
+package bootstraps
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bosskrub9992/fuel-management-backend/config"
+	"github.com/bosskrub9992/fuel-management-backend/internal/services"
+	"github.com/bosskrub9992/fuel-management-backend/library/storages"
+)
+
+const (
+	StorageLocal = "local"
+)
+
+// NewStorage returns the file storage chosen by cfg.Storage.Use.
+func NewStorage(cfg *config.Config) (services.FileStorage, error) {
+	switch strings.ToLower(cfg.Storage.Use) {
+	case StorageLocal:
+		return storages.NewLocal(&cfg.Storage.Local)
+	default:
+		return nil, fmt.Errorf("unknown storage.use %q, should be %q",
+			cfg.Storage.Use,
+			StorageLocal,
+		)
+	}
+}
@@ -0,0 +1,32 @@
+package bootstraps
+
+import (
+	"testing"
+
+	"github.com/bosskrub9992/fuel-management-backend/config"
+	"github.com/bosskrub9992/fuel-management-backend/library/storages"
+)
+
+func TestNewStorage(t *testing.T) {
+	t.Run("unknown storage", func(t *testing.T) {
+		var cfg config.Config
+		cfg.Storage.Use = "s3"
+		if _, err := NewStorage(&cfg); err == nil {
+			t.Error("expected an error on unknown storage.use")
+		}
+	})
+
+	t.Run("local", func(t *testing.T) {
+		var cfg config.Config
+		cfg.Storage.Use = "Local"
+		cfg.Storage.Local.Dir = t.TempDir()
+
+		storage, err := NewStorage(&cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := storage.(*storages.Local); !ok {
+			t.Errorf("storage = %T, want *storages.Local", storage)
+		}
+	})
+}
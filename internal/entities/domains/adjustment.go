@@ -0,0 +1,39 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	AdjustmentReferenceTypeFuelUsage  = "FUEL_USAGE"
+	AdjustmentReferenceTypeFuelRefill = "FUEL_REFILL"
+)
+
+// Adjustment corrects the amount of a user on a paid fuel usage or refill,
+// which is locked from edits. The share or the refill takes the new amount
+// and keeps what was paid, and the difference is posted to the ledger.
+type Adjustment struct {
+	ID            int64  `gorm:"column:id"`
+	CarID         int64  `gorm:"column:car_id"`
+	ReferenceType string `gorm:"column:reference_type"`
+	ReferenceID   int64  `gorm:"column:reference_id"`
+	UserID        int64  `gorm:"column:user_id"`
+	// OldAmount is the amount in effect before the adjustment, the share of
+	// a fuel user or the money fronted by a refiller
+	OldAmount  decimal.Decimal `gorm:"column:old_amount"`
+	NewAmount  decimal.Decimal `gorm:"column:new_amount"`
+	Reason     string          `gorm:"column:reason"`
+	CreateBy   int64           `gorm:"column:create_by"`
+	CreateTime time.Time       `gorm:"column:create_time"`
+}
+
+func (d Adjustment) TableName() string {
+	return "adjustments"
+}
+
+// Difference is how much the amount grows, negative when it shrinks.
+func (d Adjustment) Difference() decimal.Decimal {
+	return d.NewAmount.Sub(d.OldAmount)
+}
@@ -1,10 +1,46 @@
 package domains
 
-import "time"
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	CarFuelTypeGasohol91 = "GASOHOL_91"
+	CarFuelTypeGasohol95 = "GASOHOL_95"
+	CarFuelTypeE20       = "E20"
+	CarFuelTypeE85       = "E85"
+	CarFuelTypeBenzine   = "BENZINE"
+	CarFuelTypeDiesel    = "DIESEL"
+)
+
+const (
+	CarFareRuleNone       = "NONE"
+	CarFareRuleDriverFree = "DRIVER_FREE"
+	CarFareRuleDriverHalf = "DRIVER_HALF"
+	CarFareRuleOwnerFree  = "OWNER_FREE"
+)
 
 type Car struct {
-	ID         int64     `gorm:"column:id"`
-	Name       string    `gorm:"column:name"`
+	ID          int64  `gorm:"column:id"`
+	Name        string `gorm:"column:name"`
+	PlateNumber string `gorm:"column:plate_number"`
+	FuelType    string `gorm:"column:fuel_type"`
+	// TankCapacity is in liters
+	TankCapacity decimal.Decimal `gorm:"column:tank_capacity"`
+	// OwnerUserID is 0 when the car has no owner
+	OwnerUserID   int64            `gorm:"column:owner_user_id"`
+	PricingPolicy CarPricingPolicy `gorm:"embedded"`
+	// FareRule lowers the share of the driver or the owner in the equal
+	// and share splits of the car
+	FareRule string `gorm:"column:fare_rule"`
+	// IsWalletEnabled pays the new fuel usages and refills of the car from
+	// its wallet
+	IsWalletEnabled bool `gorm:"column:is_wallet_enabled"`
+	// IsArchived hides the car from new usages and refills while its
+	// history keeps resolving the car name
+	IsArchived bool      `gorm:"column:is_archived"`
 	CreateTime time.Time `gorm:"column:create_time"`
 	UpdateTime time.Time `gorm:"column:update_time"`
 }
@@ -12,3 +48,16 @@ type Car struct {
 func (d Car) TableName() string {
 	return "cars"
 }
+
+// CarPricingPolicy is charged on top of the fuel cost of every fuel usage of
+// the car, to recover wear and depreciation.
+type CarPricingPolicy struct {
+	// ExtraPerKm is in baht per kilometer used
+	ExtraPerKm decimal.Decimal `gorm:"column:extra_per_km"`
+	// TripFee is in baht per fuel usage
+	TripFee decimal.Decimal `gorm:"column:trip_fee"`
+	// MinimumCharge is the least total money of a fuel usage, 0 for none
+	MinimumCharge decimal.Decimal `gorm:"column:minimum_charge"`
+	// IsRoundedUp rounds the total money up to the nearest baht
+	IsRoundedUp bool `gorm:"column:is_rounded_up"`
+}
@@ -0,0 +1,33 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// DebtSettlement settles the unpaid records of the whole group with the
+// least transfers, car id 0 means every car was included.
+type DebtSettlement struct {
+	ID              int64           `gorm:"column:id"`
+	CarID           int64           `gorm:"column:car_id"`
+	UnsettledAmount decimal.Decimal `gorm:"column:unsettled_amount"`
+	CreateBy        int64           `gorm:"column:create_by"`
+	CreateTime      time.Time       `gorm:"column:create_time"`
+}
+
+func (d DebtSettlement) TableName() string {
+	return "debt_settlements"
+}
+
+type DebtSettlementTransfer struct {
+	ID               int64           `gorm:"column:id"`
+	DebtSettlementID int64           `gorm:"column:debt_settlement_id"`
+	FromUserID       int64           `gorm:"column:from_user_id"`
+	ToUserID         int64           `gorm:"column:to_user_id"`
+	Amount           decimal.Decimal `gorm:"column:amount"`
+}
+
+func (d DebtSettlementTransfer) TableName() string {
+	return "debt_settlement_transfers"
+}
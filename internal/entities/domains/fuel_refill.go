@@ -15,13 +15,24 @@ type FuelRefill struct {
 	KilometerAfterRefill  int64           `gorm:"column:kilometer_after_refill"`
 	FuelPriceCalculated   decimal.Decimal `gorm:"column:fuel_price_calculated"`
 	IsPaid                bool            `gorm:"column:is_paid"`
+	PaidAmount            decimal.Decimal `gorm:"column:paid_amount"`
+	ClaimedAmount         decimal.Decimal `gorm:"column:claimed_amount"`
 	RefillBy              int64           `gorm:"column:refill_by"`
-	CreateBy              int64           `gorm:"column:create_by"`
-	CreateTime            time.Time       `gorm:"column:create_time"`
-	UpdateBy              int64           `gorm:"column:update_by"`
-	UpdateTime            time.Time       `gorm:"column:update_time"`
+	// IsPaidFromWallet takes the money from the wallet of the car, the
+	// refiller has nothing to be reimbursed
+	IsPaidFromWallet bool      `gorm:"column:is_paid_from_wallet"`
+	CreateBy         int64     `gorm:"column:create_by"`
+	CreateTime       time.Time `gorm:"column:create_time"`
+	UpdateBy         int64     `gorm:"column:update_by"`
+	UpdateTime       time.Time `gorm:"column:update_time"`
 }
 
 func (d FuelRefill) TableName() string {
 	return "fuel_refills"
 }
+
+// Outstanding is the money left to reimburse to the refiller, neither paid
+// nor claimed.
+func (d FuelRefill) Outstanding() decimal.Decimal {
+	return decimal.Max(d.TotalMoney.Sub(d.PaidAmount).Sub(d.ClaimedAmount), decimal.Zero)
+}
@@ -6,6 +6,13 @@ import (
 	"github.com/shopspring/decimal"
 )
 
+const (
+	FuelUsageSplitModeEqual      = "EQUAL"
+	FuelUsageSplitModePercentage = "PERCENTAGE"
+	FuelUsageSplitModeAmount     = "AMOUNT"
+	FuelUsageSplitModeShare      = "SHARE"
+)
+
 type FuelUsage struct {
 	ID                 int64           `gorm:"column:id"`
 	CarID              int64           `gorm:"column:car_id"`
@@ -15,11 +22,35 @@ type FuelUsage struct {
 	KilometerAfterUse  int64           `gorm:"column:kilometer_after_use"`
 	Description        string          `gorm:"column:description"`
 	TotalMoney         decimal.Decimal `gorm:"column:total_money"`
-	PayEach            decimal.Decimal `gorm:"column:pay_each"`
-	CreateTime         time.Time       `gorm:"column:create_time"`
-	UpdateTime         time.Time       `gorm:"column:update_time"`
+	Cost               FuelUsageCost   `gorm:"embedded"`
+	// FuelRefillID is the refill FuelPrice was taken from,
+	// 0 when the fuel price was given by hand
+	FuelRefillID int64 `gorm:"column:fuel_refill_id"`
+	// SplitMode tells how SplitValue of every fuel usage user is read
+	SplitMode string `gorm:"column:split_mode"`
+	// DriverUserID is 0 when the driver is not recorded
+	DriverUserID int64 `gorm:"column:driver_user_id"`
+	// FareRule is the fare rule of the car applied to the split,
+	// NONE when it did not apply
+	FareRule string `gorm:"column:fare_rule"`
+	// IsPaidFromWallet deducts the shares from the wallet of the car, the
+	// shares are paid as soon as they are recorded
+	IsPaidFromWallet bool      `gorm:"column:is_paid_from_wallet"`
+	CreateTime       time.Time `gorm:"column:create_time"`
+	UpdateTime       time.Time `gorm:"column:update_time"`
 }
 
 func (d FuelUsage) TableName() string {
 	return "fuel_usages"
 }
+
+// FuelUsageCost is the breakdown of TotalMoney, the parts sum to it.
+type FuelUsageCost struct {
+	FuelCost    decimal.Decimal `gorm:"column:fuel_cost"`
+	DistanceFee decimal.Decimal `gorm:"column:distance_fee"`
+	TripFee     decimal.Decimal `gorm:"column:trip_fee"`
+	// MinimumChargeTopUp lifts the total money to the minimum charge
+	MinimumChargeTopUp decimal.Decimal `gorm:"column:minimum_charge_top_up"`
+	// RoundUp lifts the total money to the nearest baht
+	RoundUp decimal.Decimal `gorm:"column:round_up"`
+}
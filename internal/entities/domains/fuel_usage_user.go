@@ -1,12 +1,34 @@
 package domains
 
+import "github.com/shopspring/decimal"
+
 type FuelUsageUser struct {
 	ID          int64 `gorm:"column:id"`
 	FuelUsageID int64 `gorm:"column:fuel_usage_id"`
 	UserID      int64 `gorm:"column:user_id"`
 	IsPaid      bool  `gorm:"column:is_paid"`
+	// SplitValue is the percentage, the fixed amount or the shares of the
+	// user depending on the split mode of the fuel usage, 0 for an equal split
+	SplitValue decimal.Decimal `gorm:"column:split_value"`
+	// FareWeight scales the share of the user by the fare rule, 1 is a full
+	// share, 0.5 a half share and 0 free
+	FareWeight decimal.Decimal `gorm:"column:fare_weight"`
+	// Amount is the money the user pays for the fuel usage
+	Amount decimal.Decimal `gorm:"column:amount"`
+	// PaidAmount is the money paid on the amount so far, IsPaid is set once
+	// it covers the amount
+	PaidAmount decimal.Decimal `gorm:"column:paid_amount"`
+	// ClaimedAmount is the money the user claims to have paid, which waits
+	// for the creditor to confirm
+	ClaimedAmount decimal.Decimal `gorm:"column:claimed_amount"`
 }
 
 func (d FuelUsageUser) TableName() string {
 	return "fuel_usage_users"
 }
+
+// Outstanding is the money left to pay on the amount, neither paid nor
+// claimed.
+func (d FuelUsageUser) Outstanding() decimal.Decimal {
+	return decimal.Max(d.Amount.Sub(d.PaidAmount).Sub(d.ClaimedAmount), decimal.Zero)
+}
@@ -0,0 +1,43 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	LedgerEntryTypeFuelUsageShare          = "FUEL_USAGE_SHARE"
+	LedgerEntryTypeFuelUsageSharePayment   = "FUEL_USAGE_SHARE_PAYMENT"
+	LedgerEntryTypeFuelRefill              = "FUEL_REFILL"
+	LedgerEntryTypeFuelRefillReimbursement = "FUEL_REFILL_REIMBURSEMENT"
+	LedgerEntryTypeSettlementRemaining     = "SETTLEMENT_REMAINING"
+	LedgerEntryTypeReversal                = "REVERSAL"
+	LedgerEntryTypeAdjustment              = "ADJUSTMENT"
+)
+
+const (
+	LedgerReferenceTypeFuelUsage      = "FUEL_USAGE"
+	LedgerReferenceTypeFuelRefill     = "FUEL_REFILL"
+	LedgerReferenceTypeSettlement     = "SETTLEMENT"
+	LedgerReferenceTypeDebtSettlement = "DEBT_SETTLEMENT"
+	LedgerReferenceTypeAdjustment     = "ADJUSTMENT"
+)
+
+// LedgerEntry is an append only record of money a user owes (debit) or is
+// owed (credit) for a car, so credit minus debit is the balance of the user.
+type LedgerEntry struct {
+	ID            int64           `gorm:"column:id"`
+	UserID        int64           `gorm:"column:user_id"`
+	CarID         int64           `gorm:"column:car_id"`
+	EntryType     string          `gorm:"column:entry_type"`
+	ReferenceType string          `gorm:"column:reference_type"`
+	ReferenceID   int64           `gorm:"column:reference_id"`
+	Debit         decimal.Decimal `gorm:"column:debit"`
+	Credit        decimal.Decimal `gorm:"column:credit"`
+	CreateTime    time.Time       `gorm:"column:create_time"`
+}
+
+func (d LedgerEntry) TableName() string {
+	return "ledger_entries"
+}
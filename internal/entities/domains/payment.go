@@ -0,0 +1,89 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	PaymentMethodCash         = "CASH"
+	PaymentMethodBankTransfer = "BANK_TRANSFER"
+	PaymentMethodPromptPay    = "PROMPTPAY"
+	// PaymentMethodSettlement pays shares with refills of the same user
+	// instead of money
+	PaymentMethodSettlement = "SETTLEMENT"
+)
+
+const (
+	PaymentConfirmStatusPending   = "PENDING"
+	PaymentConfirmStatusConfirmed = "CONFIRMED"
+	PaymentConfirmStatusRejected  = "REJECTED"
+)
+
+// Payment is money the payer gives the payee, a user id of 0 is the car
+// itself, like the fuel money kept in the car.
+type Payment struct {
+	ID          int64           `gorm:"column:id"`
+	CarID       int64           `gorm:"column:car_id"`
+	PayerUserID int64           `gorm:"column:payer_user_id"`
+	PayeeUserID int64           `gorm:"column:payee_user_id"`
+	Amount      decimal.Decimal `gorm:"column:amount"`
+	Method      string          `gorm:"column:method"`
+	PayTime     time.Time       `gorm:"column:pay_time"`
+	// Status is pending until ConfirmUserID, the creditor, confirms that
+	// the money arrived, only a confirmed payment pays its allocations
+	Status        string `gorm:"column:status"`
+	ConfirmUserID int64  `gorm:"column:confirm_user_id"`
+	RejectReason  string `gorm:"column:reject_reason"`
+	ReviewBy      int64  `gorm:"column:review_by"`
+	// ReviewTime is nil until the payment is confirmed or rejected
+	ReviewTime *time.Time `gorm:"column:review_time"`
+	CreateBy   int64      `gorm:"column:create_by"`
+	CreateTime time.Time  `gorm:"column:create_time"`
+}
+
+func (d Payment) TableName() string {
+	return "payments"
+}
+
+const (
+	PaymentItemTypeFuelUsageUser = "FUEL_USAGE_USER"
+	PaymentItemTypeFuelRefill    = "FUEL_REFILL"
+)
+
+// PaymentAllocation is the part of a payment which pays a share of the payer
+// or reimburses a refill of the payee.
+type PaymentAllocation struct {
+	ID        int64           `gorm:"column:id"`
+	PaymentID int64           `gorm:"column:payment_id"`
+	ItemType  string          `gorm:"column:item_type"`
+	ItemID    int64           `gorm:"column:item_id"`
+	Amount    decimal.Decimal `gorm:"column:amount"`
+}
+
+func (d PaymentAllocation) TableName() string {
+	return "payment_allocations"
+}
+
+const (
+	PaymentStatusUnpaid        = "UNPAID"
+	PaymentStatusClaimed       = "CLAIMED"
+	PaymentStatusPartiallyPaid = "PARTIALLY_PAID"
+	PaymentStatusPaid          = "PAID"
+)
+
+// PaymentStatusOf derives the status of a share or a refill from the money
+// paid on it and the money claimed paid which waits for the creditor.
+func PaymentStatusOf(amount, paidAmount, claimedAmount decimal.Decimal) string {
+	switch {
+	case paidAmount.GreaterThanOrEqual(amount):
+		return PaymentStatusPaid
+	case claimedAmount.IsPositive():
+		return PaymentStatusClaimed
+	case paidAmount.IsPositive():
+		return PaymentStatusPartiallyPaid
+	default:
+		return PaymentStatusUnpaid
+	}
+}
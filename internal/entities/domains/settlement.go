@@ -0,0 +1,46 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	SettlementDirectionNone         = "NONE"
+	SettlementDirectionUserPays     = "USER_PAYS"
+	SettlementDirectionUserReceives = "USER_RECEIVES"
+)
+
+type Settlement struct {
+	ID                 int64           `gorm:"column:id"`
+	UserID             int64           `gorm:"column:user_id"`
+	CarID              int64           `gorm:"column:car_id"`
+	FuelUsageAmount    decimal.Decimal `gorm:"column:fuel_usage_amount"`
+	FuelRefillAmount   decimal.Decimal `gorm:"column:fuel_refill_amount"`
+	RemainingAmount    decimal.Decimal `gorm:"column:remaining_amount"`
+	RemainingDirection string          `gorm:"column:remaining_direction"`
+	CreateBy           int64           `gorm:"column:create_by"`
+	CreateTime         time.Time       `gorm:"column:create_time"`
+}
+
+func (d Settlement) TableName() string {
+	return "settlements"
+}
+
+const (
+	SettlementItemTypeFuelUsageUser = "FUEL_USAGE_USER"
+	SettlementItemTypeFuelRefill    = "FUEL_REFILL"
+)
+
+type SettlementItem struct {
+	ID           int64           `gorm:"column:id"`
+	SettlementID int64           `gorm:"column:settlement_id"`
+	ItemType     string          `gorm:"column:item_type"`
+	ItemID       int64           `gorm:"column:item_id"`
+	Amount       decimal.Decimal `gorm:"column:amount"`
+}
+
+func (d SettlementItem) TableName() string {
+	return "settlement_items"
+}
@@ -3,12 +3,18 @@ package domains
 import "time"
 
 type User struct {
-	ID              int64     `gorm:"column:id"`
-	DefaultCarID    int64     `gorm:"column:default_car_id"`
-	Nickname        string    `gorm:"column:nickname"`
-	ProfileImageURL string    `gorm:"column:profile_image_url"`
-	CreateTime      time.Time `gorm:"column:create_time"`
-	UpdateTime      time.Time `gorm:"column:update_time"`
+	ID              int64  `gorm:"column:id"`
+	DefaultCarID    int64  `gorm:"column:default_car_id"`
+	Nickname        string `gorm:"column:nickname"`
+	ProfileImageURL string `gorm:"column:profile_image_url"`
+	// PromptPayID is the phone number, the national id or the e-wallet id
+	// the user is paid to, in digits only, empty when not given
+	PromptPayID string `gorm:"column:promptpay_id"`
+	// IsDeactivated hides the user from pickers and login while the
+	// fuel usages of the user keep resolving the nickname
+	IsDeactivated bool      `gorm:"column:is_deactivated"`
+	CreateTime    time.Time `gorm:"column:create_time"`
+	UpdateTime    time.Time `gorm:"column:update_time"`
 }
 
 func (d User) TableName() string {
@@ -0,0 +1,16 @@
+package domains
+
+import "time"
+
+type UserCredential struct {
+	ID           int64     `gorm:"column:id"`
+	UserID       int64     `gorm:"column:user_id"`
+	Username     string    `gorm:"column:username"`
+	PasswordHash string    `gorm:"column:password_hash"`
+	CreateTime   time.Time `gorm:"column:create_time"`
+	UpdateTime   time.Time `gorm:"column:update_time"`
+}
+
+func (d UserCredential) TableName() string {
+	return "user_credentials"
+}
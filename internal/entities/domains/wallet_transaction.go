@@ -0,0 +1,39 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	WalletTransactionTypeTopUp          = "TOP_UP"
+	WalletTransactionTypeFuelUsageShare = "FUEL_USAGE_SHARE"
+	WalletTransactionTypeFuelRefill     = "FUEL_REFILL"
+)
+
+const (
+	WalletReferenceTypeFuelUsage  = "FUEL_USAGE"
+	WalletReferenceTypeFuelRefill = "FUEL_REFILL"
+)
+
+// WalletTransaction is an append only record of the wallet of a car. A top
+// up is money a user puts in, a fuel refill is money taken out and a fuel
+// usage share is the fuel a user consumed from what was put in. A
+// transaction is taken back by another one of the negative amount.
+type WalletTransaction struct {
+	ID              int64  `gorm:"column:id"`
+	CarID           int64  `gorm:"column:car_id"`
+	UserID          int64  `gorm:"column:user_id"`
+	TransactionType string `gorm:"column:transaction_type"`
+	// ReferenceType and ReferenceID are empty for a top up
+	ReferenceType string          `gorm:"column:reference_type"`
+	ReferenceID   int64           `gorm:"column:reference_id"`
+	Amount        decimal.Decimal `gorm:"column:amount"`
+	Description   string          `gorm:"column:description"`
+	CreateTime    time.Time       `gorm:"column:create_time"`
+}
+
+func (d WalletTransaction) TableName() string {
+	return "wallet_transactions"
+}
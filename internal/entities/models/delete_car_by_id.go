@@ -0,0 +1,14 @@
+package models
+
+import (
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+)
+
+// DeleteCarByIDRequest archives the car, its history is kept.
+type DeleteCarByIDRequest struct {
+	CarID int64 `param:"carId" validate:"required"`
+}
+
+func (req DeleteCarByIDRequest) Validate() error {
+	return validators.Validate(req)
+}
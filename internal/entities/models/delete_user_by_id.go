@@ -0,0 +1,14 @@
+package models
+
+import (
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+)
+
+// DeleteUserByIDRequest deactivates the user, its history is kept.
+type DeleteUserByIDRequest struct {
+	UserID int64 `param:"userId" validate:"required"`
+}
+
+func (req DeleteUserByIDRequest) Validate() error {
+	return validators.Validate(req)
+}
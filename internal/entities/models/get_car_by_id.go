@@ -0,0 +1,13 @@
+package models
+
+import (
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+)
+
+type GetCarByIDRequest struct {
+	CarID int64 `param:"carId" validate:"required"`
+}
+
+func (req GetCarByIDRequest) Validate() error {
+	return validators.Validate(req)
+}
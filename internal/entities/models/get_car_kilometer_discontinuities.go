@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+)
+
+const (
+	KilometerRecordTypeFuelUsage  = "FUEL_USAGE"
+	KilometerRecordTypeFuelRefill = "FUEL_REFILL"
+)
+
+type GetCarKilometerDiscontinuitiesRequest struct {
+	CarID int64 `param:"carId" validate:"required"`
+}
+
+func (req GetCarKilometerDiscontinuitiesRequest) Validate() error {
+	return validators.Validate(req)
+}
+
+type GetCarKilometerDiscontinuitiesResponse struct {
+	KilometerTolerance int64                    `json:"kilometerTolerance"`
+	Data               []KilometerDiscontinuity `json:"data"`
+}
+
+// KilometerDiscontinuity is where Next does not begin where Previous ended.
+type KilometerDiscontinuity struct {
+	// Code is KILOMETER_GAP or KILOMETER_OVERLAP
+	Code       string          `json:"code"`
+	Kilometers int64           `json:"kilometers"`
+	Previous   KilometerRecord `json:"previous"`
+	Next       KilometerRecord `json:"next"`
+}
+
+// KilometerRecord is a fuel usage or a fuel refill.
+type KilometerRecord struct {
+	Type            string    `json:"type"`
+	ID              int64     `json:"id"`
+	Time            time.Time `json:"time"`
+	KilometerBefore int64     `json:"kilometerBefore"`
+	KilometerAfter  int64     `json:"kilometerAfter"`
+}
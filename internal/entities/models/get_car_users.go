@@ -0,0 +1,14 @@
+package models
+
+import (
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+)
+
+// GetCarUsersRequest lists the active users whose default car is CarID.
+type GetCarUsersRequest struct {
+	CarID int64 `param:"carId" validate:"required"`
+}
+
+func (req GetCarUsersRequest) Validate() error {
+	return validators.Validate(req)
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+	"github.com/shopspring/decimal"
+)
+
+type GetCarWalletStatementRequest struct {
+	CarID int64 `param:"carId" validate:"required"`
+}
+
+func (req GetCarWalletStatementRequest) Validate() error {
+	return validators.Validate(req)
+}
+
+type GetCarWalletStatementResponse struct {
+	CarID           int64 `json:"carId"`
+	IsWalletEnabled bool  `json:"isWalletEnabled"`
+	// Balance is the money left in the wallet, the top ups less the refills
+	Balance     decimal.Decimal `json:"balance"`
+	TotalTopUp  decimal.Decimal `json:"totalTopUp"`
+	TotalRefill decimal.Decimal `json:"totalRefill"`
+	// TotalConsumption is the shares deducted, what was refilled but not
+	// consumed yet is still in the tank
+	TotalConsumption decimal.Decimal     `json:"totalConsumption"`
+	Members          []WalletMember      `json:"members"`
+	Transactions     []WalletTransaction `json:"transactions"`
+}
+
+// WalletMember compares what the user put in the wallet with the fuel the
+// user consumed from it.
+type WalletMember struct {
+	UserID       int64           `json:"userId"`
+	Nickname     string          `json:"nickname"`
+	Contribution decimal.Decimal `json:"contribution"`
+	Consumption  decimal.Decimal `json:"consumption"`
+	// Balance is negative when the user consumed more than put in
+	Balance decimal.Decimal `json:"balance"`
+}
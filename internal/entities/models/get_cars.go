@@ -1,10 +1,54 @@
 package models
 
+import (
+	"errors"
+
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+	"github.com/shopspring/decimal"
+)
+
+type GetCarsRequest struct {
+	IncludeArchived bool `query:"includeArchived"`
+}
+
+func (req GetCarsRequest) Validate() error {
+	return validators.Validate(req)
+}
+
 type GetCarData struct {
 	Data []CarDatum `json:"data"`
 }
 
 type CarDatum struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name"`
+	ID              int64            `json:"id"`
+	Name            string           `json:"name"`
+	PlateNumber     string           `json:"plateNumber"`
+	FuelType        string           `json:"fuelType"`
+	TankCapacity    decimal.Decimal  `json:"tankCapacity"`
+	OwnerUserID     int64            `json:"ownerUserId"`
+	PricingPolicy   CarPricingPolicy `json:"pricingPolicy"`
+	FareRule        string           `json:"fareRule"`
+	IsWalletEnabled bool             `json:"isWalletEnabled"`
+	IsArchived      bool             `json:"isArchived"`
+}
+
+// CarPricingPolicy is charged on top of the fuel cost of every fuel usage.
+type CarPricingPolicy struct {
+	ExtraPerKm    decimal.Decimal `json:"extraPerKm"`
+	TripFee       decimal.Decimal `json:"tripFee"`
+	MinimumCharge decimal.Decimal `json:"minimumCharge"`
+	IsRoundedUp   bool            `json:"isRoundedUp"`
+}
+
+func (p CarPricingPolicy) validate() (err error) {
+	if p.ExtraPerKm.IsNegative() {
+		err = errors.Join(err, errors.New("pricingPolicy.extraPerKm should >= 0"))
+	}
+	if p.TripFee.IsNegative() {
+		err = errors.Join(err, errors.New("pricingPolicy.tripFee should >= 0"))
+	}
+	if p.MinimumCharge.IsNegative() {
+		err = errors.Join(err, errors.New("pricingPolicy.minimumCharge should >= 0"))
+	}
+	return err
 }
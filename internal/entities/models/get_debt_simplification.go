@@ -0,0 +1,40 @@
+package models
+
+import (
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+	"github.com/shopspring/decimal"
+)
+
+type GetDebtSimplificationRequest struct {
+	CarID int64 `query:"carId" validate:"gte=0"`
+}
+
+func (req GetDebtSimplificationRequest) Validate() error {
+	return validators.Validate(req)
+}
+
+type GetDebtSimplificationResponse struct {
+	Positions       []DebtPosition  `json:"positions"`
+	Transfers       []DebtTransfer  `json:"transfers"`
+	UnsettledAmount decimal.Decimal `json:"unsettledAmount"`
+}
+
+// DebtPosition net amount is refill amount minus fuel usage amount,
+// a negative net amount is the money the user should pay.
+type DebtPosition struct {
+	User             DebtUser        `json:"user"`
+	FuelUsageAmount  decimal.Decimal `json:"fuelUsageAmount"`
+	FuelRefillAmount decimal.Decimal `json:"fuelRefillAmount"`
+	NetAmount        decimal.Decimal `json:"netAmount"`
+}
+
+type DebtTransfer struct {
+	FromUser DebtUser        `json:"fromUser"`
+	ToUser   DebtUser        `json:"toUser"`
+	Amount   decimal.Decimal `json:"amount"`
+}
+
+type DebtUser struct {
+	ID       int64  `json:"id"`
+	Nickname string `json:"nickname"`
+}
@@ -19,7 +19,11 @@ type GetFuelRefillByIDResponse struct {
 	TotalMoney            decimal.Decimal `json:"totalMoney"`
 	FuelPriceCalculated   decimal.Decimal `json:"fuelPriceCalculated"`
 	IsPaid                bool            `json:"isPaid"`
+	PaidAmount            decimal.Decimal `json:"paidAmount"`
+	ClaimedAmount         decimal.Decimal `json:"claimedAmount"`
+	PaymentStatus         string          `json:"paymentStatus"`
 	RefillBy              int64           `json:"refillBy"`
+	IsPaidFromWallet      bool            `json:"isPaidFromWallet"`
 }
 
 func (req GetFuelRefillByIDRequest) Validate() error {
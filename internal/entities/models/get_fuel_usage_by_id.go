@@ -14,18 +14,39 @@ type GetFuelUsageByIDRequest struct {
 type GetFuelUsageByIDResponse struct {
 	FuelUseTime        time.Time       `json:"fuelUseTime"`
 	FuelPrice          decimal.Decimal `json:"fuelPrice"`
+	FuelRefillID       int64           `json:"fuelRefillId"`
 	FuelUsers          []GetFuelUser   `json:"fuelUsers"`
+	SplitMode          string          `json:"splitMode"`
+	DriverUserID       int64           `json:"driverUserId"`
+	FareRule           string          `json:"fareRule"`
 	Description        string          `json:"description"`
 	KilometerBeforeUse int64           `json:"kilometerBeforeUse"`
 	KilometerAfterUse  int64           `json:"kilometerAfterUse"`
 	TotalMoney         decimal.Decimal `json:"totalMoney"`
-	EachShouldPay      decimal.Decimal `json:"eachShouldPay"`
+	CostBreakdown      FuelUsageCost   `json:"costBreakdown"`
+	IsPaidFromWallet   bool            `json:"isPaidFromWallet"`
+}
+
+// FuelUsageCost is the breakdown of TotalMoney.
+type FuelUsageCost struct {
+	FuelCost           decimal.Decimal `json:"fuelCost"`
+	DistanceFee        decimal.Decimal `json:"distanceFee"`
+	TripFee            decimal.Decimal `json:"tripFee"`
+	MinimumChargeTopUp decimal.Decimal `json:"minimumChargeTopUp"`
+	RoundUp            decimal.Decimal `json:"roundUp"`
 }
 
 type GetFuelUser struct {
-	UserID   int64  `json:"userId"`
-	Nickname string `json:"nickname"`
-	IsPaid   bool   `json:"isPaid"`
+	UserID        int64           `json:"userId"`
+	Nickname      string          `json:"nickname"`
+	IsPaid        bool            `json:"isPaid"`
+	SplitValue    decimal.Decimal `json:"splitValue"`
+	FareWeight    decimal.Decimal `json:"fareWeight"`
+	Amount        decimal.Decimal `json:"amount"`
+	PaidAmount    decimal.Decimal `json:"paidAmount"`
+	ClaimedAmount decimal.Decimal `json:"claimedAmount"`
+	// PaymentStatus is UNPAID, PARTIALLY_PAID or PAID
+	PaymentStatus string `json:"paymentStatus"`
 }
 
 func (req GetFuelUsageByIDRequest) Validate() error {
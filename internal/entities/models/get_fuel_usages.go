@@ -6,10 +6,9 @@ import (
 )
 
 type GetFuelUsagesRequest struct {
-	CurrentCarID  int64 `query:"currentCarId" validate:"required"`
-	CurrentUserID int64 `query:"currentUserId" validate:"required"`
-	PageIndex     int   `query:"pageIndex"`
-	PageSize      int   `query:"pageSize"`
+	CurrentCarID int64 `query:"currentCarId" validate:"required"`
+	PageIndex    int   `query:"pageIndex"`
+	PageSize     int   `query:"pageSize"`
 }
 
 type GetFuelUsagesResponse struct {
@@ -11,6 +11,7 @@ type GetLatestFuelInfoRequest struct {
 
 type GetLatestFuelInfoResponse struct {
 	LatestFuelPrice         decimal.Decimal `json:"latestFuelPrice"`
+	LatestFuelRefillID      int64           `json:"latestFuelRefillId"`
 	LatestKilometerAfterUse int64           `json:"latestKilometerAfterUse"`
 }
 
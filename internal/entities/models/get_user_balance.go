@@ -0,0 +1,31 @@
+package models
+
+import (
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+	"github.com/shopspring/decimal"
+)
+
+type GetUserBalanceRequest struct {
+	UserID int64 `param:"userId" validate:"required"`
+}
+
+func (req GetUserBalanceRequest) Validate() error {
+	return validators.Validate(req)
+}
+
+// GetUserBalanceResponse balance is credit minus debit, a negative balance
+// is the money the user still owes.
+type GetUserBalanceResponse struct {
+	UserID      int64           `json:"userId"`
+	TotalDebit  decimal.Decimal `json:"totalDebit"`
+	TotalCredit decimal.Decimal `json:"totalCredit"`
+	Balance     decimal.Decimal `json:"balance"`
+	Cars        []CarBalance    `json:"cars"`
+}
+
+type CarBalance struct {
+	Car         CarInfo         `json:"car"`
+	TotalDebit  decimal.Decimal `json:"totalDebit"`
+	TotalCredit decimal.Decimal `json:"totalCredit"`
+	Balance     decimal.Decimal `json:"balance"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "github.com/bosskrub9992/fuel-management-backend/library/validators"
+
+type GetUserCarPaymentsRequest struct {
+	UserID int64 `param:"userId" validate:"required"`
+	CarID  int64 `param:"carId" validate:"required"`
+}
+
+func (req GetUserCarPaymentsRequest) Validate() error {
+	return validators.Validate(req)
+}
+
+type GetUserCarPaymentsResponse struct {
+	Data []Payment `json:"data"`
+}
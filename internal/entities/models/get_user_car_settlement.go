@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+	"github.com/shopspring/decimal"
+)
+
+type GetUserCarSettlementRequest struct {
+	UserID int64 `param:"userId" validate:"required"`
+	CarID  int64 `param:"carId" validate:"required"`
+}
+
+func (req GetUserCarSettlementRequest) Validate() error {
+	return validators.Validate(req)
+}
+
+type GetUserCarSettlementResponse struct {
+	FuelUsageAmount    decimal.Decimal `json:"fuelUsageAmount"`
+	FuelRefillAmount   decimal.Decimal `json:"fuelRefillAmount"`
+	NetAmount          decimal.Decimal `json:"netAmount"`
+	OffsetFuelUsages   []FuelUsage     `json:"offsetFuelUsages"`
+	OffsetFuelRefills  []FuelRefill    `json:"offsetFuelRefills"`
+	RemainingAmount    decimal.Decimal `json:"remainingAmount"`
+	RemainingDirection string          `json:"remainingDirection"`
+}
@@ -8,6 +8,8 @@ import (
 type GetUserCarUnpaidActivitiesRequest struct {
 	UserID int64 `validate:"required"`
 	CarID  int64 `validate:"required"`
+	// IsPromptPayIncluded adds a PromptPay QR to pay each creditor
+	IsPromptPayIncluded bool
 }
 
 func (req GetUserCarUnpaidActivitiesRequest) Validate() error {
@@ -17,11 +19,27 @@ func (req GetUserCarUnpaidActivitiesRequest) Validate() error {
 type GetUserCarUnpaidActivitiesResponse struct {
 	FuelUsages  []FuelUsage  `json:"fuelUsages"`
 	FuelRefills []FuelRefill `json:"fuelRefills"`
+	PromptPays  []PromptPay  `json:"promptPays,omitempty"`
+}
+
+// PromptPay is what the user owes a creditor for the unpaid shares, with
+// the QR to transfer it. Payload and QRCode are empty when the creditor has
+// no PromptPay id.
+type PromptPay struct {
+	CreditorUserID int64           `json:"creditorUserId"`
+	Nickname       string          `json:"nickname"`
+	Amount         decimal.Decimal `json:"amount"`
+	Payload        string          `json:"payload"`
+	// QRCode is a PNG data URL usable as an image src
+	QRCode string `json:"qrCode"`
 }
 
 type FuelRefill struct {
-	FuelRefillID int64           `json:"fuelRefillId"`
-	RefillTime   string          `json:"refillTime"`
-	IsPaid       string          `json:"isPaid"`
-	TotalMoney   decimal.Decimal `json:"totalMoney"`
+	FuelRefillID  int64           `json:"fuelRefillId"`
+	RefillTime    string          `json:"refillTime"`
+	IsPaid        string          `json:"isPaid"`
+	TotalMoney    decimal.Decimal `json:"totalMoney"`
+	PaidAmount    decimal.Decimal `json:"paidAmount"`
+	ClaimedAmount decimal.Decimal `json:"claimedAmount"`
+	PaymentStatus string          `json:"paymentStatus"`
 }
@@ -35,4 +35,12 @@ type FuelUsage struct {
 	Description     string          `json:"description"`
 	FuelUsers       string          `json:"fuelUsers"`
 	PayEach         decimal.Decimal `json:"payEach"`
+	PaidAmount      decimal.Decimal `json:"paidAmount"`
+	ClaimedAmount   decimal.Decimal `json:"claimedAmount"`
+	PaymentStatus   string          `json:"paymentStatus"`
+	DriverUserID    int64           `json:"driverUserId"`
+	// FareRule and FareWeight explain a share which differs from the others,
+	// a fare weight of 1 is a full share, 0.5 a half share and 0 free
+	FareRule   string          `json:"fareRule"`
+	FareWeight decimal.Decimal `json:"fareWeight"`
 }
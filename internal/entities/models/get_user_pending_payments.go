@@ -0,0 +1,18 @@
+package models
+
+import "github.com/bosskrub9992/fuel-management-backend/library/validators"
+
+type GetUserPendingPaymentsRequest struct {
+	UserID int64 `param:"userId" validate:"required"`
+}
+
+func (req GetUserPendingPaymentsRequest) Validate() error {
+	return validators.Validate(req)
+}
+
+type GetUserPendingPaymentsResponse struct {
+	// ToConfirm are the payments waiting for the user to confirm
+	ToConfirm []Payment `json:"toConfirm"`
+	// Awaiting are the payments the user made waiting for the others
+	Awaiting []Payment `json:"awaiting"`
+}
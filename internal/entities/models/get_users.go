@@ -1,10 +1,25 @@
 package models
 
+import (
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+)
+
+type GetUsersRequest struct {
+	IncludeDeactivated bool `query:"includeDeactivated"`
+}
+
+func (req GetUsersRequest) Validate() error {
+	return validators.Validate(req)
+}
+
 type GetUserDatum struct {
 	ID              int64  `json:"id"`
 	DefaultCarID    int64  `json:"defaultCarId"`
 	Nickname        string `json:"nickname"`
 	ProfileImageURL string `json:"profileImageUrl"`
+	// PromptPayID shows only the last 4 digits
+	PromptPayID   string `json:"promptPayId"`
+	IsDeactivated bool   `json:"isDeactivated"`
 }
 
 type GetUserData struct {
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+)
+
+type PatchUserDefaultCarRequest struct {
+	UserID int64 `param:"userId" validate:"required"`
+	CarID  int64 `json:"carId" validate:"required"`
+}
+
+func (req PatchUserDefaultCarRequest) Validate() error {
+	return validators.Validate(req)
+}
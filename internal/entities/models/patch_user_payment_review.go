@@ -0,0 +1,26 @@
+package models
+
+import "github.com/bosskrub9992/fuel-management-backend/library/validators"
+
+// PatchUserPaymentConfirmRequest confirms the money of a pending payment
+// arrived, the user should be the one to confirm it.
+type PatchUserPaymentConfirmRequest struct {
+	UserID    int64 `param:"userId" validate:"required"`
+	PaymentID int64 `param:"paymentId" validate:"required"`
+}
+
+func (req PatchUserPaymentConfirmRequest) Validate() error {
+	return validators.Validate(req)
+}
+
+// PatchUserPaymentRejectRequest rejects a pending payment whose money did
+// not arrive, the shares and refills it claimed become unpaid again.
+type PatchUserPaymentRejectRequest struct {
+	UserID    int64  `param:"userId" validate:"required"`
+	PaymentID int64  `param:"paymentId" validate:"required"`
+	Reason    string `json:"reason" validate:"required,max=255"`
+}
+
+func (req PatchUserPaymentRejectRequest) Validate() error {
+	return validators.Validate(req)
+}
@@ -7,8 +7,15 @@ type PayUserCarUnpaidActivitiesRequest struct {
 	CarID            int64   `param:"carId" validate:"required"`
 	FuelUsageUserIDs []int64 `json:"fuelUsageUserIds"`
 	FuelRefillIDs    []int64 `json:"fuelRefillIds"`
+	Method           string  `json:"method" validate:"omitempty,oneof=CASH BANK_TRANSFER PROMPTPAY"`
 }
 
 func (req PayUserCarUnpaidActivitiesRequest) Validate() error {
 	return validators.Validate(req)
 }
+
+// PayUserCarUnpaidActivitiesResponse has a payment of the user for the
+// shares and a payment to the user for the refills, when there are any.
+type PayUserCarUnpaidActivitiesResponse struct {
+	Payments []Payment `json:"payments"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+	"github.com/shopspring/decimal"
+)
+
+type PostDebtSettlementRequest struct {
+	CarID int64 `json:"carId" validate:"gte=0"`
+}
+
+func (req PostDebtSettlementRequest) Validate() error {
+	return validators.Validate(req)
+}
+
+type PostDebtSettlementResponse struct {
+	DebtSettlementID int64           `json:"debtSettlementId"`
+	Transfers        []DebtTransfer  `json:"transfers"`
+	UnsettledAmount  decimal.Decimal `json:"unsettledAmount"`
+	// Payments wait for the payees to confirm the transfers and offsets
+	Payments []Payment `json:"payments"`
+}
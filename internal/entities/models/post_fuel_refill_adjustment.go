@@ -0,0 +1,29 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+	"github.com/shopspring/decimal"
+)
+
+// PostFuelRefillAdjustmentRequest corrects the money the refiller fronted for
+// a paid refill.
+type PostFuelRefillAdjustmentRequest struct {
+	FuelRefillID int64           `param:"fuelRefillId" validate:"required"`
+	TotalMoney   decimal.Decimal `json:"totalMoney"`
+	Reason       string          `json:"reason" validate:"required,max=255"`
+}
+
+func (req PostFuelRefillAdjustmentRequest) Validate() error {
+	err := validators.Validate(req)
+	if !req.TotalMoney.IsPositive() {
+		err = errors.Join(err, fmt.Errorf("totalMoney should > 0"))
+	}
+	return err
+}
+
+type PostFuelRefillAdjustmentResponse struct {
+	Adjustments []Adjustment `json:"adjustments"`
+}
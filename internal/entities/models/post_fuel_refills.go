@@ -17,7 +17,6 @@ type CreateFuelRefillRequest struct {
 	TotalMoney            decimal.Decimal `json:"totalMoney" validate:"required"`
 	IsPaid                bool            `json:"isPaid"`
 	RefillBy              int64           `json:"refillBy" validate:"required"`
-	CurrentUserID         int64           `json:"currentUserId" validate:"required"`
 }
 
 func (req CreateFuelRefillRequest) Validate() error {
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+	"github.com/shopspring/decimal"
+)
+
+// PostFuelUsageAdjustmentRequest corrects what the users owe for a paid fuel
+// usage, a user of the fuel usage left out of FuelUsers owes nothing.
+type PostFuelUsageAdjustmentRequest struct {
+	FuelUsageID int64              `param:"fuelUsageId" validate:"required"`
+	Reason      string             `json:"reason" validate:"required,max=255"`
+	FuelUsers   []AdjustedFuelUser `json:"fuelUsers" validate:"min=1"`
+}
+
+type AdjustedFuelUser struct {
+	UserID int64           `json:"userId"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+func (req PostFuelUsageAdjustmentRequest) Validate() error {
+	err := validators.Validate(req)
+
+	uniqueUserID := make(map[int64]bool)
+	for _, fuelUser := range req.FuelUsers {
+		if fuelUser.UserID <= 0 {
+			err = errors.Join(err, fmt.Errorf("userId should > 0, got: [%d]", fuelUser.UserID))
+		}
+		if fuelUser.Amount.IsNegative() {
+			err = errors.Join(err, fmt.Errorf("amount should >= 0, got: [%s]", fuelUser.Amount))
+		}
+		uniqueUserID[fuelUser.UserID] = true
+	}
+	if len(req.FuelUsers) > len(uniqueUserID) {
+		err = errors.Join(err, errors.New("fuelUsers has duplicate userId"))
+	}
+
+	return err
+}
+
+type PostFuelUsageAdjustmentResponse struct {
+	Adjustments []Adjustment `json:"adjustments"`
+}
+
+type Adjustment struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"userId"`
+	// OldAmount is the amount in effect before the adjustment
+	OldAmount decimal.Decimal `json:"oldAmount"`
+	NewAmount decimal.Decimal `json:"newAmount"`
+	// Difference is posted to the ledger of the user, negative when the
+	// amount shrinks
+	Difference decimal.Decimal `json:"difference"`
+	Reason     string          `json:"reason"`
+	CreateBy   int64           `json:"createBy"`
+	CreateTime time.Time       `json:"createTime"`
+}
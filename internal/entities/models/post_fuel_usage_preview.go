@@ -0,0 +1,28 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// PreviewFuelUsageResponse is what CreateFuelUsageRequest would save.
+type PreviewFuelUsageResponse struct {
+	FuelPrice     decimal.Decimal   `json:"fuelPrice"`
+	FuelRefillID  int64             `json:"fuelRefillId"`
+	TotalMoney    decimal.Decimal   `json:"totalMoney"`
+	CostBreakdown FuelUsageCost     `json:"costBreakdown"`
+	SplitMode     string            `json:"splitMode"`
+	FareRule      string            `json:"fareRule"`
+	FuelUsers     []PreviewFuelUser `json:"fuelUsers"`
+	// LatestFuelPrice is the price of the latest refill, zero without refill
+	LatestFuelPrice decimal.Decimal `json:"latestFuelPrice"`
+	// LatestKilometerAfterUse is where the usage is expected to begin
+	LatestKilometerAfterUse int64              `json:"latestKilometerAfterUse"`
+	Warnings                []FuelUsageWarning `json:"warnings"`
+}
+
+type PreviewFuelUser struct {
+	UserID     int64           `json:"userId"`
+	SplitValue decimal.Decimal `json:"splitValue"`
+	FareWeight decimal.Decimal `json:"fareWeight"`
+	Amount     decimal.Decimal `json:"amount"`
+}
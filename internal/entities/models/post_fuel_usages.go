@@ -9,18 +9,44 @@ import (
 )
 
 type CreateFuelUsageRequest struct {
-	CurrentCarID       int64           `json:"currentCarId" validate:"required"`
-	FuelUseTime        time.Time       `json:"fuelUseTime"`
-	FuelPrice          decimal.Decimal `json:"fuelPrice"`
-	FuelUsers          []FuelUser      `json:"fuelUsers" validate:"min=1"`
-	Description        string          `json:"description" validate:"max=500"`
-	KilometerBeforeUse int64           `json:"kilometerBeforeUse"`
-	KilometerAfterUse  int64           `json:"kilometerAfterUse"`
+	CurrentCarID int64           `json:"currentCarId" validate:"required"`
+	FuelUseTime  time.Time       `json:"fuelUseTime"`
+	FuelPrice    decimal.Decimal `json:"fuelPrice"`
+	// FuelRefillID is the refill to take the fuel price from, 0 takes the
+	// latest refill of the car when fuelPrice is its price
+	FuelRefillID       int64      `json:"fuelRefillId" validate:"gte=0"`
+	FuelUsers          []FuelUser `json:"fuelUsers" validate:"min=1"`
+	SplitMode          string     `json:"splitMode" validate:"omitempty,oneof=EQUAL PERCENTAGE AMOUNT SHARE"`
+	DriverUserID       int64      `json:"driverUserId" validate:"gte=0"`
+	Description        string     `json:"description" validate:"max=500"`
+	KilometerBeforeUse int64      `json:"kilometerBeforeUse"`
+	KilometerAfterUse  int64      `json:"kilometerAfterUse"`
+}
+
+const (
+	FuelUsageWarningKilometerGap     = "KILOMETER_GAP"
+	FuelUsageWarningKilometerOverlap = "KILOMETER_OVERLAP"
+	FuelUsageWarningFuelPrice        = "FUEL_PRICE_DIFFERS"
+)
+
+type CreateFuelUsageResponse struct {
+	ID       int64              `json:"id"`
+	Warnings []FuelUsageWarning `json:"warnings"`
+}
+
+// FuelUsageWarning does not stop the fuel usage from being saved, unless
+// kilometer discontinuities are configured to be rejected.
+type FuelUsageWarning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 type FuelUser struct {
 	UserID int64 `json:"userId" validate:"required"`
 	IsPaid bool  `json:"isPaid" validate:"required"`
+	// SplitValue is the percentage, the amount or the shares of the user
+	// depending on SplitMode, it is ignored by an equal split
+	SplitValue decimal.Decimal `json:"splitValue"`
 }
 
 func (req CreateFuelUsageRequest) Validate() (err error) {
@@ -41,5 +67,19 @@ func (req CreateFuelUsageRequest) Validate() (err error) {
 		}
 	}
 
-	return err
+	return errors.Join(err, validateDriverUserID(req.DriverUserID, req.FuelUsers))
+}
+
+// validateDriverUserID accepts 0 as no driver, else the driver should be one
+// of the fuel users.
+func validateDriverUserID(driverUserID int64, fuelUsers []FuelUser) error {
+	if driverUserID == 0 {
+		return nil
+	}
+	for _, fuelUser := range fuelUsers {
+		if fuelUser.UserID == driverUserID {
+			return nil
+		}
+	}
+	return errors.New("driverUserId should be one of fuelUsers")
 }
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+)
+
+type PostLoginRequest struct {
+	Username string `json:"username" validate:"required,max=100"`
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+func (req PostLoginRequest) Validate() error {
+	return validators.Validate(req)
+}
+
+type PostLoginResponse struct {
+	AccessToken string       `json:"accessToken"`
+	ExpireTime  time.Time    `json:"expireTime"`
+	User        GetUserDatum `json:"user"`
+}
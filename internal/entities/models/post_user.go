@@ -0,0 +1,30 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/bosskrub9992/fuel-management-backend/library/promptpay"
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+)
+
+type PostUserRequest struct {
+	Nickname     string `json:"nickname" validate:"required,max=500"`
+	DefaultCarID int64  `json:"defaultCarId" validate:"required"`
+	Username     string `json:"username" validate:"required,max=100"`
+	Password     string `json:"password" validate:"required,min=8,max=72"`
+	PromptPayID  string `json:"promptPayId"`
+}
+
+func (req PostUserRequest) Validate() error {
+	err := validators.Validate(req)
+	if req.PromptPayID != "" {
+		if _, promptPayErr := promptpay.NormalizeID(req.PromptPayID); promptPayErr != nil {
+			err = errors.Join(err, promptPayErr)
+		}
+	}
+	return err
+}
+
+type PostUserResponse struct {
+	ID int64 `json:"id"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+)
+
+// PostUserAvatarRequest carries the uploaded multipart file "avatar".
+type PostUserAvatarRequest struct {
+	UserID int64  `param:"userId" validate:"required"`
+	Image  []byte `validate:"required"`
+}
+
+func (req PostUserAvatarRequest) Validate() error {
+	return validators.Validate(req)
+}
+
+type PostUserAvatarResponse struct {
+	ProfileImageURL string `json:"profileImageUrl"`
+	// Thumbnails maps the thumbnail size in pixels to its URL
+	Thumbnails map[int]string `json:"thumbnails"`
+}
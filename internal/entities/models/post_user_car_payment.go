@@ -0,0 +1,58 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+	"github.com/shopspring/decimal"
+)
+
+// PostUserCarPaymentRequest is a payment of the user for the car. The amount
+// pays the shares the user owes the payee, or the owner without a payee, and
+// reimburses the refills of the payee as far as they are owed. Without ids
+// the oldest unpaid shares and unreimbursed refills go first.
+type PostUserCarPaymentRequest struct {
+	UserID           int64           `param:"userId" validate:"required"`
+	CarID            int64           `param:"carId" validate:"required"`
+	PayeeUserID      int64           `json:"payeeUserId" validate:"gte=0"`
+	Amount           decimal.Decimal `json:"amount" validate:"required"`
+	Method           string          `json:"method" validate:"omitempty,oneof=CASH BANK_TRANSFER PROMPTPAY"`
+	PayTime          time.Time       `json:"payTime"`
+	FuelUsageUserIDs []int64         `json:"fuelUsageUserIds"`
+	FuelRefillIDs    []int64         `json:"fuelRefillIds"`
+}
+
+func (req PostUserCarPaymentRequest) Validate() error {
+	err := validators.Validate(req)
+	if !req.Amount.IsPositive() {
+		err = errors.Join(err, fmt.Errorf("amount should > 0"))
+	}
+	if req.PayeeUserID == req.UserID {
+		err = errors.Join(err, fmt.Errorf("payeeUserId should not be userId"))
+	}
+	return err
+}
+
+type Payment struct {
+	ID          int64           `json:"id"`
+	CarID       int64           `json:"carId"`
+	PayerUserID int64           `json:"payerUserId"`
+	PayeeUserID int64           `json:"payeeUserId"`
+	Amount      decimal.Decimal `json:"amount"`
+	Method      string          `json:"method"`
+	PayTime     time.Time       `json:"payTime"`
+	// Status is PENDING until ConfirmUserID confirms or rejects the payment
+	Status        string              `json:"status"`
+	ConfirmUserID int64               `json:"confirmUserId"`
+	RejectReason  string              `json:"rejectReason"`
+	ReviewTime    *time.Time          `json:"reviewTime"`
+	Allocations   []PaymentAllocation `json:"allocations"`
+}
+
+type PaymentAllocation struct {
+	ItemType string          `json:"itemType"`
+	ItemID   int64           `json:"itemId"`
+	Amount   decimal.Decimal `json:"amount"`
+}
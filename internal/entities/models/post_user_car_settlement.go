@@ -0,0 +1,23 @@
+package models
+
+import (
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+	"github.com/shopspring/decimal"
+)
+
+type PostUserCarSettlementRequest struct {
+	UserID int64 `param:"userId" validate:"required"`
+	CarID  int64 `param:"carId" validate:"required"`
+}
+
+func (req PostUserCarSettlementRequest) Validate() error {
+	return validators.Validate(req)
+}
+
+type PostUserCarSettlementResponse struct {
+	SettlementID       int64           `json:"settlementId"`
+	RemainingAmount    decimal.Decimal `json:"remainingAmount"`
+	RemainingDirection string          `json:"remainingDirection"`
+	// Payments wait for the creditors to confirm the offset
+	Payments []Payment `json:"payments"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+	"github.com/shopspring/decimal"
+)
+
+// PostUserCarWalletTopUpRequest is money the user puts in the wallet of the
+// car.
+type PostUserCarWalletTopUpRequest struct {
+	UserID      int64           `param:"userId" validate:"required"`
+	CarID       int64           `param:"carId" validate:"required"`
+	Amount      decimal.Decimal `json:"amount" validate:"required"`
+	Description string          `json:"description" validate:"max=255"`
+}
+
+func (req PostUserCarWalletTopUpRequest) Validate() error {
+	err := validators.Validate(req)
+	if !req.Amount.IsPositive() {
+		err = errors.Join(err, fmt.Errorf("amount should > 0"))
+	}
+	return err
+}
+
+type WalletTransaction struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"userId"`
+	// TransactionType is TOP_UP, FUEL_USAGE_SHARE or FUEL_REFILL
+	TransactionType string `json:"transactionType"`
+	ReferenceType   string `json:"referenceType"`
+	ReferenceID     int64  `json:"referenceId"`
+	// Amount is negative when it takes back an earlier transaction
+	Amount      decimal.Decimal `json:"amount"`
+	Description string          `json:"description"`
+	CreateTime  time.Time       `json:"createTime"`
+}
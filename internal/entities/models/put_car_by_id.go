@@ -0,0 +1,30 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+	"github.com/shopspring/decimal"
+)
+
+type PutCarByIDRequest struct {
+	CarID         int64            `param:"carId" validate:"required"`
+	Name          string           `json:"name" validate:"required,max=500"`
+	PlateNumber   string           `json:"plateNumber" validate:"max=50"`
+	FuelType      string           `json:"fuelType" validate:"omitempty,oneof=GASOHOL_91 GASOHOL_95 E20 E85 BENZINE DIESEL"`
+	TankCapacity  decimal.Decimal  `json:"tankCapacity"`
+	OwnerUserID   int64            `json:"ownerUserId" validate:"gte=0"`
+	PricingPolicy CarPricingPolicy `json:"pricingPolicy"`
+	FareRule      string           `json:"fareRule" validate:"omitempty,oneof=NONE DRIVER_FREE DRIVER_HALF OWNER_FREE"`
+	// IsWalletEnabled pays the fuel usages and refills recorded from now on
+	// from the wallet of the car
+	IsWalletEnabled bool `json:"isWalletEnabled"`
+}
+
+func (req PutCarByIDRequest) Validate() error {
+	err := validators.Validate(req)
+	if req.TankCapacity.IsNegative() {
+		err = errors.Join(err, errors.New("tankCapacity should >= 0"))
+	}
+	return errors.Join(err, req.PricingPolicy.validate())
+}
@@ -16,7 +16,57 @@ type PutFuelRefillByIDRequest struct {
 	TotalMoney            decimal.Decimal `json:"totalMoney" validate:"required"`
 	IsPaid                bool            `json:"isPaid"`
 	RefillBy              int64           `json:"refillBy" validate:"required"`
-	CurrentUserID         int64           `json:"currentUserId" validate:"required"`
+	// RecalculateFuelUsages prices the fuel usages of the refill again when
+	// its fuel price changes, NONE by default
+	RecalculateFuelUsages string `json:"recalculateFuelUsages" validate:"omitempty,oneof=NONE UNPAID ALL"`
+}
+
+const (
+	RecalculateFuelUsagesNone   = "NONE"
+	RecalculateFuelUsagesUnpaid = "UNPAID"
+	RecalculateFuelUsagesAll    = "ALL"
+)
+
+const (
+	SkippedFuelUsageReasonPaid        = "PAID"
+	SkippedFuelUsageReasonAmountSplit = "AMOUNT_SPLIT"
+	// SkippedFuelUsageReasonClaimed is a fuel usage with a payment waiting
+	// for confirmation, it is skipped even when ALL is recalculated
+	SkippedFuelUsageReasonClaimed = "CLAIMED"
+	// SkippedFuelUsageReasonAdjusted is a fuel usage corrected by
+	// adjustments, it is skipped even when ALL is recalculated
+	SkippedFuelUsageReasonAdjusted = "ADJUSTED"
+)
+
+type PutFuelRefillByIDResponse struct {
+	RecalculatedFuelUsages []RecalculatedFuelUsage `json:"recalculatedFuelUsages"`
+	SkippedFuelUsages      []SkippedFuelUsage      `json:"skippedFuelUsages"`
+}
+
+type RecalculatedFuelUsage struct {
+	FuelUsageID   int64                  `json:"fuelUsageId"`
+	OldFuelPrice  decimal.Decimal        `json:"oldFuelPrice"`
+	NewFuelPrice  decimal.Decimal        `json:"newFuelPrice"`
+	OldTotalMoney decimal.Decimal        `json:"oldTotalMoney"`
+	NewTotalMoney decimal.Decimal        `json:"newTotalMoney"`
+	FuelUsers     []RecalculatedFuelUser `json:"fuelUsers"`
+	// IsAdjusted is a paid fuel usage, its new amounts are posted by
+	// adjustments
+	IsAdjusted bool `json:"isAdjusted"`
+}
+
+type RecalculatedFuelUser struct {
+	UserID    int64           `json:"userId"`
+	IsPaid    bool            `json:"isPaid"`
+	OldAmount decimal.Decimal `json:"oldAmount"`
+	NewAmount decimal.Decimal `json:"newAmount"`
+}
+
+// SkippedFuelUsage keeps its fuel price, paid usages are skipped unless ALL
+// is asked and amount splits never follow the fuel price.
+type SkippedFuelUsage struct {
+	FuelUsageID int64  `json:"fuelUsageId"`
+	Reason      string `json:"reason"`
 }
 
 func (req PutFuelRefillByIDRequest) Validate() error {
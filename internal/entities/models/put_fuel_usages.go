@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	"github.com/bosskrub9992/fuel-management-backend/library/validators"
@@ -8,16 +9,24 @@ import (
 )
 
 type PutFuelUsageRequest struct {
-	FuelUsageID        int64           `param:"fuelUsageId" validate:"required"`
-	CurrentCarID       int64           `json:"currentCarId" validate:"required"`
-	FuelUseTime        time.Time       `json:"fuelUseTime"`
-	FuelPrice          decimal.Decimal `json:"fuelPrice"`
-	FuelUsers          []FuelUser      `json:"fuelUsers" validate:"min=1"`
-	Description        string          `json:"description" validate:"max=500"`
-	KilometerBeforeUse int64           `json:"kilometerBeforeUse"`
-	KilometerAfterUse  int64           `json:"kilometerAfterUse"`
+	FuelUsageID  int64           `param:"fuelUsageId" validate:"required"`
+	CurrentCarID int64           `json:"currentCarId" validate:"required"`
+	FuelUseTime  time.Time       `json:"fuelUseTime"`
+	FuelPrice    decimal.Decimal `json:"fuelPrice"`
+	// FuelRefillID is the refill to take the fuel price from, 0 takes the
+	// latest refill of the car when fuelPrice is its price
+	FuelRefillID       int64      `json:"fuelRefillId" validate:"gte=0"`
+	FuelUsers          []FuelUser `json:"fuelUsers" validate:"min=1"`
+	SplitMode          string     `json:"splitMode" validate:"omitempty,oneof=EQUAL PERCENTAGE AMOUNT SHARE"`
+	DriverUserID       int64      `json:"driverUserId" validate:"gte=0"`
+	Description        string     `json:"description" validate:"max=500"`
+	KilometerBeforeUse int64      `json:"kilometerBeforeUse"`
+	KilometerAfterUse  int64      `json:"kilometerAfterUse"`
 }
 
 func (req PutFuelUsageRequest) Validate() error {
-	return validators.Validate(req)
+	return errors.Join(
+		validators.Validate(req),
+		validateDriverUserID(req.DriverUserID, req.FuelUsers),
+	)
 }
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/bosskrub9992/fuel-management-backend/library/promptpay"
+	"github.com/bosskrub9992/fuel-management-backend/library/validators"
+)
+
+type PutUserByIDRequest struct {
+	UserID   int64  `param:"userId" validate:"required"`
+	Nickname string `json:"nickname" validate:"required,max=500"`
+	// PromptPayID is cleared when empty
+	PromptPayID string `json:"promptPayId"`
+}
+
+func (req PutUserByIDRequest) Validate() error {
+	err := validators.Validate(req)
+	if req.PromptPayID != "" {
+		if _, promptPayErr := promptpay.NormalizeID(req.PromptPayID); promptPayErr != nil {
+			err = errors.Join(err, promptPayErr)
+		}
+	}
+	return err
+}
@@ -1,6 +1,7 @@
 package resthandler
 
 import (
+	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -27,7 +28,14 @@ func New(service *services.Service, serverStartTime time.Time) *RESTHandler {
 func (h RESTHandler) GetUsers(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	users, err := h.service.GetUsers(ctx)
+	var req models.GetUsersRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	data, err := h.service.GetUsers(ctx, req)
 	if err != nil {
 		if response, ok := err.(errs.Err); ok {
 			return c.JSON(response.Status, response)
@@ -36,13 +44,20 @@ func (h RESTHandler) GetUsers(c echo.Context) error {
 		return c.JSON(response.Status, response)
 	}
 
-	return c.JSON(http.StatusOK, users)
+	return c.JSON(http.StatusOK, data)
 }
 
-func (h RESTHandler) GetCars(c echo.Context) error {
+func (h RESTHandler) PostUser(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	users, err := h.service.GetCars(ctx)
+	var req models.PostUserRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	data, err := h.service.CreateUser(ctx, req)
 	if err != nil {
 		if response, ok := err.(errs.Err); ok {
 			return c.JSON(response.Status, response)
@@ -51,21 +66,20 @@ func (h RESTHandler) GetCars(c echo.Context) error {
 		return c.JSON(response.Status, response)
 	}
 
-	return c.JSON(http.StatusOK, users)
+	return c.JSON(http.StatusOK, data)
 }
 
-func (h RESTHandler) GetFuelUsages(c echo.Context) error {
+func (h RESTHandler) PutUserByID(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	var req models.GetFuelUsagesRequest
+	var req models.PutUserByIDRequest
 	if err := c.Bind(&req); err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response := errs.ErrBadRequest
 		return c.JSON(response.Status, response)
 	}
 
-	data, err := h.service.GetFuelUsages(ctx, req)
-	if err != nil {
+	if err := h.service.UpdateUserByID(ctx, req); err != nil {
 		if response, ok := err.(errs.Err); ok {
 			return c.JSON(response.Status, response)
 		}
@@ -73,20 +87,20 @@ func (h RESTHandler) GetFuelUsages(c echo.Context) error {
 		return c.JSON(response.Status, response)
 	}
 
-	return c.JSON(http.StatusOK, data)
+	return c.JSON(http.StatusOK, nil)
 }
 
-func (h RESTHandler) PostFuelUsage(c echo.Context) error {
+func (h RESTHandler) DeleteUserByID(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	var req models.CreateFuelUsageRequest
+	var req models.DeleteUserByIDRequest
 	if err := c.Bind(&req); err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response := errs.ErrBadRequest
 		return c.JSON(response.Status, response)
 	}
 
-	if err := h.service.CreateFuelUsage(ctx, req); err != nil {
+	if err := h.service.DeactivateUserByID(ctx, req); err != nil {
 		if response, ok := err.(errs.Err); ok {
 			return c.JSON(response.Status, response)
 		}
@@ -97,17 +111,17 @@ func (h RESTHandler) PostFuelUsage(c echo.Context) error {
 	return c.JSON(http.StatusOK, nil)
 }
 
-func (h RESTHandler) PutFuelUsage(c echo.Context) error {
+func (h RESTHandler) PatchUserDefaultCar(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	var req models.PutFuelUsageRequest
+	var req models.PatchUserDefaultCarRequest
 	if err := c.Bind(&req); err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response := errs.ErrBadRequest
 		return c.JSON(response.Status, response)
 	}
 
-	if err := h.service.UpdateFuelUsage(ctx, req); err != nil {
+	if err := h.service.UpdateUserDefaultCar(ctx, req); err != nil {
 		if response, ok := err.(errs.Err); ok {
 			return c.JSON(response.Status, response)
 		}
@@ -118,21 +132,18 @@ func (h RESTHandler) PutFuelUsage(c echo.Context) error {
 	return c.JSON(http.StatusOK, nil)
 }
 
-func (h RESTHandler) DeleteFuelUsage(c echo.Context) error {
+func (h RESTHandler) GetCarUsers(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	fuelUsageID, err := strconv.Atoi(c.Param("fuelUsageId"))
-	if err != nil {
+	var req models.GetCarUsersRequest
+	if err := c.Bind(&req); err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response := errs.ErrBadRequest
 		return c.JSON(response.Status, response)
 	}
 
-	req := models.DeleteFuelUsageByIDRequest{
-		FuelUsageID: int64(fuelUsageID),
-	}
-
-	if err := h.service.DeleteFuelUsageByID(ctx, req); err != nil {
+	data, err := h.service.GetCarUsers(ctx, req)
+	if err != nil {
 		if response, ok := err.(errs.Err); ok {
 			return c.JSON(response.Status, response)
 		}
@@ -140,24 +151,20 @@ func (h RESTHandler) DeleteFuelUsage(c echo.Context) error {
 		return c.JSON(response.Status, response)
 	}
 
-	return c.JSON(http.StatusOK, nil)
+	return c.JSON(http.StatusOK, data)
 }
 
-func (h RESTHandler) GetFuelUsageByID(c echo.Context) error {
+func (h RESTHandler) GetCarKilometerDiscontinuities(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	fuelUsageID, err := strconv.Atoi(c.Param("fuelUsageId"))
-	if err != nil {
+	var req models.GetCarKilometerDiscontinuitiesRequest
+	if err := c.Bind(&req); err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response := errs.ErrBadRequest
 		return c.JSON(response.Status, response)
 	}
 
-	req := models.GetFuelUsageByIDRequest{
-		FuelUsageID: int64(fuelUsageID),
-	}
-
-	data, err := h.service.GetFuelUsageByID(ctx, req)
+	data, err := h.service.GetCarKilometerDiscontinuities(ctx, req)
 	if err != nil {
 		if response, ok := err.(errs.Err); ok {
 			return c.JSON(response.Status, response)
@@ -169,17 +176,17 @@ func (h RESTHandler) GetFuelUsageByID(c echo.Context) error {
 	return c.JSON(http.StatusOK, data)
 }
 
-func (h RESTHandler) GetFuelRefills(c echo.Context) error {
+func (h RESTHandler) GetCarWalletStatement(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	req := models.GetFuelRefillRequest{}
+	var req models.GetCarWalletStatementRequest
 	if err := c.Bind(&req); err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response := errs.ErrBadRequest
 		return c.JSON(response.Status, response)
 	}
 
-	data, err := h.service.GetFuelRefills(ctx, req)
+	data, err := h.service.GetCarWalletStatement(ctx, req)
 	if err != nil {
 		if response, ok := err.(errs.Err); ok {
 			return c.JSON(response.Status, response)
@@ -191,17 +198,18 @@ func (h RESTHandler) GetFuelRefills(c echo.Context) error {
 	return c.JSON(http.StatusOK, data)
 }
 
-func (h RESTHandler) PostFuelRefill(c echo.Context) error {
+func (h RESTHandler) GetCars(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	var req models.CreateFuelRefillRequest
+	var req models.GetCarsRequest
 	if err := c.Bind(&req); err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response := errs.ErrBadRequest
 		return c.JSON(response.Status, response)
 	}
 
-	if err := h.service.CreateFuelRefill(ctx, req); err != nil {
+	data, err := h.service.GetCars(ctx, req)
+	if err != nil {
 		if response, ok := err.(errs.Err); ok {
 			return c.JSON(response.Status, response)
 		}
@@ -209,24 +217,42 @@ func (h RESTHandler) PostFuelRefill(c echo.Context) error {
 		return c.JSON(response.Status, response)
 	}
 
-	return c.JSON(http.StatusOK, nil)
+	return c.JSON(http.StatusOK, data)
 }
 
-func (h RESTHandler) GetFuelRefillByID(c echo.Context) error {
+func (h RESTHandler) GetCarByID(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	fuelRefillID, err := strconv.Atoi(c.Param("fuelRefillId"))
-	if err != nil {
+	var req models.GetCarByIDRequest
+	if err := c.Bind(&req); err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response := errs.ErrBadRequest
 		return c.JSON(response.Status, response)
 	}
 
-	req := models.GetFuelRefillByIDRequest{
-		FuelRefillID: int64(fuelRefillID),
+	data, err := h.service.GetCarByID(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
 	}
 
-	response, err := h.service.GetFuelRefillByID(ctx, req)
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) PostCar(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.PostCarRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	data, err := h.service.CreateCar(ctx, req)
 	if err != nil {
 		if response, ok := err.(errs.Err); ok {
 			return c.JSON(response.Status, response)
@@ -235,20 +261,20 @@ func (h RESTHandler) GetFuelRefillByID(c echo.Context) error {
 		return c.JSON(response.Status, response)
 	}
 
-	return c.JSON(http.StatusOK, response)
+	return c.JSON(http.StatusOK, data)
 }
 
-func (h RESTHandler) PutFuelRefillByID(c echo.Context) error {
+func (h RESTHandler) PutCarByID(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	req := models.PutFuelRefillByIDRequest{}
+	var req models.PutCarByIDRequest
 	if err := c.Bind(&req); err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response := errs.ErrBadRequest
 		return c.JSON(response.Status, response)
 	}
 
-	if err := h.service.UpdateFuelRefillByID(ctx, req); err != nil {
+	if err := h.service.UpdateCarByID(ctx, req); err != nil {
 		if response, ok := err.(errs.Err); ok {
 			return c.JSON(response.Status, response)
 		}
@@ -259,21 +285,17 @@ func (h RESTHandler) PutFuelRefillByID(c echo.Context) error {
 	return c.JSON(http.StatusOK, nil)
 }
 
-func (h RESTHandler) DeleteFuelRefillByID(c echo.Context) error {
+func (h RESTHandler) DeleteCarByID(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	fuelRefillID, err := strconv.Atoi(c.Param("fuelRefillId"))
-	if err != nil {
+	var req models.DeleteCarByIDRequest
+	if err := c.Bind(&req); err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response := errs.ErrBadRequest
 		return c.JSON(response.Status, response)
 	}
 
-	req := models.DeleteFuelRefillByIDRequest{
-		FuelRefillID: int64(fuelRefillID),
-	}
-
-	if err := h.service.DeleteFuelRefillByID(ctx, req); err != nil {
+	if err := h.service.ArchiveCarByID(ctx, req); err != nil {
 		if response, ok := err.(errs.Err); ok {
 			return c.JSON(response.Status, response)
 		}
@@ -284,21 +306,39 @@ func (h RESTHandler) DeleteFuelRefillByID(c echo.Context) error {
 	return c.JSON(http.StatusOK, nil)
 }
 
-func (h RESTHandler) GetLatestFuelInfoResponse(c echo.Context) error {
+func (h RESTHandler) GetFuelUsages(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	carID, err := strconv.Atoi(c.QueryParam("carId"))
-	if err != nil {
+	var req models.GetFuelUsagesRequest
+	if err := c.Bind(&req); err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response := errs.ErrBadRequest
 		return c.JSON(response.Status, response)
 	}
 
-	req := models.GetLatestFuelInfoRequest{
-		CarID: int64(carID),
+	data, err := h.service.GetFuelUsages(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
 	}
 
-	response, err := h.service.GetLatestFuelInfoResponse(ctx, req)
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) PostFuelUsage(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.CreateFuelUsageRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	response, err := h.service.CreateFuelUsage(ctx, req)
 	if err != nil {
 		if response, ok := err.(errs.Err); ok {
 			return c.JSON(response.Status, response)
@@ -310,38 +350,64 @@ func (h RESTHandler) GetLatestFuelInfoResponse(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
-func (h RESTHandler) GetHealth(c echo.Context) error {
-	return c.JSON(http.StatusOK, struct {
-		ServerStartTime time.Time `json:"serverStartTime"`
-	}{
-		ServerStartTime: h.serverStartTime,
-	})
+func (h RESTHandler) PostFuelUsagePreview(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.CreateFuelUsageRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	response, err := h.service.PreviewFuelUsage(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, response)
 }
 
-func (h RESTHandler) GetUserFuelUsages(c echo.Context) error {
+func (h RESTHandler) PutFuelUsage(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	userID, err := strconv.Atoi(c.Param("userId"))
-	if err != nil {
+	var req models.PutFuelUsageRequest
+	if err := c.Bind(&req); err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response := errs.ErrBadRequest
 		return c.JSON(response.Status, response)
 	}
 
-	isPaid, err := strconv.ParseBool(c.QueryParam("isPaid"))
+	if err := h.service.UpdateFuelUsage(ctx, req); err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, nil)
+}
+
+func (h RESTHandler) DeleteFuelUsage(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	fuelUsageID, err := strconv.Atoi(c.Param("fuelUsageId"))
 	if err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response := errs.ErrBadRequest
 		return c.JSON(response.Status, response)
 	}
 
-	req := models.GetUserFuelUsagesRequest{
-		UserID: int64(userID),
-		IsPaid: isPaid,
+	req := models.DeleteFuelUsageByIDRequest{
+		FuelUsageID: int64(fuelUsageID),
 	}
 
-	data, err := h.service.GetUserFuelUsages(ctx, req)
-	if err != nil {
+	if err := h.service.DeleteFuelUsageByID(ctx, req); err != nil {
 		if response, ok := err.(errs.Err); ok {
 			return c.JSON(response.Status, response)
 		}
@@ -349,32 +415,46 @@ func (h RESTHandler) GetUserFuelUsages(c echo.Context) error {
 		return c.JSON(response.Status, response)
 	}
 
-	return c.JSON(http.StatusOK, data)
+	return c.JSON(http.StatusOK, nil)
 }
 
-func (h RESTHandler) GetUserCarUnpaidActivities(c echo.Context) error {
+func (h RESTHandler) PostFuelUsageAdjustment(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	userID, err := strconv.Atoi(c.Param("userId"))
-	if err != nil {
+	var req models.PostFuelUsageAdjustmentRequest
+	if err := c.Bind(&req); err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response := errs.ErrBadRequest
 		return c.JSON(response.Status, response)
 	}
 
-	carID, err := strconv.Atoi(c.Param("carId"))
+	data, err := h.service.AdjustFuelUsage(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) GetFuelUsageByID(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	fuelUsageID, err := strconv.Atoi(c.Param("fuelUsageId"))
 	if err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response := errs.ErrBadRequest
 		return c.JSON(response.Status, response)
 	}
 
-	req := models.GetUserCarUnpaidActivitiesRequest{
-		UserID: int64(userID),
-		CarID:  int64(carID),
+	req := models.GetFuelUsageByIDRequest{
+		FuelUsageID: int64(fuelUsageID),
 	}
 
-	data, err := h.service.GetUserCarUnpaidActivities(ctx, req)
+	data, err := h.service.GetFuelUsageByID(ctx, req)
 	if err != nil {
 		if response, ok := err.(errs.Err); ok {
 			return c.JSON(response.Status, response)
@@ -386,26 +466,39 @@ func (h RESTHandler) GetUserCarUnpaidActivities(c echo.Context) error {
 	return c.JSON(http.StatusOK, data)
 }
 
-func (h RESTHandler) BulkUpdateUserFuelUsagePaymentStatus(c echo.Context) error {
+func (h RESTHandler) GetFuelRefills(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	var req models.BulkUpdateUserFuelUsagePaymentStatusRequest
+	req := models.GetFuelRefillRequest{}
 	if err := c.Bind(&req); err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response := errs.ErrBadRequest
 		return c.JSON(response.Status, response)
 	}
 
-	userID, err := strconv.Atoi(c.Param("userId"))
+	data, err := h.service.GetFuelRefills(ctx, req)
 	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) PostFuelRefill(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.CreateFuelRefillRequest
+	if err := c.Bind(&req); err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		response := errs.ErrBadRequest
 		return c.JSON(response.Status, response)
 	}
 
-	req.UserID = int64(userID)
-
-	if err := h.service.BulkUpdateUserFuelUsagePaymentStatus(ctx, req); err != nil {
+	if err := h.service.CreateFuelRefill(ctx, req); err != nil {
 		if response, ok := err.(errs.Err); ok {
 			return c.JSON(response.Status, response)
 		}
@@ -416,17 +509,22 @@ func (h RESTHandler) BulkUpdateUserFuelUsagePaymentStatus(c echo.Context) error
 	return c.JSON(http.StatusOK, nil)
 }
 
-func (h RESTHandler) PayUserCarUnpaidActivities(c echo.Context) error {
+func (h RESTHandler) GetFuelRefillByID(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	var req models.PayUserCarUnpaidActivitiesRequest
-	if err := c.Bind(&req); err != nil {
+	fuelRefillID, err := strconv.Atoi(c.Param("fuelRefillId"))
+	if err != nil {
 		slog.ErrorContext(ctx, err.Error())
-		res := errs.ErrBadRequest
-		return c.JSON(res.Status, res)
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
 	}
 
-	if err := h.service.PayUserCarUnpaidActivities(ctx, req); err != nil {
+	req := models.GetFuelRefillByIDRequest{
+		FuelRefillID: int64(fuelRefillID),
+	}
+
+	response, err := h.service.GetFuelRefillByID(ctx, req)
+	if err != nil {
 		if response, ok := err.(errs.Err); ok {
 			return c.JSON(response.Status, response)
 		}
@@ -434,5 +532,548 @@ func (h RESTHandler) PayUserCarUnpaidActivities(c echo.Context) error {
 		return c.JSON(response.Status, response)
 	}
 
-	return c.JSON(http.StatusOK, nil)
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h RESTHandler) PutFuelRefillByID(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	req := models.PutFuelRefillByIDRequest{}
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	response, err := h.service.UpdateFuelRefillByID(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h RESTHandler) DeleteFuelRefillByID(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	fuelRefillID, err := strconv.Atoi(c.Param("fuelRefillId"))
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	req := models.DeleteFuelRefillByIDRequest{
+		FuelRefillID: int64(fuelRefillID),
+	}
+
+	if err := h.service.DeleteFuelRefillByID(ctx, req); err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, nil)
+}
+
+func (h RESTHandler) PostFuelRefillAdjustment(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.PostFuelRefillAdjustmentRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	data, err := h.service.AdjustFuelRefill(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) GetLatestFuelInfoResponse(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	carID, err := strconv.Atoi(c.QueryParam("carId"))
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	req := models.GetLatestFuelInfoRequest{
+		CarID: int64(carID),
+	}
+
+	response, err := h.service.GetLatestFuelInfoResponse(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h RESTHandler) GetHealth(c echo.Context) error {
+	return c.JSON(http.StatusOK, struct {
+		ServerStartTime time.Time `json:"serverStartTime"`
+	}{
+		ServerStartTime: h.serverStartTime,
+	})
+}
+
+func (h RESTHandler) GetUserFuelUsages(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	isPaid, err := strconv.ParseBool(c.QueryParam("isPaid"))
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	req := models.GetUserFuelUsagesRequest{
+		UserID: int64(userID),
+		IsPaid: isPaid,
+	}
+
+	data, err := h.service.GetUserFuelUsages(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) GetUserCarUnpaidActivities(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	carID, err := strconv.Atoi(c.Param("carId"))
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	var isPromptPayIncluded bool
+	if promptPay := c.QueryParam("promptPay"); promptPay != "" {
+		isPromptPayIncluded, err = strconv.ParseBool(promptPay)
+		if err != nil {
+			slog.ErrorContext(ctx, err.Error())
+			response := errs.ErrBadRequest
+			return c.JSON(response.Status, response)
+		}
+	}
+
+	req := models.GetUserCarUnpaidActivitiesRequest{
+		UserID:              int64(userID),
+		CarID:               int64(carID),
+		IsPromptPayIncluded: isPromptPayIncluded,
+	}
+
+	data, err := h.service.GetUserCarUnpaidActivities(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) BulkUpdateUserFuelUsagePaymentStatus(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.BulkUpdateUserFuelUsagePaymentStatusRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	req.UserID = int64(userID)
+
+	if err := h.service.BulkUpdateUserFuelUsagePaymentStatus(ctx, req); err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, nil)
+}
+
+func (h RESTHandler) PayUserCarUnpaidActivities(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.PayUserCarUnpaidActivitiesRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		res := errs.ErrBadRequest
+		return c.JSON(res.Status, res)
+	}
+
+	data, err := h.service.PayUserCarUnpaidActivities(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) PostLogin(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.PostLoginRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	data, err := h.service.Login(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) GetUserCarSettlement(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.GetUserCarSettlementRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	data, err := h.service.GetUserCarSettlement(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) PostUserCarSettlement(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.PostUserCarSettlementRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	data, err := h.service.CreateUserCarSettlement(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) PostUserCarPayment(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.PostUserCarPaymentRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	data, err := h.service.CreateUserCarPayment(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) GetUserCarPayments(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.GetUserCarPaymentsRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	data, err := h.service.GetUserCarPayments(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) GetUserPendingPayments(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.GetUserPendingPaymentsRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	data, err := h.service.GetUserPendingPayments(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) ConfirmUserPayment(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.PatchUserPaymentConfirmRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	data, err := h.service.ConfirmUserPayment(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) RejectUserPayment(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.PatchUserPaymentRejectRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	data, err := h.service.RejectUserPayment(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) PostUserCarWalletTopUp(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.PostUserCarWalletTopUpRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	data, err := h.service.TopUpUserCarWallet(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) GetUserBalance(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.GetUserBalanceRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	data, err := h.service.GetUserBalance(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) GetDebtSimplification(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.GetDebtSimplificationRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	data, err := h.service.GetDebtSimplification(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) PostDebtSettlement(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.PostDebtSettlementRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	data, err := h.service.CreateDebtSettlement(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h RESTHandler) PostUserAvatar(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.PostUserAvatarRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	fileHeader, err := c.FormFile("avatar")
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+	defer file.Close()
+
+	// read one byte over the limit so the service can reject a larger file
+	req.Image, err = io.ReadAll(io.LimitReader(file, services.AvatarMaxBytes+1))
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		response := errs.ErrBadRequest
+		return c.JSON(response.Status, response)
+	}
+
+	data, err := h.service.UploadUserAvatar(ctx, req)
+	if err != nil {
+		if response, ok := err.(errs.Err); ok {
+			return c.JSON(response.Status, response)
+		}
+		response := errs.ErrAPIFailed
+		return c.JSON(response.Status, response)
+	}
+
+	return c.JSON(http.StatusOK, data)
 }
@@ -0,0 +1,88 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// postgresLockKey is the pg_advisory_lock key every instance uses to migrate.
+	postgresLockKey int64 = 20240101
+
+	sqliteLockRetryInterval = time.Second
+	// sqliteLockStaleAfter clears a lock left behind by a crashed instance.
+	sqliteLockStaleAfter = 10 * time.Minute
+)
+
+// Lock blocks until it holds a database-level lock so only one instance
+// migrates at a time, the returned unlock releases it.
+func Lock(ctx context.Context, db *gorm.DB) (unlock func() error, err error) {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return lockPostgres(ctx, db)
+	case "sqlite":
+		return lockSQLite(ctx, db)
+	default:
+		return nil, fmt.Errorf("lock is not supported on %s", db.Dialector.Name())
+	}
+}
+
+// lockPostgres holds a session-level advisory lock on its own connection,
+// so migrations still run on the other connections of the pool.
+func lockPostgres(ctx context.Context, db *gorm.DB) (func() error, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", postgresLockKey); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return func() error {
+		defer conn.Close()
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", postgresLockKey)
+		return err
+	}, nil
+}
+
+// lockSQLite holds the lock by owning the only row of migration_locks.
+func lockSQLite(ctx context.Context, db *gorm.DB) (func() error, error) {
+	db = db.WithContext(ctx)
+	err := db.Exec(`CREATE TABLE IF NOT EXISTS migration_locks (
+		id INTEGER PRIMARY KEY,
+		locked_at DATETIME NOT NULL
+	);`).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		now := time.Now()
+		if err := db.Exec(`DELETE FROM migration_locks WHERE locked_at < ?;`, now.Add(-sqliteLockStaleAfter)).Error; err != nil {
+			return nil, err
+		}
+		result := db.Exec(`INSERT OR IGNORE INTO migration_locks (id, locked_at) VALUES (1, ?);`, now)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(sqliteLockRetryInterval):
+		}
+	}
+
+	return func() error {
+		return db.WithContext(context.Background()).Exec(`DELETE FROM migration_locks WHERE id = 1;`).Error
+	}, nil
+}
@@ -0,0 +1,72 @@
+package mgpostgres
+
+import (
+	"context"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		ID:         13,
+		Up:         up13,
+		VerifyUp:   verifyUp13,
+		Down:       down13,
+		VerifyDown: verifyDown13,
+	})
+}
+
+func up13(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`ALTER TABLE cars ADD COLUMN plate_number VARCHAR(50) NOT NULL DEFAULT '';`,
+		`ALTER TABLE cars ADD COLUMN fuel_type VARCHAR(50) NOT NULL DEFAULT '';`,
+		`ALTER TABLE cars ADD COLUMN tank_capacity DECIMAL(10,3) NOT NULL DEFAULT 0;`,
+		`ALTER TABLE cars ADD COLUMN owner_user_id BIGINT NOT NULL DEFAULT 0;`,
+		`ALTER TABLE cars ADD COLUMN is_archived BOOL NOT NULL DEFAULT false;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyUp13(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"cars": {
+			ShouldHaveColumn: {"plate_number", "fuel_type", "tank_capacity", "owner_user_id", "is_archived"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
+
+func down13(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`ALTER TABLE cars DROP COLUMN plate_number;`,
+		`ALTER TABLE cars DROP COLUMN fuel_type;`,
+		`ALTER TABLE cars DROP COLUMN tank_capacity;`,
+		`ALTER TABLE cars DROP COLUMN owner_user_id;`,
+		`ALTER TABLE cars DROP COLUMN is_archived;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyDown13(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"cars": {
+			ShouldNotHaveColumn: {"plate_number", "fuel_type", "tank_capacity", "owner_user_id", "is_archived"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
@@ -0,0 +1,64 @@
+package mgpostgres
+
+import (
+	"context"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		ID:         14,
+		Up:         up14,
+		VerifyUp:   verifyUp14,
+		Down:       down14,
+		VerifyDown: verifyDown14,
+	})
+}
+
+func up14(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`ALTER TABLE users ADD COLUMN is_deactivated BOOL NOT NULL DEFAULT false;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyUp14(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"users": {
+			ShouldHaveColumn: {"is_deactivated"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
+
+func down14(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`ALTER TABLE users DROP COLUMN is_deactivated;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyDown14(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"users": {
+			ShouldNotHaveColumn: {"is_deactivated"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
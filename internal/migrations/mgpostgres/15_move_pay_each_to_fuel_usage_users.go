@@ -0,0 +1,173 @@
+package mgpostgres
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/library/allocations"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		ID:         15,
+		Up:         up15,
+		VerifyUp:   verifyUp15,
+		Down:       down15,
+		VerifyDown: verifyDown15,
+	})
+}
+
+// up15 keeps the amount of every user on fuel_usage_users, so the users of
+// one fuel usage can pay different amounts.
+func up15(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`ALTER TABLE fuel_usages ADD COLUMN split_mode VARCHAR(20) NOT NULL DEFAULT 'EQUAL';`,
+		`ALTER TABLE fuel_usage_users ADD COLUMN split_value DECIMAL(10,3) NOT NULL DEFAULT 0;`,
+		`ALTER TABLE fuel_usage_users ADD COLUMN amount DECIMAL(10,3) NOT NULL DEFAULT 0;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+
+	if err := allocateAmounts15(ctx, tx); err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+
+	if err := tx.WithContext(ctx).Exec(`ALTER TABLE fuel_usages DROP COLUMN pay_each;`).Error; err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// allocateAmounts15 splits the total money of every fuel usage equally by
+// the largest remainder method, the leftover satang go to the first users,
+// so the amounts sum to the total money. The ledger backfilled from the
+// rounded pay_each is corrected by the difference.
+func allocateAmounts15(ctx context.Context, tx *gorm.DB) error {
+	type fuelUsageUser struct {
+		ID          int64           `gorm:"column:id"`
+		FuelUsageID int64           `gorm:"column:fuel_usage_id"`
+		UserID      int64           `gorm:"column:user_id"`
+		IsPaid      bool            `gorm:"column:is_paid"`
+		CarID       int64           `gorm:"column:car_id"`
+		TotalMoney  decimal.Decimal `gorm:"column:total_money"`
+		PayEach     decimal.Decimal `gorm:"column:pay_each"`
+	}
+
+	var fuelUsageUsers []fuelUsageUser
+	err := tx.WithContext(ctx).Raw(
+		`SELECT fuu.id, fuu.fuel_usage_id, fuu.user_id, fuu.is_paid, fu.car_id, fu.total_money, COALESCE(fu.pay_each, 0) AS pay_each
+		FROM fuel_usage_users AS fuu
+		INNER JOIN fuel_usages AS fu ON fu.id = fuu.fuel_usage_id
+		ORDER BY fuu.fuel_usage_id, fuu.id`,
+	).Scan(&fuelUsageUsers).Error
+	if err != nil {
+		return err
+	}
+
+	insertLedgerEntry := `INSERT INTO ledger_entries (user_id, car_id, entry_type, reference_type, reference_id, debit, credit, create_time)
+		VALUES (?, ?, ?, 'FUEL_USAGE', ?, ?, ?, ?);`
+	now := time.Now()
+
+	for start := 0; start < len(fuelUsageUsers); {
+		end := start
+		for end < len(fuelUsageUsers) && fuelUsageUsers[end].FuelUsageID == fuelUsageUsers[start].FuelUsageID {
+			end++
+		}
+		sameFuelUsage := fuelUsageUsers[start:end]
+		start = end
+
+		weights := make([]decimal.Decimal, len(sameFuelUsage))
+		order := make([]int, len(sameFuelUsage))
+		for i := range sameFuelUsage {
+			weights[i] = decimal.NewFromInt(1)
+			order[i] = i
+		}
+		amounts := allocations.ByWeights(sameFuelUsage[0].TotalMoney, weights, order)
+
+		for i, f := range sameFuelUsage {
+			err := tx.WithContext(ctx).Exec(`UPDATE fuel_usage_users SET amount = ? WHERE id = ?;`, amounts[i], f.ID).Error
+			if err != nil {
+				return err
+			}
+
+			difference := amounts[i].Sub(f.PayEach)
+			if difference.IsZero() {
+				continue
+			}
+			debit, credit := difference, decimal.Zero
+			if difference.IsNegative() {
+				debit, credit = decimal.Zero, difference.Neg()
+			}
+			err = tx.WithContext(ctx).Exec(insertLedgerEntry, f.UserID, f.CarID, "FUEL_USAGE_SHARE", f.FuelUsageID, debit, credit, now).Error
+			if err != nil {
+				return err
+			}
+			// a paid share was paid in full, so is its difference
+			if f.IsPaid {
+				err := tx.WithContext(ctx).Exec(insertLedgerEntry, f.UserID, f.CarID, "FUEL_USAGE_SHARE_PAYMENT", f.FuelUsageID, credit, debit, now).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func verifyUp15(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"fuel_usages": {
+			ShouldHaveColumn:    {"split_mode"},
+			ShouldNotHaveColumn: {"pay_each"},
+		},
+		"fuel_usage_users": {
+			ShouldHaveColumn: {"split_value", "amount"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
+
+// down15 puts back the average amount as pay_each, which is exact for the
+// equal splits only.
+func down15(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`ALTER TABLE fuel_usages ADD COLUMN pay_each DECIMAL(10,3) DEFAULT NULL;`,
+		`UPDATE fuel_usages SET pay_each = (
+			SELECT ROUND(AVG(fuu.amount), 2) FROM fuel_usage_users AS fuu WHERE fuu.fuel_usage_id = fuel_usages.id
+		);`,
+		`ALTER TABLE fuel_usage_users DROP COLUMN amount;`,
+		`ALTER TABLE fuel_usage_users DROP COLUMN split_value;`,
+		`ALTER TABLE fuel_usages DROP COLUMN split_mode;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyDown15(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"fuel_usages": {
+			ShouldHaveColumn:    {"pay_each"},
+			ShouldNotHaveColumn: {"split_mode"},
+		},
+		"fuel_usage_users": {
+			ShouldNotHaveColumn: {"split_value", "amount"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
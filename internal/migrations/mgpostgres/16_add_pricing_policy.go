@@ -0,0 +1,89 @@
+package mgpostgres
+
+import (
+	"context"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		ID:         16,
+		Up:         up16,
+		VerifyUp:   verifyUp16,
+		Down:       down16,
+		VerifyDown: verifyDown16,
+	})
+}
+
+// up16 adds the pricing policy of a car and the cost breakdown of a fuel
+// usage, the fuel usages before are all fuel cost.
+func up16(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`ALTER TABLE cars ADD COLUMN extra_per_km DECIMAL(10,3) NOT NULL DEFAULT 0;`,
+		`ALTER TABLE cars ADD COLUMN trip_fee DECIMAL(10,3) NOT NULL DEFAULT 0;`,
+		`ALTER TABLE cars ADD COLUMN minimum_charge DECIMAL(10,3) NOT NULL DEFAULT 0;`,
+		`ALTER TABLE cars ADD COLUMN is_rounded_up BOOL NOT NULL DEFAULT false;`,
+		`ALTER TABLE fuel_usages ADD COLUMN fuel_cost DECIMAL(10,3) NOT NULL DEFAULT 0;`,
+		`ALTER TABLE fuel_usages ADD COLUMN distance_fee DECIMAL(10,3) NOT NULL DEFAULT 0;`,
+		`ALTER TABLE fuel_usages ADD COLUMN trip_fee DECIMAL(10,3) NOT NULL DEFAULT 0;`,
+		`ALTER TABLE fuel_usages ADD COLUMN minimum_charge_top_up DECIMAL(10,3) NOT NULL DEFAULT 0;`,
+		`ALTER TABLE fuel_usages ADD COLUMN round_up DECIMAL(10,3) NOT NULL DEFAULT 0;`,
+		`UPDATE fuel_usages SET fuel_cost = total_money;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyUp16(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"cars": {
+			ShouldHaveColumn: {"extra_per_km", "trip_fee", "minimum_charge", "is_rounded_up"},
+		},
+		"fuel_usages": {
+			ShouldHaveColumn: {"fuel_cost", "distance_fee", "trip_fee", "minimum_charge_top_up", "round_up"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
+
+func down16(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`ALTER TABLE fuel_usages DROP COLUMN round_up;`,
+		`ALTER TABLE fuel_usages DROP COLUMN minimum_charge_top_up;`,
+		`ALTER TABLE fuel_usages DROP COLUMN trip_fee;`,
+		`ALTER TABLE fuel_usages DROP COLUMN distance_fee;`,
+		`ALTER TABLE fuel_usages DROP COLUMN fuel_cost;`,
+		`ALTER TABLE cars DROP COLUMN is_rounded_up;`,
+		`ALTER TABLE cars DROP COLUMN minimum_charge;`,
+		`ALTER TABLE cars DROP COLUMN trip_fee;`,
+		`ALTER TABLE cars DROP COLUMN extra_per_km;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyDown16(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"cars": {
+			ShouldNotHaveColumn: {"extra_per_km", "trip_fee", "minimum_charge", "is_rounded_up"},
+		},
+		"fuel_usages": {
+			ShouldNotHaveColumn: {"fuel_cost", "distance_fee", "trip_fee", "minimum_charge_top_up", "round_up"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
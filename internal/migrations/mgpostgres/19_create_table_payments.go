@@ -0,0 +1,102 @@
+package mgpostgres
+
+import (
+	"context"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		ID:         19,
+		Up:         up19,
+		VerifyUp:   verifyUp19,
+		Down:       down19,
+		VerifyDown: verifyDown19,
+	})
+}
+
+// up19 records the money paid on every share and refill, the shares and
+// refills which were already paid are taken as paid in full.
+func up19(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`CREATE TABLE IF NOT EXISTS payments (
+			id SERIAL PRIMARY KEY NOT NULL,
+			car_id BIGINT NOT NULL,
+			payer_user_id BIGINT NOT NULL DEFAULT 0,
+			payee_user_id BIGINT NOT NULL DEFAULT 0,
+			amount DECIMAL(10,3) NOT NULL,
+			method VARCHAR(20) NOT NULL,
+			pay_time TIMESTAMP WITH TIME ZONE NOT NULL,
+			create_by BIGINT NOT NULL,
+			create_time TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS payment_allocations (
+			id SERIAL PRIMARY KEY NOT NULL,
+			payment_id BIGINT NOT NULL,
+			item_type VARCHAR(20) NOT NULL,
+			item_id BIGINT NOT NULL,
+			amount DECIMAL(10,3) NOT NULL
+		);`,
+		`ALTER TABLE fuel_usage_users ADD COLUMN paid_amount DECIMAL(10,3) NOT NULL DEFAULT 0;`,
+		`UPDATE fuel_usage_users SET paid_amount = amount WHERE is_paid = true;`,
+		`ALTER TABLE fuel_refills ADD COLUMN paid_amount DECIMAL(10,3) NOT NULL DEFAULT 0;`,
+		`UPDATE fuel_refills SET paid_amount = total_money WHERE is_paid = true;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyUp19(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	if err := tableShouldExist(migrator, "payments", "payment_allocations"); err != nil {
+		return err
+	}
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"fuel_usage_users": {
+			ShouldHaveColumn: {"paid_amount"},
+		},
+		"fuel_refills": {
+			ShouldHaveColumn: {"paid_amount"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
+
+func down19(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`DROP TABLE IF EXISTS payments;`,
+		`DROP TABLE IF EXISTS payment_allocations;`,
+		`ALTER TABLE fuel_usage_users DROP COLUMN paid_amount;`,
+		`ALTER TABLE fuel_refills DROP COLUMN paid_amount;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyDown19(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	if err := tableShouldNotExist(migrator, "payments", "payment_allocations"); err != nil {
+		return err
+	}
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"fuel_usage_users": {
+			ShouldNotHaveColumn: {"paid_amount"},
+		},
+		"fuel_refills": {
+			ShouldNotHaveColumn: {"paid_amount"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
@@ -0,0 +1,100 @@
+package mgpostgres
+
+import (
+	"context"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		ID:         22,
+		Up:         up22,
+		VerifyUp:   verifyUp22,
+		Down:       down22,
+		VerifyDown: verifyDown22,
+	})
+}
+
+// up22 adds the wallet of a car, the fuel usages and refills which were
+// already recorded stay out of it.
+func up22(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`CREATE TABLE IF NOT EXISTS wallet_transactions (
+			id SERIAL PRIMARY KEY NOT NULL,
+			car_id BIGINT NOT NULL,
+			user_id BIGINT NOT NULL DEFAULT 0,
+			transaction_type VARCHAR(20) NOT NULL,
+			reference_type VARCHAR(20) NOT NULL DEFAULT '',
+			reference_id BIGINT NOT NULL DEFAULT 0,
+			amount DECIMAL(10,3) NOT NULL,
+			description VARCHAR(255) NOT NULL DEFAULT '',
+			create_time TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);`,
+		`ALTER TABLE cars ADD COLUMN is_wallet_enabled BOOL NOT NULL DEFAULT false;`,
+		`ALTER TABLE fuel_usages ADD COLUMN is_paid_from_wallet BOOL NOT NULL DEFAULT false;`,
+		`ALTER TABLE fuel_refills ADD COLUMN is_paid_from_wallet BOOL NOT NULL DEFAULT false;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyUp22(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	if err := tableShouldExist(migrator, "wallet_transactions"); err != nil {
+		return err
+	}
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"cars": {
+			ShouldHaveColumn: {"is_wallet_enabled"},
+		},
+		"fuel_usages": {
+			ShouldHaveColumn: {"is_paid_from_wallet"},
+		},
+		"fuel_refills": {
+			ShouldHaveColumn: {"is_paid_from_wallet"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
+
+func down22(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`DROP TABLE IF EXISTS wallet_transactions;`,
+		`ALTER TABLE cars DROP COLUMN is_wallet_enabled;`,
+		`ALTER TABLE fuel_usages DROP COLUMN is_paid_from_wallet;`,
+		`ALTER TABLE fuel_refills DROP COLUMN is_paid_from_wallet;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyDown22(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	if err := tableShouldNotExist(migrator, "wallet_transactions"); err != nil {
+		return err
+	}
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"cars": {
+			ShouldNotHaveColumn: {"is_wallet_enabled"},
+		},
+		"fuel_usages": {
+			ShouldNotHaveColumn: {"is_paid_from_wallet"},
+		},
+		"fuel_refills": {
+			ShouldNotHaveColumn: {"is_paid_from_wallet"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
@@ -0,0 +1,66 @@
+package mgpostgres
+
+import (
+	"context"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		ID:         9,
+		Up:         up9,
+		VerifyUp:   verifyUp9,
+		Down:       down9,
+		VerifyDown: verifyDown9,
+	})
+}
+
+func up9(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`CREATE TABLE IF NOT EXISTS user_credentials (
+			id SERIAL PRIMARY KEY NOT NULL,
+			user_id BIGINT NOT NULL UNIQUE,
+			username VARCHAR(100) NOT NULL UNIQUE,
+			password_hash VARCHAR(200) NOT NULL,
+			create_time TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			update_time TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyUp9(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"user_credentials": {
+			ShouldHaveColumn: {"id", "user_id", "username", "password_hash", "create_time", "update_time"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
+
+func down9(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`DROP TABLE IF EXISTS user_credentials;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyDown9(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	return tableShouldNotExist(migrator, "user_credentials")
+}
@@ -0,0 +1,78 @@
+package mgsqlite
+
+import (
+	"context"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		ID:         10,
+		Up:         up10,
+		VerifyUp:   verifyUp10,
+		Down:       down10,
+		VerifyDown: verifyDown10,
+	})
+}
+
+func up10(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`CREATE TABLE IF NOT EXISTS settlements (
+			id INTEGER PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			car_id BIGINT NOT NULL,
+			fuel_usage_amount DECIMAL(10,3) NOT NULL,
+			fuel_refill_amount DECIMAL(10,3) NOT NULL,
+			remaining_amount DECIMAL(10,3) NOT NULL,
+			remaining_direction VARCHAR(20) NOT NULL,
+			create_by BIGINT NOT NULL,
+			create_time DATETIME NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS settlement_items (
+			id INTEGER PRIMARY KEY,
+			settlement_id BIGINT NOT NULL,
+			item_type VARCHAR(20) NOT NULL,
+			item_id BIGINT NOT NULL,
+			amount DECIMAL(10,3) NOT NULL
+		);`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyUp10(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	return tableShouldExist(migrator,
+		"settlements",
+		"settlement_items",
+	)
+}
+
+func down10(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`DROP TABLE IF EXISTS settlements;`,
+		`DROP TABLE IF EXISTS settlement_items;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyDown10(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	return tableShouldNotExist(migrator,
+		"settlements",
+		"settlement_items",
+	)
+}
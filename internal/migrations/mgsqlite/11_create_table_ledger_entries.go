@@ -0,0 +1,99 @@
+package mgsqlite
+
+import (
+	"context"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		ID:         11,
+		Up:         up11,
+		VerifyUp:   verifyUp11,
+		Down:       down11,
+		VerifyDown: verifyDown11,
+	})
+}
+
+func up11(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`CREATE TABLE IF NOT EXISTS ledger_entries (
+			id INTEGER PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			car_id BIGINT NOT NULL,
+			entry_type VARCHAR(50) NOT NULL,
+			reference_type VARCHAR(50) NOT NULL,
+			reference_id BIGINT NOT NULL,
+			debit DECIMAL(10,3) NOT NULL DEFAULT 0,
+			credit DECIMAL(10,3) NOT NULL DEFAULT 0,
+			create_time DATETIME NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS ledger_entries_user_id_car_id_idx ON ledger_entries (user_id, car_id);`,
+		`CREATE INDEX IF NOT EXISTS ledger_entries_reference_idx ON ledger_entries (reference_type, reference_id);`,
+
+		// backfill the ledger from the existing payment flags
+		`INSERT INTO ledger_entries (user_id, car_id, entry_type, reference_type, reference_id, debit, credit, create_time)
+		SELECT fuu.user_id, fu.car_id, 'FUEL_USAGE_SHARE', 'FUEL_USAGE', fu.id, fu.pay_each, 0, fu.fuel_use_time
+		FROM fuel_usage_users AS fuu
+		INNER JOIN fuel_usages AS fu ON fu.id = fuu.fuel_usage_id;`,
+		`INSERT INTO ledger_entries (user_id, car_id, entry_type, reference_type, reference_id, debit, credit, create_time)
+		SELECT fuu.user_id, fu.car_id, 'FUEL_USAGE_SHARE_PAYMENT', 'FUEL_USAGE', fu.id, 0, fu.pay_each, fu.fuel_use_time
+		FROM fuel_usage_users AS fuu
+		INNER JOIN fuel_usages AS fu ON fu.id = fuu.fuel_usage_id
+		WHERE fuu.is_paid = true;`,
+		`INSERT INTO ledger_entries (user_id, car_id, entry_type, reference_type, reference_id, debit, credit, create_time)
+		SELECT fr.refill_by, fr.car_id, 'FUEL_REFILL', 'FUEL_REFILL', fr.id, 0, fr.total_money, fr.refill_time
+		FROM fuel_refills AS fr;`,
+		`INSERT INTO ledger_entries (user_id, car_id, entry_type, reference_type, reference_id, debit, credit, create_time)
+		SELECT fr.refill_by, fr.car_id, 'FUEL_REFILL_REIMBURSEMENT', 'FUEL_REFILL', fr.id, fr.total_money, 0, fr.refill_time
+		FROM fuel_refills AS fr
+		WHERE fr.is_paid = true;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyUp11(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"ledger_entries": {
+			ShouldHaveColumn: {
+				"id",
+				"user_id",
+				"car_id",
+				"entry_type",
+				"reference_type",
+				"reference_id",
+				"debit",
+				"credit",
+				"create_time",
+			},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
+
+func down11(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`DROP TABLE IF EXISTS ledger_entries;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyDown11(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	return tableShouldNotExist(migrator, "ledger_entries")
+}
@@ -0,0 +1,74 @@
+package mgsqlite
+
+import (
+	"context"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		ID:         12,
+		Up:         up12,
+		VerifyUp:   verifyUp12,
+		Down:       down12,
+		VerifyDown: verifyDown12,
+	})
+}
+
+func up12(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`CREATE TABLE IF NOT EXISTS debt_settlements (
+			id INTEGER PRIMARY KEY,
+			car_id BIGINT NOT NULL,
+			unsettled_amount DECIMAL(10,3) NOT NULL,
+			create_by BIGINT NOT NULL,
+			create_time DATETIME NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS debt_settlement_transfers (
+			id INTEGER PRIMARY KEY,
+			debt_settlement_id BIGINT NOT NULL,
+			from_user_id BIGINT NOT NULL,
+			to_user_id BIGINT NOT NULL,
+			amount DECIMAL(10,3) NOT NULL
+		);`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyUp12(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	return tableShouldExist(migrator,
+		"debt_settlements",
+		"debt_settlement_transfers",
+	)
+}
+
+func down12(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`DROP TABLE IF EXISTS debt_settlements;`,
+		`DROP TABLE IF EXISTS debt_settlement_transfers;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyDown12(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	return tableShouldNotExist(migrator,
+		"debt_settlements",
+		"debt_settlement_transfers",
+	)
+}
@@ -0,0 +1,82 @@
+package mgsqlite
+
+import (
+	"context"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		ID:         17,
+		Up:         up17,
+		VerifyUp:   verifyUp17,
+		Down:       down17,
+		VerifyDown: verifyDown17,
+	})
+}
+
+func up17(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`ALTER TABLE cars ADD COLUMN fare_rule VARCHAR(20) NOT NULL DEFAULT 'NONE';`,
+		`ALTER TABLE fuel_usages ADD COLUMN driver_user_id BIGINT NOT NULL DEFAULT 0;`,
+		`ALTER TABLE fuel_usages ADD COLUMN fare_rule VARCHAR(20) NOT NULL DEFAULT 'NONE';`,
+		`ALTER TABLE fuel_usage_users ADD COLUMN fare_weight DECIMAL(10,3) NOT NULL DEFAULT 1;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyUp17(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"cars": {
+			ShouldHaveColumn: {"fare_rule"},
+		},
+		"fuel_usages": {
+			ShouldHaveColumn: {"driver_user_id", "fare_rule"},
+		},
+		"fuel_usage_users": {
+			ShouldHaveColumn: {"fare_weight"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
+
+func down17(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`ALTER TABLE fuel_usage_users DROP COLUMN fare_weight;`,
+		`ALTER TABLE fuel_usages DROP COLUMN fare_rule;`,
+		`ALTER TABLE fuel_usages DROP COLUMN driver_user_id;`,
+		`ALTER TABLE cars DROP COLUMN fare_rule;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyDown17(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"cars": {
+			ShouldNotHaveColumn: {"fare_rule"},
+		},
+		"fuel_usages": {
+			ShouldNotHaveColumn: {"driver_user_id", "fare_rule"},
+		},
+		"fuel_usage_users": {
+			ShouldNotHaveColumn: {"fare_weight"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
@@ -0,0 +1,73 @@
+package mgsqlite
+
+import (
+	"context"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		ID:         18,
+		Up:         up18,
+		VerifyUp:   verifyUp18,
+		Down:       down18,
+		VerifyDown: verifyDown18,
+	})
+}
+
+func up18(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`ALTER TABLE fuel_usages ADD COLUMN fuel_refill_id BIGINT NOT NULL DEFAULT 0;`,
+		// the usages were priced by the latest refill of the car before them
+		`UPDATE fuel_usages SET fuel_refill_id = COALESCE((
+			SELECT fr.id FROM fuel_refills fr
+			WHERE fr.car_id = fuel_usages.car_id
+				AND fr.refill_time <= fuel_usages.fuel_use_time
+				AND fr.fuel_price_calculated = fuel_usages.fuel_price
+			ORDER BY fr.refill_time DESC, fr.id DESC
+			LIMIT 1
+		), 0);`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyUp18(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"fuel_usages": {
+			ShouldHaveColumn: {"fuel_refill_id"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
+
+func down18(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`ALTER TABLE fuel_usages DROP COLUMN fuel_refill_id;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyDown18(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"fuel_usages": {
+			ShouldNotHaveColumn: {"fuel_refill_id"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
@@ -0,0 +1,90 @@
+package mgsqlite
+
+import (
+	"context"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		ID:         20,
+		Up:         up20,
+		VerifyUp:   verifyUp20,
+		Down:       down20,
+		VerifyDown: verifyDown20,
+	})
+}
+
+// up20 lets the creditor confirm or reject a payment, the payments made so
+// far are taken as confirmed.
+func up20(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`ALTER TABLE payments ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'CONFIRMED';`,
+		`ALTER TABLE payments ADD COLUMN confirm_user_id BIGINT NOT NULL DEFAULT 0;`,
+		`ALTER TABLE payments ADD COLUMN reject_reason VARCHAR(255) NOT NULL DEFAULT '';`,
+		`ALTER TABLE payments ADD COLUMN review_by BIGINT NOT NULL DEFAULT 0;`,
+		`ALTER TABLE payments ADD COLUMN review_time DATETIME;`,
+		`ALTER TABLE fuel_usage_users ADD COLUMN claimed_amount DECIMAL(10,3) NOT NULL DEFAULT 0;`,
+		`ALTER TABLE fuel_refills ADD COLUMN claimed_amount DECIMAL(10,3) NOT NULL DEFAULT 0;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyUp20(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"payments": {
+			ShouldHaveColumn: {"status", "confirm_user_id", "reject_reason", "review_by", "review_time"},
+		},
+		"fuel_usage_users": {
+			ShouldHaveColumn: {"claimed_amount"},
+		},
+		"fuel_refills": {
+			ShouldHaveColumn: {"claimed_amount"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
+
+func down20(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`ALTER TABLE payments DROP COLUMN status;`,
+		`ALTER TABLE payments DROP COLUMN confirm_user_id;`,
+		`ALTER TABLE payments DROP COLUMN reject_reason;`,
+		`ALTER TABLE payments DROP COLUMN review_by;`,
+		`ALTER TABLE payments DROP COLUMN review_time;`,
+		`ALTER TABLE fuel_usage_users DROP COLUMN claimed_amount;`,
+		`ALTER TABLE fuel_refills DROP COLUMN claimed_amount;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyDown20(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"payments": {
+			ShouldNotHaveColumn: {"status", "confirm_user_id", "reject_reason", "review_by", "review_time"},
+		},
+		"fuel_usage_users": {
+			ShouldNotHaveColumn: {"claimed_amount"},
+		},
+		"fuel_refills": {
+			ShouldNotHaveColumn: {"claimed_amount"},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
@@ -0,0 +1,82 @@
+package mgsqlite
+
+import (
+	"context"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		ID:         23,
+		Up:         up23,
+		VerifyUp:   verifyUp23,
+		Down:       down23,
+		VerifyDown: verifyDown23,
+	})
+}
+
+func up23(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`CREATE TABLE IF NOT EXISTS adjustments (
+			id INTEGER PRIMARY KEY,
+			car_id BIGINT NOT NULL,
+			reference_type VARCHAR(20) NOT NULL,
+			reference_id BIGINT NOT NULL,
+			user_id BIGINT NOT NULL,
+			old_amount DECIMAL(10,3) NOT NULL,
+			new_amount DECIMAL(10,3) NOT NULL,
+			reason VARCHAR(255) NOT NULL,
+			create_by BIGINT NOT NULL,
+			create_time DATETIME NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS adjustments_reference_idx ON adjustments (reference_type, reference_id);`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyUp23(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	validateColumnExistMap := map[string]map[ColumnType][]string{
+		"adjustments": {
+			ShouldHaveColumn: {
+				"id",
+				"car_id",
+				"reference_type",
+				"reference_id",
+				"user_id",
+				"old_amount",
+				"new_amount",
+				"reason",
+				"create_by",
+				"create_time",
+			},
+		},
+	}
+	return validateColumnExist(migrator, validateColumnExistMap)
+}
+
+func down23(ctx context.Context, tx *gorm.DB) error {
+	sqlStatements := []string{
+		`DROP TABLE IF EXISTS adjustments;`,
+	}
+	for index, sqlStatement := range sqlStatements {
+		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
+			slog.Error(err.Error(), "index", index)
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyDown23(ctx context.Context, tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	return tableShouldNotExist(migrator, "adjustments")
+}
@@ -26,15 +26,15 @@ func up2(ctx context.Context, tx *gorm.DB) error {
 			update_time DATETIME NOT NULL
 		);`,
 
-		`ALTER TABLE fuel_refills ADD COLUMN car_id BIGINT NOT NULL;`, 
-		`ALTER TABLE fuel_refills ADD COLUMN update_by BIGINT NOT NULL;`, 
-		`ALTER TABLE fuel_refills ADD COLUMN create_by BIGINT NOT NULL;`, 
+		`ALTER TABLE fuel_refills ADD COLUMN car_id BIGINT NOT NULL DEFAULT 0;`,
+		`ALTER TABLE fuel_refills ADD COLUMN update_by BIGINT NOT NULL DEFAULT 0;`,
+		`ALTER TABLE fuel_refills ADD COLUMN create_by BIGINT NOT NULL DEFAULT 0;`,
 
 		`ALTER TABLE fuel_refills DROP COLUMN refill_by;`,
 
-		`ALTER TABLE fuel_usages ADD COLUMN car_id BIGINT NOT NULL;`,
+		`ALTER TABLE fuel_usages ADD COLUMN car_id BIGINT NOT NULL DEFAULT 0;`,
 
-		`ALTER TABLE users ADD COLUMN default_car_id BIGINT NOT NULL;`,
+		`ALTER TABLE users ADD COLUMN default_car_id BIGINT NOT NULL DEFAULT 0;`,
 	}
 	for index, sqlStatement := range sqlStatements {
 		if err := tx.WithContext(ctx).Exec(sqlStatement).Error; err != nil {
@@ -0,0 +1,227 @@
+package migrations_test
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/migrations"
+	"github.com/bosskrub9992/fuel-management-backend/internal/migrations/mgpostgres"
+	"github.com/bosskrub9992/fuel-management-backend/internal/migrations/mgsqlite"
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type column struct {
+	Name    string `gorm:"column:name"`
+	Type    string `gorm:"column:type"`
+	NotNull bool   `gorm:"column:notnull"`
+	PK      bool   `gorm:"column:pk"`
+}
+
+var addNotNullColumn = regexp.MustCompile(`(ADD COLUMN \w+ BIGINT NOT NULL);`)
+
+// postgresToSQLite applies the same rules used to write mgsqlite from
+// mgpostgres, so the postgres migrations can run on SQLite as well.
+func postgresToSQLite(sql string) string {
+	sql = strings.ReplaceAll(sql, "SERIAL PRIMARY KEY NOT NULL", "INTEGER PRIMARY KEY")
+	sql = strings.ReplaceAll(sql, "TIMESTAMP WITH TIME ZONE", "DATETIME")
+	sql = strings.ReplaceAll(sql, " DEFAULT NOW()", "")
+	return addNotNullColumn.ReplaceAllString(sql, "$1 DEFAULT 0;")
+}
+
+func newDB(t *testing.T, translatePostgres bool) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to file::memory: is a new database
+	sqlDB.SetMaxOpenConns(1)
+
+	if translatePostgres {
+		err := db.Callback().Raw().Before("gorm:raw").Register("test:postgres_to_sqlite", func(db *gorm.DB) {
+			sql := postgresToSQLite(db.Statement.SQL.String())
+			db.Statement.SQL.Reset()
+			db.Statement.SQL.WriteString(sql)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func schemaOf(t *testing.T, db *gorm.DB) map[string][]column {
+	t.Helper()
+	var tables []string
+	err := db.Raw(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`).
+		Scan(&tables).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	tableToColumns := make(map[string][]column)
+	for _, table := range tables {
+		var columns []column
+		if err := db.Raw(fmt.Sprintf("PRAGMA table_info(%q)", table)).Scan(&columns).Error; err != nil {
+			t.Fatal(err)
+		}
+		slices.SortFunc(columns, func(a, b column) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+		tableToColumns[table] = columns
+	}
+	return tableToColumns
+}
+
+func compareSchemas(t *testing.T, step string, postgresDB, sqliteDB *gorm.DB) {
+	t.Helper()
+	postgresSchema := schemaOf(t, postgresDB)
+	sqliteSchema := schemaOf(t, sqliteDB)
+	for table, columns := range postgresSchema {
+		if !slices.Equal(columns, sqliteSchema[table]) {
+			t.Errorf("%s: table %q differs\n mgpostgres: %+v\n mgsqlite:   %+v", step, table, columns, sqliteSchema[table])
+		}
+	}
+	for table := range sqliteSchema {
+		if _, found := postgresSchema[table]; !found {
+			t.Errorf("%s: table %q only exists in mgsqlite", step, table)
+		}
+	}
+}
+
+func runMigration(t *testing.T, db *gorm.DB, m migrations.Migration, isUp bool) {
+	t.Helper()
+	ctx := context.Background()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if isUp {
+			if err := m.Up(ctx, tx); err != nil {
+				return err
+			}
+			return m.VerifyUp(ctx, tx)
+		}
+		if err := m.Down(ctx, tx); err != nil {
+			return err
+		}
+		return m.VerifyDown(ctx, tx)
+	})
+	if err != nil {
+		t.Fatalf("migration %d (up: %v): %v", m.ID, isUp, err)
+	}
+}
+
+// seedBeforePayEach inserts the rows the data backfill of migration 7
+// (pay_each, moved to fuel_usage_users.amount by 15) and 8 (refill_by) has
+// to fill.
+func seedBeforePayEach(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	sqlStatements := []string{
+		`INSERT INTO cars (id, name, create_time, update_time) VALUES (1, 'Mazda 2', '2024-01-01', '2024-01-01');`,
+		`INSERT INTO users (id, nickname, default_car_id, create_time, update_time) VALUES
+			(1, 'Boss', 1, '2024-01-01', '2024-01-01'),
+			(2, 'Best', 1, '2024-01-01', '2024-01-01'),
+			(3, 'Bank', 1, '2024-01-01', '2024-01-01');`,
+		`INSERT INTO fuel_usages (id, car_id, fuel_use_time, fuel_price, kilometer_before_use, kilometer_after_use, total_money, create_time, update_time)
+			VALUES (1, 1, '2024-01-02', 5, 100, 0, 100, '2024-01-02', '2024-01-02');`,
+		`INSERT INTO fuel_usage_users (fuel_usage_id, user_id, is_paid) VALUES (1, 1, false), (1, 2, true), (1, 3, false);`,
+		`INSERT INTO fuel_refills (id, car_id, refill_time, total_money, kilometer_before_refill, kilometer_after_refill, fuel_price_calculated, is_paid, create_by, update_by, create_time, update_time)
+			VALUES (1, 1, '2024-01-03', 1000, 0, 200, 5, false, 2, 2, '2024-01-03', '2024-01-03');`,
+	}
+	for _, sqlStatement := range sqlStatements {
+		if err := db.Exec(sqlStatement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMigrationsParity(t *testing.T) {
+	var postgresMigrations, sqliteMigrations []migrations.Migration
+	for _, m := range mgpostgres.Migrations {
+		postgresMigrations = append(postgresMigrations, migrations.Migration(m))
+	}
+	for _, m := range mgsqlite.Migrations {
+		sqliteMigrations = append(sqliteMigrations, migrations.Migration(m))
+	}
+	byID := func(a, b migrations.Migration) int {
+		return int(a.ID) - int(b.ID)
+	}
+	slices.SortFunc(postgresMigrations, byID)
+	slices.SortFunc(sqliteMigrations, byID)
+
+	idsOf := func(ms []migrations.Migration) []uint {
+		var ids []uint
+		for _, m := range ms {
+			ids = append(ids, m.ID)
+		}
+		return ids
+	}
+	if !slices.Equal(idsOf(postgresMigrations), idsOf(sqliteMigrations)) {
+		t.Fatalf("migration ids differ\n mgpostgres: %v\n mgsqlite:   %v",
+			idsOf(postgresMigrations),
+			idsOf(sqliteMigrations),
+		)
+	}
+
+	postgresDB := newDB(t, true)
+	sqliteDB := newDB(t, false)
+
+	for i := range postgresMigrations {
+		runMigration(t, postgresDB, postgresMigrations[i], true)
+		runMigration(t, sqliteDB, sqliteMigrations[i], true)
+		compareSchemas(t, fmt.Sprintf("up %d", postgresMigrations[i].ID), postgresDB, sqliteDB)
+
+		if postgresMigrations[i].ID == 6 {
+			seedBeforePayEach(t, postgresDB)
+			seedBeforePayEach(t, sqliteDB)
+		}
+	}
+
+	for _, db := range []*gorm.DB{postgresDB, sqliteDB} {
+		var amounts []decimal.Decimal
+		if err := db.Raw(`SELECT amount FROM fuel_usage_users WHERE fuel_usage_id = 1 ORDER BY id`).Scan(&amounts).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(amounts) != 3 {
+			t.Fatalf("got %d fuel usage users, want 3", len(amounts))
+		}
+		// the amounts sum to the total money 100, the leftover satang to the first user
+		for i, want := range []string{"33.34", "33.33", "33.33"} {
+			if !amounts[i].Equal(decimal.RequireFromString(want)) {
+				t.Errorf("amounts = %v, want [33.34 33.33 33.33]", amounts)
+				break
+			}
+		}
+		var balance decimal.Decimal
+		if err := db.Raw(`SELECT SUM(credit) - SUM(debit) FROM ledger_entries WHERE reference_type = 'FUEL_USAGE' AND user_id = 1`).Scan(&balance).Error; err != nil {
+			t.Fatal(err)
+		}
+		if !balance.Round(2).Equal(decimal.RequireFromString("-33.34")) {
+			t.Errorf("ledger balance of user 1 = %s, want -33.34", balance)
+		}
+		var refillBy int64
+		if err := db.Raw(`SELECT refill_by FROM fuel_refills WHERE id = 1`).Scan(&refillBy).Error; err != nil {
+			t.Fatal(err)
+		}
+		if refillBy != 2 {
+			t.Errorf("refill_by = %d, want 2", refillBy)
+		}
+	}
+
+	for i := len(postgresMigrations) - 1; i >= 0; i-- {
+		runMigration(t, postgresDB, postgresMigrations[i], false)
+		runMigration(t, sqliteDB, sqliteMigrations[i], false)
+		compareSchemas(t, fmt.Sprintf("down %d", postgresMigrations[i].ID), postgresDB, sqliteDB)
+	}
+
+	if schema := schemaOf(t, sqliteDB); len(schema) != 0 {
+		t.Errorf("every table should be dropped after migrating down, got %v", schema)
+	}
+}
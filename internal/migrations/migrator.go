@@ -0,0 +1,325 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Migration is the migration shape shared by mgpostgres and mgsqlite.
+type Migration struct {
+	ID         uint
+	Up         func(ctx context.Context, tx *gorm.DB) error
+	VerifyUp   func(ctx context.Context, tx *gorm.DB) error
+	Down       func(ctx context.Context, tx *gorm.DB) error
+	VerifyDown func(ctx context.Context, tx *gorm.DB) error
+}
+
+type Status struct {
+	ID          uint
+	IsApplied   bool
+	AppliedTime time.Time
+	// IsUnknown is an applied id that has no migration in the code
+	IsUnknown bool
+}
+
+type step struct {
+	migration Migration
+	isUp      bool
+}
+
+var errDryRun = errors.New("dry run")
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	dryRun     bool
+	out        io.Writer
+}
+
+// NewMigrator sorts migrations by id, in dry run every statement of
+// Up and Down is written to out and the transaction is rolled back.
+func NewMigrator(db *gorm.DB, migrations []Migration, dryRun bool, out io.Writer) (*Migrator, error) {
+	sorted := slices.Clone(migrations)
+	slices.SortFunc(sorted, func(a, b Migration) int {
+		return int(a.ID) - int(b.ID)
+	})
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].ID == sorted[i-1].ID {
+			return nil, fmt.Errorf("duplicate migration id: [%d]", sorted[i].ID)
+		}
+	}
+	return &Migrator{
+		db:         db,
+		migrations: sorted,
+		dryRun:     dryRun,
+		out:        out,
+	}, nil
+}
+
+func (m *Migrator) appliedMigrations(ctx context.Context) (map[uint]domains.Migration, error) {
+	db := m.db.WithContext(ctx)
+	idToApplied := make(map[uint]domains.Migration)
+	if !db.Migrator().HasTable(domains.Migration{}.TableName()) {
+		return idToApplied, nil
+	}
+	var applied []domains.Migration
+	if err := db.Model(&domains.Migration{}).Find(&applied).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range applied {
+		idToApplied[a.ID] = a
+	}
+	return idToApplied, nil
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	idToApplied, err := m.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, migration := range m.migrations {
+		applied, isApplied := idToApplied[migration.ID]
+		statuses = append(statuses, Status{
+			ID:          migration.ID,
+			IsApplied:   isApplied,
+			AppliedTime: applied.CreatedAt,
+		})
+		delete(idToApplied, migration.ID)
+	}
+	for id, applied := range idToApplied {
+		statuses = append(statuses, Status{
+			ID:          id,
+			IsApplied:   true,
+			AppliedTime: applied.CreatedAt,
+			IsUnknown:   true,
+		})
+	}
+	slices.SortFunc(statuses, func(a, b Status) int {
+		return int(a.ID) - int(b.ID)
+	})
+	return statuses, nil
+}
+
+// Up applies the next n pending migrations, n <= 0 applies all of them.
+func (m *Migrator) Up(ctx context.Context, n int) (int, error) {
+	idToApplied, err := m.appliedMigrations(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var steps []step
+	for _, migration := range m.migrations {
+		if n > 0 && len(steps) == n {
+			break
+		}
+		if _, found := idToApplied[migration.ID]; !found {
+			steps = append(steps, step{migration: migration, isUp: true})
+		}
+	}
+	return len(steps), m.apply(ctx, steps)
+}
+
+// UpWithLock applies all pending migrations while holding the database-level
+// lock, so instances starting at the same time don't race each other.
+func (m *Migrator) UpWithLock(ctx context.Context) (int, error) {
+	unlock, err := Lock(ctx, m.db)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := unlock(); err != nil {
+			slog.ErrorContext(ctx, err.Error())
+		}
+	}()
+	return m.Up(ctx, 0)
+}
+
+// Down reverts the last n applied migrations, n <= 0 reverts all of them.
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	idToApplied, err := m.appliedMigrations(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var steps []step
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		if n > 0 && len(steps) == n {
+			break
+		}
+		if _, found := idToApplied[m.migrations[i].ID]; found {
+			steps = append(steps, step{migration: m.migrations[i], isUp: false})
+		}
+	}
+	return len(steps), m.apply(ctx, steps)
+}
+
+// Goto reverts every applied migration after id and applies every pending
+// migration up to id, id 0 reverts all of them.
+func (m *Migrator) Goto(ctx context.Context, id uint) (int, error) {
+	isKnown := slices.ContainsFunc(m.migrations, func(migration Migration) bool {
+		return migration.ID == id
+	})
+	if id != 0 && !isKnown {
+		return 0, fmt.Errorf("not found migration id: [%d]", id)
+	}
+
+	idToApplied, err := m.appliedMigrations(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var steps []step
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, found := idToApplied[migration.ID]; found && migration.ID > id {
+			steps = append(steps, step{migration: migration, isUp: false})
+		}
+	}
+	for _, migration := range m.migrations {
+		if _, found := idToApplied[migration.ID]; !found && migration.ID <= id {
+			steps = append(steps, step{migration: migration, isUp: true})
+		}
+	}
+	return len(steps), m.apply(ctx, steps)
+}
+
+// Redo reverts and applies again the last applied migration.
+func (m *Migrator) Redo(ctx context.Context) (uint, error) {
+	idToApplied, err := m.appliedMigrations(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, found := idToApplied[migration.ID]; found {
+			return migration.ID, m.apply(ctx, []step{
+				{migration: migration, isUp: false},
+				{migration: migration, isUp: true},
+			})
+		}
+	}
+	return 0, errors.New("no applied migration to redo")
+}
+
+// apply runs every step in its own transaction, in dry run all steps share
+// one transaction which is rolled back so later steps see earlier ones.
+func (m *Migrator) apply(ctx context.Context, steps []step) error {
+	if len(steps) == 0 {
+		return nil
+	}
+
+	if m.dryRun {
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			for _, s := range steps {
+				if err := m.applyStep(ctx, tx, s); err != nil {
+					return err
+				}
+			}
+			return errDryRun
+		})
+		if errors.Is(err, errDryRun) {
+			return nil
+		}
+		return err
+	}
+
+	if err := m.createMigrationTable(ctx); err != nil {
+		return err
+	}
+	for _, s := range steps {
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return m.applyStep(ctx, tx, s)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) createMigrationTable(ctx context.Context) error {
+	dbMigrator := m.db.WithContext(ctx).Migrator()
+	tableMigration := domains.Migration{}.TableName()
+	if dbMigrator.HasTable(tableMigration) {
+		return nil
+	}
+	if err := dbMigrator.CreateTable(&domains.Migration{}); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, fmt.Sprintf("created table [%s]", tableMigration))
+	return nil
+}
+
+func (m *Migrator) applyStep(ctx context.Context, tx *gorm.DB, s step) error {
+	direction := "down"
+	run, verify := s.migration.Down, s.migration.VerifyDown
+	if s.isUp {
+		direction = "up"
+		run, verify = s.migration.Up, s.migration.VerifyUp
+	}
+
+	runTx := tx
+	if m.dryRun {
+		fmt.Fprintf(m.out, "-- migration id: [%d] %s\n", s.migration.ID, direction)
+		runTx = tx.Session(&gorm.Session{Logger: sqlRecorder{out: m.out}})
+	}
+
+	if err := run(ctx, runTx); err != nil {
+		slog.ErrorContext(ctx, err.Error(), "id", s.migration.ID)
+		return err
+	}
+	if err := verify(ctx, tx); err != nil {
+		slog.ErrorContext(ctx, err.Error(), "id", s.migration.ID)
+		return err
+	}
+
+	if m.dryRun {
+		return nil
+	}
+
+	if s.isUp {
+		migrated := domains.Migration{
+			ID:        s.migration.ID,
+			CreatedAt: time.Now(),
+		}
+		if err := tx.Create(&migrated).Error; err != nil {
+			return err
+		}
+	} else {
+		if err := tx.Where("id = ?", s.migration.ID).Delete(&domains.Migration{}).Error; err != nil {
+			return err
+		}
+	}
+
+	slog.InfoContext(ctx, fmt.Sprintf("successfully migrated id: [%d] %s", s.migration.ID, direction))
+	return nil
+}
+
+// sqlRecorder is a gorm logger writing every executed statement to out.
+type sqlRecorder struct {
+	out io.Writer
+}
+
+func (r sqlRecorder) LogMode(logger.LogLevel) logger.Interface {
+	return r
+}
+
+func (r sqlRecorder) Info(context.Context, string, ...any) {}
+
+func (r sqlRecorder) Warn(context.Context, string, ...any) {}
+
+func (r sqlRecorder) Error(context.Context, string, ...any) {}
+
+func (r sqlRecorder) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+	fmt.Fprintf(r.out, "%s;\n", strings.TrimSuffix(strings.TrimSpace(sql), ";"))
+}
@@ -0,0 +1,213 @@
+package migrations_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/migrations"
+	"github.com/bosskrub9992/fuel-management-backend/internal/migrations/mgsqlite"
+)
+
+func sqliteMigrations() []migrations.Migration {
+	var ms []migrations.Migration
+	for _, m := range mgsqlite.Migrations {
+		ms = append(ms, migrations.Migration(m))
+	}
+	return ms
+}
+
+func appliedIDs(t *testing.T, migrator *migrations.Migrator) []uint {
+	t.Helper()
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint
+	for _, status := range statuses {
+		if status.IsApplied {
+			if status.AppliedTime.IsZero() {
+				t.Errorf("migration %d is applied without applied time", status.ID)
+			}
+			ids = append(ids, status.ID)
+		}
+	}
+	return ids
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t, false)
+	ms := sqliteMigrations()
+	maxID := uint(len(ms))
+
+	migrator, err := migrations.NewMigrator(db, ms, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := appliedIDs(t, migrator); len(ids) != 0 {
+		t.Fatalf("applied before migrating = %v, want none", ids)
+	}
+
+	if _, err := migrator.Up(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if ids := appliedIDs(t, migrator); !slices.Equal(ids, []uint{1}) {
+		t.Errorf("applied after up = %v, want [1]", ids)
+	}
+
+	if _, err := migrator.Goto(ctx, 5); err != nil {
+		t.Fatal(err)
+	}
+	if ids := appliedIDs(t, migrator); !slices.Equal(ids, []uint{1, 2, 3, 4, 5}) {
+		t.Errorf("applied after goto 5 = %v, want [1 2 3 4 5]", ids)
+	}
+
+	if _, err := migrator.Goto(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	if ids := appliedIDs(t, migrator); !slices.Equal(ids, []uint{1, 2, 3}) {
+		t.Errorf("applied after goto 3 = %v, want [1 2 3]", ids)
+	}
+
+	id, err := migrator.Redo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 3 {
+		t.Errorf("redo id = %d, want 3", id)
+	}
+	if ids := appliedIDs(t, migrator); !slices.Equal(ids, []uint{1, 2, 3}) {
+		t.Errorf("applied after redo = %v, want [1 2 3]", ids)
+	}
+
+	if _, err := migrator.Goto(ctx, maxID+1); err == nil {
+		t.Error("goto an unknown id should fail")
+	}
+
+	if _, err := migrator.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if ids := appliedIDs(t, migrator); len(ids) != len(ms) {
+		t.Errorf("applied after up all = %v, want all %d", ids, len(ms))
+	}
+
+	if _, err := migrator.Goto(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if ids := appliedIDs(t, migrator); len(ids) != 0 {
+		t.Errorf("applied after goto 0 = %v, want none", ids)
+	}
+}
+
+func TestMigrator_DryRun(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t, false)
+	ms := sqliteMigrations()
+
+	var out bytes.Buffer
+	dryRunMigrator, err := migrations.NewMigrator(db, ms, true, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migratedCount, err := dryRunMigrator.Up(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migratedCount != len(ms) {
+		t.Errorf("dry run count = %d, want %d", migratedCount, len(ms))
+	}
+	if !strings.Contains(out.String(), "CREATE TABLE") {
+		t.Errorf("dry run output has no CREATE TABLE statement:\n%s", out.String())
+	}
+	if schema := schemaOf(t, db); len(schema) != 0 {
+		t.Errorf("dry run should not commit, got tables %v", schema)
+	}
+
+	migrator, err := migrations.NewMigrator(db, ms, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	if _, err := dryRunMigrator.Redo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "down") || !strings.Contains(out.String(), "up") {
+		t.Errorf("dry run redo should print down and up:\n%s", out.String())
+	}
+	if ids := appliedIDs(t, migrator); len(ids) != len(ms) {
+		t.Errorf("applied after dry run redo = %v, want all %d", ids, len(ms))
+	}
+}
+
+func TestNewMigrator_DuplicateID(t *testing.T) {
+	ms := sqliteMigrations()
+	ms = append(ms, ms[0])
+	if _, err := migrations.NewMigrator(nil, ms, false, nil); err == nil {
+		t.Error("duplicate migration id should fail")
+	}
+}
+
+func TestLock_SQLite(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t, false)
+
+	unlock, err := migrations.Lock(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := migrations.Lock(timeoutCtx, db); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("second lock err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if err := unlock(); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err = migrations.Lock(ctx, db)
+	if err != nil {
+		t.Fatalf("lock after unlock: %v", err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrator_UpWithLock(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t, false)
+	ms := sqliteMigrations()
+
+	migrator, err := migrations.NewMigrator(db, ms, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migratedCount, err := migrator.UpWithLock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migratedCount != len(ms) {
+		t.Errorf("migrated count = %d, want %d", migratedCount, len(ms))
+	}
+
+	// the lock is released so the next startup is not blocked
+	migratedCount, err = migrator.UpWithLock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migratedCount != 0 {
+		t.Errorf("migrated count on second startup = %d, want 0", migratedCount)
+	}
+}
@@ -2,6 +2,7 @@ package routers
 
 import (
 	"github.com/bosskrub9992/fuel-management-backend/internal/handlers/resthandler"
+	"github.com/bosskrub9992/fuel-management-backend/library/jwts"
 	"github.com/bosskrub9992/fuel-management-backend/library/middlewares"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -10,12 +11,14 @@ import (
 type Router struct {
 	e           *echo.Echo
 	restHandler *resthandler.RESTHandler
+	jwt         *jwts.JWT
 }
 
-func New(e *echo.Echo, restHandler *resthandler.RESTHandler) *Router {
+func New(e *echo.Echo, restHandler *resthandler.RESTHandler, jwt *jwts.JWT) *Router {
 	return &Router{
 		e:           e,
 		restHandler: restHandler,
+		jwt:         jwt,
 	}
 }
 
@@ -30,25 +33,54 @@ func (r Router) Init() *echo.Echo {
 	r.e.Static("/public", "./public")
 	r.e.GET("/health", r.restHandler.GetHealth)
 
-	apiV1 := r.e.Group("/api/v1")
+	r.e.POST("/api/v1/auth/login", r.restHandler.PostLogin)
+
+	apiV1 := r.e.Group("/api/v1", middlewares.Authentication(r.jwt))
 	apiV1.GET("/cars", r.restHandler.GetCars)
+	apiV1.POST("/cars", r.restHandler.PostCar)
+	apiV1.GET("/cars/:carId", r.restHandler.GetCarByID)
+	apiV1.PUT("/cars/:carId", r.restHandler.PutCarByID)
+	apiV1.DELETE("/cars/:carId", r.restHandler.DeleteCarByID)
+	apiV1.GET("/cars/:carId/users", r.restHandler.GetCarUsers)
+	apiV1.GET("/cars/:carId/kilometer-discontinuities", r.restHandler.GetCarKilometerDiscontinuities)
+	apiV1.GET("/cars/:carId/wallet", r.restHandler.GetCarWalletStatement)
 	apiV1.GET("/users", r.restHandler.GetUsers)
+	apiV1.POST("/users", r.restHandler.PostUser)
+	apiV1.PUT("/users/:userId", r.restHandler.PutUserByID)
+	apiV1.DELETE("/users/:userId", r.restHandler.DeleteUserByID)
+	apiV1.PATCH("/users/:userId/default-car", r.restHandler.PatchUserDefaultCar)
+	apiV1.POST("/users/:userId/avatar", r.restHandler.PostUserAvatar)
+	apiV1.GET("/users/:userId/balance", r.restHandler.GetUserBalance)
 	apiV1.GET("/users/:userId/fuel-usages", r.restHandler.GetUserFuelUsages)
 	apiV1.PATCH("/users/:userId/fuel-usages/payment-status", r.restHandler.BulkUpdateUserFuelUsagePaymentStatus)
 	apiV1.PATCH("/users/:userId/cars/:carId/unpaid-activities", r.restHandler.PayUserCarUnpaidActivities)
 	apiV1.GET("/users/:userId/cars/:carId/unpaid-activities", r.restHandler.GetUserCarUnpaidActivities)
+	apiV1.GET("/users/:userId/cars/:carId/settlement", r.restHandler.GetUserCarSettlement)
+	apiV1.POST("/users/:userId/cars/:carId/settlements", r.restHandler.PostUserCarSettlement)
+	apiV1.GET("/users/:userId/cars/:carId/payments", r.restHandler.GetUserCarPayments)
+	apiV1.POST("/users/:userId/cars/:carId/payments", r.restHandler.PostUserCarPayment)
+	apiV1.GET("/users/:userId/pending-payments", r.restHandler.GetUserPendingPayments)
+	apiV1.PATCH("/users/:userId/payments/:paymentId/confirm", r.restHandler.ConfirmUserPayment)
+	apiV1.PATCH("/users/:userId/payments/:paymentId/reject", r.restHandler.RejectUserPayment)
+	apiV1.POST("/users/:userId/cars/:carId/wallet/top-ups", r.restHandler.PostUserCarWalletTopUp)
+
+	apiV1.GET("/debts/simplification", r.restHandler.GetDebtSimplification)
+	apiV1.POST("/debts/settlements", r.restHandler.PostDebtSettlement)
 
 	apiV1.POST("/fuel/usages", r.restHandler.PostFuelUsage)
+	apiV1.POST("/fuel/usages/preview", r.restHandler.PostFuelUsagePreview)
 	apiV1.GET("/fuel/usages", r.restHandler.GetFuelUsages)
 	apiV1.GET("/fuel/usages/:fuelUsageId", r.restHandler.GetFuelUsageByID)
 	apiV1.PUT("/fuel/usages/:fuelUsageId", r.restHandler.PutFuelUsage)
 	apiV1.DELETE("/fuel/usages/:fuelUsageId", r.restHandler.DeleteFuelUsage)
+	apiV1.POST("/fuel/usages/:fuelUsageId/adjustments", r.restHandler.PostFuelUsageAdjustment)
 
 	apiV1.POST("/fuel/refills", r.restHandler.PostFuelRefill)
 	apiV1.GET("/fuel/refills", r.restHandler.GetFuelRefills)
 	apiV1.GET("/fuel/refills/:fuelRefillId", r.restHandler.GetFuelRefillByID)
 	apiV1.PUT("/fuel/refills/:fuelRefillId", r.restHandler.PutFuelRefillByID)
 	apiV1.DELETE("/fuel/refills/:fuelRefillId", r.restHandler.DeleteFuelRefillByID)
+	apiV1.POST("/fuel/refills/:fuelRefillId/adjustments", r.restHandler.PostFuelRefillAdjustment)
 
 	apiV1.GET("/latest-fuel-info", r.restHandler.GetLatestFuelInfoResponse)
 	return r.e
@@ -0,0 +1,448 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// isFuelUsagePaid is a fuel usage with money paid on any of its shares. A
+// fuel usage paid from the wallet takes its deductions back by itself, so it
+// is never taken as paid.
+func isFuelUsagePaid(fuelUsage domains.FuelUsage, fuelUsageUsers []FuelUsageUser) bool {
+	if fuelUsage.IsPaidFromWallet {
+		return false
+	}
+	return slices.ContainsFunc(fuelUsageUsers, func(fuelUsageUser FuelUsageUser) bool {
+		return fuelUsageUser.IsPaid || fuelUsageUser.PaidAmount.IsPositive()
+	})
+}
+
+// isFuelRefillPaid is a refill with money reimbursed to the refiller, a
+// refill paid from the wallet is never taken as paid.
+func isFuelRefillPaid(fuelRefill domains.FuelRefill) bool {
+	if fuelRefill.IsPaidFromWallet {
+		return false
+	}
+	return fuelRefill.IsPaid || fuelRefill.PaidAmount.IsPositive()
+}
+
+// shouldNotBeLockedFuelUsage keeps a paid or adjusted fuel usage from being
+// edited or deleted, it is corrected by an adjustment instead.
+func (s *Service) shouldNotBeLockedFuelUsage(ctx context.Context, fuelUsage domains.FuelUsage, fuelUsageUsers []FuelUsageUser) error {
+	isLocked := isFuelUsagePaid(fuelUsage, fuelUsageUsers)
+	if !isLocked {
+		adjustments, err := s.db.GetAdjustmentsByReference(ctx, domains.AdjustmentReferenceTypeFuelUsage, fuelUsage.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, err.Error())
+			return err
+		}
+		isLocked = len(adjustments) > 0
+	}
+	if isLocked {
+		slog.WarnContext(ctx, "the fuel usage is paid", "fuelUsageId", fuelUsage.ID)
+		return errs.ErrPaidRecordLocked
+	}
+	return nil
+}
+
+// shouldNotBeLockedFuelRefill keeps a paid or adjusted refill from being
+// edited or deleted, it is corrected by an adjustment instead.
+func (s *Service) shouldNotBeLockedFuelRefill(ctx context.Context, fuelRefill domains.FuelRefill) error {
+	isLocked := isFuelRefillPaid(fuelRefill)
+	if !isLocked {
+		adjustments, err := s.db.GetAdjustmentsByReference(ctx, domains.AdjustmentReferenceTypeFuelRefill, fuelRefill.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, err.Error())
+			return err
+		}
+		isLocked = len(adjustments) > 0
+	}
+	if isLocked {
+		slog.WarnContext(ctx, "the fuel refill is paid", "fuelRefillId", fuelRefill.ID)
+		return errs.ErrPaidRecordLocked
+	}
+	return nil
+}
+
+// shouldNotUnpayLockedFuelUsages keeps a paid share paid, turning it back to
+// unpaid would drop the payment which paid it. A share of a locked fuel usage
+// is corrected by an adjustment instead.
+func (s *Service) shouldNotUnpayLockedFuelUsages(ctx context.Context, fuelUsageUsers []domains.FuelUsageUser) error {
+	if len(fuelUsageUsers) == 0 {
+		return nil
+	}
+
+	var fuelUsageUserIDs []int64
+	for _, fuelUsageUser := range fuelUsageUsers {
+		fuelUsageUserIDs = append(fuelUsageUserIDs, fuelUsageUser.ID)
+	}
+
+	fuelUsageUsersWithFuelUsage, err := s.db.GetFuelUsageUsersWithFuelUsageByIDs(ctx, fuelUsageUserIDs)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+	for _, fuelUsageUser := range fuelUsageUsersWithFuelUsage {
+		if fuelUsageUser.IsPaid || fuelUsageUser.PaidAmount.IsPositive() {
+			slog.WarnContext(ctx, "the share is paid", "fuelUsageUserId", fuelUsageUser.ID)
+			return errs.ErrPaidRecordLocked
+		}
+
+		fuelUsage, err := s.db.GetFuelUsageByID(ctx, fuelUsageUser.FuelUsageID)
+		if err != nil {
+			slog.ErrorContext(ctx, err.Error())
+			return err
+		}
+		sameFuelUsageUsers, err := s.db.GetFuelUsageUsersByFuelUsageID(ctx, fuelUsageUser.FuelUsageID)
+		if err != nil {
+			slog.ErrorContext(ctx, err.Error())
+			return err
+		}
+		if err := s.shouldNotBeLockedFuelUsage(ctx, *fuelUsage, sameFuelUsageUsers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AdjustmentLedgerEntries posts the difference of every adjustment. A fuel
+// user who owes more is debited and the creditor of the fuel usage is
+// credited as much, a refiller who fronted more is credited.
+func AdjustmentLedgerEntries(adjustments []domains.Adjustment, creditorUserID int64) []domains.LedgerEntry {
+	var ledgerEntries []domains.LedgerEntry
+	for _, adjustment := range adjustments {
+		difference := adjustment.Difference()
+		if difference.IsZero() {
+			continue
+		}
+		ledgerEntry := domains.LedgerEntry{
+			UserID:        adjustment.UserID,
+			CarID:         adjustment.CarID,
+			EntryType:     domains.LedgerEntryTypeAdjustment,
+			ReferenceType: domains.LedgerReferenceTypeAdjustment,
+			ReferenceID:   adjustment.ID,
+			Debit:         decimal.Zero,
+			Credit:        decimal.Zero,
+			CreateTime:    adjustment.CreateTime,
+		}
+		if adjustment.ReferenceType == domains.AdjustmentReferenceTypeFuelRefill {
+			difference = difference.Neg()
+		}
+		if difference.IsPositive() {
+			ledgerEntry.Debit = difference
+		} else {
+			ledgerEntry.Credit = difference.Neg()
+		}
+		ledgerEntries = append(ledgerEntries, ledgerEntry)
+
+		if adjustment.ReferenceType == domains.AdjustmentReferenceTypeFuelUsage {
+			creditorLedgerEntry := ledgerEntry
+			creditorLedgerEntry.UserID = creditorUserID
+			creditorLedgerEntry.Debit, creditorLedgerEntry.Credit = ledgerEntry.Credit, ledgerEntry.Debit
+			ledgerEntries = append(ledgerEntries, creditorLedgerEntry)
+		}
+	}
+	return ledgerEntries
+}
+
+// fuelUsageCreditorUserID is who fronted the money of the fuel usage, the
+// refiller of the refill its fuel price came from or else the owner of the
+// car.
+func (s *Service) fuelUsageCreditorUserID(ctx context.Context, fuelUsage domains.FuelUsage, car domains.Car) (int64, error) {
+	if fuelUsage.FuelRefillID != 0 {
+		fuelRefill, err := s.db.GetFuelRefillByID(ctx, fuelUsage.FuelRefillID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, err
+		}
+		if err == nil && fuelRefill.RefillBy != 0 {
+			return fuelRefill.RefillBy, nil
+		}
+	}
+	return car.OwnerUserID, nil
+}
+
+// AdjustFuelUsage corrects what the users owe for a paid fuel usage. The
+// shares take the new amounts and keep what was paid, so a larger amount is
+// left to pay, and the difference of every user is posted to the ledger.
+// Only the owner of the car or the creditor of the fuel usage adjusts it.
+func (s *Service) AdjustFuelUsage(ctx context.Context, req models.PostFuelUsageAdjustmentRequest) (*models.PostFuelUsageAdjustmentResponse, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	fuelUsage, err := s.db.GetFuelUsageByID(ctx, req.FuelUsageID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+	if fuelUsage.IsPaidFromWallet {
+		slog.WarnContext(ctx, "the fuel usage is paid from the wallet", "fuelUsageId", req.FuelUsageID)
+		return nil, errs.ErrConflict
+	}
+
+	car, err := s.getCarByID(ctx, fuelUsage.CarID)
+	if err != nil {
+		return nil, err
+	}
+
+	creditorUserID, err := s.fuelUsageCreditorUserID(ctx, *fuelUsage, *car)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	currentUserID, err := shouldBeOwnerOrCreditor(ctx, *car, creditorUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	var adjustments []domains.Adjustment
+	// the shares are locked as they are read, so a payment or another
+	// adjustment made meanwhile waits for this one and sees its amounts
+	err = s.db.Transaction(ctx, func(ctxTx context.Context) error {
+		fuelUsage, err := s.db.GetFuelUsageByID(ctxTx, req.FuelUsageID)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		fuelUsageUsers, err := s.db.GetFuelUsageUsersByFuelUsageID(ctxTx, req.FuelUsageID)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+		if err := shouldNotBeClaimed(ctxTx, fuelUsageUsers); err != nil {
+			return err
+		}
+
+		oldAdjustments, err := s.db.GetAdjustmentsByReference(ctxTx, domains.AdjustmentReferenceTypeFuelUsage, req.FuelUsageID)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+		if !isFuelUsagePaid(*fuelUsage, fuelUsageUsers) && len(oldAdjustments) == 0 {
+			slog.WarnContext(ctxTx, "the fuel usage is not paid, it is edited instead", "fuelUsageId", req.FuelUsageID)
+			return errs.ErrConflict
+		}
+
+		var userIDs []int64
+		userIDToFuelUsageUser := make(map[int64]domains.FuelUsageUser)
+		for _, fuelUsageUser := range fuelUsageUsers {
+			userIDs = append(userIDs, fuelUsageUser.UserID)
+			userIDToFuelUsageUser[fuelUsageUser.UserID] = fuelUsageUser.FuelUsageUser
+		}
+
+		userIDToNewAmount := make(map[int64]decimal.Decimal)
+		for _, fuelUser := range req.FuelUsers {
+			if _, found := userIDToFuelUsageUser[fuelUser.UserID]; !found {
+				userIDs = append(userIDs, fuelUser.UserID)
+			}
+			userIDToNewAmount[fuelUser.UserID] = fuelUser.Amount
+		}
+
+		for _, userID := range userIDs {
+			oldAmount := decimal.Zero
+			if fuelUsageUser, found := userIDToFuelUsageUser[userID]; found {
+				oldAmount = fuelUsageUser.Amount
+			}
+			newAmount, found := userIDToNewAmount[userID]
+			if !found {
+				newAmount = decimal.Zero
+			}
+			if newAmount.Equal(oldAmount) {
+				continue
+			}
+			adjustments = append(adjustments, domains.Adjustment{
+				CarID:         fuelUsage.CarID,
+				ReferenceType: domains.AdjustmentReferenceTypeFuelUsage,
+				ReferenceID:   fuelUsage.ID,
+				UserID:        userID,
+				OldAmount:     oldAmount,
+				NewAmount:     newAmount,
+				Reason:        req.Reason,
+				CreateBy:      currentUserID,
+				CreateTime:    now,
+			})
+		}
+
+		if len(adjustments) == 0 {
+			return nil
+		}
+
+		if err := s.createAdjustments(ctxTx, adjustments, creditorUserID); err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		var newFuelUsageUsers []domains.FuelUsageUser
+		for _, adjustment := range adjustments {
+			fuelUsageUser, found := userIDToFuelUsageUser[adjustment.UserID]
+			if !found {
+				newFuelUsageUsers = append(newFuelUsageUsers, domains.FuelUsageUser{
+					FuelUsageID: fuelUsage.ID,
+					UserID:      adjustment.UserID,
+					IsPaid:      false,
+					SplitValue:  decimal.Zero,
+					FareWeight:  fullFare,
+					Amount:      adjustment.NewAmount,
+					PaidAmount:  decimal.Zero,
+				})
+				continue
+			}
+			if err := s.db.UpdateFuelUsageUserAmount(ctxTx, fuelUsageUser.ID, adjustment.NewAmount); err != nil {
+				slog.ErrorContext(ctxTx, err.Error())
+				return err
+			}
+		}
+		if len(newFuelUsageUsers) > 0 {
+			if err := s.db.CreateFuelUsageUsers(ctxTx, newFuelUsageUsers); err != nil {
+				slog.ErrorContext(ctxTx, err.Error())
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PostFuelUsageAdjustmentResponse{
+		Adjustments: toAdjustmentModels(adjustments),
+	}, nil
+}
+
+// AdjustFuelRefill corrects the money the refiller fronted for a paid
+// refill. The refill takes the new money and keeps what was reimbursed, so
+// a larger amount is left to reimburse, and the difference is posted to the
+// ledger. Only the owner of the car or the refiller adjusts it.
+func (s *Service) AdjustFuelRefill(ctx context.Context, req models.PostFuelRefillAdjustmentRequest) (*models.PostFuelRefillAdjustmentResponse, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	fuelRefill, err := s.db.GetFuelRefillByID(ctx, req.FuelRefillID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+	if fuelRefill.IsPaidFromWallet {
+		slog.WarnContext(ctx, "the fuel refill is paid from the wallet", "fuelRefillId", req.FuelRefillID)
+		return nil, errs.ErrConflict
+	}
+
+	car, err := s.getCarByID(ctx, fuelRefill.CarID)
+	if err != nil {
+		return nil, err
+	}
+
+	currentUserID, err := shouldBeOwnerOrCreditor(ctx, *car, fuelRefill.RefillBy)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	var adjustments []domains.Adjustment
+	// the refill is locked as it is read, so a payment or another
+	// adjustment made meanwhile waits for this one and sees its money
+	err = s.db.Transaction(ctx, func(ctxTx context.Context) error {
+		fuelRefill, err := s.db.GetFuelRefillByID(ctxTx, req.FuelRefillID)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		if fuelRefill.ClaimedAmount.IsPositive() {
+			slog.WarnContext(ctxTx, "a payment of the fuel refill waits for confirmation",
+				"fuelRefillId", req.FuelRefillID,
+			)
+			return errs.ErrConflict
+		}
+
+		oldAdjustments, err := s.db.GetAdjustmentsByReference(ctxTx, domains.AdjustmentReferenceTypeFuelRefill, req.FuelRefillID)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+		if !isFuelRefillPaid(*fuelRefill) && len(oldAdjustments) == 0 {
+			slog.WarnContext(ctxTx, "the fuel refill is not paid, it is edited instead", "fuelRefillId", req.FuelRefillID)
+			return errs.ErrConflict
+		}
+
+		if req.TotalMoney.Equal(fuelRefill.TotalMoney) {
+			return nil
+		}
+		adjustments = append(adjustments, domains.Adjustment{
+			CarID:         fuelRefill.CarID,
+			ReferenceType: domains.AdjustmentReferenceTypeFuelRefill,
+			ReferenceID:   fuelRefill.ID,
+			UserID:        fuelRefill.RefillBy,
+			OldAmount:     fuelRefill.TotalMoney,
+			NewAmount:     req.TotalMoney,
+			Reason:        req.Reason,
+			CreateBy:      currentUserID,
+			CreateTime:    now,
+		})
+
+		fuelRefill.TotalMoney = req.TotalMoney
+		fuelRefill.IsPaid = fuelRefill.PaidAmount.GreaterThanOrEqual(fuelRefill.TotalMoney)
+		fuelRefill.UpdateBy = currentUserID
+		fuelRefill.UpdateTime = now
+
+		if err := s.createAdjustments(ctxTx, adjustments, fuelRefill.RefillBy); err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+		if err := s.db.UpdateFuelRefill(ctxTx, *fuelRefill); err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PostFuelRefillAdjustmentResponse{
+		Adjustments: toAdjustmentModels(adjustments),
+	}, nil
+}
+
+// createAdjustments saves the adjustments first, their ids are what the
+// ledger entries refer to. It should be called in a transaction.
+func (s *Service) createAdjustments(ctx context.Context, adjustments []domains.Adjustment, creditorUserID int64) error {
+	if err := s.db.CreateAdjustments(ctx, adjustments); err != nil {
+		return err
+	}
+	return s.createLedgerEntries(ctx, AdjustmentLedgerEntries(adjustments, creditorUserID))
+}
+
+func toAdjustmentModels(adjustments []domains.Adjustment) []models.Adjustment {
+	adjustmentModels := []models.Adjustment{}
+	for _, adjustment := range adjustments {
+		adjustmentModels = append(adjustmentModels, models.Adjustment{
+			ID:         adjustment.ID,
+			UserID:     adjustment.UserID,
+			OldAmount:  adjustment.OldAmount,
+			NewAmount:  adjustment.NewAmount,
+			Difference: adjustment.Difference(),
+			Reason:     adjustment.Reason,
+			CreateBy:   adjustment.CreateBy,
+			CreateTime: adjustment.CreateTime,
+		})
+	}
+	return adjustmentModels
+}
@@ -0,0 +1,398 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+func (stub *stubDatabaseAdaptor) GetFuelUsageByID(ctx context.Context, fuelUsageID int64) (*domains.FuelUsage, error) {
+	for _, fuelUsage := range stub.fuelUsages {
+		if fuelUsage.ID == fuelUsageID {
+			return &fuelUsage, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (stub *stubDatabaseAdaptor) GetFuelUsageUsersByFuelUsageID(ctx context.Context, fuelUsageID int64) ([]FuelUsageUser, error) {
+	return stub.GetFuelUsageUsersByFuelUsageIDs(ctx, []int64{fuelUsageID})
+}
+
+func (stub *stubDatabaseAdaptor) CreateAdjustments(ctx context.Context, adjustments []domains.Adjustment) error {
+	for i := range adjustments {
+		adjustments[i].ID = int64(len(stub.adjustments) + 1)
+		stub.adjustments = append(stub.adjustments, adjustments[i])
+	}
+	return nil
+}
+
+func (stub *stubDatabaseAdaptor) GetAdjustmentsByReference(ctx context.Context, referenceType string, referenceID int64) ([]domains.Adjustment, error) {
+	var adjustments []domains.Adjustment
+	for _, adjustment := range stub.adjustments {
+		if adjustment.ReferenceType == referenceType && adjustment.ReferenceID == referenceID {
+			adjustments = append(adjustments, adjustment)
+		}
+	}
+	return adjustments, nil
+}
+
+func TestAdjustmentLedgerEntries(t *testing.T) {
+	adjustments := []domains.Adjustment{
+		{ID: 1, ReferenceType: domains.AdjustmentReferenceTypeFuelUsage, UserID: 1, OldAmount: decimal.NewFromInt(50), NewAmount: decimal.NewFromInt(60)},
+		{ID: 2, ReferenceType: domains.AdjustmentReferenceTypeFuelUsage, UserID: 2, OldAmount: decimal.NewFromInt(50), NewAmount: decimal.NewFromInt(40)},
+		{ID: 3, ReferenceType: domains.AdjustmentReferenceTypeFuelRefill, UserID: 3, OldAmount: decimal.NewFromInt(1000), NewAmount: decimal.NewFromInt(1200)},
+	}
+
+	const creditor = 9
+	ledgerEntries := AdjustmentLedgerEntries(adjustments, creditor)
+	if len(ledgerEntries) != 5 {
+		t.Fatalf("got %d entries, want 5", len(ledgerEntries))
+	}
+	for i, ledgerEntry := range ledgerEntries {
+		if ledgerEntry.ReferenceType != domains.LedgerReferenceTypeAdjustment || ledgerEntry.ReferenceID == 0 {
+			t.Errorf("entry %d refers to %s %d, want the adjustment", i, ledgerEntry.ReferenceType, ledgerEntry.ReferenceID)
+		}
+	}
+
+	// the fuel usage adjustments are balanced by the creditor
+	balances := ledgerBalances(ledgerEntries)
+	want := map[int64]int64{1: -10, 2: 10, 3: 200, creditor: 0}
+	for userID, amount := range want {
+		if !balances[userID].Equal(decimal.NewFromInt(amount)) {
+			t.Errorf("balance of user %d = %s, want %d", userID, balances[userID], amount)
+		}
+	}
+}
+
+func TestService_AdjustFuelUsage(t *testing.T) {
+	const (
+		boss = 1
+		best = 2
+		carl = 3
+	)
+	newStub := func() *stubDatabaseAdaptor {
+		return &stubDatabaseAdaptor{
+			cars: []domains.Car{{ID: 1, OwnerUserID: boss}},
+			fuelUsages: []domains.FuelUsage{
+				{ID: 1, CarID: 1, TotalMoney: decimal.NewFromInt(100)},
+				{ID: 2, CarID: 1, TotalMoney: decimal.NewFromInt(100)},
+				{ID: 3, CarID: 1, TotalMoney: decimal.NewFromInt(100), IsPaidFromWallet: true},
+			},
+			fuelUsageUsers: []FuelUsageUser{
+				{FuelUsageUser: domains.FuelUsageUser{ID: 1, FuelUsageID: 1, UserID: boss, IsPaid: true, Amount: decimal.NewFromInt(50), PaidAmount: decimal.NewFromInt(50)}},
+				{FuelUsageUser: domains.FuelUsageUser{ID: 2, FuelUsageID: 1, UserID: best, Amount: decimal.NewFromInt(50), PaidAmount: decimal.Zero}},
+				{FuelUsageUser: domains.FuelUsageUser{ID: 3, FuelUsageID: 2, UserID: boss, Amount: decimal.NewFromInt(100), PaidAmount: decimal.Zero}},
+				{FuelUsageUser: domains.FuelUsageUser{ID: 4, FuelUsageID: 3, UserID: boss, IsPaid: true, Amount: decimal.NewFromInt(100), PaidAmount: decimal.NewFromInt(100)}},
+			},
+		}
+	}
+	newReq := func(fuelUsageID int64, amounts map[int64]int64) models.PostFuelUsageAdjustmentRequest {
+		req := models.PostFuelUsageAdjustmentRequest{
+			FuelUsageID: fuelUsageID,
+			Reason:      "wrong kilometers",
+		}
+		for _, userID := range []int64{boss, best, carl} {
+			if amount, found := amounts[userID]; found {
+				req.FuelUsers = append(req.FuelUsers, models.AdjustedFuelUser{UserID: userID, Amount: decimal.NewFromInt(amount)})
+			}
+		}
+		return req
+	}
+
+	t.Run("posts the difference of every user", func(t *testing.T) {
+		stub := newStub()
+		s := New(nil, stub, nil, nil)
+
+		response, err := s.AdjustFuelUsage(contextWithUser(boss), newReq(1, map[int64]int64{boss: 40, best: 50, carl: 20}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Adjustments) != 2 {
+			t.Fatalf("adjustments = %+v, want boss and carl", response.Adjustments)
+		}
+		if a := response.Adjustments[0]; a.UserID != boss || !a.OldAmount.Equal(decimal.NewFromInt(50)) || !a.Difference.Equal(decimal.NewFromInt(-10)) {
+			t.Errorf("adjustment of boss = %+v, want 50 less 10", a)
+		}
+		if a := response.Adjustments[1]; a.UserID != carl || !a.OldAmount.IsZero() || !a.NewAmount.Equal(decimal.NewFromInt(20)) {
+			t.Errorf("adjustment of carl = %+v, want 0 now 20", a)
+		}
+		if stub.adjustments[0].CreateBy != boss {
+			t.Errorf("create by = %d, want %d", stub.adjustments[0].CreateBy, boss)
+		}
+
+		// the shares take the new amounts and keep what was paid
+		if share := stub.fuelUsageUsers[0]; !share.Amount.Equal(decimal.NewFromInt(40)) || !share.IsPaid {
+			t.Errorf("share of boss = %+v, want 40 and paid", share)
+		}
+		if share := stub.fuelUsageUsers[4]; share.UserID != carl || !share.Outstanding().Equal(decimal.NewFromInt(20)) {
+			t.Errorf("share of carl = %+v, want 20 left to pay", share)
+		}
+
+		// boss is the creditor of the fuel usage as the owner of the car
+		balances := ledgerBalances(stub.ledgerEntries)
+		if !balances[boss].Equal(decimal.NewFromInt(20)) || !balances[carl].Equal(decimal.NewFromInt(-20)) || !balances[best].IsZero() {
+			t.Errorf("balances = %v, want boss 20, carl -20 and best 0", balances)
+		}
+
+		// a later adjustment starts from the amounts in effect
+		response, err = s.AdjustFuelUsage(contextWithUser(boss), newReq(1, map[int64]int64{boss: 40, best: 60}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Adjustments) != 2 {
+			t.Fatalf("adjustments = %+v, want best and carl", response.Adjustments)
+		}
+		if a := response.Adjustments[1]; a.UserID != carl || !a.OldAmount.Equal(decimal.NewFromInt(20)) || !a.NewAmount.IsZero() {
+			t.Errorf("adjustment of carl = %+v, want 20 now 0", a)
+		}
+		balances = ledgerBalances(stub.ledgerEntries)
+		if !balances[boss].Equal(decimal.NewFromInt(10)) || !balances[best].Equal(decimal.NewFromInt(-10)) || !balances[carl].IsZero() {
+			t.Errorf("balances = %v, want boss 10, best -10 and carl 0", balances)
+		}
+	})
+
+	t.Run("not the owner or the creditor", func(t *testing.T) {
+		stub := newStub()
+		s := New(nil, stub, nil, nil)
+		_, err := s.AdjustFuelUsage(contextWithUser(best), newReq(1, map[int64]int64{boss: 40, best: 60}))
+		if !errors.Is(err, errs.ErrForbidden) {
+			t.Errorf("err = %v, want %v", err, errs.ErrForbidden)
+		}
+		if len(stub.adjustments) != 0 {
+			t.Errorf("adjustments = %+v, want none", stub.adjustments)
+		}
+	})
+
+	t.Run("claimed by a pending payment", func(t *testing.T) {
+		stub := newStub()
+		stub.fuelUsageUsers[1].ClaimedAmount = decimal.NewFromInt(50)
+		s := New(nil, stub, nil, nil)
+		_, err := s.AdjustFuelUsage(contextWithUser(boss), newReq(1, map[int64]int64{boss: 40, best: 60}))
+		if !errors.Is(err, errs.ErrConflict) {
+			t.Errorf("err = %v, want %v", err, errs.ErrConflict)
+		}
+	})
+
+	t.Run("claimed or adjusted meanwhile", func(t *testing.T) {
+		stub := newStub()
+		stub.beforeTransaction = func() {
+			stub.fuelUsageUsers[1].ClaimedAmount = decimal.NewFromInt(50)
+		}
+		s := New(nil, stub, nil, nil)
+		_, err := s.AdjustFuelUsage(contextWithUser(boss), newReq(1, map[int64]int64{boss: 40, best: 60}))
+		if !errors.Is(err, errs.ErrConflict) {
+			t.Errorf("err = %v, want %v", err, errs.ErrConflict)
+		}
+		if len(stub.adjustments) != 0 || len(stub.ledgerEntries) != 0 {
+			t.Errorf("adjustments = %+v, want none", stub.adjustments)
+		}
+
+		stub = newStub()
+		stub.beforeTransaction = func() {
+			stub.fuelUsageUsers[0].Amount = decimal.NewFromInt(45)
+		}
+		s = New(nil, stub, nil, nil)
+		response, err := s.AdjustFuelUsage(contextWithUser(boss), newReq(1, map[int64]int64{boss: 40, best: 50}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a := response.Adjustments[0]; !a.OldAmount.Equal(decimal.NewFromInt(45)) || !a.Difference.Equal(decimal.NewFromInt(-5)) {
+			t.Errorf("adjustment of boss = %+v, want 45 less 5", a)
+		}
+	})
+
+	t.Run("unpaid fuel usage", func(t *testing.T) {
+		s := New(nil, newStub(), nil, nil)
+		_, err := s.AdjustFuelUsage(contextWithUser(boss), newReq(2, map[int64]int64{boss: 90}))
+		if !errors.Is(err, errs.ErrConflict) {
+			t.Errorf("err = %v, want %v", err, errs.ErrConflict)
+		}
+	})
+
+	t.Run("paid from the wallet", func(t *testing.T) {
+		s := New(nil, newStub(), nil, nil)
+		_, err := s.AdjustFuelUsage(contextWithUser(boss), newReq(3, map[int64]int64{boss: 90}))
+		if !errors.Is(err, errs.ErrConflict) {
+			t.Errorf("err = %v, want %v", err, errs.ErrConflict)
+		}
+	})
+}
+
+func TestService_AdjustFuelRefill(t *testing.T) {
+	const refiller = 9
+	stub := &stubDatabaseAdaptor{
+		cars: []domains.Car{{ID: 1, OwnerUserID: 1}},
+		fuelRefills: []domains.FuelRefill{
+			{ID: 1, CarID: 1, TotalMoney: decimal.NewFromInt(1000), IsPaid: true, PaidAmount: decimal.NewFromInt(1000), RefillBy: refiller},
+			{ID: 2, CarID: 1, TotalMoney: decimal.NewFromInt(1000), PaidAmount: decimal.Zero, RefillBy: refiller},
+		},
+	}
+	s := New(nil, stub, nil, nil)
+
+	response, err := s.AdjustFuelRefill(contextWithUser(1), models.PostFuelRefillAdjustmentRequest{
+		FuelRefillID: 1,
+		TotalMoney:   decimal.NewFromInt(1200),
+		Reason:       "receipt was misread",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Adjustments) != 1 || !response.Adjustments[0].Difference.Equal(decimal.NewFromInt(200)) {
+		t.Fatalf("adjustments = %+v, want 200 more", response.Adjustments)
+	}
+	if fuelRefill := stub.fuelRefills[0]; !fuelRefill.TotalMoney.Equal(decimal.NewFromInt(1200)) || fuelRefill.IsPaid || !fuelRefill.Outstanding().Equal(decimal.NewFromInt(200)) {
+		t.Errorf("fuel refill = %+v, want 1200 with 200 left to reimburse", fuelRefill)
+	}
+	if balance := ledgerBalances(stub.ledgerEntries)[refiller]; !balance.Equal(decimal.NewFromInt(200)) {
+		t.Errorf("balance = %s, want 200", balance)
+	}
+
+	_, err = s.AdjustFuelRefill(contextWithUser(1), models.PostFuelRefillAdjustmentRequest{
+		FuelRefillID: 2,
+		TotalMoney:   decimal.NewFromInt(1200),
+		Reason:       "receipt was misread",
+	})
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("err = %v, want %v", err, errs.ErrConflict)
+	}
+
+	_, err = s.AdjustFuelRefill(contextWithUser(2), models.PostFuelRefillAdjustmentRequest{
+		FuelRefillID: 1,
+		TotalMoney:   decimal.NewFromInt(1500),
+		Reason:       "receipt was misread",
+	})
+	if !errors.Is(err, errs.ErrForbidden) {
+		t.Errorf("err = %v, want %v", err, errs.ErrForbidden)
+	}
+
+	// a payment claims the refill while it is adjusted
+	stub.beforeTransaction = func() {
+		stub.fuelRefills[0].ClaimedAmount = decimal.NewFromInt(200)
+	}
+	_, err = s.AdjustFuelRefill(contextWithUser(1), models.PostFuelRefillAdjustmentRequest{
+		FuelRefillID: 1,
+		TotalMoney:   decimal.NewFromInt(1500),
+		Reason:       "receipt was misread",
+	})
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("err = %v, want %v", err, errs.ErrConflict)
+	}
+	if len(stub.adjustments) != 1 {
+		t.Errorf("adjustments = %+v, want only the first one", stub.adjustments)
+	}
+}
+
+func TestService_paidRecordLocked(t *testing.T) {
+	stub := &stubDatabaseAdaptor{
+		fuelUsages: []domains.FuelUsage{
+			{ID: 1, CarID: 1},
+			{ID: 2, CarID: 1},
+		},
+		fuelUsageUsers: []FuelUsageUser{
+			{FuelUsageUser: domains.FuelUsageUser{ID: 1, FuelUsageID: 1, UserID: 1, Amount: decimal.NewFromInt(50), PaidAmount: decimal.NewFromInt(20)}},
+			{FuelUsageUser: domains.FuelUsageUser{ID: 2, FuelUsageID: 2, UserID: 1, Amount: decimal.NewFromInt(50), PaidAmount: decimal.Zero}},
+		},
+		fuelRefills: []domains.FuelRefill{
+			{ID: 1, CarID: 1, TotalMoney: decimal.NewFromInt(1000), IsPaid: true, PaidAmount: decimal.NewFromInt(1000), RefillBy: 1},
+		},
+		// fuel usage 2 is unpaid, but was corrected
+		adjustments: []domains.Adjustment{
+			{ID: 1, CarID: 1, ReferenceType: domains.AdjustmentReferenceTypeFuelUsage, ReferenceID: 2, UserID: 1},
+		},
+	}
+	s := New(nil, stub, nil, nil)
+	ctx := contextWithUser(1)
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{
+			name: "update partially paid fuel usage",
+			call: func() error {
+				return s.UpdateFuelUsage(ctx, models.PutFuelUsageRequest{
+					FuelUsageID:  1,
+					CurrentCarID: 1,
+					FuelUsers:    []models.FuelUser{{UserID: 1, IsPaid: true}},
+				})
+			},
+		},
+		{
+			name: "delete partially paid fuel usage",
+			call: func() error {
+				return s.DeleteFuelUsageByID(ctx, models.DeleteFuelUsageByIDRequest{FuelUsageID: 1})
+			},
+		},
+		{
+			name: "delete adjusted fuel usage",
+			call: func() error {
+				return s.DeleteFuelUsageByID(ctx, models.DeleteFuelUsageByIDRequest{FuelUsageID: 2})
+			},
+		},
+		{
+			name: "update paid fuel refill",
+			call: func() error {
+				_, err := s.UpdateFuelRefillByID(ctx, models.PutFuelRefillByIDRequest{
+					FuelRefillID:          1,
+					CurrentCarID:          1,
+					RefillTime:            time.Now(),
+					KilometerBeforeRefill: 100,
+					KilometerAfterRefill:  300,
+					TotalMoney:            decimal.NewFromInt(1200),
+					RefillBy:              1,
+				})
+				return err
+			},
+		},
+		{
+			name: "delete paid fuel refill",
+			call: func() error {
+				return s.DeleteFuelRefillByID(ctx, models.DeleteFuelRefillByIDRequest{FuelRefillID: 1})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, errs.ErrPaidRecordLocked) {
+				t.Errorf("err = %v, want %v", err, errs.ErrPaidRecordLocked)
+			}
+		})
+	}
+
+	if len(stub.ledgerEntries) != 0 || !stub.fuelRefills[0].TotalMoney.Equal(decimal.NewFromInt(1000)) {
+		t.Errorf("locked records should be kept as they are")
+	}
+}
+
+func TestService_UpdateFuelRefillByID_claimed(t *testing.T) {
+	stub := &stubDatabaseAdaptor{
+		fuelRefills: []domains.FuelRefill{
+			{ID: 1, CarID: 1, TotalMoney: decimal.NewFromInt(1000), ClaimedAmount: decimal.NewFromInt(400), RefillBy: 1},
+		},
+	}
+	s := New(nil, stub, nil, nil)
+
+	_, err := s.UpdateFuelRefillByID(contextWithUser(1), models.PutFuelRefillByIDRequest{
+		FuelRefillID:          1,
+		CurrentCarID:          1,
+		RefillTime:            time.Now(),
+		KilometerBeforeRefill: 100,
+		KilometerAfterRefill:  300,
+		TotalMoney:            decimal.NewFromInt(1200),
+		RefillBy:              1,
+	})
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Fatalf("UpdateFuelRefillByID() error = %v, want %v", err, errs.ErrConflict)
+	}
+	if !stub.fuelRefills[0].TotalMoney.Equal(decimal.NewFromInt(1000)) {
+		t.Errorf("total money = %s, want 1000 kept", stub.fuelRefills[0].TotalMoney)
+	}
+}
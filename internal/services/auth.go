@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/bosskrub9992/fuel-management-backend/library/middlewares"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func (s *Service) Login(ctx context.Context, req models.PostLoginRequest) (*models.PostLoginResponse, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	credential, err := s.db.GetUserCredentialByUsername(ctx, req.Username)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.WarnContext(ctx, "not found username", "username", req.Username)
+			return nil, errs.ErrUnauthorized
+		}
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(credential.PasswordHash), []byte(req.Password))
+	if err != nil {
+		slog.WarnContext(ctx, err.Error(), "username", req.Username)
+		return nil, errs.ErrUnauthorized
+	}
+
+	user, err := s.db.GetUserByID(ctx, credential.UserID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	if user.IsDeactivated {
+		slog.WarnContext(ctx, "deactivated user tries to login", "userId", user.ID)
+		return nil, errs.ErrUnauthorized
+	}
+
+	accessToken, expireTime, err := s.jwt.Sign(user.ID, time.Now())
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	return &models.PostLoginResponse{
+		AccessToken: accessToken,
+		ExpireTime:  expireTime,
+		User:        toUserDatum(*user),
+	}, nil
+}
+
+// HashPassword returns the bcrypt hash to store in user_credentials.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// actingUserID returns the authenticated user of the request.
+func actingUserID(ctx context.Context) (int64, error) {
+	userID, ok := middlewares.UserIDFromContext(ctx)
+	if !ok {
+		slog.ErrorContext(ctx, "not found authenticated user in context")
+		return 0, errs.ErrUnauthorized
+	}
+	return userID, nil
+}
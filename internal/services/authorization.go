@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+)
+
+// paymentScope is the set of records a payment operation is about to mark as paid.
+type paymentScope struct {
+	UserID           int64
+	CarID            int64
+	FuelUsageUserIDs []int64
+	FuelRefillIDs    []int64
+}
+
+// shouldActAsUser rejects a request that tries to change the data of
+// a user other than the authenticated one.
+func shouldActAsUser(ctx context.Context, userID int64) error {
+	actingUserID, err := actingUserID(ctx)
+	if err != nil {
+		return err
+	}
+	if actingUserID != userID {
+		slog.WarnContext(ctx, "user tries to act as another user",
+			"targetUserId", userID,
+		)
+		return errs.ErrForbidden
+	}
+	return nil
+}
+
+// shouldBeOwnerOrCreditor allows only the owner of the car or the creditor,
+// who is owed the money, and returns the authenticated user.
+func shouldBeOwnerOrCreditor(ctx context.Context, car domains.Car, creditorUserID int64) (int64, error) {
+	currentUserID, err := actingUserID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if currentUserID != car.OwnerUserID && currentUserID != creditorUserID {
+		slog.WarnContext(ctx, "user is neither the owner of the car nor the creditor",
+			"carId", car.ID,
+			"creditorUserId", creditorUserID,
+		)
+		return 0, errs.ErrForbidden
+	}
+	return currentUserID, nil
+}
+
+// authorizePayment allows a payment only when the authenticated user is the
+// payer and every fuel usage share and refill in scope belongs to that user
+// and to the given car.
+func (s *Service) authorizePayment(ctx context.Context, scope paymentScope) error {
+	if err := shouldActAsUser(ctx, scope.UserID); err != nil {
+		return err
+	}
+
+	isUserOwnAllFuelUsageUser, err := s.db.IsUserOwnAllFuelUsageUser(ctx,
+		scope.UserID,
+		scope.CarID,
+		scope.FuelUsageUserIDs,
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+
+	if !isUserOwnAllFuelUsageUser {
+		slog.WarnContext(ctx, errors.New("there is fuel usage user that user not own").Error(),
+			"userId", scope.UserID,
+			"carId", scope.CarID,
+			"fuelUsageUserIds", scope.FuelUsageUserIDs,
+		)
+		return errs.ErrForbidden
+	}
+
+	isUserOwnAllFuelRefills, err := s.db.IsUserOwnAllFuelRefills(ctx,
+		scope.UserID,
+		scope.CarID,
+		scope.FuelRefillIDs,
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+
+	if !isUserOwnAllFuelRefills {
+		slog.WarnContext(ctx, errors.New("there is fuel refill that user not own").Error(),
+			"userId", scope.UserID,
+			"carId", scope.CarID,
+			"fuelRefillIds", scope.FuelRefillIDs,
+		)
+		return errs.ErrForbidden
+	}
+
+	return nil
+}
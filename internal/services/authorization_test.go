@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/bosskrub9992/fuel-management-backend/library/middlewares"
+	"github.com/shopspring/decimal"
+)
+
+// stubDatabaseAdaptor overrides only the methods a test needs,
+// calling any other method panics on the nil embedded interface.
+type stubDatabaseAdaptor struct {
+	DatabaseAdaptor
+	isUserOwnAllFuelUsageUser bool
+	isUserOwnAllFuelRefills   bool
+	paidFuelUsageUserIDs      []int64
+	paidFuelRefillIDs         []int64
+	cars                      []domains.Car
+	users                     []domains.User
+	userCredentials           []domains.UserCredential
+	updatedUsers              []domains.User
+	fuelUsages                []domains.FuelUsage
+	fuelRefills               []domains.FuelRefill
+	fuelUsageUsers            []FuelUsageUser
+	ledgerEntries             []domains.LedgerEntry
+	payments                  []domains.Payment
+	paymentAllocations        []domains.PaymentAllocation
+	walletTransactions        []domains.WalletTransaction
+	adjustments               []domains.Adjustment
+	settlements               []domains.Settlement
+	settlementItems           []domains.SettlementItem
+	// beforeTransaction changes the data as a concurrent request would
+	// between the reads of the service and its transaction
+	beforeTransaction func()
+}
+
+func (stub *stubDatabaseAdaptor) Transaction(ctx context.Context, fn func(ctxTx context.Context) error) error {
+	if stub.beforeTransaction != nil {
+		stub.beforeTransaction()
+	}
+	return fn(ctx)
+}
+
+func (stub *stubDatabaseAdaptor) IsUserOwnAllFuelUsageUser(ctx context.Context, userID int64, carID int64, fuelUsageUserIds []int64) (bool, error) {
+	return stub.isUserOwnAllFuelUsageUser, nil
+}
+
+func (stub *stubDatabaseAdaptor) IsUserOwnAllFuelRefills(ctx context.Context, userID int64, carID int64, fuelRefillIDs []int64) (bool, error) {
+	return stub.isUserOwnAllFuelRefills, nil
+}
+
+func (stub *stubDatabaseAdaptor) PayFuelUsageUsers(ctx context.Context, fuelUsageUserIds []int64) error {
+	stub.paidFuelUsageUserIDs = append(stub.paidFuelUsageUserIDs, fuelUsageUserIds...)
+	return nil
+}
+
+func (stub *stubDatabaseAdaptor) PayFuelRefills(ctx context.Context, fuelRefillIDs []int64) error {
+	stub.paidFuelRefillIDs = append(stub.paidFuelRefillIDs, fuelRefillIDs...)
+	return nil
+}
+
+func (stub *stubDatabaseAdaptor) GetFuelUsageUsersWithFuelUsageByIDs(ctx context.Context, fuelUsageUserIDs []int64) ([]FuelUsageUserWithFuelUsage, error) {
+	var data []FuelUsageUserWithFuelUsage
+	for _, fuelUsageUser := range stub.fuelUsageUsers {
+		if slices.Contains(fuelUsageUserIDs, fuelUsageUser.ID) {
+			data = append(data, stub.withFuelUsage(fuelUsageUser))
+		}
+	}
+	return data, nil
+}
+
+func (stub *stubDatabaseAdaptor) GetFuelRefillsByIDs(ctx context.Context, fuelRefillIDs []int64) ([]domains.FuelRefill, error) {
+	var fuelRefills []domains.FuelRefill
+	for _, fuelRefill := range stub.fuelRefills {
+		if slices.Contains(fuelRefillIDs, fuelRefill.ID) {
+			fuelRefills = append(fuelRefills, fuelRefill)
+		}
+	}
+	return fuelRefills, nil
+}
+
+func (stub *stubDatabaseAdaptor) withFuelUsage(fuelUsageUser FuelUsageUser) FuelUsageUserWithFuelUsage {
+	data := FuelUsageUserWithFuelUsage{
+		FuelUsageUser: fuelUsageUser.FuelUsageUser,
+	}
+	for _, fuelUsage := range stub.fuelUsages {
+		if fuelUsage.ID == fuelUsageUser.FuelUsageID {
+			data.FuelUseTime = fuelUsage.FuelUseTime
+			data.CarID = fuelUsage.CarID
+			data.CreditorUserID = stub.creditorUserID(fuelUsage)
+			data.IsPaidFromWallet = fuelUsage.IsPaidFromWallet
+		}
+	}
+	return data
+}
+
+// creditorUserID is the refiller of the refill of the fuel usage, or else
+// the owner of the car.
+func (stub *stubDatabaseAdaptor) creditorUserID(fuelUsage domains.FuelUsage) int64 {
+	for _, fuelRefill := range stub.fuelRefills {
+		if fuelRefill.ID == fuelUsage.FuelRefillID && fuelRefill.RefillBy != 0 {
+			return fuelRefill.RefillBy
+		}
+	}
+	for _, car := range stub.cars {
+		if car.ID == fuelUsage.CarID {
+			return car.OwnerUserID
+		}
+	}
+	return 0
+}
+
+func contextWithUser(userID int64) context.Context {
+	return context.WithValue(context.Background(), middlewares.ContextKeyUserID, userID)
+}
+
+func TestService_PayUserCarUnpaidActivities(t *testing.T) {
+	req := models.PayUserCarUnpaidActivitiesRequest{
+		UserID:           1,
+		CarID:            1,
+		FuelUsageUserIDs: []int64{10},
+		FuelRefillIDs:    []int64{20},
+	}
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		db       *stubDatabaseAdaptor
+		wantErr  error
+		wantPaid bool
+	}{
+		{
+			name:    "not authenticated",
+			ctx:     context.Background(),
+			db:      &stubDatabaseAdaptor{isUserOwnAllFuelUsageUser: true, isUserOwnAllFuelRefills: true},
+			wantErr: errs.ErrUnauthorized,
+		},
+		{
+			name:    "pay on behalf of another user",
+			ctx:     contextWithUser(2),
+			db:      &stubDatabaseAdaptor{isUserOwnAllFuelUsageUser: true, isUserOwnAllFuelRefills: true},
+			wantErr: errs.ErrForbidden,
+		},
+		{
+			name:    "pay fuel usage share of another user",
+			ctx:     contextWithUser(1),
+			db:      &stubDatabaseAdaptor{isUserOwnAllFuelUsageUser: false, isUserOwnAllFuelRefills: true},
+			wantErr: errs.ErrForbidden,
+		},
+		{
+			name:    "pay fuel refill of another user",
+			ctx:     contextWithUser(1),
+			db:      &stubDatabaseAdaptor{isUserOwnAllFuelUsageUser: true, isUserOwnAllFuelRefills: false},
+			wantErr: errs.ErrForbidden,
+		},
+		{
+			name: "pay own activities",
+			ctx:  contextWithUser(1),
+			db: &stubDatabaseAdaptor{
+				isUserOwnAllFuelUsageUser: true,
+				isUserOwnAllFuelRefills:   true,
+				cars:                      []domains.Car{{ID: 1, OwnerUserID: 1}},
+				fuelUsages:                []domains.FuelUsage{{ID: 1, CarID: 1}},
+				fuelUsageUsers: []FuelUsageUser{
+					{FuelUsageUser: domains.FuelUsageUser{ID: 10, FuelUsageID: 1, UserID: 1, Amount: decimal.NewFromInt(50)}},
+				},
+				fuelRefills: []domains.FuelRefill{
+					{ID: 20, CarID: 1, RefillBy: 1, TotalMoney: decimal.NewFromInt(500)},
+				},
+			},
+			wantPaid: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(nil, tt.db, nil, nil)
+			_, err := s.PayUserCarUnpaidActivities(tt.ctx, req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PayUserCarUnpaidActivities() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			isPaid := len(tt.db.payments) > 0
+			if isPaid != tt.wantPaid {
+				t.Errorf("PayUserCarUnpaidActivities() paid = %v, want %v", isPaid, tt.wantPaid)
+			}
+		})
+	}
+}
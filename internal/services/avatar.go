@@ -0,0 +1,112 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/bosskrub9992/fuel-management-backend/library/images"
+)
+
+const (
+	// AvatarMaxBytes is the largest avatar upload accepted.
+	AvatarMaxBytes = 5 << 20
+	// avatarMaxPixels rejects images which are small files but huge once decoded.
+	avatarMaxPixels = 4096 * 4096
+)
+
+var (
+	// avatarSizes are the thumbnail sizes in pixels, ProfileImageURL is the largest.
+	avatarSizes = []int{64, 128, 256}
+
+	avatarContentTypes = []string{"image/png", "image/jpeg"}
+)
+
+func (s *Service) UploadUserAvatar(ctx context.Context, req models.PostUserAvatarRequest) (*models.PostUserAvatarResponse, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	if err := shouldActAsUser(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+
+	avatar, err := decodeAvatar(req.Image)
+	if err != nil {
+		slog.WarnContext(ctx, err.Error(), "userId", req.UserID)
+		return nil, errs.ErrValidateFailed
+	}
+
+	user, err := s.getUserByID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	// the keys are replaced on every upload, the version busts caches
+	thumbnails := make(map[int]string)
+	for _, size := range avatarSizes {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, images.Thumbnail(avatar, size)); err != nil {
+			slog.ErrorContext(ctx, err.Error())
+			return nil, err
+		}
+
+		key := fmt.Sprintf("avatars/%d/%d.png", user.ID, size)
+		url, err := s.storage.Put(ctx, key, buf.Bytes(), "image/png")
+		if err != nil {
+			slog.ErrorContext(ctx, err.Error(), "key", key)
+			return nil, err
+		}
+		thumbnails[size] = fmt.Sprintf("%s?v=%d", url, now.Unix())
+	}
+
+	user.ProfileImageURL = thumbnails[slices.Max(avatarSizes)]
+	user.UpdateTime = now
+
+	if err := s.db.UpdateUser(ctx, *user); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	return &models.PostUserAvatarResponse{
+		ProfileImageURL: user.ProfileImageURL,
+		Thumbnails:      thumbnails,
+	}, nil
+}
+
+func decodeAvatar(data []byte) (image.Image, error) {
+	if len(data) > AvatarMaxBytes {
+		return nil, fmt.Errorf("avatar should <= %d bytes, got: [%d]", AvatarMaxBytes, len(data))
+	}
+
+	contentType := http.DetectContentType(data)
+	if !slices.Contains(avatarContentTypes, contentType) {
+		return nil, fmt.Errorf("avatar should be one of %v, got: [%s]", avatarContentTypes, contentType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > avatarMaxPixels {
+		return nil, fmt.Errorf("avatar should <= %d pixels, got: [%dx%d]", avatarMaxPixels, config.Width, config.Height)
+	}
+
+	avatar, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return avatar, nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+)
+
+type stubFileStorage struct {
+	keys []string
+}
+
+func (stub *stubFileStorage) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	stub.keys = append(stub.keys, key)
+	return "http://localhost:8080/public/uploads/" + key, nil
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestService_UploadUserAvatar(t *testing.T) {
+	tests := []struct {
+		name       string
+		actingUser int64
+		image      []byte
+		wantErr    error
+	}{
+		{
+			name:       "png",
+			actingUser: 1,
+			image:      encodePNG(t, 300, 200),
+		},
+		{
+			name:       "not an image",
+			actingUser: 1,
+			image:      []byte("%PDF-1.4"),
+			wantErr:    errs.ErrValidateFailed,
+		},
+		{
+			name:       "too many pixels",
+			actingUser: 1,
+			image:      encodePNG(t, 5000, 4000),
+			wantErr:    errs.ErrValidateFailed,
+		},
+		{
+			name:       "avatar of another user",
+			actingUser: 2,
+			image:      encodePNG(t, 10, 10),
+			wantErr:    errs.ErrForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &stubDatabaseAdaptor{
+				users: []domains.User{{ID: 1, Nickname: "Boss"}},
+			}
+			storage := &stubFileStorage{}
+			s := New(nil, db, nil, storage)
+
+			got, err := s.UploadUserAvatar(contextWithUser(tt.actingUser), models.PostUserAvatarRequest{
+				UserID: 1,
+				Image:  tt.image,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UploadUserAvatar() err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(storage.keys) != 0 || len(db.updatedUsers) != 0 {
+					t.Errorf("nothing should be stored, got keys %v and users %+v", storage.keys, db.updatedUsers)
+				}
+				return
+			}
+
+			if len(storage.keys) != len(avatarSizes) {
+				t.Errorf("stored keys = %v, want one per size %v", storage.keys, avatarSizes)
+			}
+			if !strings.HasPrefix(got.ProfileImageURL, "http://localhost:8080/public/uploads/avatars/1/256.png?v=") {
+				t.Errorf("ProfileImageURL = %q", got.ProfileImageURL)
+			}
+			if len(db.updatedUsers) != 1 || db.updatedUsers[0].ProfileImageURL != got.ProfileImageURL {
+				t.Errorf("updated users = %+v", db.updatedUsers)
+			}
+		})
+	}
+}
+
+func Test_toUserDatum(t *testing.T) {
+	withImage := toUserDatum(domains.User{Nickname: "Boss", ProfileImageURL: "http://localhost:8080/public/BOSS.PNG"})
+	if withImage.ProfileImageURL != "http://localhost:8080/public/BOSS.PNG" {
+		t.Errorf("ProfileImageURL = %q, want the uploaded image", withImage.ProfileImageURL)
+	}
+	withoutImage := toUserDatum(domains.User{Nickname: "Boss"})
+	if !strings.HasPrefix(withoutImage.ProfileImageURL, "data:image/svg+xml;base64,") {
+		t.Errorf("ProfileImageURL = %q, want an initials avatar", withoutImage.ProfileImageURL)
+	}
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"gorm.io/gorm"
+)
+
+func (s *Service) GetCars(ctx context.Context, req models.GetCarsRequest) (*models.GetCarData, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	cars, err := s.db.GetAllCars(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	carData := []models.CarDatum{}
+	for _, car := range cars {
+		if car.IsArchived && !req.IncludeArchived {
+			continue
+		}
+		carData = append(carData, toCarDatum(car))
+	}
+
+	return &models.GetCarData{
+		Data: carData,
+	}, nil
+}
+
+func (s *Service) GetCarByID(ctx context.Context, req models.GetCarByIDRequest) (*models.CarDatum, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	car, err := s.getCarByID(ctx, req.CarID)
+	if err != nil {
+		return nil, err
+	}
+
+	carDatum := toCarDatum(*car)
+	return &carDatum, nil
+}
+
+func (s *Service) CreateCar(ctx context.Context, req models.PostCarRequest) (*models.PostCarResponse, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	if err := s.shouldBeExistingUser(ctx, req.OwnerUserID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	carID, err := s.db.CreateCar(ctx, domains.Car{
+		Name:         req.Name,
+		PlateNumber:  req.PlateNumber,
+		FuelType:     req.FuelType,
+		TankCapacity: req.TankCapacity,
+		OwnerUserID:  req.OwnerUserID,
+		PricingPolicy: domains.CarPricingPolicy{
+			ExtraPerKm:    req.PricingPolicy.ExtraPerKm,
+			TripFee:       req.PricingPolicy.TripFee,
+			MinimumCharge: req.PricingPolicy.MinimumCharge,
+			IsRoundedUp:   req.PricingPolicy.IsRoundedUp,
+		},
+		FareRule:        cmp.Or(req.FareRule, domains.CarFareRuleNone),
+		IsWalletEnabled: req.IsWalletEnabled,
+		CreateTime:      now,
+		UpdateTime:      now,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	return &models.PostCarResponse{
+		ID: carID,
+	}, nil
+}
+
+func (s *Service) UpdateCarByID(ctx context.Context, req models.PutCarByIDRequest) error {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return errs.ErrValidateFailed
+	}
+
+	car, err := s.getCarByID(ctx, req.CarID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.shouldBeExistingUser(ctx, req.OwnerUserID); err != nil {
+		return err
+	}
+
+	car.Name = req.Name
+	car.PlateNumber = req.PlateNumber
+	car.FuelType = req.FuelType
+	car.TankCapacity = req.TankCapacity
+	car.OwnerUserID = req.OwnerUserID
+	car.PricingPolicy = domains.CarPricingPolicy{
+		ExtraPerKm:    req.PricingPolicy.ExtraPerKm,
+		TripFee:       req.PricingPolicy.TripFee,
+		MinimumCharge: req.PricingPolicy.MinimumCharge,
+		IsRoundedUp:   req.PricingPolicy.IsRoundedUp,
+	}
+	car.FareRule = cmp.Or(req.FareRule, domains.CarFareRuleNone)
+	car.IsWalletEnabled = req.IsWalletEnabled
+	car.UpdateTime = time.Now()
+
+	if err := s.db.UpdateCar(ctx, *car); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// ArchiveCarByID archives instead of deleting, so fuel usages and refills
+// of the car keep resolving its name.
+func (s *Service) ArchiveCarByID(ctx context.Context, req models.DeleteCarByIDRequest) error {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return errs.ErrValidateFailed
+	}
+
+	car, err := s.getCarByID(ctx, req.CarID)
+	if err != nil {
+		return err
+	}
+
+	if car.IsArchived {
+		return nil
+	}
+
+	car.IsArchived = true
+	car.UpdateTime = time.Now()
+
+	if err := s.db.UpdateCar(ctx, *car); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) getCarByID(ctx context.Context, carID int64) (*domains.Car, error) {
+	car, err := s.db.GetCarByID(ctx, carID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.WarnContext(ctx, "not found car", "carId", carID)
+			return nil, errs.ErrNotFound
+		}
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+	return car, nil
+}
+
+// shouldBeActiveCar rejects a new usage or refill on a car which does not
+// exist or is archived.
+func (s *Service) shouldBeActiveCar(ctx context.Context, carID int64) error {
+	_, err := s.getActiveCarByID(ctx, carID)
+	return err
+}
+
+func (s *Service) getActiveCarByID(ctx context.Context, carID int64) (*domains.Car, error) {
+	car, err := s.getCarByID(ctx, carID)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, errs.ErrValidateFailed
+		}
+		return nil, err
+	}
+	if car.IsArchived {
+		slog.WarnContext(ctx, "car is archived", "carId", carID)
+		return nil, errs.ErrValidateFailed
+	}
+	return car, nil
+}
+
+// shouldBeExistingUser accepts 0 as no user.
+func (s *Service) shouldBeExistingUser(ctx context.Context, userID int64) error {
+	if userID == 0 {
+		return nil
+	}
+	if _, err := s.db.GetUserByID(ctx, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.WarnContext(ctx, "not found user", "userId", userID)
+			return errs.ErrValidateFailed
+		}
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+	return nil
+}
+
+func toCarDatum(car domains.Car) models.CarDatum {
+	return models.CarDatum{
+		ID:           car.ID,
+		Name:         car.Name,
+		PlateNumber:  car.PlateNumber,
+		FuelType:     car.FuelType,
+		TankCapacity: car.TankCapacity,
+		OwnerUserID:  car.OwnerUserID,
+		PricingPolicy: models.CarPricingPolicy{
+			ExtraPerKm:    car.PricingPolicy.ExtraPerKm,
+			TripFee:       car.PricingPolicy.TripFee,
+			MinimumCharge: car.PricingPolicy.MinimumCharge,
+			IsRoundedUp:   car.PricingPolicy.IsRoundedUp,
+		},
+		FareRule:        car.FareRule,
+		IsWalletEnabled: car.IsWalletEnabled,
+		IsArchived:      car.IsArchived,
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+func (stub *stubDatabaseAdaptor) GetAllCars(ctx context.Context) ([]domains.Car, error) {
+	return stub.cars, nil
+}
+
+func (stub *stubDatabaseAdaptor) GetCarByID(ctx context.Context, carID int64) (*domains.Car, error) {
+	for _, car := range stub.cars {
+		if car.ID == carID {
+			return &car, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestService_GetCars(t *testing.T) {
+	s := New(nil, &stubDatabaseAdaptor{
+		cars: []domains.Car{
+			{ID: 1, Name: "Mazda 2"},
+			{ID: 2, Name: "Ford", IsArchived: true},
+		},
+	}, nil, nil)
+
+	tests := []struct {
+		name            string
+		includeArchived bool
+		wantIDs         []int64
+	}{
+		{
+			name:    "hide archived cars",
+			wantIDs: []int64{1},
+		},
+		{
+			name:            "include archived cars",
+			includeArchived: true,
+			wantIDs:         []int64{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetCars(context.Background(), models.GetCarsRequest{
+				IncludeArchived: tt.includeArchived,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotIDs []int64
+			for _, car := range got.Data {
+				gotIDs = append(gotIDs, car.ID)
+			}
+			if len(gotIDs) != len(tt.wantIDs) {
+				t.Fatalf("GetCars() ids = %v, want %v", gotIDs, tt.wantIDs)
+			}
+			for i := range gotIDs {
+				if gotIDs[i] != tt.wantIDs[i] {
+					t.Errorf("GetCars() ids = %v, want %v", gotIDs, tt.wantIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestService_CreateFuelRefill_ArchivedCar(t *testing.T) {
+	s := New(nil, &stubDatabaseAdaptor{
+		cars: []domains.Car{
+			{ID: 1, Name: "Mazda 2", IsArchived: true},
+		},
+	}, nil, nil)
+
+	tests := []struct {
+		name  string
+		carID int64
+	}{
+		{
+			name:  "archived car",
+			carID: 1,
+		},
+		{
+			name:  "not found car",
+			carID: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.CreateFuelRefill(contextWithUser(1), models.CreateFuelRefillRequest{
+				CurrentCarID:          tt.carID,
+				RefillTime:            time.Now(),
+				KilometerBeforeRefill: 100,
+				KilometerAfterRefill:  500,
+				TotalMoney:            decimal.NewFromInt(1000),
+				RefillBy:              1,
+			})
+			if !errors.Is(err, errs.ErrValidateFailed) {
+				t.Errorf("CreateFuelRefill() err = %v, want %v", err, errs.ErrValidateFailed)
+			}
+		})
+	}
+}
@@ -0,0 +1,429 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/shopspring/decimal"
+)
+
+// debtPosition is what a user fronted in unpaid refills minus the unpaid
+// fuel usage shares of the same user, kept per car as well.
+type debtPosition struct {
+	UserID           int64
+	FuelUsageAmount  decimal.Decimal
+	FuelRefillAmount decimal.Decimal
+	NetAmount        decimal.Decimal
+	CarIDToNetAmount map[int64]decimal.Decimal
+}
+
+type debtTransfer struct {
+	FromUserID int64
+	ToUserID   int64
+	Amount     decimal.Decimal
+}
+
+type debtPlan struct {
+	Positions       []debtPosition
+	Transfers       []debtTransfer
+	UserIDToUnpaid  map[int64]decimal.Decimal
+	UnsettledAmount decimal.Decimal
+	FuelUsages      []FuelUsageUserWithFuelUsage
+	FuelRefills     []domains.FuelRefill
+}
+
+// isEmpty is true when no transfer is needed, a settlement pays only what
+// the transfers cover.
+func (p debtPlan) isEmpty() bool {
+	return len(p.Transfers) == 0
+}
+
+// transferUserIDs are the users who pay or receive a transfer, in user id
+// order.
+func (p debtPlan) transferUserIDs() []int64 {
+	var userIDs []int64
+	for _, t := range p.Transfers {
+		userIDs = append(userIDs, t.FromUserID, t.ToUserID)
+	}
+	slices.Sort(userIDs)
+	return slices.Compact(userIDs)
+}
+
+// carIDs are the cars of the unpaid shares and refills of the plan.
+func (p debtPlan) carIDs() []int64 {
+	var carIDs []int64
+	for _, fu := range p.FuelUsages {
+		carIDs = append(carIDs, fu.CarID)
+	}
+	for _, fr := range p.FuelRefills {
+		carIDs = append(carIDs, fr.CarID)
+	}
+	slices.Sort(carIDs)
+	return slices.Compact(carIDs)
+}
+
+func netDebtPositions(fuelUsages []FuelUsageUserWithFuelUsage, fuelRefills []domains.FuelRefill) []debtPosition {
+	userIDToPosition := make(map[int64]*debtPosition)
+	positionOf := func(userID int64) *debtPosition {
+		position, found := userIDToPosition[userID]
+		if !found {
+			position = &debtPosition{
+				UserID:           userID,
+				FuelUsageAmount:  decimal.Zero,
+				FuelRefillAmount: decimal.Zero,
+				NetAmount:        decimal.Zero,
+				CarIDToNetAmount: make(map[int64]decimal.Decimal),
+			}
+			userIDToPosition[userID] = position
+		}
+		return position
+	}
+
+	for _, fu := range fuelUsages {
+		position := positionOf(fu.UserID)
+		position.FuelUsageAmount = position.FuelUsageAmount.Add(fu.Outstanding())
+		position.NetAmount = position.NetAmount.Sub(fu.Outstanding())
+		position.CarIDToNetAmount[fu.CarID] = position.CarIDToNetAmount[fu.CarID].Sub(fu.Outstanding())
+	}
+	for _, fr := range fuelRefills {
+		position := positionOf(fr.RefillBy)
+		position.FuelRefillAmount = position.FuelRefillAmount.Add(fr.Outstanding())
+		position.NetAmount = position.NetAmount.Add(fr.Outstanding())
+		position.CarIDToNetAmount[fr.CarID] = position.CarIDToNetAmount[fr.CarID].Add(fr.Outstanding())
+	}
+
+	positions := []debtPosition{}
+	for _, position := range userIDToPosition {
+		positions = append(positions, *position)
+	}
+	slices.SortFunc(positions, func(a, b debtPosition) int {
+		return cmp.Compare(a.UserID, b.UserID)
+	})
+	return positions
+}
+
+// simplifyDebts repeatedly lets the user owing the most pay the user owed
+// the most, every transfer clears at least one of them so the plan never
+// has more transfers than users. Shares and refills are not always equal,
+// so the amount left on the larger side is returned per user as unpaid.
+func simplifyDebts(positions []debtPosition) ([]debtTransfer, map[int64]decimal.Decimal) {
+	type balance struct {
+		userID int64
+		amount decimal.Decimal
+	}
+	var debtors, creditors []balance
+	for _, position := range positions {
+		switch {
+		case position.NetAmount.IsNegative():
+			debtors = append(debtors, balance{userID: position.UserID, amount: position.NetAmount.Neg()})
+		case position.NetAmount.IsPositive():
+			creditors = append(creditors, balance{userID: position.UserID, amount: position.NetAmount})
+		}
+	}
+	byAmountDesc := func(a, b balance) int {
+		if c := b.amount.Cmp(a.amount); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.userID, b.userID)
+	}
+
+	transfers := []debtTransfer{}
+	for len(debtors) > 0 && len(creditors) > 0 {
+		slices.SortFunc(debtors, byAmountDesc)
+		slices.SortFunc(creditors, byAmountDesc)
+
+		amount := decimal.Min(debtors[0].amount, creditors[0].amount)
+		transfers = append(transfers, debtTransfer{
+			FromUserID: debtors[0].userID,
+			ToUserID:   creditors[0].userID,
+			Amount:     amount,
+		})
+
+		debtors[0].amount = debtors[0].amount.Sub(amount)
+		creditors[0].amount = creditors[0].amount.Sub(amount)
+		if debtors[0].amount.IsZero() {
+			debtors = debtors[1:]
+		}
+		if creditors[0].amount.IsZero() {
+			creditors = creditors[1:]
+		}
+	}
+
+	userIDToUnpaid := make(map[int64]decimal.Decimal)
+	for _, d := range debtors {
+		userIDToUnpaid[d.userID] = d.amount.Neg()
+	}
+	for _, c := range creditors {
+		userIDToUnpaid[c.userID] = c.amount
+	}
+	return transfers, userIDToUnpaid
+}
+
+func (s *Service) getDebtPlan(ctx context.Context, carID int64) (*debtPlan, error) {
+	fuelUsages, err := s.db.GetUnpaidFuelUsageUsers(ctx, carID)
+	if err != nil {
+		return nil, err
+	}
+
+	fuelRefills, err := s.db.GetUnpaidFuelRefills(ctx, carID)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := netDebtPositions(fuelUsages, fuelRefills)
+	transfers, userIDToUnpaid := simplifyDebts(positions)
+
+	unsettledAmount := decimal.Zero
+	for _, unpaid := range userIDToUnpaid {
+		unsettledAmount = unsettledAmount.Add(unpaid.Abs())
+	}
+
+	return &debtPlan{
+		Positions:       positions,
+		Transfers:       transfers,
+		UserIDToUnpaid:  userIDToUnpaid,
+		UnsettledAmount: unsettledAmount,
+		FuelUsages:      fuelUsages,
+		FuelRefills:     fuelRefills,
+	}, nil
+}
+
+func (s *Service) getMapUserIDToDebtUser(ctx context.Context) (map[int64]models.DebtUser, error) {
+	users, err := s.db.GetAllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userIDToDebtUser := make(map[int64]models.DebtUser)
+	for _, user := range users {
+		userIDToDebtUser[user.ID] = models.DebtUser{
+			ID:       user.ID,
+			Nickname: user.Nickname,
+		}
+	}
+	return userIDToDebtUser, nil
+}
+
+func toDebtTransferModels(transfers []debtTransfer, userIDToDebtUser map[int64]models.DebtUser) []models.DebtTransfer {
+	debtTransfers := []models.DebtTransfer{}
+	for _, t := range transfers {
+		debtTransfers = append(debtTransfers, models.DebtTransfer{
+			FromUser: userIDToDebtUser[t.FromUserID],
+			ToUser:   userIDToDebtUser[t.ToUserID],
+			Amount:   t.Amount,
+		})
+	}
+	return debtTransfers
+}
+
+func (s *Service) GetDebtSimplification(ctx context.Context, req models.GetDebtSimplificationRequest) (*models.GetDebtSimplificationResponse, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	plan, err := s.getDebtPlan(ctx, req.CarID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	userIDToDebtUser, err := s.getMapUserIDToDebtUser(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	response := models.GetDebtSimplificationResponse{
+		Positions:       []models.DebtPosition{},
+		Transfers:       toDebtTransferModels(plan.Transfers, userIDToDebtUser),
+		UnsettledAmount: plan.UnsettledAmount,
+	}
+	for _, position := range plan.Positions {
+		response.Positions = append(response.Positions, models.DebtPosition{
+			User:             userIDToDebtUser[position.UserID],
+			FuelUsageAmount:  position.FuelUsageAmount,
+			FuelRefillAmount: position.FuelRefillAmount,
+			NetAmount:        position.NetAmount,
+		})
+	}
+
+	return &response, nil
+}
+
+// CreateDebtSettlement pays each transfer of the plan and nets the shares of
+// the users of the transfers against the refills of the same user, all with
+// pending payments the payees confirm. Only a user who pays or receives
+// every transfer can settle. What the transfers leave unpaid stays
+// outstanding on the shares and refills.
+func (s *Service) CreateDebtSettlement(ctx context.Context, req models.PostDebtSettlementRequest) (*models.PostDebtSettlementResponse, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	currentUserID, err := actingUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDToDebtUser, err := s.getMapUserIDToDebtUser(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	var response models.PostDebtSettlementResponse
+
+	now := time.Now()
+
+	err = s.db.Transaction(ctx, func(ctxTx context.Context) error {
+		plan, err := s.getDebtPlan(ctxTx, req.CarID)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		if plan.isEmpty() {
+			slog.WarnContext(ctxTx, errors.New("nothing to settle").Error(), "carId", req.CarID)
+			return errs.ErrValidateFailed
+		}
+
+		for _, t := range plan.Transfers {
+			if t.FromUserID != currentUserID && t.ToUserID != currentUserID {
+				slog.WarnContext(ctxTx, "user neither pays nor receives a transfer of the plan",
+					"fromUserId", t.FromUserID,
+					"toUserId", t.ToUserID,
+				)
+				return errs.ErrForbidden
+			}
+		}
+
+		if req.CarID == 0 {
+			if err := s.shouldBeMemberOfCars(ctxTx, currentUserID, plan.carIDs()); err != nil {
+				return err
+			}
+		}
+
+		debtSettlementID, err := s.db.CreateDebtSettlement(ctxTx, domains.DebtSettlement{
+			CarID:           req.CarID,
+			UnsettledAmount: plan.UnsettledAmount,
+			CreateBy:        currentUserID,
+			CreateTime:      now,
+		})
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		var transfers []domains.DebtSettlementTransfer
+		for _, t := range plan.Transfers {
+			transfers = append(transfers, domains.DebtSettlementTransfer{
+				DebtSettlementID: debtSettlementID,
+				FromUserID:       t.FromUserID,
+				ToUserID:         t.ToUserID,
+				Amount:           t.Amount,
+			})
+		}
+		if len(transfers) > 0 {
+			if err := s.db.CreateDebtSettlementTransfers(ctxTx, transfers); err != nil {
+				slog.ErrorContext(ctxTx, err.Error())
+				return err
+			}
+		}
+
+		queueOf := func(userIDToQueue map[int64]*paymentItemQueue, userID int64) *paymentItemQueue {
+			if _, found := userIDToQueue[userID]; !found {
+				userIDToQueue[userID] = &paymentItemQueue{}
+			}
+			return userIDToQueue[userID]
+		}
+		userIDToShares := make(map[int64]*paymentItemQueue)
+		for _, item := range fuelUsageUserPaymentItems(plan.FuelUsages) {
+			shares := queueOf(userIDToShares, item.UserID)
+			*shares = append(*shares, item)
+		}
+		userIDToRefills := make(map[int64]*paymentItemQueue)
+		for _, item := range fuelRefillPaymentItems(plan.FuelRefills) {
+			refills := queueOf(userIDToRefills, item.UserID)
+			*refills = append(*refills, item)
+		}
+
+		payments := []models.Payment{}
+
+		transferUserIDs := plan.transferUserIDs()
+		for _, position := range plan.Positions {
+			if !slices.Contains(transferUserIDs, position.UserID) {
+				continue
+			}
+			offsetAmount := decimal.Min(position.FuelUsageAmount, position.FuelRefillAmount)
+			if !offsetAmount.IsPositive() {
+				continue
+			}
+			offsetPayments, err := s.createSettlementPayments(ctxTx, domains.Payment{
+				PayerUserID: position.UserID,
+				Method:      domains.PaymentMethodSettlement,
+				PayTime:     now,
+				CreateBy:    currentUserID,
+				CreateTime:  now,
+			}, offsetAmount, queueOf(userIDToShares, position.UserID), queueOf(userIDToRefills, position.UserID), now)
+			if err != nil {
+				slog.ErrorContext(ctxTx, err.Error())
+				return err
+			}
+			payments = append(payments, offsetPayments...)
+		}
+
+		for _, t := range plan.Transfers {
+			transferPayments, err := s.createSettlementPayments(ctxTx, domains.Payment{
+				PayerUserID: t.FromUserID,
+				PayeeUserID: t.ToUserID,
+				Method:      domains.PaymentMethodCash,
+				PayTime:     now,
+				CreateBy:    currentUserID,
+				CreateTime:  now,
+			}, t.Amount, queueOf(userIDToShares, t.FromUserID), queueOf(userIDToRefills, t.ToUserID), now)
+			if err != nil {
+				slog.ErrorContext(ctxTx, err.Error())
+				return err
+			}
+			payments = append(payments, transferPayments...)
+		}
+
+		response = models.PostDebtSettlementResponse{
+			DebtSettlementID: debtSettlementID,
+			Transfers:        toDebtTransferModels(plan.Transfers, userIDToDebtUser),
+			UnsettledAmount:  plan.UnsettledAmount,
+			Payments:         payments,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// shouldBeMemberOfCars allows a settlement of every car at once only for a
+// user who owns or shares every car it settles.
+func (s *Service) shouldBeMemberOfCars(ctx context.Context, userID int64, carIDs []int64) error {
+	userCarIDs, err := s.db.GetUserCarIDs(ctx, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+	for _, carID := range carIDs {
+		if !slices.Contains(userCarIDs, carID) {
+			slog.WarnContext(ctx, "user is not a member of the car", "carId", carID)
+			return errs.ErrForbidden
+		}
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/shopspring/decimal"
+)
+
+func (stub *stubDatabaseAdaptor) GetUnpaidFuelUsageUsers(ctx context.Context, carID int64) ([]FuelUsageUserWithFuelUsage, error) {
+	var data []FuelUsageUserWithFuelUsage
+	for _, fuelUsageUser := range stub.fuelUsageUsers {
+		d := stub.withFuelUsage(fuelUsageUser)
+		if !d.IsPaid && (carID == 0 || d.CarID == carID) {
+			data = append(data, d)
+		}
+	}
+	return data, nil
+}
+
+func (stub *stubDatabaseAdaptor) GetUnpaidFuelRefills(ctx context.Context, carID int64) ([]domains.FuelRefill, error) {
+	var fuelRefills []domains.FuelRefill
+	for _, fuelRefill := range stub.fuelRefills {
+		if !fuelRefill.IsPaid && (carID == 0 || fuelRefill.CarID == carID) {
+			fuelRefills = append(fuelRefills, fuelRefill)
+		}
+	}
+	return fuelRefills, nil
+}
+
+func (stub *stubDatabaseAdaptor) CreateDebtSettlement(ctx context.Context, debtSettlement domains.DebtSettlement) (int64, error) {
+	return 1, nil
+}
+
+func (stub *stubDatabaseAdaptor) CreateDebtSettlementTransfers(ctx context.Context, transfers []domains.DebtSettlementTransfer) error {
+	return nil
+}
+
+func (stub *stubDatabaseAdaptor) GetUserCarIDs(ctx context.Context, userID int64) ([]int64, error) {
+	var carIDs []int64
+	for _, car := range stub.cars {
+		if car.OwnerUserID == userID {
+			carIDs = append(carIDs, car.ID)
+		}
+	}
+	for _, fuelUsageUser := range stub.fuelUsageUsers {
+		if fuelUsageUser.UserID == userID {
+			carIDs = append(carIDs, stub.withFuelUsage(fuelUsageUser).CarID)
+		}
+	}
+	for _, fuelRefill := range stub.fuelRefills {
+		if fuelRefill.RefillBy == userID {
+			carIDs = append(carIDs, fuelRefill.CarID)
+		}
+	}
+	return carIDs, nil
+}
+
+func Test_simplifyDebts(t *testing.T) {
+	position := func(userID int64, netAmount float64) debtPosition {
+		return debtPosition{UserID: userID, NetAmount: decimal.NewFromFloat(netAmount)}
+	}
+	transfer := func(fromUserID, toUserID int64, amount float64) debtTransfer {
+		return debtTransfer{FromUserID: fromUserID, ToUserID: toUserID, Amount: decimal.NewFromFloat(amount)}
+	}
+	tests := []struct {
+		name             string
+		positions        []debtPosition
+		wantTransfers    []debtTransfer
+		wantUnpaidByUser map[int64]decimal.Decimal
+	}{
+		{
+			name:             "nobody owes",
+			positions:        []debtPosition{position(1, 0)},
+			wantTransfers:    []debtTransfer{},
+			wantUnpaidByUser: map[int64]decimal.Decimal{},
+		},
+		{
+			name:      "chain of debts collapses into direct transfers",
+			positions: []debtPosition{position(1, -100), position(2, 0), position(3, 100)},
+			wantTransfers: []debtTransfer{
+				transfer(1, 3, 100),
+			},
+			wantUnpaidByUser: map[int64]decimal.Decimal{},
+		},
+		{
+			name:      "largest debtor pays largest creditor first",
+			positions: []debtPosition{position(1, -300), position(2, -200), position(3, 350), position(4, 150)},
+			wantTransfers: []debtTransfer{
+				transfer(1, 3, 300),
+				transfer(2, 4, 150),
+				transfer(2, 3, 50),
+			},
+			wantUnpaidByUser: map[int64]decimal.Decimal{},
+		},
+		{
+			name:      "refills larger than shares leave the refiller unpaid",
+			positions: []debtPosition{position(1, 700), position(2, -300), position(3, -200)},
+			wantTransfers: []debtTransfer{
+				transfer(2, 1, 300),
+				transfer(3, 1, 200),
+			},
+			wantUnpaidByUser: map[int64]decimal.Decimal{1: decimal.NewFromInt(200)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transfers, unpaidByUser := simplifyDebts(tt.positions)
+			isEqual := slices.EqualFunc(transfers, tt.wantTransfers, func(a, b debtTransfer) bool {
+				return a.FromUserID == b.FromUserID && a.ToUserID == b.ToUserID && a.Amount.Equal(b.Amount)
+			})
+			if !isEqual {
+				t.Errorf("transfers = %v, want %v", transfers, tt.wantTransfers)
+			}
+			if len(unpaidByUser) != len(tt.wantUnpaidByUser) {
+				t.Fatalf("unpaid = %v, want %v", unpaidByUser, tt.wantUnpaidByUser)
+			}
+			for userID, want := range tt.wantUnpaidByUser {
+				if !unpaidByUser[userID].Equal(want) {
+					t.Errorf("unpaid of userId %d = %s, want %s", userID, unpaidByUser[userID], want)
+				}
+			}
+		})
+	}
+}
+
+func TestService_CreateDebtSettlement(t *testing.T) {
+	db := &stubDatabaseAdaptor{
+		cars:       []domains.Car{{ID: 1, OwnerUserID: 1}},
+		fuelUsages: []domains.FuelUsage{{ID: 1, CarID: 1, FuelRefillID: 1}},
+		fuelUsageUsers: []FuelUsageUser{
+			{FuelUsageUser: domains.FuelUsageUser{ID: 1, FuelUsageID: 1, UserID: 1, Amount: decimal.NewFromInt(100)}},
+			{FuelUsageUser: domains.FuelUsageUser{ID: 2, FuelUsageID: 1, UserID: 2, Amount: decimal.NewFromInt(300)}},
+		},
+		fuelRefills: []domains.FuelRefill{
+			{ID: 1, CarID: 1, RefillBy: 1, TotalMoney: decimal.NewFromInt(500)},
+		},
+	}
+	s := New(nil, db, nil, nil)
+
+	response, err := s.CreateDebtSettlement(contextWithUser(2), models.PostDebtSettlementRequest{CarID: 1})
+	if err != nil {
+		t.Fatalf("CreateDebtSettlement() error = %v", err)
+	}
+	if len(response.Payments) != 2 {
+		t.Fatalf("payments = %+v, want the offset of user 1 and the transfer of user 2", response.Payments)
+	}
+
+	// nothing is paid until the refiller confirms
+	for _, payment := range response.Payments {
+		if payment.Status != domains.PaymentConfirmStatusPending || payment.ConfirmUserID != 1 {
+			t.Errorf("payment = %+v, want pending for user 1", payment)
+		}
+	}
+	if refill := db.fuelRefills[0]; !refill.PaidAmount.IsZero() || !refill.ClaimedAmount.Equal(decimal.NewFromInt(400)) {
+		t.Errorf("refill = %+v, want 400 claimed", refill)
+	}
+
+	for _, payment := range response.Payments {
+		_, err := s.ConfirmUserPayment(contextWithUser(1), models.PatchUserPaymentConfirmRequest{
+			UserID:    1,
+			PaymentID: payment.ID,
+		})
+		if err != nil {
+			t.Fatalf("ConfirmUserPayment() error = %v", err)
+		}
+	}
+
+	for _, share := range db.fuelUsageUsers {
+		if !share.IsPaid {
+			t.Errorf("share = %+v, want paid", share)
+		}
+	}
+	// the 100 the transfers do not cover is still owed to the refiller
+	if refill := db.fuelRefills[0]; refill.IsPaid || !refill.Outstanding().Equal(decimal.NewFromInt(100)) {
+		t.Errorf("refill = %+v, want 100 left to reimburse", refill)
+	}
+}
+
+func TestService_CreateDebtSettlement_authorization(t *testing.T) {
+	newDB := func() *stubDatabaseAdaptor {
+		return &stubDatabaseAdaptor{
+			cars: []domains.Car{{ID: 1, OwnerUserID: 1}, {ID: 2, OwnerUserID: 2}},
+			fuelUsages: []domains.FuelUsage{
+				{ID: 1, CarID: 1, FuelRefillID: 1},
+				{ID: 2, CarID: 2},
+			},
+			fuelUsageUsers: []FuelUsageUser{
+				{FuelUsageUser: domains.FuelUsageUser{ID: 1, FuelUsageID: 1, UserID: 2, Amount: decimal.NewFromInt(300)}},
+				{FuelUsageUser: domains.FuelUsageUser{ID: 2, FuelUsageID: 1, UserID: 3, Amount: decimal.NewFromInt(200)}},
+				{FuelUsageUser: domains.FuelUsageUser{ID: 3, FuelUsageID: 2, UserID: 2, Amount: decimal.NewFromInt(50)}},
+			},
+			fuelRefills: []domains.FuelRefill{
+				{ID: 1, CarID: 1, RefillBy: 1, TotalMoney: decimal.NewFromInt(500)},
+			},
+		}
+	}
+	tests := []struct {
+		name    string
+		userID  int64
+		carID   int64
+		wantErr error
+	}{
+		{
+			name:   "payee of every transfer",
+			userID: 1,
+			carID:  1,
+		},
+		{
+			name:    "payer of one transfer only",
+			userID:  2,
+			carID:   1,
+			wantErr: errs.ErrForbidden,
+		},
+		{
+			name:    "every car without being a member of the second one",
+			userID:  1,
+			carID:   0,
+			wantErr: errs.ErrForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newDB()
+			s := New(nil, db, nil, nil)
+			_, err := s.CreateDebtSettlement(contextWithUser(tt.userID), models.PostDebtSettlementRequest{CarID: tt.carID})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateDebtSettlement() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && len(db.payments) != 0 {
+				t.Errorf("payments = %+v, want none", db.payments)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// resolveFuelRefill returns the refill a fuel usage takes its fuel price
+// from and that price. A given refill should be of the car, its price
+// replaces a zero fuelPrice and should equal any other. Without a given
+// refill, the latest refill of the car is taken when fuelPrice is its price,
+// else the fuel price was given by hand and the refill is 0.
+func (s *Service) resolveFuelRefill(ctx context.Context, carID, fuelRefillID int64, fuelPrice decimal.Decimal) (int64, decimal.Decimal, error) {
+	if fuelRefillID == 0 {
+		latestFuelRefill, err := s.db.GetLatestFuelRefillByCarID(ctx, carID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return 0, fuelPrice, nil
+			}
+			slog.ErrorContext(ctx, err.Error())
+			return 0, decimal.Zero, err
+		}
+		if !latestFuelRefill.FuelPriceCalculated.Equal(fuelPrice) {
+			return 0, fuelPrice, nil
+		}
+		return latestFuelRefill.ID, fuelPrice, nil
+	}
+
+	fuelRefill, err := s.db.GetFuelRefillByID(ctx, fuelRefillID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.WarnContext(ctx, "not found fuel refill", "fuelRefillId", fuelRefillID)
+			return 0, decimal.Zero, errs.ErrValidateFailed
+		}
+		slog.ErrorContext(ctx, err.Error())
+		return 0, decimal.Zero, err
+	}
+	if fuelRefill.CarID != carID {
+		slog.WarnContext(ctx, "fuel refill is of another car", "fuelRefillId", fuelRefillID, "carId", carID)
+		return 0, decimal.Zero, errs.ErrValidateFailed
+	}
+	if !fuelPrice.IsZero() && !fuelPrice.Equal(fuelRefill.FuelPriceCalculated) {
+		slog.WarnContext(ctx, "fuel price differs from the fuel refill",
+			"fuelRefillId", fuelRefillID,
+			"fuelPrice", fuelPrice,
+			"fuelPriceCalculated", fuelRefill.FuelPriceCalculated,
+		)
+		return 0, decimal.Zero, errs.ErrValidateFailed
+	}
+	return fuelRefill.ID, fuelRefill.FuelPriceCalculated, nil
+}
+
+// recalculateFuelUsages prices the fuel usages of the refill again with its
+// fuel price, then rewrites their amounts and ledger entries. The pricing
+// policy is the current one of the car, the fare weights are kept. A paid
+// fuel usage is locked, so when isPaidIncluded its new amounts are posted by
+// adjustments instead of rewriting its ledger entries.
+func (s *Service) recalculateFuelUsages(
+	ctx context.Context,
+	fuelRefill domains.FuelRefill,
+	isPaidIncluded bool,
+	now time.Time,
+) (
+	*models.PutFuelRefillByIDResponse,
+	error,
+) {
+	response := models.PutFuelRefillByIDResponse{
+		RecalculatedFuelUsages: []models.RecalculatedFuelUsage{},
+		SkippedFuelUsages:      []models.SkippedFuelUsage{},
+	}
+
+	fuelUsages, err := s.db.GetFuelUsagesByFuelRefillID(ctx, fuelRefill.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(fuelUsages) == 0 {
+		return &response, nil
+	}
+
+	var fuelUsageIDs []int64
+	for _, fuelUsage := range fuelUsages {
+		fuelUsageIDs = append(fuelUsageIDs, fuelUsage.ID)
+	}
+
+	fuelUsageUsers, err := s.db.GetFuelUsageUsersByFuelUsageIDs(ctx, fuelUsageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	fuelUsageIDToFuelUsageUsers := make(map[int64][]domains.FuelUsageUser)
+	for _, fuelUsageUser := range fuelUsageUsers {
+		fuelUsageIDToFuelUsageUsers[fuelUsageUser.FuelUsageID] = append(
+			fuelUsageIDToFuelUsageUsers[fuelUsageUser.FuelUsageID],
+			fuelUsageUser.FuelUsageUser,
+		)
+	}
+
+	carIDToCar := make(map[int64]*domains.Car)
+	for _, fuelUsage := range fuelUsages {
+		fuelUsageUsers := fuelUsageIDToFuelUsageUsers[fuelUsage.ID]
+
+		// the users are created in the order of the request
+		slices.SortFunc(fuelUsageUsers, func(a, b domains.FuelUsageUser) int {
+			return cmp.Compare(a.ID, b.ID)
+		})
+
+		isClaimed := slices.ContainsFunc(fuelUsageUsers, func(fuelUsageUser domains.FuelUsageUser) bool {
+			return fuelUsageUser.ClaimedAmount.IsPositive()
+		})
+		if isClaimed {
+			response.SkippedFuelUsages = append(response.SkippedFuelUsages, models.SkippedFuelUsage{
+				FuelUsageID: fuelUsage.ID,
+				Reason:      models.SkippedFuelUsageReasonClaimed,
+			})
+			continue
+		}
+
+		// the amounts in effect of an adjusted fuel usage are its adjustments
+		adjustments, err := s.db.GetAdjustmentsByReference(ctx, domains.AdjustmentReferenceTypeFuelUsage, fuelUsage.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(adjustments) > 0 {
+			response.SkippedFuelUsages = append(response.SkippedFuelUsages, models.SkippedFuelUsage{
+				FuelUsageID: fuelUsage.ID,
+				Reason:      models.SkippedFuelUsageReasonAdjusted,
+			})
+			continue
+		}
+
+		isPaid := slices.ContainsFunc(fuelUsageUsers, func(fuelUsageUser domains.FuelUsageUser) bool {
+			return fuelUsageUser.IsPaid || fuelUsageUser.PaidAmount.IsPositive()
+		})
+		// the wallet pays the new amounts of a fuel usage paid from it
+		if isPaid && !isPaidIncluded && !fuelUsage.IsPaidFromWallet {
+			response.SkippedFuelUsages = append(response.SkippedFuelUsages, models.SkippedFuelUsage{
+				FuelUsageID: fuelUsage.ID,
+				Reason:      models.SkippedFuelUsageReasonPaid,
+			})
+			continue
+		}
+		if fuelUsage.SplitMode == domains.FuelUsageSplitModeAmount {
+			response.SkippedFuelUsages = append(response.SkippedFuelUsages, models.SkippedFuelUsage{
+				FuelUsageID: fuelUsage.ID,
+				Reason:      models.SkippedFuelUsageReasonAmountSplit,
+			})
+			continue
+		}
+
+		car, found := carIDToCar[fuelUsage.CarID]
+		if !found {
+			car, err = s.getCarByID(ctx, fuelUsage.CarID)
+			if err != nil {
+				return nil, err
+			}
+			carIDToCar[fuelUsage.CarID] = car
+		}
+
+		isAdjusted := isPaid && !fuelUsage.IsPaidFromWallet
+
+		recalculated := models.RecalculatedFuelUsage{
+			FuelUsageID:   fuelUsage.ID,
+			IsAdjusted:    isAdjusted,
+			OldFuelPrice:  fuelUsage.FuelPrice,
+			NewFuelPrice:  fuelRefill.FuelPriceCalculated,
+			OldTotalMoney: fuelUsage.TotalMoney,
+		}
+		oldAmounts := make([]decimal.Decimal, len(fuelUsageUsers))
+		for i, fuelUsageUser := range fuelUsageUsers {
+			oldAmounts[i] = fuelUsageUser.Amount
+		}
+
+		fuelUsage.FuelPrice = fuelRefill.FuelPriceCalculated
+		fuelUsage.TotalMoney, fuelUsage.Cost, err = calculateTotalMoney(
+			fuelUsage.KilometerBeforeUse,
+			fuelUsage.KilometerAfterUse,
+			fuelUsage.FuelPrice,
+			car.PricingPolicy,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("fuelUsageId: [%d]: %w", fuelUsage.ID, err)
+		}
+		if err := splitFuelUsage(fuelUsage, fuelUsageUsers, s.splitTieBreak()); err != nil {
+			return nil, fmt.Errorf("fuelUsageId: [%d]: %w", fuelUsage.ID, err)
+		}
+		fuelUsage.UpdateTime = now
+		recalculated.NewTotalMoney = fuelUsage.TotalMoney
+
+		if err := s.db.UpdateFuelUsage(ctx, fuelUsage); err != nil {
+			return nil, err
+		}
+		if fuelUsage.IsPaidFromWallet {
+			payFuelUsageUsersFromWallet(fuelUsageUsers)
+		}
+		var priceAdjustments []domains.Adjustment
+		for i, fuelUsageUser := range fuelUsageUsers {
+			// what was paid stays, so a larger amount is left partially paid
+			fuelUsageUser.IsPaid = fuelUsageUser.PaidAmount.GreaterThanOrEqual(fuelUsageUser.Amount)
+			recalculated.FuelUsers = append(recalculated.FuelUsers, models.RecalculatedFuelUser{
+				UserID:    fuelUsageUser.UserID,
+				IsPaid:    fuelUsageUser.IsPaid,
+				OldAmount: oldAmounts[i],
+				NewAmount: fuelUsageUser.Amount,
+			})
+			if fuelUsageUser.Amount.Equal(oldAmounts[i]) {
+				continue
+			}
+			if isAdjusted {
+				priceAdjustments = append(priceAdjustments, domains.Adjustment{
+					CarID:         fuelUsage.CarID,
+					ReferenceType: domains.AdjustmentReferenceTypeFuelUsage,
+					ReferenceID:   fuelUsage.ID,
+					UserID:        fuelUsageUser.UserID,
+					OldAmount:     oldAmounts[i],
+					NewAmount:     fuelUsageUser.Amount,
+					Reason:        fmt.Sprintf("fuel price of fuel refill %d changed", fuelRefill.ID),
+					CreateBy:      fuelRefill.UpdateBy,
+					CreateTime:    now,
+				})
+			}
+			if err := s.db.UpdateFuelUsageUserAmount(ctx, fuelUsageUser.ID, fuelUsageUser.Amount); err != nil {
+				return nil, err
+			}
+			if fuelUsage.IsPaidFromWallet {
+				isAdded, err := s.db.AddFuelUsageUserPaidAmount(ctx, fuelUsageUser.ID, fuelUsageUser.Amount.Sub(oldAmounts[i]))
+				if err != nil {
+					return nil, err
+				}
+				if !isAdded {
+					slog.WarnContext(ctx, "the share would be paid more than it owes", "fuelUsageUserId", fuelUsageUser.ID)
+					return nil, errs.ErrConflict
+				}
+			}
+		}
+		if isAdjusted && len(priceAdjustments) > 0 {
+			// the refiller fronted the money of the fuel usage, else the owner
+			creditorUserID := cmp.Or(fuelRefill.RefillBy, car.OwnerUserID)
+			if err := s.createAdjustments(ctx, priceAdjustments, creditorUserID); err != nil {
+				return nil, err
+			}
+		}
+		if isAdjusted {
+			response.RecalculatedFuelUsages = append(response.RecalculatedFuelUsages, recalculated)
+			continue
+		}
+		if err := s.replaceFuelUsageLedgerEntries(ctx, fuelUsage, fuelUsageUsers, now); err != nil {
+			return nil, err
+		}
+		if fuelUsage.IsPaidFromWallet {
+			if err := s.replaceFuelUsageWalletTransactions(ctx, fuelUsage, fuelUsageUsers, now); err != nil {
+				return nil, err
+			}
+		}
+
+		response.RecalculatedFuelUsages = append(response.RecalculatedFuelUsages, recalculated)
+	}
+
+	return &response, nil
+}
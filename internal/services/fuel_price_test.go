@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+func (stub *stubDatabaseAdaptor) GetFuelRefillByID(ctx context.Context, fuelRefillID int64) (*domains.FuelRefill, error) {
+	for _, fuelRefill := range stub.fuelRefills {
+		if fuelRefill.ID == fuelRefillID {
+			return &fuelRefill, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (stub *stubDatabaseAdaptor) UpdateFuelRefill(ctx context.Context, fuelRefill domains.FuelRefill) error {
+	for i := range stub.fuelRefills {
+		if stub.fuelRefills[i].ID == fuelRefill.ID {
+			stub.fuelRefills[i] = fuelRefill
+		}
+	}
+	return nil
+}
+
+func (stub *stubDatabaseAdaptor) GetFuelUsagesByFuelRefillID(ctx context.Context, fuelRefillID int64) ([]domains.FuelUsage, error) {
+	var fuelUsages []domains.FuelUsage
+	for _, fuelUsage := range stub.fuelUsages {
+		if fuelUsage.FuelRefillID == fuelRefillID {
+			fuelUsages = append(fuelUsages, fuelUsage)
+		}
+	}
+	return fuelUsages, nil
+}
+
+func (stub *stubDatabaseAdaptor) UpdateFuelUsage(ctx context.Context, fuelUsage domains.FuelUsage) error {
+	for i := range stub.fuelUsages {
+		if stub.fuelUsages[i].ID == fuelUsage.ID {
+			stub.fuelUsages[i] = fuelUsage
+		}
+	}
+	return nil
+}
+
+func TestService_resolveFuelRefill(t *testing.T) {
+	stub := &stubDatabaseAdaptor{
+		fuelRefills: []domains.FuelRefill{
+			{ID: 1, CarID: 1, RefillTime: time.Now().Add(-time.Hour), FuelPriceCalculated: decimal.NewFromInt(4)},
+			{ID: 2, CarID: 1, RefillTime: time.Now(), FuelPriceCalculated: decimal.NewFromInt(5)},
+			{ID: 3, CarID: 2, RefillTime: time.Now(), FuelPriceCalculated: decimal.NewFromInt(5)},
+		},
+	}
+	s := New(nil, stub, nil, nil)
+
+	tests := []struct {
+		name             string
+		fuelRefillID     int64
+		fuelPrice        int64
+		wantFuelRefillID int64
+		wantFuelPrice    int64
+		wantErr          error
+	}{
+		{name: "latest refill of the same price", fuelPrice: 5, wantFuelRefillID: 2, wantFuelPrice: 5},
+		{name: "fuel price by hand", fuelPrice: 6, wantFuelRefillID: 0, wantFuelPrice: 6},
+		{name: "price taken from the given refill", fuelRefillID: 1, wantFuelRefillID: 1, wantFuelPrice: 4},
+		{name: "price equal to the given refill", fuelRefillID: 1, fuelPrice: 4, wantFuelRefillID: 1, wantFuelPrice: 4},
+		{name: "price differs from the given refill", fuelRefillID: 1, fuelPrice: 5, wantErr: errs.ErrValidateFailed},
+		{name: "refill of another car", fuelRefillID: 3, wantErr: errs.ErrValidateFailed},
+		{name: "not found refill", fuelRefillID: 4, wantErr: errs.ErrValidateFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fuelRefillID, fuelPrice, err := s.resolveFuelRefill(context.Background(), 1, tt.fuelRefillID, decimal.NewFromInt(tt.fuelPrice))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if fuelRefillID != tt.wantFuelRefillID || !fuelPrice.Equal(decimal.NewFromInt(tt.wantFuelPrice)) {
+				t.Errorf("got %d at %s, want %d at %d", fuelRefillID, fuelPrice, tt.wantFuelRefillID, tt.wantFuelPrice)
+			}
+		})
+	}
+}
+
+func TestService_UpdateFuelRefillByID_RecalculateFuelUsages(t *testing.T) {
+	const (
+		boss = 1
+		best = 2
+	)
+	newStub := func() *stubDatabaseAdaptor {
+		stub := &stubDatabaseAdaptor{
+			cars: []domains.Car{{ID: 1}},
+			fuelRefills: []domains.FuelRefill{
+				{ID: 1, CarID: 1, TotalMoney: decimal.NewFromInt(1000), KilometerBeforeRefill: 100, KilometerAfterRefill: 300, FuelPriceCalculated: decimal.NewFromInt(5), RefillBy: 9},
+			},
+			fuelUsages: []domains.FuelUsage{
+				{ID: 1, CarID: 1, FuelRefillID: 1, FuelPrice: decimal.NewFromInt(5), KilometerBeforeUse: 100, KilometerAfterUse: 80, TotalMoney: decimal.NewFromInt(100), SplitMode: domains.FuelUsageSplitModeEqual},
+				{ID: 2, CarID: 1, FuelRefillID: 1, FuelPrice: decimal.NewFromInt(5), KilometerBeforeUse: 80, KilometerAfterUse: 70, TotalMoney: decimal.NewFromInt(50), SplitMode: domains.FuelUsageSplitModeEqual},
+				{ID: 3, CarID: 1, FuelRefillID: 1, FuelPrice: decimal.NewFromInt(5), KilometerBeforeUse: 70, KilometerAfterUse: 60, TotalMoney: decimal.NewFromInt(50), SplitMode: domains.FuelUsageSplitModeAmount},
+			},
+		}
+		fuelUsageUser := func(id, fuelUsageID, userID int64, isPaid bool, amount int64) FuelUsageUser {
+			paidAmount := decimal.Zero
+			if isPaid {
+				paidAmount = decimal.NewFromInt(amount)
+			}
+			return FuelUsageUser{FuelUsageUser: domains.FuelUsageUser{
+				ID:          id,
+				FuelUsageID: fuelUsageID,
+				UserID:      userID,
+				IsPaid:      isPaid,
+				SplitValue:  decimal.NewFromInt(amount),
+				FareWeight:  fullFare,
+				Amount:      decimal.NewFromInt(amount),
+				PaidAmount:  paidAmount,
+			}}
+		}
+		stub.fuelUsageUsers = []FuelUsageUser{
+			fuelUsageUser(1, 1, boss, false, 50),
+			fuelUsageUser(2, 1, best, false, 50),
+			fuelUsageUser(3, 2, boss, true, 25),
+			fuelUsageUser(4, 2, best, false, 25),
+			fuelUsageUser(5, 3, best, false, 50),
+		}
+		for _, fuelUsage := range stub.fuelUsages {
+			var fuelUsageUsers []domains.FuelUsageUser
+			for _, u := range stub.fuelUsageUsers {
+				if u.FuelUsageID == fuelUsage.ID {
+					fuelUsageUsers = append(fuelUsageUsers, u.FuelUsageUser)
+				}
+			}
+			stub.ledgerEntries = append(stub.ledgerEntries, FuelUsageLedgerEntries(fuelUsage, fuelUsageUsers, time.Now())...)
+		}
+		return stub
+	}
+	newReq := func(recalculateFuelUsages string) models.PutFuelRefillByIDRequest {
+		return models.PutFuelRefillByIDRequest{
+			FuelRefillID:          1,
+			CurrentCarID:          1,
+			RefillTime:            time.Now(),
+			KilometerBeforeRefill: 100,
+			KilometerAfterRefill:  300,
+			TotalMoney:            decimal.NewFromInt(1200),
+			RefillBy:              9,
+			RecalculateFuelUsages: recalculateFuelUsages,
+		}
+	}
+	recalculatedIDs := func(response *models.PutFuelRefillByIDResponse) (ids []int64) {
+		for _, r := range response.RecalculatedFuelUsages {
+			ids = append(ids, r.FuelUsageID)
+		}
+		return ids
+	}
+
+	t.Run("none", func(t *testing.T) {
+		stub := newStub()
+		s := New(nil, stub, nil, nil)
+		response, err := s.UpdateFuelRefillByID(contextWithUser(boss), newReq(""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(response.RecalculatedFuelUsages) != 0 || !stub.fuelUsages[0].FuelPrice.Equal(decimal.NewFromInt(5)) {
+			t.Errorf("fuel usages should keep their fuel price, got %+v", response)
+		}
+	})
+
+	t.Run("unpaid", func(t *testing.T) {
+		stub := newStub()
+		s := New(nil, stub, nil, nil)
+		response, err := s.UpdateFuelRefillByID(contextWithUser(boss), newReq(models.RecalculateFuelUsagesUnpaid))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := recalculatedIDs(response); len(ids) != 1 || ids[0] != 1 {
+			t.Errorf("recalculated = %v, want [1]", ids)
+		}
+		wantSkipped := []models.SkippedFuelUsage{
+			{FuelUsageID: 2, Reason: models.SkippedFuelUsageReasonPaid},
+			{FuelUsageID: 3, Reason: models.SkippedFuelUsageReasonAmountSplit},
+		}
+		if len(response.SkippedFuelUsages) != len(wantSkipped) {
+			t.Fatalf("skipped = %+v, want %+v", response.SkippedFuelUsages, wantSkipped)
+		}
+		for i := range wantSkipped {
+			if response.SkippedFuelUsages[i] != wantSkipped[i] {
+				t.Errorf("skipped = %+v, want %+v", response.SkippedFuelUsages, wantSkipped)
+			}
+		}
+		if !stub.fuelUsages[0].TotalMoney.Equal(decimal.NewFromInt(120)) || !stub.fuelUsages[0].FuelPrice.Equal(decimal.NewFromInt(6)) {
+			t.Errorf("fuel usage 1 = %s at %s, want 120 at 6", stub.fuelUsages[0].TotalMoney, stub.fuelUsages[0].FuelPrice)
+		}
+		if !stub.fuelUsageUsers[0].Amount.Equal(decimal.NewFromInt(60)) {
+			t.Errorf("amount = %s, want 60", stub.fuelUsageUsers[0].Amount)
+		}
+		if balance := ledgerBalances(stub.ledgerEntries)[boss]; !balance.Equal(decimal.NewFromInt(-60)) {
+			t.Errorf("balance = %s, want -60", balance)
+		}
+	})
+
+	t.Run("all", func(t *testing.T) {
+		stub := newStub()
+		s := New(nil, stub, nil, nil)
+		response, err := s.UpdateFuelRefillByID(contextWithUser(boss), newReq(models.RecalculateFuelUsagesAll))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := recalculatedIDs(response); len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+			t.Errorf("recalculated = %v, want [1 2]", ids)
+		}
+		// the 25 paid stays, so the user has 5 left to pay
+		paidFuelUser := response.RecalculatedFuelUsages[1].FuelUsers[0]
+		if paidFuelUser.IsPaid || !paidFuelUser.OldAmount.Equal(decimal.NewFromInt(25)) || !paidFuelUser.NewAmount.Equal(decimal.NewFromInt(30)) {
+			t.Errorf("paid fuel user = %+v, want partially paid 25 now 30", paidFuelUser)
+		}
+
+		// the paid fuel usage is locked, its new amounts are adjustments
+		if !response.RecalculatedFuelUsages[1].IsAdjusted || response.RecalculatedFuelUsages[0].IsAdjusted {
+			t.Errorf("recalculated = %+v, want only fuel usage 2 adjusted", response.RecalculatedFuelUsages)
+		}
+		if len(stub.adjustments) != 2 || stub.adjustments[0].ReferenceID != 2 || stub.adjustments[0].CreateBy != boss {
+			t.Errorf("adjustments = %+v, want both users of fuel usage 2", stub.adjustments)
+		}
+		if share := stub.fuelUsageUsers[2]; !share.Outstanding().Equal(decimal.NewFromInt(5)) {
+			t.Errorf("share = %+v, want 5 left to pay", share)
+		}
+		for _, ledgerEntry := range stub.ledgerEntries {
+			if ledgerEntry.ReferenceType == domains.LedgerReferenceTypeFuelUsage && ledgerEntry.ReferenceID == 2 && ledgerEntry.EntryType == domains.LedgerEntryTypeReversal {
+				t.Errorf("ledger entries of the paid fuel usage should not be reversed, got %+v", ledgerEntry)
+			}
+		}
+		// boss owes 60 on fuel usage 1 and 5 more on fuel usage 2, the
+		// refiller is credited the 10 more of fuel usage 2
+		balances := ledgerBalances(stub.ledgerEntries)
+		if !balances[boss].Equal(decimal.NewFromInt(-65)) || !balances[9].Equal(decimal.NewFromInt(1210)) {
+			t.Errorf("balances = %v, want boss -65 and the refiller 1210", balances)
+		}
+	})
+
+	t.Run("adjusted", func(t *testing.T) {
+		stub := newStub()
+		stub.adjustments = []domains.Adjustment{
+			{ID: 1, CarID: 1, ReferenceType: domains.AdjustmentReferenceTypeFuelUsage, ReferenceID: 2, UserID: best},
+		}
+		s := New(nil, stub, nil, nil)
+		response, err := s.UpdateFuelRefillByID(contextWithUser(boss), newReq(models.RecalculateFuelUsagesAll))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := recalculatedIDs(response); len(ids) != 1 || ids[0] != 1 {
+			t.Errorf("recalculated = %v, want [1]", ids)
+		}
+		if skipped := response.SkippedFuelUsages[0]; skipped.FuelUsageID != 2 || skipped.Reason != models.SkippedFuelUsageReasonAdjusted {
+			t.Errorf("skipped = %+v, want fuel usage 2 adjusted", skipped)
+		}
+	})
+}
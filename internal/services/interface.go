@@ -11,10 +11,17 @@ import (
 type DatabaseAdaptor interface {
 	Transaction(ctx context.Context, fn func(ctxTx context.Context) error) error
 	GetFuelUsageInPagination(ctx context.Context, params GetFuelUsageInPaginationParams) ([]domains.FuelUsage, int64, error)
-	GetUserFuelUsagesByPaidStatus(ctx context.Context, userID int64, isPaid bool, carID int64) ([]FuelUsageUserWithPayEach, error)
+	GetUserFuelUsagesByPaidStatus(ctx context.Context, userID int64, isPaid bool, carID int64) ([]FuelUsageUserWithFuelUsage, error)
 	GetFuelUsageUsersByFuelUsageIDs(ctx context.Context, fuelUsageIDs []int64) ([]FuelUsageUser, error)
 	GetAllUsers(context.Context) ([]domains.User, error)
+	GetUsersByDefaultCarID(ctx context.Context, carID int64) ([]domains.User, error)
+	CreateUser(ctx context.Context, user domains.User) (int64, error)
+	UpdateUser(ctx context.Context, user domains.User) error
+	CreateUserCredential(ctx context.Context, userCredential domains.UserCredential) error
 	GetAllCars(context.Context) ([]domains.Car, error)
+	GetCarByID(ctx context.Context, carID int64) (*domains.Car, error)
+	CreateCar(ctx context.Context, car domains.Car) (int64, error)
+	UpdateCar(ctx context.Context, car domains.Car) error
 	GetLatestFuelRefillByCarID(ctx context.Context, carID int64) (*domains.FuelRefill, error)
 	CreateFuelUsage(ctx context.Context, fuelUsage domains.FuelUsage) (int64, error)
 	CreateFuelUsageUsers(ctx context.Context, fuelUsageUsers []domains.FuelUsageUser) error
@@ -23,19 +30,61 @@ type DatabaseAdaptor interface {
 	GetLatestFuelUsageByCarID(ctx context.Context, carID int64) (*domains.FuelUsage, error)
 	UpdateFuelUsage(context.Context, domains.FuelUsage) error
 	UpdateUserFuelUsagePaymentStatus(ctx context.Context, userFuelUsage domains.FuelUsageUser) error
+	UpdateFuelUsageUserAmount(ctx context.Context, fuelUsageUserID int64, amount decimal.Decimal) error
+	GetAllFuelUsages(ctx context.Context) ([]domains.FuelUsage, error)
+	GetFuelUsagesByCarID(ctx context.Context, carID int64) ([]domains.FuelUsage, error)
+	GetFuelRefillsByCarID(ctx context.Context, carID int64) ([]domains.FuelRefill, error)
+	GetFuelUsagesByFuelRefillID(ctx context.Context, fuelRefillID int64) ([]domains.FuelUsage, error)
+	UnsetFuelUsagesFuelRefillID(ctx context.Context, fuelRefillID int64) error
 	GetUserFuelUsageByUserID(ctx context.Context, userID int64) ([]domains.FuelUsageUser, error)
-	IsUserOwnAllFuelUsageUser(ctx context.Context, userID int64, fuelUsageUserIds []int64) (bool, error)
+	IsUserOwnAllFuelUsageUser(ctx context.Context, userID int64, carID int64, fuelUsageUserIds []int64) (bool, error)
 	DeleteFuelUsageUsersByFuelUsageID(ctx context.Context, fuelUsageID int64) error
 	DeleteFuelUsageByID(ctx context.Context, id int64) error
 	GetFuelRefillPagination(ctx context.Context, params GetFuelRefillPaginationParams) ([]domains.FuelRefill, int, error)
-	CreateFuelRefill(context.Context, domains.FuelRefill) error
+	CreateFuelRefill(context.Context, domains.FuelRefill) (int64, error)
 	GetFuelRefillByID(ctx context.Context, fuelRefillID int64) (*domains.FuelRefill, error)
-	IsUserOwnAllFuelRefills(ctx context.Context, userID int64, fuelRefillIDs []int64) (bool, error)
+	IsUserOwnAllFuelRefills(ctx context.Context, userID int64, carID int64, fuelRefillIDs []int64) (bool, error)
 	GetUserUnpaidFuelRefills(ctx context.Context, userID int64, carID int64) ([]domains.FuelRefill, error)
 	UpdateFuelRefill(ctx context.Context, fr domains.FuelRefill) error
 	DeleteFuelRefillByID(ctx context.Context, fuelRefillID int64) error
 	PayFuelRefills(ctx context.Context, fuelRefillIDs []int64) error
 	PayFuelUsageUsers(ctx context.Context, fuelUsageUserIds []int64) error
+	GetUserByID(ctx context.Context, userID int64) (*domains.User, error)
+	GetUserCredentialByUsername(ctx context.Context, username string) (*domains.UserCredential, error)
+	CreateSettlement(ctx context.Context, settlement domains.Settlement) (int64, error)
+	CreateSettlementItems(ctx context.Context, settlementItems []domains.SettlementItem) error
+	GetFuelUsageUsersWithFuelUsageByIDs(ctx context.Context, fuelUsageUserIDs []int64) ([]FuelUsageUserWithFuelUsage, error)
+	GetFuelRefillsByIDs(ctx context.Context, fuelRefillIDs []int64) ([]domains.FuelRefill, error)
+	CreateLedgerEntries(ctx context.Context, ledgerEntries []domains.LedgerEntry) error
+	GetLedgerNetAmountsByReference(ctx context.Context, referenceType string, referenceID int64) ([]LedgerNetAmount, error)
+	GetUserLedgerBalances(ctx context.Context, userID int64) ([]UserCarLedgerBalance, error)
+	GetUnpaidFuelUsageUsers(ctx context.Context, carID int64) ([]FuelUsageUserWithFuelUsage, error)
+	GetUnpaidFuelRefills(ctx context.Context, carID int64) ([]domains.FuelRefill, error)
+	GetUserCarIDs(ctx context.Context, userID int64) ([]int64, error)
+	CreateDebtSettlement(ctx context.Context, debtSettlement domains.DebtSettlement) (int64, error)
+	CreateDebtSettlementTransfers(ctx context.Context, transfers []domains.DebtSettlementTransfer) error
+	CreatePayment(ctx context.Context, payment domains.Payment) (int64, error)
+	CreatePaymentAllocations(ctx context.Context, paymentAllocations []domains.PaymentAllocation) error
+	GetUserCarPayments(ctx context.Context, userID int64, carID int64) ([]domains.Payment, error)
+	GetPaymentAllocationsByPaymentIDs(ctx context.Context, paymentIDs []int64) ([]domains.PaymentAllocation, error)
+	AddFuelUsageUserPaidAmount(ctx context.Context, fuelUsageUserID int64, amount decimal.Decimal) (bool, error)
+	AddFuelRefillPaidAmount(ctx context.Context, fuelRefillID int64, amount decimal.Decimal) (bool, error)
+	AddFuelUsageUserClaimedAmount(ctx context.Context, fuelUsageUserID int64, amount decimal.Decimal) (bool, error)
+	AddFuelRefillClaimedAmount(ctx context.Context, fuelRefillID int64, amount decimal.Decimal) (bool, error)
+	GetPaymentByID(ctx context.Context, paymentID int64) (*domains.Payment, error)
+	ReviewPendingPayment(ctx context.Context, payment domains.Payment) (bool, error)
+	GetUserPendingPayments(ctx context.Context, userID int64) ([]domains.Payment, error)
+	CreateWalletTransactions(ctx context.Context, walletTransactions []domains.WalletTransaction) error
+	GetWalletNetAmountsByReference(ctx context.Context, referenceType string, referenceID int64) ([]WalletNetAmount, error)
+	GetCarWalletTransactions(ctx context.Context, carID int64) ([]domains.WalletTransaction, error)
+	CreateAdjustments(ctx context.Context, adjustments []domains.Adjustment) error
+	GetAdjustmentsByReference(ctx context.Context, referenceType string, referenceID int64) ([]domains.Adjustment, error)
+}
+
+// FileStorage stores uploaded files, Put replaces the file at key
+// and returns the URL to read it.
+type FileStorage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) (url string, err error)
 }
 
 type FuelUsageWithUser struct {
@@ -65,11 +114,38 @@ type GetFuelRefillPaginationParams struct {
 	PageSize  int
 }
 
-type FuelUsageUserWithPayEach struct {
+type FuelUsageUserWithFuelUsage struct {
 	domains.FuelUsageUser
-	PayEach     decimal.Decimal `gorm:"column:pay_each"`
-	FuelUseTime time.Time       `gorm:"column:fuel_use_time"`
-	Description string          `gorm:"column:description"`
+	FuelUseTime  time.Time `gorm:"column:fuel_use_time"`
+	Description  string    `gorm:"column:description"`
+	DriverUserID int64     `gorm:"column:driver_user_id"`
+	FareRule     string    `gorm:"column:fare_rule"`
+	CarID        int64     `gorm:"column:car_id"`
+	CarName      string    `gorm:"column:car_name"`
+	// CreditorUserID fronted the money of the share, the refiller of the
+	// refill the fuel price came from or else the owner of the car, 0 when
+	// there is neither
+	CreditorUserID int64 `gorm:"column:creditor_user_id"`
+	// IsPaidFromWallet shares are paid by the wallet of the car
+	IsPaidFromWallet bool `gorm:"column:is_paid_from_wallet"`
+}
+
+type LedgerNetAmount struct {
+	UserID    int64           `gorm:"column:user_id"`
+	CarID     int64           `gorm:"column:car_id"`
+	NetAmount decimal.Decimal `gorm:"column:net_amount"`
+}
+
+type WalletNetAmount struct {
+	UserID          int64           `gorm:"column:user_id"`
+	CarID           int64           `gorm:"column:car_id"`
+	TransactionType string          `gorm:"column:transaction_type"`
+	NetAmount       decimal.Decimal `gorm:"column:net_amount"`
+}
+
+type UserCarLedgerBalance struct {
 	CarID       int64           `gorm:"column:car_id"`
 	CarName     string          `gorm:"column:car_name"`
+	TotalDebit  decimal.Decimal `gorm:"column:total_debit"`
+	TotalCredit decimal.Decimal `gorm:"column:total_credit"`
 }
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"gorm.io/gorm"
+)
+
+const (
+	KilometerDiscontinuityWarn   = "warn"
+	KilometerDiscontinuityReject = "reject"
+)
+
+// GetCarKilometerDiscontinuities lists every fuel usage and fuel refill of
+// the car which does not begin where the record before it ended.
+func (s *Service) GetCarKilometerDiscontinuities(ctx context.Context, req models.GetCarKilometerDiscontinuitiesRequest) (*models.GetCarKilometerDiscontinuitiesResponse, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	if _, err := s.getCarByID(ctx, req.CarID); err != nil {
+		return nil, err
+	}
+
+	fuelUsages, err := s.db.GetFuelUsagesByCarID(ctx, req.CarID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	fuelRefills, err := s.db.GetFuelRefillsByCarID(ctx, req.CarID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	tolerance := s.kilometerTolerance()
+	records := toKilometerRecords(fuelUsages, fuelRefills)
+
+	discontinuities := []models.KilometerDiscontinuity{}
+	for i := 1; i < len(records); i++ {
+		previous, next := records[i-1], records[i]
+		code, kilometers := kilometerDiscontinuity(next.KilometerBefore, previous.KilometerAfter, tolerance)
+		if code == "" {
+			continue
+		}
+		discontinuities = append(discontinuities, models.KilometerDiscontinuity{
+			Code:       code,
+			Kilometers: kilometers,
+			Previous:   previous,
+			Next:       next,
+		})
+	}
+
+	return &models.GetCarKilometerDiscontinuitiesResponse{
+		KilometerTolerance: tolerance,
+		Data:               discontinuities,
+	}, nil
+}
+
+// checkKilometerContinuity compares where a new fuel usage begins with where
+// the latest record of the car ended. Discontinuities are returned as
+// warnings, or as ErrKilometerDiscontinuity when they are rejected.
+func (s *Service) checkKilometerContinuity(ctx context.Context, carID int64, kmBeforeUse int64) ([]models.FuelUsageWarning, error) {
+	latestFuelUsage, latestFuelRefill, err := s.getLatestFuelRecords(ctx, carID)
+	if err != nil {
+		return nil, err
+	}
+
+	latestKmAfterUse, found := latestKilometerAfterUse(latestFuelUsage, latestFuelRefill)
+	if !found {
+		return nil, nil
+	}
+
+	warnings := kilometerWarnings(kmBeforeUse, latestKmAfterUse, s.kilometerTolerance())
+	if len(warnings) > 0 && s.isKilometerDiscontinuityRejected() {
+		slog.WarnContext(ctx, "kilometer discontinuity",
+			"carId", carID,
+			"kilometerBeforeUse", kmBeforeUse,
+			"latestKilometerAfterUse", latestKmAfterUse,
+		)
+		return nil, errs.ErrKilometerDiscontinuity.WithData(warnings)
+	}
+
+	return warnings, nil
+}
+
+// getLatestFuelRecords returns nil for a car without fuel usage or without
+// fuel refill yet.
+func (s *Service) getLatestFuelRecords(ctx context.Context, carID int64) (*domains.FuelUsage, *domains.FuelRefill, error) {
+	latestFuelUsage, err := s.db.GetLatestFuelUsageByCarID(ctx, carID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, nil, err
+	}
+
+	latestFuelRefill, err := s.db.GetLatestFuelRefillByCarID(ctx, carID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, nil, err
+	}
+
+	return latestFuelUsage, latestFuelRefill, nil
+}
+
+func (s *Service) kilometerTolerance() int64 {
+	if s.cfg == nil {
+		return 0
+	}
+	return s.cfg.FuelUsage.KilometerTolerance
+}
+
+func (s *Service) isKilometerDiscontinuityRejected() bool {
+	return s.cfg != nil && s.cfg.FuelUsage.KilometerDiscontinuity == KilometerDiscontinuityReject
+}
+
+// latestKilometerAfterUse is where the next fuel usage should begin, from
+// the latest of the fuel usage and the fuel refill, either may be nil.
+func latestKilometerAfterUse(latestFuelUsage *domains.FuelUsage, latestFuelRefill *domains.FuelRefill) (int64, bool) {
+	switch {
+	case latestFuelUsage == nil && latestFuelRefill == nil:
+		return 0, false
+	case latestFuelUsage == nil:
+		return latestFuelRefill.KilometerAfterRefill, true
+	case latestFuelRefill == nil:
+		return latestFuelUsage.KilometerAfterUse, true
+	case latestFuelRefill.RefillTime.After(latestFuelUsage.FuelUseTime):
+		return latestFuelRefill.KilometerAfterRefill, true
+	default:
+		return latestFuelUsage.KilometerAfterUse, true
+	}
+}
+
+func kilometerWarnings(kmBeforeUse, latestKmAfterUse, tolerance int64) []models.FuelUsageWarning {
+	code, kilometers := kilometerDiscontinuity(kmBeforeUse, latestKmAfterUse, tolerance)
+	switch code {
+	case models.FuelUsageWarningKilometerGap:
+		return []models.FuelUsageWarning{{
+			Code: code,
+			Message: fmt.Sprintf(
+				"%d km are not recorded, kilometerBeforeUse: [%d], latestKilometerAfterUse: [%d]",
+				kilometers,
+				kmBeforeUse,
+				latestKmAfterUse,
+			),
+		}}
+	case models.FuelUsageWarningKilometerOverlap:
+		return []models.FuelUsageWarning{{
+			Code: code,
+			Message: fmt.Sprintf(
+				"%d km are recorded twice, kilometerBeforeUse: [%d], latestKilometerAfterUse: [%d]",
+				kilometers,
+				kmBeforeUse,
+				latestKmAfterUse,
+			),
+		}}
+	default:
+		return nil
+	}
+}
+
+// kilometerDiscontinuity returns an empty code when kmBefore is within the
+// tolerance of where the record before ended. The kilometers decrease as
+// the car is driven, so beginning below is a gap and above is an overlap.
+func kilometerDiscontinuity(kmBefore, previousKmAfter, tolerance int64) (string, int64) {
+	switch diff := previousKmAfter - kmBefore; {
+	case diff > tolerance:
+		return models.FuelUsageWarningKilometerGap, diff
+	case -diff > tolerance:
+		return models.FuelUsageWarningKilometerOverlap, -diff
+	default:
+		return "", 0
+	}
+}
+
+// toKilometerRecords orders the records by time, a refill at the same time
+// as a usage comes first as in latestKilometerAfterUse.
+func toKilometerRecords(fuelUsages []domains.FuelUsage, fuelRefills []domains.FuelRefill) []models.KilometerRecord {
+	var records []models.KilometerRecord
+	for _, fuelRefill := range fuelRefills {
+		records = append(records, models.KilometerRecord{
+			Type:            models.KilometerRecordTypeFuelRefill,
+			ID:              fuelRefill.ID,
+			Time:            fuelRefill.RefillTime,
+			KilometerBefore: fuelRefill.KilometerBeforeRefill,
+			KilometerAfter:  fuelRefill.KilometerAfterRefill,
+		})
+	}
+	for _, fuelUsage := range fuelUsages {
+		records = append(records, models.KilometerRecord{
+			Type:            models.KilometerRecordTypeFuelUsage,
+			ID:              fuelUsage.ID,
+			Time:            fuelUsage.FuelUseTime,
+			KilometerBefore: fuelUsage.KilometerBeforeUse,
+			KilometerAfter:  fuelUsage.KilometerAfterUse,
+		})
+	}
+	slices.SortStableFunc(records, func(a, b models.KilometerRecord) int {
+		return a.Time.Compare(b.Time)
+	})
+	return records
+}
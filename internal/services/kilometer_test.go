@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/config"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+)
+
+func (stub *stubDatabaseAdaptor) GetFuelUsagesByCarID(ctx context.Context, carID int64) ([]domains.FuelUsage, error) {
+	var fuelUsages []domains.FuelUsage
+	for _, fuelUsage := range stub.fuelUsages {
+		if fuelUsage.CarID == carID {
+			fuelUsages = append(fuelUsages, fuelUsage)
+		}
+	}
+	return fuelUsages, nil
+}
+
+func (stub *stubDatabaseAdaptor) GetFuelRefillsByCarID(ctx context.Context, carID int64) ([]domains.FuelRefill, error) {
+	var fuelRefills []domains.FuelRefill
+	for _, fuelRefill := range stub.fuelRefills {
+		if fuelRefill.CarID == carID {
+			fuelRefills = append(fuelRefills, fuelRefill)
+		}
+	}
+	return fuelRefills, nil
+}
+
+func newKilometerConfig(tolerance int64, discontinuity string) *config.Config {
+	var cfg config.Config
+	cfg.FuelUsage.KilometerTolerance = tolerance
+	cfg.FuelUsage.KilometerDiscontinuity = discontinuity
+	return &cfg
+}
+
+func Test_kilometerDiscontinuity(t *testing.T) {
+	tests := []struct {
+		name            string
+		kmBefore        int64
+		previousKmAfter int64
+		tolerance       int64
+		wantCode        string
+		wantKilometers  int64
+	}{
+		{name: "continuous", kmBefore: 500, previousKmAfter: 500},
+		{name: "gap", kmBefore: 480, previousKmAfter: 500, wantCode: models.FuelUsageWarningKilometerGap, wantKilometers: 20},
+		{name: "overlap", kmBefore: 520, previousKmAfter: 500, wantCode: models.FuelUsageWarningKilometerOverlap, wantKilometers: 20},
+		{name: "gap within tolerance", kmBefore: 495, previousKmAfter: 500, tolerance: 5},
+		{name: "overlap within tolerance", kmBefore: 505, previousKmAfter: 500, tolerance: 5},
+		{name: "gap beyond tolerance", kmBefore: 494, previousKmAfter: 500, tolerance: 5, wantCode: models.FuelUsageWarningKilometerGap, wantKilometers: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, kilometers := kilometerDiscontinuity(tt.kmBefore, tt.previousKmAfter, tt.tolerance)
+			if code != tt.wantCode || kilometers != tt.wantKilometers {
+				t.Errorf("got %q %d km, want %q %d km", code, kilometers, tt.wantCode, tt.wantKilometers)
+			}
+		})
+	}
+}
+
+func TestService_checkKilometerContinuity(t *testing.T) {
+	stub := &stubDatabaseAdaptor{
+		fuelUsages: []domains.FuelUsage{
+			{ID: 1, CarID: 1, FuelUseTime: time.Now(), KilometerBeforeUse: 600, KilometerAfterUse: 500},
+		},
+	}
+
+	t.Run("warn", func(t *testing.T) {
+		s := New(newKilometerConfig(0, KilometerDiscontinuityWarn), stub, nil, nil)
+		warnings, err := s.checkKilometerContinuity(context.Background(), 1, 480)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(warnings) != 1 || warnings[0].Code != models.FuelUsageWarningKilometerGap {
+			t.Errorf("warnings = %+v, want a gap", warnings)
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		s := New(newKilometerConfig(0, KilometerDiscontinuityReject), stub, nil, nil)
+		_, err := s.checkKilometerContinuity(context.Background(), 1, 480)
+		var errResponse errs.Err
+		if !errors.As(err, &errResponse) || errResponse.Code != errs.CodeKilometerDiscontinuity {
+			t.Fatalf("err = %v, want %v", err, errs.ErrKilometerDiscontinuity)
+		}
+		if warnings, ok := errResponse.Data.([]models.FuelUsageWarning); !ok || len(warnings) != 1 {
+			t.Errorf("data = %+v, want the warnings", errResponse.Data)
+		}
+	})
+
+	t.Run("reject within tolerance", func(t *testing.T) {
+		s := New(newKilometerConfig(20, KilometerDiscontinuityReject), stub, nil, nil)
+		warnings, err := s.checkKilometerContinuity(context.Background(), 1, 480)
+		if err != nil || len(warnings) != 0 {
+			t.Errorf("got %+v, %v, want no warning", warnings, err)
+		}
+	})
+
+	t.Run("car without records", func(t *testing.T) {
+		s := New(newKilometerConfig(0, KilometerDiscontinuityReject), stub, nil, nil)
+		warnings, err := s.checkKilometerContinuity(context.Background(), 2, 480)
+		if err != nil || len(warnings) != 0 {
+			t.Errorf("got %+v, %v, want no warning", warnings, err)
+		}
+	})
+}
+
+func TestService_GetCarKilometerDiscontinuities(t *testing.T) {
+	day := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	stub := &stubDatabaseAdaptor{
+		cars: []domains.Car{{ID: 1}},
+		fuelUsages: []domains.FuelUsage{
+			{ID: 1, CarID: 1, FuelUseTime: day.Add(1 * time.Hour), KilometerBeforeUse: 600, KilometerAfterUse: 500},
+			// gap of 20 km after usage 1
+			{ID: 2, CarID: 1, FuelUseTime: day.Add(2 * time.Hour), KilometerBeforeUse: 480, KilometerAfterUse: 400},
+			// continues from the refill at the same time
+			{ID: 3, CarID: 1, FuelUseTime: day.Add(3 * time.Hour), KilometerBeforeUse: 700, KilometerAfterUse: 650},
+			// overlap of 10 km after usage 3
+			{ID: 4, CarID: 1, FuelUseTime: day.Add(4 * time.Hour), KilometerBeforeUse: 660, KilometerAfterUse: 600},
+			{ID: 5, CarID: 2, FuelUseTime: day, KilometerBeforeUse: 100, KilometerAfterUse: 50},
+		},
+		fuelRefills: []domains.FuelRefill{
+			{ID: 1, CarID: 1, RefillTime: day, KilometerBeforeRefill: 300, KilometerAfterRefill: 600},
+			{ID: 2, CarID: 1, RefillTime: day.Add(3 * time.Hour), KilometerBeforeRefill: 402, KilometerAfterRefill: 700},
+		},
+	}
+	s := New(newKilometerConfig(2, KilometerDiscontinuityWarn), stub, nil, nil)
+
+	response, err := s.GetCarKilometerDiscontinuities(context.Background(), models.GetCarKilometerDiscontinuitiesRequest{CarID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type discontinuity struct {
+		code             string
+		kilometers       int64
+		previousID       int64
+		nextID           int64
+		previousIsRefill bool
+	}
+	want := []discontinuity{
+		{code: models.FuelUsageWarningKilometerGap, kilometers: 20, previousID: 1, nextID: 2},
+		{code: models.FuelUsageWarningKilometerOverlap, kilometers: 10, previousID: 3, nextID: 4},
+	}
+	if len(response.Data) != len(want) {
+		t.Fatalf("got %+v, want %+v", response.Data, want)
+	}
+	for i, d := range response.Data {
+		got := discontinuity{
+			code:             d.Code,
+			kilometers:       d.Kilometers,
+			previousID:       d.Previous.ID,
+			nextID:           d.Next.ID,
+			previousIsRefill: d.Previous.Type == models.KilometerRecordTypeFuelRefill,
+		}
+		if got != want[i] {
+			t.Errorf("data[%d] = %+v, want %+v", i, got, want[i])
+		}
+	}
+
+	_, err = s.GetCarKilometerDiscontinuities(context.Background(), models.GetCarKilometerDiscontinuitiesRequest{CarID: 3})
+	if !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("err = %v, want %v", err, errs.ErrNotFound)
+	}
+}
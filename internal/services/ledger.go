@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/shopspring/decimal"
+)
+
+// FuelUsageLedgerEntries debits the share of every fuel user and credits
+// back what the user already paid on it.
+func FuelUsageLedgerEntries(fuelUsage domains.FuelUsage, fuelUsageUsers []domains.FuelUsageUser, now time.Time) []domains.LedgerEntry {
+	var ledgerEntries []domains.LedgerEntry
+	for _, fuelUsageUser := range fuelUsageUsers {
+		ledgerEntries = append(ledgerEntries, domains.LedgerEntry{
+			UserID:        fuelUsageUser.UserID,
+			CarID:         fuelUsage.CarID,
+			EntryType:     domains.LedgerEntryTypeFuelUsageShare,
+			ReferenceType: domains.LedgerReferenceTypeFuelUsage,
+			ReferenceID:   fuelUsage.ID,
+			Debit:         fuelUsageUser.Amount,
+			Credit:        decimal.Zero,
+			CreateTime:    now,
+		})
+		if fuelUsageUser.PaidAmount.IsPositive() {
+			ledgerEntries = append(ledgerEntries, domains.LedgerEntry{
+				UserID:        fuelUsageUser.UserID,
+				CarID:         fuelUsage.CarID,
+				EntryType:     domains.LedgerEntryTypeFuelUsageSharePayment,
+				ReferenceType: domains.LedgerReferenceTypeFuelUsage,
+				ReferenceID:   fuelUsage.ID,
+				Debit:         decimal.Zero,
+				Credit:        fuelUsageUser.PaidAmount,
+				CreateTime:    now,
+			})
+		}
+	}
+	return ledgerEntries
+}
+
+// FuelRefillLedgerEntries credits the refiller with the money fronted and
+// debits back what is already reimbursed.
+func FuelRefillLedgerEntries(fuelRefill domains.FuelRefill, now time.Time) []domains.LedgerEntry {
+	ledgerEntries := []domains.LedgerEntry{
+		{
+			UserID:        fuelRefill.RefillBy,
+			CarID:         fuelRefill.CarID,
+			EntryType:     domains.LedgerEntryTypeFuelRefill,
+			ReferenceType: domains.LedgerReferenceTypeFuelRefill,
+			ReferenceID:   fuelRefill.ID,
+			Debit:         decimal.Zero,
+			Credit:        fuelRefill.TotalMoney,
+			CreateTime:    now,
+		},
+	}
+	if fuelRefill.PaidAmount.IsPositive() {
+		ledgerEntries = append(ledgerEntries, domains.LedgerEntry{
+			UserID:        fuelRefill.RefillBy,
+			CarID:         fuelRefill.CarID,
+			EntryType:     domains.LedgerEntryTypeFuelRefillReimbursement,
+			ReferenceType: domains.LedgerReferenceTypeFuelRefill,
+			ReferenceID:   fuelRefill.ID,
+			Debit:         fuelRefill.PaidAmount,
+			Credit:        decimal.Zero,
+			CreateTime:    now,
+		})
+	}
+	return ledgerEntries
+}
+
+func (s *Service) createLedgerEntries(ctx context.Context, ledgerEntries []domains.LedgerEntry) error {
+	if len(ledgerEntries) == 0 {
+		return nil
+	}
+	return s.db.CreateLedgerEntries(ctx, ledgerEntries)
+}
+
+// reverseLedgerEntries brings the balance every user holds on a reference
+// back to zero, the entries themselves are never updated or deleted.
+func (s *Service) reverseLedgerEntries(ctx context.Context, referenceType string, referenceID int64, now time.Time) error {
+	netAmounts, err := s.db.GetLedgerNetAmountsByReference(ctx, referenceType, referenceID)
+	if err != nil {
+		return err
+	}
+
+	var ledgerEntries []domains.LedgerEntry
+	for _, netAmount := range netAmounts {
+		if netAmount.NetAmount.IsZero() {
+			continue
+		}
+		ledgerEntry := domains.LedgerEntry{
+			UserID:        netAmount.UserID,
+			CarID:         netAmount.CarID,
+			EntryType:     domains.LedgerEntryTypeReversal,
+			ReferenceType: referenceType,
+			ReferenceID:   referenceID,
+			Debit:         decimal.Zero,
+			Credit:        decimal.Zero,
+			CreateTime:    now,
+		}
+		if netAmount.NetAmount.IsPositive() {
+			ledgerEntry.Debit = netAmount.NetAmount
+		} else {
+			ledgerEntry.Credit = netAmount.NetAmount.Neg()
+		}
+		ledgerEntries = append(ledgerEntries, ledgerEntry)
+	}
+
+	return s.createLedgerEntries(ctx, ledgerEntries)
+}
+
+// replaceFuelUsageLedgerEntries reverses the entries of the fuel usage and
+// posts them again from the current amounts of its users.
+func (s *Service) replaceFuelUsageLedgerEntries(ctx context.Context, fuelUsage domains.FuelUsage, fuelUsageUsers []domains.FuelUsageUser, now time.Time) error {
+	err := s.reverseLedgerEntries(ctx, domains.LedgerReferenceTypeFuelUsage, fuelUsage.ID, now)
+	if err != nil {
+		return err
+	}
+	return s.createLedgerEntries(ctx, FuelUsageLedgerEntries(fuelUsage, fuelUsageUsers, now))
+}
+
+// postFuelUsageUserPaymentStatus credits what is left to pay on the shares
+// that become paid and debits what was paid on the shares that become
+// unpaid, it should be called before the status is saved.
+func (s *Service) postFuelUsageUserPaymentStatus(ctx context.Context, fuelUsageUserIDs []int64, isPaid bool, now time.Time) error {
+	if len(fuelUsageUserIDs) == 0 {
+		return nil
+	}
+
+	fuelUsageUsers, err := s.db.GetFuelUsageUsersWithFuelUsageByIDs(ctx, fuelUsageUserIDs)
+	if err != nil {
+		return err
+	}
+
+	var ledgerEntries []domains.LedgerEntry
+	for _, fuelUsageUser := range fuelUsageUsers {
+		if fuelUsageUser.IsPaid == isPaid {
+			continue
+		}
+		ledgerEntry := domains.LedgerEntry{
+			UserID:        fuelUsageUser.UserID,
+			CarID:         fuelUsageUser.CarID,
+			EntryType:     domains.LedgerEntryTypeFuelUsageSharePayment,
+			ReferenceType: domains.LedgerReferenceTypeFuelUsage,
+			ReferenceID:   fuelUsageUser.FuelUsageID,
+			Debit:         decimal.Zero,
+			Credit:        fuelUsageUser.Amount.Sub(fuelUsageUser.PaidAmount),
+			CreateTime:    now,
+		}
+		if !isPaid {
+			ledgerEntry.EntryType = domains.LedgerEntryTypeReversal
+			ledgerEntry.Debit = fuelUsageUser.PaidAmount
+			ledgerEntry.Credit = decimal.Zero
+		}
+		if ledgerEntry.Debit.IsZero() && ledgerEntry.Credit.IsZero() {
+			continue
+		}
+		ledgerEntries = append(ledgerEntries, ledgerEntry)
+	}
+
+	return s.createLedgerEntries(ctx, ledgerEntries)
+}
+
+func (s *Service) GetUserBalance(ctx context.Context, req models.GetUserBalanceRequest) (*models.GetUserBalanceResponse, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	balances, err := s.db.GetUserLedgerBalances(ctx, req.UserID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	response := models.GetUserBalanceResponse{
+		UserID:      req.UserID,
+		TotalDebit:  decimal.Zero,
+		TotalCredit: decimal.Zero,
+		Balance:     decimal.Zero,
+		Cars:        []models.CarBalance{},
+	}
+
+	for _, b := range balances {
+		response.TotalDebit = response.TotalDebit.Add(b.TotalDebit)
+		response.TotalCredit = response.TotalCredit.Add(b.TotalCredit)
+		response.Cars = append(response.Cars, models.CarBalance{
+			Car: models.CarInfo{
+				ID:   b.CarID,
+				Name: b.CarName,
+			},
+			TotalDebit:  b.TotalDebit,
+			TotalCredit: b.TotalCredit,
+			Balance:     b.TotalCredit.Sub(b.TotalDebit),
+		})
+	}
+	response.Balance = response.TotalCredit.Sub(response.TotalDebit)
+
+	return &response, nil
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/shopspring/decimal"
+)
+
+func ledgerBalances(ledgerEntries []domains.LedgerEntry) map[int64]decimal.Decimal {
+	userIDToBalance := make(map[int64]decimal.Decimal)
+	for _, e := range ledgerEntries {
+		userIDToBalance[e.UserID] = userIDToBalance[e.UserID].Add(e.Credit).Sub(e.Debit)
+	}
+	return userIDToBalance
+}
+
+func TestFuelUsageLedgerEntries(t *testing.T) {
+	fuelUsage := domains.FuelUsage{ID: 1, CarID: 2}
+	fuelUsageUsers := []domains.FuelUsageUser{
+		{FuelUsageID: 1, UserID: 1, IsPaid: false, Amount: decimal.NewFromInt(50)},
+		{FuelUsageID: 1, UserID: 2, IsPaid: true, Amount: decimal.NewFromInt(30), PaidAmount: decimal.NewFromInt(30)},
+		{FuelUsageID: 1, UserID: 3, IsPaid: false, Amount: decimal.NewFromInt(40), PaidAmount: decimal.NewFromInt(15)},
+	}
+
+	ledgerEntries := FuelUsageLedgerEntries(fuelUsage, fuelUsageUsers, time.Now())
+	if len(ledgerEntries) != 5 {
+		t.Fatalf("got %d entries, want 5", len(ledgerEntries))
+	}
+	for _, e := range ledgerEntries {
+		if e.CarID != 2 || e.ReferenceType != domains.LedgerReferenceTypeFuelUsage || e.ReferenceID != 1 {
+			t.Errorf("unexpected entry reference %+v", e)
+		}
+	}
+
+	balances := ledgerBalances(ledgerEntries)
+	if !balances[1].Equal(decimal.NewFromInt(-50)) {
+		t.Errorf("unpaid user balance = %s, want -50", balances[1])
+	}
+	if !balances[2].IsZero() {
+		t.Errorf("paid user balance = %s, want 0", balances[2])
+	}
+	if !balances[3].Equal(decimal.NewFromInt(-25)) {
+		t.Errorf("partially paid user balance = %s, want -25", balances[3])
+	}
+}
+
+func TestFuelRefillLedgerEntries(t *testing.T) {
+	tests := []struct {
+		name        string
+		isPaid      bool
+		paidAmount  decimal.Decimal
+		wantBalance decimal.Decimal
+	}{
+		{
+			name:        "unpaid refill is owed to the refiller",
+			isPaid:      false,
+			paidAmount:  decimal.Zero,
+			wantBalance: decimal.NewFromInt(700),
+		},
+		{
+			name:        "partially paid refill is owed the rest",
+			isPaid:      false,
+			paidAmount:  decimal.NewFromInt(200),
+			wantBalance: decimal.NewFromInt(500),
+		},
+		{
+			name:        "paid refill is already reimbursed",
+			isPaid:      true,
+			paidAmount:  decimal.NewFromInt(700),
+			wantBalance: decimal.Zero,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fuelRefill := domains.FuelRefill{
+				ID:         1,
+				CarID:      1,
+				RefillBy:   3,
+				TotalMoney: decimal.NewFromInt(700),
+				IsPaid:     tt.isPaid,
+				PaidAmount: tt.paidAmount,
+			}
+			balances := ledgerBalances(FuelRefillLedgerEntries(fuelRefill, time.Now()))
+			if !balances[3].Equal(tt.wantBalance) {
+				t.Errorf("balance = %s, want %s", balances[3], tt.wantBalance)
+			}
+		})
+	}
+}
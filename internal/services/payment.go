@@ -0,0 +1,866 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// paymentItem is a fuel usage share of the payer or a refill fronted by the
+// payee, which a payment can pay.
+type paymentItem struct {
+	ItemType    string
+	ItemID      int64
+	Time        time.Time
+	Outstanding decimal.Decimal
+	// UserID and the reference are where the ledger entry of the item goes
+	UserID        int64
+	CarID         int64
+	ReferenceType string
+	ReferenceID   int64
+	// CreditorUserID should confirm a payment of the item, 0 when nobody can
+	CreditorUserID int64
+}
+
+func toFuelUsageUserPaymentItem(fu FuelUsageUserWithFuelUsage) paymentItem {
+	return paymentItem{
+		ItemType:       domains.PaymentItemTypeFuelUsageUser,
+		ItemID:         fu.ID,
+		Time:           fu.FuelUseTime,
+		Outstanding:    fu.Outstanding(),
+		UserID:         fu.UserID,
+		CarID:          fu.CarID,
+		ReferenceType:  domains.LedgerReferenceTypeFuelUsage,
+		ReferenceID:    fu.FuelUsageID,
+		CreditorUserID: fu.CreditorUserID,
+	}
+}
+
+func toFuelRefillPaymentItem(fr domains.FuelRefill) paymentItem {
+	return paymentItem{
+		ItemType:       domains.PaymentItemTypeFuelRefill,
+		ItemID:         fr.ID,
+		Time:           fr.RefillTime,
+		Outstanding:    fr.Outstanding(),
+		UserID:         fr.RefillBy,
+		CarID:          fr.CarID,
+		ReferenceType:  domains.LedgerReferenceTypeFuelRefill,
+		ReferenceID:    fr.ID,
+		CreditorUserID: fr.RefillBy,
+	}
+}
+
+func fuelUsageUserPaymentItems(fuelUsageUsers []FuelUsageUserWithFuelUsage) []paymentItem {
+	var items []paymentItem
+	for _, fu := range fuelUsageUsers {
+		if !fu.Outstanding().IsPositive() {
+			continue
+		}
+		items = append(items, toFuelUsageUserPaymentItem(fu))
+	}
+	return items
+}
+
+func fuelRefillPaymentItems(fuelRefills []domains.FuelRefill) []paymentItem {
+	var items []paymentItem
+	for _, fr := range fuelRefills {
+		if !fr.Outstanding().IsPositive() {
+			continue
+		}
+		items = append(items, toFuelRefillPaymentItem(fr))
+	}
+	return items
+}
+
+// allocatePayment pays the items in the given order, each one in full before
+// the next, so only the last paid item can be paid in part. The amount
+// should not be more than the items owe.
+func allocatePayment(amount decimal.Decimal, items []paymentItem) ([]domains.PaymentAllocation, error) {
+	left := amount
+	allocations := []domains.PaymentAllocation{}
+	for _, item := range items {
+		if !left.IsPositive() {
+			break
+		}
+		allocated := decimal.Min(left, item.Outstanding)
+		allocations = append(allocations, domains.PaymentAllocation{
+			ItemType: item.ItemType,
+			ItemID:   item.ItemID,
+			Amount:   allocated,
+		})
+		left = left.Sub(allocated)
+	}
+	if left.IsPositive() {
+		return nil, fmt.Errorf("amount should <= the outstanding amount, amount: [%s], left over: [%s]",
+			amount,
+			left,
+		)
+	}
+	return allocations, nil
+}
+
+// sortOldestFirst orders the items by the time they happen.
+func sortOldestFirst(items []paymentItem) {
+	slices.SortStableFunc(items, func(a, b paymentItem) int {
+		return a.Time.Compare(b.Time)
+	})
+}
+
+// paymentLedgerEntries credits the payer for the shares and debits the
+// refiller for the refills the payment pays.
+func paymentLedgerEntries(allocations []domains.PaymentAllocation, items []paymentItem, now time.Time) []domains.LedgerEntry {
+	type itemKey struct {
+		itemType string
+		itemID   int64
+	}
+	keyToItem := make(map[itemKey]paymentItem)
+	for _, item := range items {
+		keyToItem[itemKey{item.ItemType, item.ItemID}] = item
+	}
+
+	var ledgerEntries []domains.LedgerEntry
+	for _, allocation := range allocations {
+		item := keyToItem[itemKey{allocation.ItemType, allocation.ItemID}]
+		ledgerEntry := domains.LedgerEntry{
+			UserID:        item.UserID,
+			CarID:         item.CarID,
+			EntryType:     domains.LedgerEntryTypeFuelUsageSharePayment,
+			ReferenceType: item.ReferenceType,
+			ReferenceID:   item.ReferenceID,
+			Debit:         decimal.Zero,
+			Credit:        allocation.Amount,
+			CreateTime:    now,
+		}
+		if allocation.ItemType == domains.PaymentItemTypeFuelRefill {
+			ledgerEntry.EntryType = domains.LedgerEntryTypeFuelRefillReimbursement
+			ledgerEntry.Debit = allocation.Amount
+			ledgerEntry.Credit = decimal.Zero
+		}
+		ledgerEntries = append(ledgerEntries, ledgerEntry)
+	}
+	return ledgerEntries
+}
+
+// confirmStatusOf confirms at once a payment made by the user who should
+// confirm it. A payment nobody can confirm, like one for a car without an
+// owner, is not accepted.
+func confirmStatusOf(createBy, confirmUserID int64) (string, error) {
+	if confirmUserID == 0 {
+		return "", fmt.Errorf("there is no creditor or owner to confirm the payment")
+	}
+	if confirmUserID == createBy {
+		return domains.PaymentConfirmStatusConfirmed, nil
+	}
+	return domains.PaymentConfirmStatusPending, nil
+}
+
+// createPayment saves the payment with its allocations. A confirmed payment
+// pays the items at once, a pending one only claims them until it is
+// confirmed.
+func (s *Service) createPayment(
+	ctx context.Context,
+	payment domains.Payment,
+	allocations []domains.PaymentAllocation,
+	items []paymentItem,
+	now time.Time,
+) (*domains.Payment, error) {
+	if payment.Status == domains.PaymentConfirmStatusConfirmed {
+		payment.ReviewBy = payment.CreateBy
+		payment.ReviewTime = &now
+	}
+
+	paymentID, err := s.db.CreatePayment(ctx, payment)
+	if err != nil {
+		return nil, err
+	}
+	payment.ID = paymentID
+
+	for i := range allocations {
+		allocations[i].PaymentID = paymentID
+	}
+
+	if err := s.db.CreatePaymentAllocations(ctx, allocations); err != nil {
+		return nil, err
+	}
+
+	if payment.Status == domains.PaymentConfirmStatusPending {
+		if err := s.claimPaymentAllocations(ctx, allocations, true); err != nil {
+			return nil, err
+		}
+		return &payment, nil
+	}
+
+	if err := s.payPaymentAllocations(ctx, allocations, items, now); err != nil {
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+// claimPaymentAllocations holds the allocated amounts on the items while the
+// payment is pending, or releases them once it is confirmed or rejected.
+func (s *Service) claimPaymentAllocations(ctx context.Context, allocations []domains.PaymentAllocation, isClaimed bool) error {
+	for _, allocation := range allocations {
+		amount := allocation.Amount
+		if !isClaimed {
+			amount = amount.Neg()
+		}
+		var isAdded bool
+		var err error
+		switch allocation.ItemType {
+		case domains.PaymentItemTypeFuelUsageUser:
+			isAdded, err = s.db.AddFuelUsageUserClaimedAmount(ctx, allocation.ItemID, amount)
+		case domains.PaymentItemTypeFuelRefill:
+			isAdded, err = s.db.AddFuelRefillClaimedAmount(ctx, allocation.ItemID, amount)
+		}
+		if err != nil {
+			return err
+		}
+		if !isAdded {
+			slog.WarnContext(ctx, "the item would be claimed more than it owes",
+				"itemType", allocation.ItemType,
+				"itemId", allocation.ItemID,
+			)
+			return errs.ErrConflict
+		}
+	}
+	return nil
+}
+
+// payPaymentAllocations adds the allocated amounts to the items and posts
+// them to the ledger.
+func (s *Service) payPaymentAllocations(
+	ctx context.Context,
+	allocations []domains.PaymentAllocation,
+	items []paymentItem,
+	now time.Time,
+) error {
+	for _, allocation := range allocations {
+		var isAdded bool
+		var err error
+		switch allocation.ItemType {
+		case domains.PaymentItemTypeFuelUsageUser:
+			isAdded, err = s.db.AddFuelUsageUserPaidAmount(ctx, allocation.ItemID, allocation.Amount)
+		case domains.PaymentItemTypeFuelRefill:
+			isAdded, err = s.db.AddFuelRefillPaidAmount(ctx, allocation.ItemID, allocation.Amount)
+		}
+		if err != nil {
+			return err
+		}
+		if !isAdded {
+			slog.WarnContext(ctx, "the item would be paid more than it owes",
+				"itemType", allocation.ItemType,
+				"itemId", allocation.ItemID,
+			)
+			return errs.ErrConflict
+		}
+	}
+	return s.createLedgerEntries(ctx, paymentLedgerEntries(allocations, items, now))
+}
+
+// payFuelUsageUserItems pays the shares of the user in full, with a payment
+// to each creditor, who confirms it unless the user is the creditor.
+func (s *Service) payFuelUsageUserItems(
+	ctx context.Context,
+	userID int64,
+	items []paymentItem,
+	method string,
+	now time.Time,
+) ([]models.Payment, error) {
+	type paymentKey struct {
+		carID          int64
+		creditorUserID int64
+	}
+	var keys []paymentKey
+	keyToItems := make(map[paymentKey][]paymentItem)
+	for _, item := range items {
+		key := paymentKey{item.CarID, item.CreditorUserID}
+		if _, found := keyToItems[key]; !found {
+			keys = append(keys, key)
+		}
+		keyToItems[key] = append(keyToItems[key], item)
+	}
+
+	payments := []models.Payment{}
+	for _, key := range keys {
+		amount := decimal.Zero
+		for _, item := range keyToItems[key] {
+			amount = amount.Add(item.Outstanding)
+		}
+
+		allocations, err := allocatePayment(amount, keyToItems[key])
+		if err != nil {
+			return nil, err
+		}
+
+		status, err := confirmStatusOf(userID, key.creditorUserID)
+		if err != nil {
+			slog.ErrorContext(ctx, err.Error(), "carId", key.carID)
+			return nil, errs.ErrValidateFailed
+		}
+
+		payeeUserID := key.creditorUserID
+		if payeeUserID == userID {
+			payeeUserID = 0
+		}
+
+		payment, err := s.createPayment(ctx, domains.Payment{
+			CarID:         key.carID,
+			PayerUserID:   userID,
+			PayeeUserID:   payeeUserID,
+			Amount:        amount,
+			Method:        method,
+			PayTime:       now,
+			Status:        status,
+			ConfirmUserID: key.creditorUserID,
+			CreateBy:      userID,
+			CreateTime:    now,
+		}, allocations, keyToItems[key], now)
+		if err != nil {
+			return nil, err
+		}
+
+		payments = append(payments, toPaymentModel(*payment, allocations))
+	}
+	return payments, nil
+}
+
+// CreateUserCarPayment records money the user paid, which pays the shares
+// the user owes the payee. The same money reimburses the refills fronted by
+// the payee, see models.PostUserCarPaymentRequest.
+func (s *Service) CreateUserCarPayment(ctx context.Context, req models.PostUserCarPaymentRequest) (*models.Payment, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	car, err := s.getCarByID(ctx, req.CarID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.PayeeUserID != 0 {
+		if _, err := s.getUserByID(ctx, req.PayeeUserID); err != nil {
+			return nil, err
+		}
+	} else if len(req.FuelRefillIDs) > 0 {
+		slog.ErrorContext(ctx, "refills are reimbursed to their refiller, payeeUserId is required")
+		return nil, errs.ErrValidateFailed
+	}
+
+	err = s.authorizePayment(ctx, paymentScope{
+		UserID:           req.UserID,
+		CarID:            req.CarID,
+		FuelUsageUserIDs: req.FuelUsageUserIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	isUserOwnAllFuelRefills, err := s.db.IsUserOwnAllFuelRefills(ctx, req.PayeeUserID, req.CarID, req.FuelRefillIDs)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+	if !isUserOwnAllFuelRefills {
+		slog.WarnContext(ctx, "there is fuel refill that payee not own",
+			"payeeUserId", req.PayeeUserID,
+			"carId", req.CarID,
+			"fuelRefillIds", req.FuelRefillIDs,
+		)
+		return nil, errs.ErrForbidden
+	}
+
+	// the payee confirms, or the owner when the car is paid
+	creditorUserID := cmp.Or(req.PayeeUserID, car.OwnerUserID)
+	status, err := confirmStatusOf(req.UserID, creditorUserID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error(), "carId", req.CarID)
+		return nil, errs.ErrValidateFailed
+	}
+
+	now := time.Now()
+
+	payment := domains.Payment{
+		CarID:         req.CarID,
+		PayerUserID:   req.UserID,
+		PayeeUserID:   req.PayeeUserID,
+		Amount:        req.Amount,
+		Method:        cmp.Or(req.Method, domains.PaymentMethodCash),
+		PayTime:       req.PayTime,
+		Status:        status,
+		ConfirmUserID: creditorUserID,
+		CreateBy:      req.UserID,
+		CreateTime:    now,
+	}
+	if payment.PayTime.IsZero() {
+		payment.PayTime = now
+	}
+
+	var created *domains.Payment
+	var allocations []domains.PaymentAllocation
+	// the items are locked as they are read, so a payment made meanwhile
+	// allocates against what is left after this one
+	err = s.db.Transaction(ctx, func(ctxTx context.Context) error {
+		shareItems, err := s.getSharePaymentItems(ctxTx, req, creditorUserID)
+		if err != nil {
+			return err
+		}
+
+		refillItems, err := s.getRefillPaymentItems(ctxTx, req)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		allocations, err = allocatePayment(req.Amount, shareItems)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return errs.ErrValidateFailed
+		}
+		allocations = append(allocations, netFuelRefills(req.Amount, refillItems)...)
+		items := append(shareItems, refillItems...)
+
+		created, err = s.createPayment(ctxTx, payment, allocations, items, now)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := toPaymentModel(*created, allocations)
+	return &response, nil
+}
+
+// getSharePaymentItems returns the picked shares of the user in the given
+// order, or every unpaid one from the oldest when none is picked. Only the
+// shares owed to the creditor can be paid.
+func (s *Service) getSharePaymentItems(ctx context.Context, req models.PostUserCarPaymentRequest, creditorUserID int64) ([]paymentItem, error) {
+	if len(req.FuelUsageUserIDs) > 0 {
+		fuelUsageUsers, err := s.db.GetFuelUsageUsersWithFuelUsageByIDs(ctx, req.FuelUsageUserIDs)
+		if err != nil {
+			slog.ErrorContext(ctx, err.Error())
+			return nil, err
+		}
+		for _, fu := range fuelUsageUsers {
+			if fu.CreditorUserID != creditorUserID {
+				slog.ErrorContext(ctx, "the share is not owed to the payee",
+					"fuelUsageUserId", fu.ID,
+					"creditorUserId", fu.CreditorUserID,
+					"payeeUserId", creditorUserID,
+				)
+				return nil, errs.ErrValidateFailed
+			}
+		}
+		return pickPaymentItems(fuelUsageUserPaymentItems(fuelUsageUsers), req.FuelUsageUserIDs), nil
+	}
+
+	fuelUsageUsers, err := s.db.GetUserFuelUsagesByPaidStatus(ctx, req.UserID, false, req.CarID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+	var owed []FuelUsageUserWithFuelUsage
+	for _, fu := range fuelUsageUsers {
+		if fu.CreditorUserID == creditorUserID {
+			owed = append(owed, fu)
+		}
+	}
+	items := fuelUsageUserPaymentItems(owed)
+	sortOldestFirst(items)
+	return items, nil
+}
+
+// getRefillPaymentItems returns the picked refills of the payee in the given
+// order, or every unreimbursed one from the oldest when none is picked.
+func (s *Service) getRefillPaymentItems(ctx context.Context, req models.PostUserCarPaymentRequest) ([]paymentItem, error) {
+	if len(req.FuelRefillIDs) > 0 {
+		fuelRefills, err := s.db.GetFuelRefillsByIDs(ctx, req.FuelRefillIDs)
+		if err != nil {
+			return nil, err
+		}
+		return pickPaymentItems(fuelRefillPaymentItems(fuelRefills), req.FuelRefillIDs), nil
+	}
+
+	if req.PayeeUserID == 0 {
+		return nil, nil
+	}
+
+	fuelRefills, err := s.db.GetUserUnpaidFuelRefills(ctx, req.PayeeUserID, req.CarID)
+	if err != nil {
+		return nil, err
+	}
+	items := fuelRefillPaymentItems(fuelRefills)
+	sortOldestFirst(items)
+	return items, nil
+}
+
+// netFuelRefills reimburses the refills fronted by the payee with the money
+// the payer paid for the shares, as far as the refills are still owed.
+func netFuelRefills(amount decimal.Decimal, items []paymentItem) []domains.PaymentAllocation {
+	left := amount
+	allocations := []domains.PaymentAllocation{}
+	for _, item := range items {
+		if !left.IsPositive() {
+			break
+		}
+		allocated := decimal.Min(left, item.Outstanding)
+		allocations = append(allocations, domains.PaymentAllocation{
+			ItemType: item.ItemType,
+			ItemID:   item.ItemID,
+			Amount:   allocated,
+		})
+		left = left.Sub(allocated)
+	}
+	return allocations
+}
+
+// getPickedPaymentItems keeps the order of the ids, shares first.
+func (s *Service) getPickedPaymentItems(ctx context.Context, fuelUsageUserIDs, fuelRefillIDs []int64) ([]paymentItem, error) {
+	var items []paymentItem
+
+	if len(fuelUsageUserIDs) > 0 {
+		fuelUsageUsers, err := s.db.GetFuelUsageUsersWithFuelUsageByIDs(ctx, fuelUsageUserIDs)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, pickPaymentItems(fuelUsageUserPaymentItems(fuelUsageUsers), fuelUsageUserIDs)...)
+	}
+
+	if len(fuelRefillIDs) > 0 {
+		fuelRefills, err := s.db.GetFuelRefillsByIDs(ctx, fuelRefillIDs)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, pickPaymentItems(fuelRefillPaymentItems(fuelRefills), fuelRefillIDs)...)
+	}
+
+	return items, nil
+}
+
+// pickPaymentItems orders the items by the ids, an id given twice is paid once.
+func pickPaymentItems(items []paymentItem, ids []int64) []paymentItem {
+	idToItem := make(map[int64]paymentItem)
+	for _, item := range items {
+		idToItem[item.ItemID] = item
+	}
+	var picked []paymentItem
+	for _, id := range ids {
+		item, found := idToItem[id]
+		if !found {
+			continue
+		}
+		picked = append(picked, item)
+		delete(idToItem, id)
+	}
+	return picked
+}
+
+func (s *Service) GetUserCarPayments(ctx context.Context, req models.GetUserCarPaymentsRequest) (*models.GetUserCarPaymentsResponse, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	payments, err := s.db.GetUserCarPayments(ctx, req.UserID, req.CarID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	data, err := s.toPaymentModels(ctx, payments)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	return &models.GetUserCarPaymentsResponse{
+		Data: data,
+	}, nil
+}
+
+// toPaymentModels loads the allocations of the payments.
+func (s *Service) toPaymentModels(ctx context.Context, payments []domains.Payment) ([]models.Payment, error) {
+	data := []models.Payment{}
+	if len(payments) == 0 {
+		return data, nil
+	}
+
+	paymentIDs := []int64{}
+	for _, payment := range payments {
+		paymentIDs = append(paymentIDs, payment.ID)
+	}
+
+	allocations, err := s.db.GetPaymentAllocationsByPaymentIDs(ctx, paymentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	paymentIDToAllocations := make(map[int64][]domains.PaymentAllocation)
+	for _, allocation := range allocations {
+		paymentIDToAllocations[allocation.PaymentID] = append(paymentIDToAllocations[allocation.PaymentID], allocation)
+	}
+
+	for _, payment := range payments {
+		data = append(data, toPaymentModel(payment, paymentIDToAllocations[payment.ID]))
+	}
+
+	return data, nil
+}
+
+func toPaymentModel(payment domains.Payment, allocations []domains.PaymentAllocation) models.Payment {
+	paymentModel := models.Payment{
+		ID:            payment.ID,
+		CarID:         payment.CarID,
+		PayerUserID:   payment.PayerUserID,
+		PayeeUserID:   payment.PayeeUserID,
+		Amount:        payment.Amount,
+		Method:        payment.Method,
+		PayTime:       payment.PayTime,
+		Status:        payment.Status,
+		ConfirmUserID: payment.ConfirmUserID,
+		RejectReason:  payment.RejectReason,
+		ReviewTime:    payment.ReviewTime,
+		Allocations:   []models.PaymentAllocation{},
+	}
+	for _, allocation := range allocations {
+		paymentModel.Allocations = append(paymentModel.Allocations, models.PaymentAllocation{
+			ItemType: allocation.ItemType,
+			ItemID:   allocation.ItemID,
+			Amount:   allocation.Amount,
+		})
+	}
+	return paymentModel
+}
+
+// GetUserPendingPayments lists the payments waiting for the user to confirm
+// and the ones the user made waiting for the others.
+func (s *Service) GetUserPendingPayments(ctx context.Context, req models.GetUserPendingPaymentsRequest) (*models.GetUserPendingPaymentsResponse, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	if err := shouldActAsUser(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+
+	payments, err := s.db.GetUserPendingPayments(ctx, req.UserID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	data, err := s.toPaymentModels(ctx, payments)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	response := models.GetUserPendingPaymentsResponse{
+		ToConfirm: []models.Payment{},
+		Awaiting:  []models.Payment{},
+	}
+	for _, payment := range data {
+		if payment.ConfirmUserID == req.UserID {
+			response.ToConfirm = append(response.ToConfirm, payment)
+		} else {
+			response.Awaiting = append(response.Awaiting, payment)
+		}
+	}
+
+	return &response, nil
+}
+
+// ConfirmUserPayment pays the shares and refills a pending payment claimed.
+func (s *Service) ConfirmUserPayment(ctx context.Context, req models.PatchUserPaymentConfirmRequest) (*models.Payment, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	payment, allocations, err := s.getPaymentToReview(ctx, req.UserID, req.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.getAllocatedPaymentItems(ctx, allocations)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	now := time.Now()
+
+	payment.Status = domains.PaymentConfirmStatusConfirmed
+	payment.ReviewBy = req.UserID
+	payment.ReviewTime = &now
+
+	err = s.db.Transaction(ctx, func(ctxTx context.Context) error {
+		if err := s.reviewPendingPayment(ctxTx, *payment); err != nil {
+			return err
+		}
+		if err := s.claimPaymentAllocations(ctxTx, allocations, false); err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+		if err := s.payPaymentAllocations(ctxTx, allocations, items, now); err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := toPaymentModel(*payment, allocations)
+	return &response, nil
+}
+
+// RejectUserPayment turns the shares and refills a pending payment claimed
+// back to unpaid, with the reason the money did not arrive.
+func (s *Service) RejectUserPayment(ctx context.Context, req models.PatchUserPaymentRejectRequest) (*models.Payment, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	payment, allocations, err := s.getPaymentToReview(ctx, req.UserID, req.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	payment.Status = domains.PaymentConfirmStatusRejected
+	payment.RejectReason = req.Reason
+	payment.ReviewBy = req.UserID
+	payment.ReviewTime = &now
+
+	err = s.db.Transaction(ctx, func(ctxTx context.Context) error {
+		if err := s.reviewPendingPayment(ctxTx, *payment); err != nil {
+			return err
+		}
+		if err := s.claimPaymentAllocations(ctxTx, allocations, false); err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := toPaymentModel(*payment, allocations)
+	return &response, nil
+}
+
+// getPaymentToReview returns a pending payment the user should confirm.
+func (s *Service) getPaymentToReview(ctx context.Context, userID, paymentID int64) (*domains.Payment, []domains.PaymentAllocation, error) {
+	if err := shouldActAsUser(ctx, userID); err != nil {
+		return nil, nil, err
+	}
+
+	payment, err := s.db.GetPaymentByID(ctx, paymentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.WarnContext(ctx, "not found payment", "paymentId", paymentID)
+			return nil, nil, errs.ErrNotFound
+		}
+		slog.ErrorContext(ctx, err.Error())
+		return nil, nil, err
+	}
+
+	if payment.ConfirmUserID != userID {
+		slog.WarnContext(ctx, "the payment is not for the user to confirm",
+			"userId", userID,
+			"paymentId", paymentID,
+			"confirmUserId", payment.ConfirmUserID,
+		)
+		return nil, nil, errs.ErrForbidden
+	}
+
+	if payment.Status != domains.PaymentConfirmStatusPending {
+		slog.WarnContext(ctx, "the payment is not pending",
+			"paymentId", paymentID,
+			"status", payment.Status,
+		)
+		return nil, nil, errs.ErrConflict
+	}
+
+	allocations, err := s.db.GetPaymentAllocationsByPaymentIDs(ctx, []int64{paymentID})
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, nil, err
+	}
+
+	return payment, allocations, nil
+}
+
+// reviewPendingPayment saves the review, a payment reviewed by a concurrent
+// request since it was read is a conflict.
+func (s *Service) reviewPendingPayment(ctx context.Context, payment domains.Payment) error {
+	isReviewed, err := s.db.ReviewPendingPayment(ctx, payment)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+	if !isReviewed {
+		slog.WarnContext(ctx, "the payment is not pending anymore", "paymentId", payment.ID)
+		return errs.ErrConflict
+	}
+	return nil
+}
+
+// getAllocatedPaymentItems returns the items the allocations pay, whatever
+// is left to pay on them.
+func (s *Service) getAllocatedPaymentItems(ctx context.Context, allocations []domains.PaymentAllocation) ([]paymentItem, error) {
+	var fuelUsageUserIDs, fuelRefillIDs []int64
+	for _, allocation := range allocations {
+		switch allocation.ItemType {
+		case domains.PaymentItemTypeFuelUsageUser:
+			fuelUsageUserIDs = append(fuelUsageUserIDs, allocation.ItemID)
+		case domains.PaymentItemTypeFuelRefill:
+			fuelRefillIDs = append(fuelRefillIDs, allocation.ItemID)
+		}
+	}
+
+	var items []paymentItem
+
+	if len(fuelUsageUserIDs) > 0 {
+		fuelUsageUsers, err := s.db.GetFuelUsageUsersWithFuelUsageByIDs(ctx, fuelUsageUserIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, fu := range fuelUsageUsers {
+			items = append(items, toFuelUsageUserPaymentItem(fu))
+		}
+	}
+
+	if len(fuelRefillIDs) > 0 {
+		fuelRefills, err := s.db.GetFuelRefillsByIDs(ctx, fuelRefillIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, fr := range fuelRefills {
+			items = append(items, toFuelRefillPaymentItem(fr))
+		}
+	}
+
+	return items, nil
+}
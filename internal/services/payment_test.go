@@ -0,0 +1,651 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+func (stub *stubDatabaseAdaptor) GetUserFuelUsagesByPaidStatus(ctx context.Context, userID int64, isPaid bool, carID int64) ([]FuelUsageUserWithFuelUsage, error) {
+	var data []FuelUsageUserWithFuelUsage
+	for _, fuelUsageUser := range stub.fuelUsageUsers {
+		d := stub.withFuelUsage(fuelUsageUser)
+		if d.UserID == userID && d.IsPaid == isPaid && (carID == 0 || d.CarID == carID) {
+			data = append(data, d)
+		}
+	}
+	return data, nil
+}
+
+func (stub *stubDatabaseAdaptor) GetUserUnpaidFuelRefills(ctx context.Context, userID int64, carID int64) ([]domains.FuelRefill, error) {
+	var fuelRefills []domains.FuelRefill
+	for _, fuelRefill := range stub.fuelRefills {
+		if fuelRefill.RefillBy == userID && fuelRefill.CarID == carID && !fuelRefill.IsPaid {
+			fuelRefills = append(fuelRefills, fuelRefill)
+		}
+	}
+	return fuelRefills, nil
+}
+
+func (stub *stubDatabaseAdaptor) CreatePayment(ctx context.Context, payment domains.Payment) (int64, error) {
+	payment.ID = int64(len(stub.payments) + 1)
+	stub.payments = append(stub.payments, payment)
+	return payment.ID, nil
+}
+
+func (stub *stubDatabaseAdaptor) CreatePaymentAllocations(ctx context.Context, paymentAllocations []domains.PaymentAllocation) error {
+	stub.paymentAllocations = append(stub.paymentAllocations, paymentAllocations...)
+	return nil
+}
+
+func (stub *stubDatabaseAdaptor) AddFuelUsageUserPaidAmount(ctx context.Context, fuelUsageUserID int64, amount decimal.Decimal) (bool, error) {
+	for i, fuelUsageUser := range stub.fuelUsageUsers {
+		if fuelUsageUser.ID == fuelUsageUserID {
+			if fuelUsageUser.PaidAmount.Add(fuelUsageUser.ClaimedAmount).Add(amount).GreaterThan(fuelUsageUser.Amount) {
+				return false, nil
+			}
+			stub.fuelUsageUsers[i].PaidAmount = fuelUsageUser.PaidAmount.Add(amount)
+			stub.fuelUsageUsers[i].IsPaid = stub.fuelUsageUsers[i].PaidAmount.GreaterThanOrEqual(fuelUsageUser.Amount)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (stub *stubDatabaseAdaptor) AddFuelRefillPaidAmount(ctx context.Context, fuelRefillID int64, amount decimal.Decimal) (bool, error) {
+	for i, fuelRefill := range stub.fuelRefills {
+		if fuelRefill.ID == fuelRefillID {
+			if fuelRefill.PaidAmount.Add(fuelRefill.ClaimedAmount).Add(amount).GreaterThan(fuelRefill.TotalMoney) {
+				return false, nil
+			}
+			stub.fuelRefills[i].PaidAmount = fuelRefill.PaidAmount.Add(amount)
+			stub.fuelRefills[i].IsPaid = stub.fuelRefills[i].PaidAmount.GreaterThanOrEqual(fuelRefill.TotalMoney)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (stub *stubDatabaseAdaptor) AddFuelUsageUserClaimedAmount(ctx context.Context, fuelUsageUserID int64, amount decimal.Decimal) (bool, error) {
+	for i, fuelUsageUser := range stub.fuelUsageUsers {
+		if fuelUsageUser.ID == fuelUsageUserID {
+			if fuelUsageUser.PaidAmount.Add(fuelUsageUser.ClaimedAmount).Add(amount).GreaterThan(fuelUsageUser.Amount) {
+				return false, nil
+			}
+			stub.fuelUsageUsers[i].ClaimedAmount = fuelUsageUser.ClaimedAmount.Add(amount)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (stub *stubDatabaseAdaptor) AddFuelRefillClaimedAmount(ctx context.Context, fuelRefillID int64, amount decimal.Decimal) (bool, error) {
+	for i, fuelRefill := range stub.fuelRefills {
+		if fuelRefill.ID == fuelRefillID {
+			if fuelRefill.PaidAmount.Add(fuelRefill.ClaimedAmount).Add(amount).GreaterThan(fuelRefill.TotalMoney) {
+				return false, nil
+			}
+			stub.fuelRefills[i].ClaimedAmount = fuelRefill.ClaimedAmount.Add(amount)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (stub *stubDatabaseAdaptor) GetPaymentByID(ctx context.Context, paymentID int64) (*domains.Payment, error) {
+	for _, payment := range stub.payments {
+		if payment.ID == paymentID {
+			return &payment, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (stub *stubDatabaseAdaptor) ReviewPendingPayment(ctx context.Context, payment domains.Payment) (bool, error) {
+	for i := range stub.payments {
+		if stub.payments[i].ID == payment.ID && stub.payments[i].Status == domains.PaymentConfirmStatusPending {
+			stub.payments[i] = payment
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (stub *stubDatabaseAdaptor) GetUserPendingPayments(ctx context.Context, userID int64) ([]domains.Payment, error) {
+	var payments []domains.Payment
+	for _, payment := range stub.payments {
+		isUserPayment := payment.ConfirmUserID == userID || payment.CreateBy == userID
+		if isUserPayment && payment.Status == domains.PaymentConfirmStatusPending {
+			payments = append(payments, payment)
+		}
+	}
+	return payments, nil
+}
+
+func (stub *stubDatabaseAdaptor) GetPaymentAllocationsByPaymentIDs(ctx context.Context, paymentIDs []int64) ([]domains.PaymentAllocation, error) {
+	var paymentAllocations []domains.PaymentAllocation
+	for _, paymentAllocation := range stub.paymentAllocations {
+		if slices.Contains(paymentIDs, paymentAllocation.PaymentID) {
+			paymentAllocations = append(paymentAllocations, paymentAllocation)
+		}
+	}
+	return paymentAllocations, nil
+}
+
+func Test_allocatePayment(t *testing.T) {
+	items := []paymentItem{
+		{ItemType: domains.PaymentItemTypeFuelUsageUser, ItemID: 1, Outstanding: decimal.NewFromInt(150)},
+		{ItemType: domains.PaymentItemTypeFuelRefill, ItemID: 2, Outstanding: decimal.NewFromInt(100)},
+		{ItemType: domains.PaymentItemTypeFuelUsageUser, ItemID: 3, Outstanding: decimal.NewFromInt(100)},
+	}
+	tests := []struct {
+		name    string
+		amount  decimal.Decimal
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "pays the first item in part",
+			amount: decimal.NewFromInt(100),
+			want:   []string{"100"},
+		},
+		{
+			name:   "pays in order until the amount runs out",
+			amount: decimal.NewFromInt(200),
+			want:   []string{"150", "50"},
+		},
+		{
+			name:   "pays every item",
+			amount: decimal.NewFromInt(350),
+			want:   []string{"150", "100", "100"},
+		},
+		{
+			name:    "more than the items owe",
+			amount:  decimal.NewFromInt(351),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := allocatePayment(tt.amount, items)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("allocatePayment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("allocatePayment() got %d allocations, want %d", len(got), len(tt.want))
+			}
+			for i, allocation := range got {
+				if allocation.ItemID != items[i].ItemID || allocation.Amount.String() != tt.want[i] {
+					t.Errorf("allocation %d = %d %s, want %d %s", i, allocation.ItemID, allocation.Amount, items[i].ItemID, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestService_CreateUserCarPayment(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	newDB := func() *stubDatabaseAdaptor {
+		return &stubDatabaseAdaptor{
+			isUserOwnAllFuelUsageUser: true,
+			isUserOwnAllFuelRefills:   true,
+			cars:                      []domains.Car{{ID: 1, OwnerUserID: 2}},
+			users:                     []domains.User{{ID: 1}, {ID: 2}},
+			fuelUsages: []domains.FuelUsage{
+				{ID: 1, CarID: 1, FuelUseTime: day(3)},
+				{ID: 2, CarID: 1, FuelUseTime: day(1)},
+			},
+			fuelUsageUsers: []FuelUsageUser{
+				{FuelUsageUser: domains.FuelUsageUser{ID: 10, FuelUsageID: 1, UserID: 1, Amount: decimal.NewFromInt(350)}},
+				{FuelUsageUser: domains.FuelUsageUser{ID: 11, FuelUsageID: 2, UserID: 1, Amount: decimal.NewFromInt(100)}},
+			},
+			fuelRefills: []domains.FuelRefill{
+				{ID: 20, CarID: 1, RefillBy: 2, RefillTime: day(2), TotalMoney: decimal.NewFromInt(80)},
+			},
+		}
+	}
+
+	t.Run("pays the oldest shares first", func(t *testing.T) {
+		db := newDB()
+		s := New(nil, db, nil, nil)
+		got, err := s.CreateUserCarPayment(contextWithUser(1), models.PostUserCarPaymentRequest{
+			UserID: 1,
+			CarID:  1,
+			Amount: decimal.NewFromInt(300),
+		})
+		if err != nil {
+			t.Fatalf("CreateUserCarPayment() error = %v", err)
+		}
+		if got.Method != domains.PaymentMethodCash || len(got.Allocations) != 2 {
+			t.Fatalf("CreateUserCarPayment() = %+v", got)
+		}
+		if got.Status != domains.PaymentConfirmStatusPending || got.ConfirmUserID != 2 {
+			t.Fatalf("payment = %+v, want pending for the owner", got)
+		}
+		_, err = s.ConfirmUserPayment(contextWithUser(2), models.PatchUserPaymentConfirmRequest{
+			UserID:    2,
+			PaymentID: got.ID,
+		})
+		if err != nil {
+			t.Fatalf("ConfirmUserPayment() error = %v", err)
+		}
+		if got.Allocations[0].ItemID != 11 || !got.Allocations[0].Amount.Equal(decimal.NewFromInt(100)) {
+			t.Errorf("first allocation = %+v, want the older share in full", got.Allocations[0])
+		}
+		if got.Allocations[1].ItemID != 10 || !got.Allocations[1].Amount.Equal(decimal.NewFromInt(200)) {
+			t.Errorf("second allocation = %+v, want 200 of the newer share", got.Allocations[1])
+		}
+
+		older, newer := db.fuelUsageUsers[1], db.fuelUsageUsers[0]
+		if domains.PaymentStatusOf(older.Amount, older.PaidAmount, older.ClaimedAmount) != domains.PaymentStatusPaid || !older.IsPaid {
+			t.Errorf("older share = %+v, want paid", older)
+		}
+		if domains.PaymentStatusOf(newer.Amount, newer.PaidAmount, newer.ClaimedAmount) != domains.PaymentStatusPartiallyPaid || newer.IsPaid {
+			t.Errorf("newer share = %+v, want partially paid", newer)
+		}
+		if balance := ledgerBalances(db.ledgerEntries)[1]; !balance.Equal(decimal.NewFromInt(300)) {
+			t.Errorf("payer balance = %s, want 300", balance)
+		}
+	})
+
+	t.Run("pays the shares owed to the payee and nets the refills of the payee once confirmed", func(t *testing.T) {
+		db := newDB()
+		// the share of day 1 is fuel of the refill of the payee
+		db.fuelUsages[1].FuelRefillID = 20
+		s := New(nil, db, nil, nil)
+		got, err := s.CreateUserCarPayment(contextWithUser(1), models.PostUserCarPaymentRequest{
+			UserID:      1,
+			CarID:       1,
+			PayeeUserID: 2,
+			Amount:      decimal.NewFromInt(50),
+			Method:      domains.PaymentMethodPromptPay,
+		})
+		if err != nil {
+			t.Fatalf("CreateUserCarPayment() error = %v", err)
+		}
+		if len(got.Allocations) != 2 || got.Allocations[0].ItemID != 11 || got.Allocations[1].ItemType != domains.PaymentItemTypeFuelRefill {
+			t.Fatalf("allocations = %+v, want the share owed to the payee then the refill of the payee", got.Allocations)
+		}
+		if !got.Allocations[0].Amount.Equal(decimal.NewFromInt(50)) || !got.Allocations[1].Amount.Equal(decimal.NewFromInt(50)) {
+			t.Fatalf("allocations = %+v, want 50 each", got.Allocations)
+		}
+		if got.Status != domains.PaymentConfirmStatusPending || got.ConfirmUserID != 2 {
+			t.Fatalf("payment = %+v, want pending for the payee", got)
+		}
+		if !db.fuelRefills[0].ClaimedAmount.Equal(decimal.NewFromInt(50)) || len(db.ledgerEntries) != 0 {
+			t.Fatalf("refill = %+v, ledger = %+v, want only claimed", db.fuelRefills[0], db.ledgerEntries)
+		}
+
+		_, err = s.ConfirmUserPayment(contextWithUser(2), models.PatchUserPaymentConfirmRequest{
+			UserID:    2,
+			PaymentID: got.ID,
+		})
+		if err != nil {
+			t.Fatalf("ConfirmUserPayment() error = %v", err)
+		}
+		if !db.fuelRefills[0].ClaimedAmount.IsZero() {
+			t.Errorf("refill claimed amount = %s, want 0", db.fuelRefills[0].ClaimedAmount)
+		}
+		if !db.fuelRefills[0].PaidAmount.Equal(decimal.NewFromInt(50)) {
+			t.Errorf("refill paid amount = %s, want 50", db.fuelRefills[0].PaidAmount)
+		}
+		balances := ledgerBalances(db.ledgerEntries)
+		if !balances[1].Equal(decimal.NewFromInt(50)) || !balances[2].Equal(decimal.NewFromInt(-50)) {
+			t.Errorf("balances = %v, want the payer +50 and the payee -50", balances)
+		}
+	})
+
+	t.Run("more than owed to the payee", func(t *testing.T) {
+		db := newDB()
+		db.cars[0].OwnerUserID = 3
+		db.fuelUsages[1].FuelRefillID = 20
+		s := New(nil, db, nil, nil)
+		_, err := s.CreateUserCarPayment(contextWithUser(1), models.PostUserCarPaymentRequest{
+			UserID:      1,
+			CarID:       1,
+			PayeeUserID: 2,
+			Amount:      decimal.NewFromInt(101),
+		})
+		if !errors.Is(err, errs.ErrValidateFailed) {
+			t.Fatalf("CreateUserCarPayment() error = %v, want %v", err, errs.ErrValidateFailed)
+		}
+	})
+
+	t.Run("picked share owed to someone else", func(t *testing.T) {
+		db := newDB()
+		db.cars[0].OwnerUserID = 3
+		s := New(nil, db, nil, nil)
+		_, err := s.CreateUserCarPayment(contextWithUser(1), models.PostUserCarPaymentRequest{
+			UserID:           1,
+			CarID:            1,
+			PayeeUserID:      2,
+			Amount:           decimal.NewFromInt(100),
+			FuelUsageUserIDs: []int64{10},
+		})
+		if !errors.Is(err, errs.ErrValidateFailed) {
+			t.Fatalf("CreateUserCarPayment() error = %v, want %v", err, errs.ErrValidateFailed)
+		}
+		if len(db.payments) != 0 {
+			t.Errorf("saved %d payments, want none", len(db.payments))
+		}
+	})
+
+	t.Run("pays the picked share only", func(t *testing.T) {
+		db := newDB()
+		s := New(nil, db, nil, nil)
+		got, err := s.CreateUserCarPayment(contextWithUser(1), models.PostUserCarPaymentRequest{
+			UserID:           1,
+			CarID:            1,
+			Amount:           decimal.NewFromInt(200),
+			FuelUsageUserIDs: []int64{10},
+		})
+		if err != nil {
+			t.Fatalf("CreateUserCarPayment() error = %v", err)
+		}
+		if len(got.Allocations) != 1 || got.Allocations[0].ItemID != 10 {
+			t.Errorf("allocations = %+v, want the picked share", got.Allocations)
+		}
+	})
+
+	t.Run("more than owed", func(t *testing.T) {
+		db := newDB()
+		s := New(nil, db, nil, nil)
+		_, err := s.CreateUserCarPayment(contextWithUser(1), models.PostUserCarPaymentRequest{
+			UserID: 1,
+			CarID:  1,
+			Amount: decimal.NewFromInt(451),
+		})
+		if !errors.Is(err, errs.ErrValidateFailed) {
+			t.Fatalf("CreateUserCarPayment() error = %v, want %v", err, errs.ErrValidateFailed)
+		}
+		if len(db.payments) != 0 {
+			t.Errorf("saved %d payments, want none", len(db.payments))
+		}
+	})
+
+	t.Run("nobody to confirm", func(t *testing.T) {
+		db := newDB()
+		db.cars[0].OwnerUserID = 0
+		s := New(nil, db, nil, nil)
+		_, err := s.CreateUserCarPayment(contextWithUser(1), models.PostUserCarPaymentRequest{
+			UserID: 1,
+			CarID:  1,
+			Amount: decimal.NewFromInt(100),
+		})
+		if !errors.Is(err, errs.ErrValidateFailed) {
+			t.Fatalf("CreateUserCarPayment() error = %v, want %v", err, errs.ErrValidateFailed)
+		}
+		if len(db.payments) != 0 {
+			t.Errorf("saved %d payments, want none", len(db.payments))
+		}
+	})
+
+	t.Run("pay on behalf of another user", func(t *testing.T) {
+		db := newDB()
+		s := New(nil, db, nil, nil)
+		_, err := s.CreateUserCarPayment(contextWithUser(2), models.PostUserCarPaymentRequest{
+			UserID: 1,
+			CarID:  1,
+			Amount: decimal.NewFromInt(100),
+		})
+		if !errors.Is(err, errs.ErrForbidden) {
+			t.Fatalf("CreateUserCarPayment() error = %v, want %v", err, errs.ErrForbidden)
+		}
+	})
+}
+
+func TestService_PayUserCarUnpaidActivities_payments(t *testing.T) {
+	db := &stubDatabaseAdaptor{
+		isUserOwnAllFuelUsageUser: true,
+		isUserOwnAllFuelRefills:   true,
+		cars:                      []domains.Car{{ID: 1, OwnerUserID: 1}},
+		fuelUsages:                []domains.FuelUsage{{ID: 1, CarID: 1}},
+		fuelUsageUsers: []FuelUsageUser{
+			{FuelUsageUser: domains.FuelUsageUser{ID: 10, FuelUsageID: 1, UserID: 1, Amount: decimal.NewFromInt(50), PaidAmount: decimal.NewFromInt(20)}},
+		},
+		fuelRefills: []domains.FuelRefill{
+			{ID: 20, CarID: 1, RefillBy: 1, TotalMoney: decimal.NewFromInt(500)},
+		},
+	}
+	s := New(nil, db, nil, nil)
+
+	got, err := s.PayUserCarUnpaidActivities(contextWithUser(1), models.PayUserCarUnpaidActivitiesRequest{
+		UserID:           1,
+		CarID:            1,
+		FuelUsageUserIDs: []int64{10},
+		FuelRefillIDs:    []int64{20},
+	})
+	if err != nil {
+		t.Fatalf("PayUserCarUnpaidActivities() error = %v", err)
+	}
+	if len(got.Payments) != 2 {
+		t.Fatalf("got %d payments, want 2", len(got.Payments))
+	}
+
+	sharePayment, refillPayment := got.Payments[0], got.Payments[1]
+	if sharePayment.PayerUserID != 1 || sharePayment.PayeeUserID != 0 || !sharePayment.Amount.Equal(decimal.NewFromInt(30)) {
+		t.Errorf("share payment = %+v, want the rest of the share paid by the user", sharePayment)
+	}
+	if refillPayment.PayerUserID != 0 || refillPayment.PayeeUserID != 1 || !refillPayment.Amount.Equal(decimal.NewFromInt(500)) {
+		t.Errorf("refill payment = %+v, want the refill paid to the user", refillPayment)
+	}
+	if !db.fuelUsageUsers[0].IsPaid || !db.fuelRefills[0].IsPaid {
+		t.Errorf("share paid = %v, refill paid = %v, want both paid", db.fuelUsageUsers[0].IsPaid, db.fuelRefills[0].IsPaid)
+	}
+}
+
+func (stub *stubDatabaseAdaptor) GetUserFuelUsageByUserID(ctx context.Context, userID int64) ([]domains.FuelUsageUser, error) {
+	var fuelUsageUsers []domains.FuelUsageUser
+	for _, fuelUsageUser := range stub.fuelUsageUsers {
+		if fuelUsageUser.UserID == userID {
+			fuelUsageUsers = append(fuelUsageUsers, fuelUsageUser.FuelUsageUser)
+		}
+	}
+	return fuelUsageUsers, nil
+}
+
+func TestService_BulkUpdateUserFuelUsagePaymentStatus_claims(t *testing.T) {
+	newDB := func() *stubDatabaseAdaptor {
+		return &stubDatabaseAdaptor{
+			cars: []domains.Car{{ID: 1, OwnerUserID: 3}},
+			fuelUsages: []domains.FuelUsage{
+				{ID: 1, CarID: 1, FuelRefillID: 20},
+				{ID: 2, CarID: 1},
+			},
+			fuelUsageUsers: []FuelUsageUser{
+				{FuelUsageUser: domains.FuelUsageUser{ID: 10, FuelUsageID: 1, UserID: 1, Amount: decimal.NewFromInt(100)}},
+				{FuelUsageUser: domains.FuelUsageUser{ID: 11, FuelUsageID: 2, UserID: 1, Amount: decimal.NewFromInt(40)}},
+			},
+			fuelRefills: []domains.FuelRefill{
+				{ID: 20, CarID: 1, RefillBy: 2, TotalMoney: decimal.NewFromInt(500)},
+			},
+		}
+	}
+	markPaid := func(s *Service) error {
+		req := models.BulkUpdateUserFuelUsagePaymentStatusRequest{UserID: 1}
+		body := `{"userFuelUsages": [{"id": 10, "isPaid": true}, {"id": 11, "isPaid": true}]}`
+		if err := json.Unmarshal([]byte(body), &req); err != nil {
+			t.Fatal(err)
+		}
+		return s.BulkUpdateUserFuelUsagePaymentStatus(contextWithUser(1), req)
+	}
+
+	t.Run("claims each share to its creditor", func(t *testing.T) {
+		db := newDB()
+		s := New(nil, db, nil, nil)
+		if err := markPaid(s); err != nil {
+			t.Fatalf("BulkUpdateUserFuelUsagePaymentStatus() error = %v", err)
+		}
+		if len(db.payments) != 2 {
+			t.Fatalf("got %d payments, want one to the refiller and one to the owner", len(db.payments))
+		}
+		if db.payments[0].ConfirmUserID != 2 || db.payments[1].ConfirmUserID != 3 {
+			t.Errorf("payments = %+v, want confirmed by the refiller then the owner", db.payments)
+		}
+		for _, fuelUsageUser := range db.fuelUsageUsers {
+			status := domains.PaymentStatusOf(fuelUsageUser.Amount, fuelUsageUser.PaidAmount, fuelUsageUser.ClaimedAmount)
+			if fuelUsageUser.IsPaid || status != domains.PaymentStatusClaimed {
+				t.Errorf("share %d = %+v, want claimed", fuelUsageUser.ID, fuelUsageUser)
+			}
+		}
+		if len(db.ledgerEntries) != 0 {
+			t.Errorf("ledger = %+v, want nothing posted before the confirmation", db.ledgerEntries)
+		}
+
+		pending, err := s.GetUserPendingPayments(contextWithUser(2), models.GetUserPendingPaymentsRequest{UserID: 2})
+		if err != nil {
+			t.Fatalf("GetUserPendingPayments() error = %v", err)
+		}
+		if len(pending.ToConfirm) != 1 || len(pending.Awaiting) != 0 {
+			t.Errorf("pending of the refiller = %+v, want one to confirm", pending)
+		}
+		pending, err = s.GetUserPendingPayments(contextWithUser(1), models.GetUserPendingPaymentsRequest{UserID: 1})
+		if err != nil {
+			t.Fatalf("GetUserPendingPayments() error = %v", err)
+		}
+		if len(pending.ToConfirm) != 0 || len(pending.Awaiting) != 2 {
+			t.Errorf("pending of the payer = %+v, want two awaiting", pending)
+		}
+	})
+
+	t.Run("a share claimed twice", func(t *testing.T) {
+		db := newDB()
+		s := New(nil, db, nil, nil)
+		if err := markPaid(s); err != nil {
+			t.Fatalf("BulkUpdateUserFuelUsagePaymentStatus() error = %v", err)
+		}
+		if err := markPaid(s); err != nil {
+			t.Fatalf("BulkUpdateUserFuelUsagePaymentStatus() error = %v", err)
+		}
+		if len(db.payments) != 2 {
+			t.Errorf("got %d payments, want the claimed shares not claimed again", len(db.payments))
+		}
+	})
+
+	t.Run("the creditor confirms", func(t *testing.T) {
+		db := newDB()
+		s := New(nil, db, nil, nil)
+		if err := markPaid(s); err != nil {
+			t.Fatalf("BulkUpdateUserFuelUsagePaymentStatus() error = %v", err)
+		}
+
+		_, err := s.ConfirmUserPayment(contextWithUser(3), models.PatchUserPaymentConfirmRequest{
+			UserID:    3,
+			PaymentID: 1,
+		})
+		if !errors.Is(err, errs.ErrForbidden) {
+			t.Fatalf("ConfirmUserPayment() by the owner error = %v, want %v", err, errs.ErrForbidden)
+		}
+
+		got, err := s.ConfirmUserPayment(contextWithUser(2), models.PatchUserPaymentConfirmRequest{
+			UserID:    2,
+			PaymentID: 1,
+		})
+		if err != nil {
+			t.Fatalf("ConfirmUserPayment() error = %v", err)
+		}
+		if got.Status != domains.PaymentConfirmStatusConfirmed || got.ReviewTime == nil {
+			t.Errorf("payment = %+v, want confirmed", got)
+		}
+		if share := db.fuelUsageUsers[0]; !share.IsPaid || !share.ClaimedAmount.IsZero() {
+			t.Errorf("share = %+v, want paid", share)
+		}
+		if balance := ledgerBalances(db.ledgerEntries)[1]; !balance.Equal(decimal.NewFromInt(100)) {
+			t.Errorf("payer balance = %s, want 100", balance)
+		}
+
+		_, err = s.ConfirmUserPayment(contextWithUser(2), models.PatchUserPaymentConfirmRequest{
+			UserID:    2,
+			PaymentID: 1,
+		})
+		if !errors.Is(err, errs.ErrConflict) {
+			t.Errorf("ConfirmUserPayment() twice error = %v, want %v", err, errs.ErrConflict)
+		}
+	})
+
+	t.Run("a confirmed share is not set unpaid", func(t *testing.T) {
+		db := newDB()
+		s := New(nil, db, nil, nil)
+		if err := markPaid(s); err != nil {
+			t.Fatalf("BulkUpdateUserFuelUsagePaymentStatus() error = %v", err)
+		}
+		_, err := s.ConfirmUserPayment(contextWithUser(2), models.PatchUserPaymentConfirmRequest{
+			UserID:    2,
+			PaymentID: 1,
+		})
+		if err != nil {
+			t.Fatalf("ConfirmUserPayment() error = %v", err)
+		}
+
+		req := models.BulkUpdateUserFuelUsagePaymentStatusRequest{UserID: 1}
+		if err := json.Unmarshal([]byte(`{"userFuelUsages": [{"id": 10, "isPaid": false}]}`), &req); err != nil {
+			t.Fatal(err)
+		}
+		err = s.BulkUpdateUserFuelUsagePaymentStatus(contextWithUser(1), req)
+		if !errors.Is(err, errs.ErrPaidRecordLocked) {
+			t.Fatalf("BulkUpdateUserFuelUsagePaymentStatus() error = %v, want %v", err, errs.ErrPaidRecordLocked)
+		}
+		if share := db.fuelUsageUsers[0]; !share.IsPaid || !share.PaidAmount.Equal(decimal.NewFromInt(100)) {
+			t.Errorf("share = %+v, want still paid", share)
+		}
+	})
+
+	t.Run("the creditor rejects", func(t *testing.T) {
+		db := newDB()
+		s := New(nil, db, nil, nil)
+		if err := markPaid(s); err != nil {
+			t.Fatalf("BulkUpdateUserFuelUsagePaymentStatus() error = %v", err)
+		}
+
+		got, err := s.RejectUserPayment(contextWithUser(3), models.PatchUserPaymentRejectRequest{
+			UserID:    3,
+			PaymentID: 2,
+			Reason:    "no transfer in the bank account",
+		})
+		if err != nil {
+			t.Fatalf("RejectUserPayment() error = %v", err)
+		}
+		if got.Status != domains.PaymentConfirmStatusRejected || got.RejectReason != "no transfer in the bank account" {
+			t.Errorf("payment = %+v, want rejected with the reason", got)
+		}
+		share := db.fuelUsageUsers[1]
+		if domains.PaymentStatusOf(share.Amount, share.PaidAmount, share.ClaimedAmount) != domains.PaymentStatusUnpaid {
+			t.Errorf("share = %+v, want unpaid again", share)
+		}
+		if len(db.ledgerEntries) != 0 {
+			t.Errorf("ledger = %+v, want nothing posted", db.ledgerEntries)
+		}
+	})
+}
+
+func TestService_payPaymentAllocations_overpaid(t *testing.T) {
+	db := &stubDatabaseAdaptor{
+		fuelUsageUsers: []FuelUsageUser{
+			{FuelUsageUser: domains.FuelUsageUser{ID: 1, FuelUsageID: 1, UserID: 1, Amount: decimal.NewFromInt(100), PaidAmount: decimal.NewFromInt(70)}},
+		},
+	}
+	s := New(nil, db, nil, nil)
+
+	// another payment paid 70 since the 100 was allocated
+	allocations := []domains.PaymentAllocation{
+		{ItemType: domains.PaymentItemTypeFuelUsageUser, ItemID: 1, Amount: decimal.NewFromInt(100)},
+	}
+	err := s.payPaymentAllocations(context.Background(), allocations, nil, time.Now())
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Fatalf("payPaymentAllocations() error = %v, want %v", err, errs.ErrConflict)
+	}
+	if !db.fuelUsageUsers[0].PaidAmount.Equal(decimal.NewFromInt(70)) {
+		t.Errorf("paid amount = %s, want 70 kept", db.fuelUsageUsers[0].PaidAmount)
+	}
+}
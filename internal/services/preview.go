@@ -0,0 +1,96 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+)
+
+// PreviewFuelUsage prices the fuel usage the way CreateFuelUsage would
+// without saving it, so the frontend does not reimplement the pricing.
+func (s *Service) PreviewFuelUsage(ctx context.Context, req models.CreateFuelUsageRequest) (*models.PreviewFuelUsageResponse, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	car, err := s.getActiveCarByID(ctx, req.CurrentCarID)
+	if err != nil {
+		return nil, err
+	}
+
+	fuelRefillID, fuelPrice, err := s.resolveFuelRefill(ctx, req.CurrentCarID, req.FuelRefillID, req.FuelPrice)
+	if err != nil {
+		return nil, err
+	}
+
+	fuelUsage := domains.FuelUsage{
+		CarID:              req.CurrentCarID,
+		FuelUseTime:        req.FuelUseTime,
+		FuelPrice:          fuelPrice,
+		FuelRefillID:       fuelRefillID,
+		KilometerBeforeUse: req.KilometerBeforeUse,
+		KilometerAfterUse:  req.KilometerAfterUse,
+		SplitMode:          cmp.Or(req.SplitMode, domains.FuelUsageSplitModeEqual),
+		DriverUserID:       req.DriverUserID,
+	}
+
+	fuelUsageUsers, err := s.priceFuelUsage(ctx, *car, &fuelUsage, req.FuelUsers)
+	if err != nil {
+		return nil, err
+	}
+
+	latestFuelUsage, latestFuelRefill, err := s.getLatestFuelRecords(ctx, req.CurrentCarID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := models.PreviewFuelUsageResponse{
+		FuelPrice:     fuelUsage.FuelPrice,
+		FuelRefillID:  fuelUsage.FuelRefillID,
+		TotalMoney:    fuelUsage.TotalMoney,
+		CostBreakdown: toFuelUsageCostModel(fuelUsage.Cost),
+		SplitMode:     fuelUsage.SplitMode,
+		FareRule:      fuelUsage.FareRule,
+		Warnings:      []models.FuelUsageWarning{},
+	}
+	for _, fuelUsageUser := range fuelUsageUsers {
+		response.FuelUsers = append(response.FuelUsers, models.PreviewFuelUser{
+			UserID:     fuelUsageUser.UserID,
+			SplitValue: fuelUsageUser.SplitValue,
+			FareWeight: fuelUsageUser.FareWeight,
+			Amount:     fuelUsageUser.Amount,
+		})
+	}
+
+	if latestFuelRefill != nil {
+		response.LatestFuelPrice = latestFuelRefill.FuelPriceCalculated
+		if !fuelUsage.FuelPrice.Equal(latestFuelRefill.FuelPriceCalculated) {
+			response.Warnings = append(response.Warnings, models.FuelUsageWarning{
+				Code: models.FuelUsageWarningFuelPrice,
+				Message: fmt.Sprintf(
+					"fuelPrice [%s] differs from the latest fuel price [%s]",
+					fuelUsage.FuelPrice,
+					latestFuelRefill.FuelPriceCalculated,
+				),
+			})
+		}
+	}
+
+	latestKmAfterUse, found := latestKilometerAfterUse(latestFuelUsage, latestFuelRefill)
+	if found {
+		response.LatestKilometerAfterUse = latestKmAfterUse
+		response.Warnings = append(response.Warnings, kilometerWarnings(
+			req.KilometerBeforeUse,
+			latestKmAfterUse,
+			s.kilometerTolerance(),
+		)...)
+	}
+
+	return &response, nil
+}
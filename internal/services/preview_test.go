@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+func (stub *stubDatabaseAdaptor) GetLatestFuelUsageByCarID(ctx context.Context, carID int64) (*domains.FuelUsage, error) {
+	var latest *domains.FuelUsage
+	for i, fuelUsage := range stub.fuelUsages {
+		if fuelUsage.CarID == carID && (latest == nil || !fuelUsage.FuelUseTime.Before(latest.FuelUseTime)) {
+			latest = &stub.fuelUsages[i]
+		}
+	}
+	if latest == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return latest, nil
+}
+
+func (stub *stubDatabaseAdaptor) GetLatestFuelRefillByCarID(ctx context.Context, carID int64) (*domains.FuelRefill, error) {
+	var latest *domains.FuelRefill
+	for i, fuelRefill := range stub.fuelRefills {
+		if fuelRefill.CarID == carID && (latest == nil || !fuelRefill.RefillTime.Before(latest.RefillTime)) {
+			latest = &stub.fuelRefills[i]
+		}
+	}
+	if latest == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return latest, nil
+}
+
+func TestService_PreviewFuelUsage(t *testing.T) {
+	now := time.Now()
+	req := models.CreateFuelUsageRequest{
+		CurrentCarID: 1,
+		FuelUseTime:  now,
+		FuelPrice:    decimal.NewFromInt(2),
+		FuelUsers: []models.FuelUser{
+			{UserID: 1, IsPaid: true},
+			{UserID: 2, IsPaid: true},
+			{UserID: 3, IsPaid: true},
+		},
+		DriverUserID:       2,
+		KilometerBeforeUse: 800,
+		KilometerAfterUse:  750,
+	}
+	newStub := func() *stubDatabaseAdaptor {
+		return &stubDatabaseAdaptor{
+			cars: []domains.Car{{ID: 1, FareRule: domains.CarFareRuleDriverFree}},
+			fuelUsages: []domains.FuelUsage{
+				{ID: 1, CarID: 1, FuelUseTime: now.Add(-time.Hour), KilometerBeforeUse: 900, KilometerAfterUse: 800},
+			},
+			fuelRefills: []domains.FuelRefill{
+				{ID: 1, CarID: 1, RefillTime: now.Add(-2 * time.Hour), KilometerAfterRefill: 900, FuelPriceCalculated: decimal.NewFromInt(2)},
+			},
+		}
+	}
+
+	t.Run("prices without saving", func(t *testing.T) {
+		stub := newStub()
+		s := New(nil, stub, nil, nil)
+		response, err := s.PreviewFuelUsage(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !response.TotalMoney.Equal(decimal.NewFromInt(100)) {
+			t.Errorf("totalMoney = %s, want 100", response.TotalMoney)
+		}
+		if response.FareRule != domains.CarFareRuleDriverFree {
+			t.Errorf("fareRule = %s, want %s", response.FareRule, domains.CarFareRuleDriverFree)
+		}
+		wantAmounts := []string{"50", "0", "50"}
+		for i, fuelUser := range response.FuelUsers {
+			if !fuelUser.Amount.Equal(decimal.RequireFromString(wantAmounts[i])) {
+				t.Errorf("fuelUsers[%d].amount = %s, want %s", i, fuelUser.Amount, wantAmounts[i])
+			}
+		}
+		if !response.LatestFuelPrice.Equal(decimal.NewFromInt(2)) || response.LatestKilometerAfterUse != 800 {
+			t.Errorf("latest fuel info = %s, %d, want 2, 800", response.LatestFuelPrice, response.LatestKilometerAfterUse)
+		}
+		if len(response.Warnings) != 0 {
+			t.Errorf("warnings = %+v, want none", response.Warnings)
+		}
+		if len(stub.fuelUsages) != 1 || len(stub.ledgerEntries) != 0 {
+			t.Error("preview should not save anything")
+		}
+	})
+
+	t.Run("warns about a kilometer gap and another fuel price", func(t *testing.T) {
+		s := New(nil, newStub(), nil, nil)
+		req := req
+		req.FuelPrice = decimal.NewFromInt(3)
+		req.KilometerBeforeUse = 780
+		response, err := s.PreviewFuelUsage(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var codes []string
+		for _, warning := range response.Warnings {
+			codes = append(codes, warning.Code)
+		}
+		if len(codes) != 2 || codes[0] != models.FuelUsageWarningFuelPrice || codes[1] != models.FuelUsageWarningKilometerGap {
+			t.Errorf("warnings = %v, want [%s %s]", codes, models.FuelUsageWarningFuelPrice, models.FuelUsageWarningKilometerGap)
+		}
+	})
+
+	t.Run("car without records", func(t *testing.T) {
+		stub := newStub()
+		stub.fuelUsages, stub.fuelRefills = nil, nil
+		s := New(nil, stub, nil, nil)
+		response, err := s.PreviewFuelUsage(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Warnings) != 0 || !response.LatestFuelPrice.IsZero() {
+			t.Errorf("response = %+v, want no latest fuel info", response)
+		}
+	})
+
+	t.Run("invalid split", func(t *testing.T) {
+		s := New(nil, newStub(), nil, nil)
+		req := req
+		req.SplitMode = domains.FuelUsageSplitModePercentage
+		_, err := s.PreviewFuelUsage(context.Background(), req)
+		if !errors.Is(err, errs.ErrValidateFailed) {
+			t.Errorf("err = %v, want %v", err, errs.ErrValidateFailed)
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/promptpay"
+	"github.com/bosskrub9992/fuel-management-backend/library/qrcodes"
+	"github.com/shopspring/decimal"
+)
+
+// promptPayQRScale is the pixels of a QR module, a payload fits a QR of
+// about 45 modules with its quiet zone.
+const promptPayQRScale = 8
+
+// getPromptPays sums the unpaid shares of the user by their creditor, with
+// the PromptPay QR to transfer the sum. The QR is made here, nothing is sent
+// out.
+func (s *Service) getPromptPays(ctx context.Context, userID int64, userFuelUsages []FuelUsageUserWithFuelUsage) ([]models.PromptPay, error) {
+	var creditorUserIDs []int64
+	creditorUserIDToAmount := make(map[int64]decimal.Decimal)
+	for _, userFuelUsage := range userFuelUsages {
+		creditorUserID := userFuelUsage.CreditorUserID
+		if creditorUserID == 0 || creditorUserID == userID || !userFuelUsage.Outstanding().IsPositive() {
+			continue
+		}
+		if _, found := creditorUserIDToAmount[creditorUserID]; !found {
+			creditorUserIDs = append(creditorUserIDs, creditorUserID)
+		}
+		creditorUserIDToAmount[creditorUserID] = creditorUserIDToAmount[creditorUserID].Add(userFuelUsage.Outstanding())
+	}
+
+	promptPays := []models.PromptPay{}
+	for _, creditorUserID := range creditorUserIDs {
+		creditor, err := s.getUserByID(ctx, creditorUserID)
+		if err != nil {
+			return nil, err
+		}
+
+		promptPay := models.PromptPay{
+			CreditorUserID: creditorUserID,
+			Nickname:       creditor.Nickname,
+			Amount:         creditorUserIDToAmount[creditorUserID],
+		}
+
+		if creditor.PromptPayID != "" {
+			promptPay.Payload, err = promptpay.Payload(creditor.PromptPayID, promptPay.Amount)
+			if err != nil {
+				return nil, err
+			}
+			promptPay.QRCode, err = qrcodes.PNGDataURL([]byte(promptPay.Payload), promptPayQRScale)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		promptPays = append(promptPays, promptPay)
+	}
+
+	return promptPays, nil
+}
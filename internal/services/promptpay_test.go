@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/shopspring/decimal"
+)
+
+func TestService_getPromptPays(t *testing.T) {
+	s := New(nil, &stubDatabaseAdaptor{
+		users: []domains.User{
+			{ID: 1, Nickname: "Best"},
+			{ID: 2, Nickname: "Boss", PromptPayID: "0812345678"},
+			{ID: 3, Nickname: "Bank"},
+		},
+	}, nil, nil)
+
+	share := func(amount string, paidAmount string, creditorUserID int64) FuelUsageUserWithFuelUsage {
+		return FuelUsageUserWithFuelUsage{
+			FuelUsageUser: domains.FuelUsageUser{
+				UserID:     1,
+				Amount:     decimal.RequireFromString(amount),
+				PaidAmount: decimal.RequireFromString(paidAmount),
+			},
+			CreditorUserID: creditorUserID,
+		}
+	}
+
+	got, err := s.getPromptPays(context.Background(), 1, []FuelUsageUserWithFuelUsage{
+		share("100", "0", 2),
+		share("50.50", "20", 2),
+		share("80", "0", 3),
+		// the user fronted it or nobody did, there is no one to pay
+		share("30", "0", 1),
+		share("30", "0", 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("getPromptPays() = %+v, want 2 creditors", got)
+	}
+
+	boss := got[0]
+	if boss.CreditorUserID != 2 || !boss.Amount.Equal(decimal.RequireFromString("130.5")) {
+		t.Errorf("creditor = %d, amount = %s, want 2 and 130.5", boss.CreditorUserID, boss.Amount)
+	}
+	if !strings.Contains(boss.Payload, "5406130.50") || !strings.HasPrefix(boss.QRCode, "data:image/png;base64,") {
+		t.Errorf("payload = %s, qr code = %.30s", boss.Payload, boss.QRCode)
+	}
+
+	bank := got[1]
+	if bank.CreditorUserID != 3 || bank.Payload != "" || bank.QRCode != "" {
+		t.Errorf("a creditor without a PromptPay id got %+v", bank)
+	}
+}
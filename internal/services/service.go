@@ -14,18 +14,23 @@ import (
 	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
 	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
 	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/bosskrub9992/fuel-management-backend/library/jwts"
 	"github.com/shopspring/decimal"
 )
 
 type Service struct {
-	cfg *config.Config
-	db  DatabaseAdaptor
+	cfg     *config.Config
+	db      DatabaseAdaptor
+	jwt     *jwts.JWT
+	storage FileStorage
 }
 
-func New(cfg *config.Config, db DatabaseAdaptor) *Service {
+func New(cfg *config.Config, db DatabaseAdaptor, jwt *jwts.JWT, storage FileStorage) *Service {
 	return &Service{
-		cfg: cfg,
-		db:  db,
+		cfg:     cfg,
+		db:      db,
+		jwt:     jwt,
+		storage: storage,
 	}
 }
 
@@ -35,6 +40,24 @@ func (s *Service) DeleteFuelUsageByID(ctx context.Context, req models.DeleteFuel
 		return errs.ErrValidateFailed
 	}
 
+	fuelUsage, err := s.db.GetFuelUsageByID(ctx, req.FuelUsageID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+
+	fuelUsageUsers, err := s.db.GetFuelUsageUsersByFuelUsageID(ctx, req.FuelUsageID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+	if err := shouldNotBeClaimed(ctx, fuelUsageUsers); err != nil {
+		return err
+	}
+	if err := s.shouldNotBeLockedFuelUsage(ctx, *fuelUsage, fuelUsageUsers); err != nil {
+		return err
+	}
+
 	return s.db.Transaction(ctx, func(ctxTx context.Context) error {
 		if err := s.db.DeleteFuelUsageByID(ctxTx, req.FuelUsageID); err != nil {
 			slog.ErrorContext(ctxTx, err.Error())
@@ -46,6 +69,20 @@ func (s *Service) DeleteFuelUsageByID(ctx context.Context, req models.DeleteFuel
 			return err
 		}
 
+		now := time.Now()
+
+		err := s.reverseLedgerEntries(ctxTx, domains.LedgerReferenceTypeFuelUsage, req.FuelUsageID, now)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		err = s.reverseWalletTransactions(ctxTx, domains.WalletReferenceTypeFuelUsage, req.FuelUsageID, now)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
 		return nil
 	})
 }
@@ -62,33 +99,61 @@ func (s *Service) UpdateFuelUsage(ctx context.Context, req models.PutFuelUsageRe
 		return err
 	}
 
-	totalMoney, err := calculateTotalMoney(
-		req.KilometerBeforeUse,
-		req.KilometerAfterUse,
-		req.FuelPrice,
-	)
+	oldFuelUsageUsers, err := s.db.GetFuelUsageUsersByFuelUsageID(ctx, req.FuelUsageID)
 	if err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		return err
 	}
+	if err := shouldNotBeClaimed(ctx, oldFuelUsageUsers); err != nil {
+		return err
+	}
+	if err := s.shouldNotBeLockedFuelUsage(ctx, *oldfuelUsage, oldFuelUsageUsers); err != nil {
+		return err
+	}
 
-	payEach := calculatePayEach(totalMoney, len(req.FuelUsers))
+	var car *domains.Car
+	if req.CurrentCarID != oldfuelUsage.CarID {
+		car, err = s.getActiveCarByID(ctx, req.CurrentCarID)
+	} else {
+		car, err = s.getCarByID(ctx, req.CurrentCarID)
+	}
+	if err != nil {
+		return err
+	}
 
-	return s.db.Transaction(ctx, func(ctxTx context.Context) error {
-		fuelUsage := domains.FuelUsage{
-			ID:                 req.FuelUsageID,
-			CarID:              req.CurrentCarID,
-			FuelUseTime:        req.FuelUseTime,
-			FuelPrice:          req.FuelPrice,
-			KilometerBeforeUse: req.KilometerBeforeUse,
-			KilometerAfterUse:  req.KilometerAfterUse,
-			Description:        req.Description,
-			TotalMoney:         totalMoney,
-			PayEach:            payEach,
-			CreateTime:         oldfuelUsage.CreateTime,
-			UpdateTime:         time.Now(),
-		}
+	fuelRefillID, fuelPrice, err := s.resolveFuelRefill(ctx, req.CurrentCarID, req.FuelRefillID, req.FuelPrice)
+	if err != nil {
+		return err
+	}
 
+	now := time.Now()
+
+	fuelUsage := domains.FuelUsage{
+		ID:                 req.FuelUsageID,
+		CarID:              req.CurrentCarID,
+		FuelUseTime:        req.FuelUseTime,
+		FuelPrice:          fuelPrice,
+		FuelRefillID:       fuelRefillID,
+		KilometerBeforeUse: req.KilometerBeforeUse,
+		KilometerAfterUse:  req.KilometerAfterUse,
+		Description:        req.Description,
+		SplitMode:          cmp.Or(req.SplitMode, domains.FuelUsageSplitModeEqual),
+		DriverUserID:       req.DriverUserID,
+		IsPaidFromWallet:   oldfuelUsage.IsPaidFromWallet,
+		CreateTime:         oldfuelUsage.CreateTime,
+		UpdateTime:         now,
+	}
+
+	newFuelUsageUsers, err := s.priceFuelUsage(ctx, *car, &fuelUsage, req.FuelUsers)
+	if err != nil {
+		return err
+	}
+
+	if fuelUsage.IsPaidFromWallet {
+		payFuelUsageUsersFromWallet(newFuelUsageUsers)
+	}
+
+	return s.db.Transaction(ctx, func(ctxTx context.Context) error {
 		if err := s.db.UpdateFuelUsage(ctxTx, fuelUsage); err != nil {
 			slog.ErrorContext(ctxTx, err.Error())
 			return err
@@ -99,13 +164,8 @@ func (s *Service) UpdateFuelUsage(ctx context.Context, req models.PutFuelUsageRe
 			return err
 		}
 
-		newFuelUsageUsers := []domains.FuelUsageUser{}
-		for _, fuelUser := range req.FuelUsers {
-			newFuelUsageUsers = append(newFuelUsageUsers, domains.FuelUsageUser{
-				FuelUsageID: req.FuelUsageID,
-				UserID:      fuelUser.UserID,
-				IsPaid:      fuelUser.IsPaid,
-			})
+		for i := range newFuelUsageUsers {
+			newFuelUsageUsers[i].FuelUsageID = req.FuelUsageID
 		}
 
 		if err := s.db.CreateFuelUsageUsers(ctxTx, newFuelUsageUsers); err != nil {
@@ -113,50 +173,28 @@ func (s *Service) UpdateFuelUsage(ctx context.Context, req models.PutFuelUsageRe
 			return err
 		}
 
-		return nil
-	})
-}
-
-func (s *Service) GetUsers(ctx context.Context) (*models.GetUserData, error) {
-	users, err := s.db.GetAllUsers(ctx)
-	if err != nil {
-		slog.ErrorContext(ctx, err.Error())
-		return nil, err
-	}
-
-	var userData []models.GetUserDatum
-	for _, user := range users {
-		userData = append(userData, models.GetUserDatum{
-			ID:              user.ID,
-			DefaultCarID:    user.DefaultCarID,
-			Nickname:        user.Nickname,
-			ProfileImageURL: user.ProfileImageURL,
-		})
-	}
-
-	return &models.GetUserData{
-		Data: userData,
-	}, nil
-}
+		err := s.reverseLedgerEntries(ctxTx, domains.LedgerReferenceTypeFuelUsage, req.FuelUsageID, now)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
 
-func (s *Service) GetCars(ctx context.Context) (*models.GetCarData, error) {
-	cars, err := s.db.GetAllCars(ctx)
-	if err != nil {
-		slog.ErrorContext(ctx, err.Error())
-		return nil, err
-	}
+		err = s.createLedgerEntries(ctxTx, FuelUsageLedgerEntries(fuelUsage, newFuelUsageUsers, now))
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
 
-	var carData []models.CarDatum
-	for _, car := range cars {
-		carData = append(carData, models.CarDatum{
-			ID:   car.ID,
-			Name: car.Name,
-		})
-	}
+		if fuelUsage.IsPaidFromWallet {
+			err := s.replaceFuelUsageWalletTransactions(ctxTx, fuelUsage, newFuelUsageUsers, now)
+			if err != nil {
+				slog.ErrorContext(ctxTx, err.Error())
+				return err
+			}
+		}
 
-	return &models.GetCarData{
-		Data: carData,
-	}, nil
+		return nil
+	})
 }
 
 func (s *Service) GetFuelUsages(ctx context.Context, req models.GetFuelUsagesRequest) (*models.GetFuelUsagesResponse, error) {
@@ -213,51 +251,64 @@ func (s *Service) GetFuelUsages(ctx context.Context, req models.GetFuelUsagesReq
 	}, nil
 }
 
-func (s *Service) CreateFuelUsage(ctx context.Context, req models.CreateFuelUsageRequest) error {
+// CreateFuelUsage saves the fuel usage with the warnings of where it begins,
+// see checkKilometerContinuity.
+func (s *Service) CreateFuelUsage(ctx context.Context, req models.CreateFuelUsageRequest) (*models.CreateFuelUsageResponse, error) {
 	if err := req.Validate(); err != nil {
 		slog.ErrorContext(ctx, err.Error())
-		return errs.ErrValidateFailed
+		return nil, errs.ErrValidateFailed
 	}
 
-	totalMoney, err := calculateTotalMoney(
-		req.KilometerBeforeUse,
-		req.KilometerAfterUse,
-		req.FuelPrice,
-	)
+	car, err := s.getActiveCarByID(ctx, req.CurrentCarID)
 	if err != nil {
-		slog.ErrorContext(ctx, err.Error())
-		return err
+		return nil, err
 	}
 
-	payEach := calculatePayEach(totalMoney, len(req.FuelUsers))
+	fuelRefillID, fuelPrice, err := s.resolveFuelRefill(ctx, req.CurrentCarID, req.FuelRefillID, req.FuelPrice)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
 
 	fuelUsage := domains.FuelUsage{
 		CarID:              req.CurrentCarID,
 		FuelUseTime:        req.FuelUseTime,
-		FuelPrice:          req.FuelPrice,
+		FuelPrice:          fuelPrice,
+		FuelRefillID:       fuelRefillID,
 		KilometerBeforeUse: req.KilometerBeforeUse,
 		KilometerAfterUse:  req.KilometerAfterUse,
 		Description:        req.Description,
-		TotalMoney:         totalMoney,
-		PayEach:            payEach,
-		CreateTime:         time.Now(),
-		UpdateTime:         time.Now(),
+		SplitMode:          cmp.Or(req.SplitMode, domains.FuelUsageSplitModeEqual),
+		DriverUserID:       req.DriverUserID,
+		CreateTime:         now,
+		UpdateTime:         now,
 	}
 
-	return s.db.Transaction(ctx, func(ctxTx context.Context) error {
+	fuelUsageUsers, err := s.priceFuelUsage(ctx, *car, &fuelUsage, req.FuelUsers)
+	if err != nil {
+		return nil, err
+	}
+
+	if car.IsWalletEnabled {
+		fuelUsage.IsPaidFromWallet = true
+		payFuelUsageUsersFromWallet(fuelUsageUsers)
+	}
+
+	warnings, err := s.checkKilometerContinuity(ctx, req.CurrentCarID, req.KilometerBeforeUse)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(ctx, func(ctxTx context.Context) error {
 		fuelUsageID, err := s.db.CreateFuelUsage(ctxTx, fuelUsage)
 		if err != nil {
 			slog.ErrorContext(ctxTx, err.Error())
 			return err
 		}
 
-		var fuelUsageUsers []domains.FuelUsageUser
-		for _, fuelUser := range req.FuelUsers {
-			fuelUsageUsers = append(fuelUsageUsers, domains.FuelUsageUser{
-				FuelUsageID: fuelUsageID,
-				UserID:      fuelUser.UserID,
-				IsPaid:      fuelUser.IsPaid,
-			})
+		for i := range fuelUsageUsers {
+			fuelUsageUsers[i].FuelUsageID = fuelUsageID
 		}
 
 		if err := s.db.CreateFuelUsageUsers(ctxTx, fuelUsageUsers); err != nil {
@@ -265,8 +316,29 @@ func (s *Service) CreateFuelUsage(ctx context.Context, req models.CreateFuelUsag
 			return err
 		}
 
+		fuelUsage.ID = fuelUsageID
+		err = s.createLedgerEntries(ctxTx, FuelUsageLedgerEntries(fuelUsage, fuelUsageUsers, now))
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		err = s.createWalletTransactions(ctxTx, FuelUsageWalletTransactions(fuelUsage, fuelUsageUsers, now))
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CreateFuelUsageResponse{
+		ID:       fuelUsage.ID,
+		Warnings: append([]models.FuelUsageWarning{}, warnings...),
+	}, nil
 }
 
 func (s *Service) GetFuelUsageByID(ctx context.Context, req models.GetFuelUsageByIDRequest) (*models.GetFuelUsageByIDResponse, error) {
@@ -290,40 +362,134 @@ func (s *Service) GetFuelUsageByID(ctx context.Context, req models.GetFuelUsageB
 	fuelUsers := []models.GetFuelUser{}
 	for _, fuelUsageUser := range fuelUsageUsers {
 		fuelUsers = append(fuelUsers, models.GetFuelUser{
-			UserID:   fuelUsageUser.UserID,
-			Nickname: fuelUsageUser.Nickname,
-			IsPaid:   fuelUsageUser.IsPaid,
+			UserID:        fuelUsageUser.UserID,
+			Nickname:      fuelUsageUser.Nickname,
+			IsPaid:        fuelUsageUser.IsPaid,
+			SplitValue:    fuelUsageUser.SplitValue,
+			FareWeight:    fuelUsageUser.FareWeight,
+			Amount:        fuelUsageUser.Amount,
+			PaidAmount:    fuelUsageUser.PaidAmount,
+			ClaimedAmount: fuelUsageUser.ClaimedAmount,
+			PaymentStatus: domains.PaymentStatusOf(
+				fuelUsageUser.Amount,
+				fuelUsageUser.PaidAmount,
+				fuelUsageUser.ClaimedAmount,
+			),
 		})
 	}
 
 	response := models.GetFuelUsageByIDResponse{
 		FuelUseTime:        fuelUsage.FuelUseTime,
 		FuelPrice:          fuelUsage.FuelPrice,
+		FuelRefillID:       fuelUsage.FuelRefillID,
 		FuelUsers:          fuelUsers,
+		SplitMode:          fuelUsage.SplitMode,
+		DriverUserID:       fuelUsage.DriverUserID,
+		FareRule:           fuelUsage.FareRule,
 		Description:        fuelUsage.Description,
 		KilometerBeforeUse: fuelUsage.KilometerBeforeUse,
 		KilometerAfterUse:  fuelUsage.KilometerAfterUse,
 		TotalMoney:         fuelUsage.TotalMoney,
-		EachShouldPay:      fuelUsage.TotalMoney.DivRound(decimal.NewFromInt(int64(len(fuelUsageUsers))), 2),
+		CostBreakdown:      toFuelUsageCostModel(fuelUsage.Cost),
+		IsPaidFromWallet:   fuelUsage.IsPaidFromWallet,
 	}
 
 	return &response, nil
 }
 
-func calculateTotalMoney(kmBeforeUse, kmAfterUse int64, fuelPrice decimal.Decimal) (decimal.Decimal, error) {
+func toFuelUsageCostModel(cost domains.FuelUsageCost) models.FuelUsageCost {
+	return models.FuelUsageCost{
+		FuelCost:           cost.FuelCost,
+		DistanceFee:        cost.DistanceFee,
+		TripFee:            cost.TripFee,
+		MinimumChargeTopUp: cost.MinimumChargeTopUp,
+		RoundUp:            cost.RoundUp,
+	}
+}
+
+// priceFuelUsage sets the total money and the fare rule of the fuel usage by
+// the policies of the car, then returns the fuel users with their amounts,
+// nothing is saved.
+func (s *Service) priceFuelUsage(ctx context.Context, car domains.Car, fuelUsage *domains.FuelUsage, fuelUsers []models.FuelUser) ([]domains.FuelUsageUser, error) {
+	totalMoney, cost, err := calculateTotalMoney(
+		fuelUsage.KilometerBeforeUse,
+		fuelUsage.KilometerAfterUse,
+		fuelUsage.FuelPrice,
+		car.PricingPolicy,
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+	fuelUsage.TotalMoney = totalMoney
+	fuelUsage.Cost = cost
+
+	fuelUsageUsers := newFuelUsageUsers(fuelUsage.SplitMode, fuelUsers)
+	applyFareRule(fuelUsage, car, fuelUsageUsers)
+
+	if err := splitFuelUsage(*fuelUsage, fuelUsageUsers, s.splitTieBreak()); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	for i := range fuelUsageUsers {
+		if fuelUsageUsers[i].IsPaid {
+			fuelUsageUsers[i].PaidAmount = fuelUsageUsers[i].Amount
+		}
+	}
+
+	return fuelUsageUsers, nil
+}
+
+// shouldNotBeClaimed keeps a fuel usage with a payment waiting for
+// confirmation as it is, the payment would lose the shares it pays.
+func shouldNotBeClaimed(ctx context.Context, fuelUsageUsers []FuelUsageUser) error {
+	for _, fuelUsageUser := range fuelUsageUsers {
+		if fuelUsageUser.ClaimedAmount.IsPositive() {
+			slog.WarnContext(ctx, "a payment of the fuel usage waits for confirmation",
+				"fuelUsageId", fuelUsageUser.FuelUsageID,
+				"fuelUsageUserId", fuelUsageUser.ID,
+			)
+			return errs.ErrConflict
+		}
+	}
+	return nil
+}
+
+// calculateTotalMoney charges the fuel used, then the pricing policy of the
+// car on top, the breakdown sums to the total money.
+func calculateTotalMoney(
+	kmBeforeUse, kmAfterUse int64,
+	fuelPrice decimal.Decimal,
+	pricingPolicy domains.CarPricingPolicy,
+) (decimal.Decimal, domains.FuelUsageCost, error) {
 	if kmBeforeUse < kmAfterUse {
-		return decimal.Zero, fmt.Errorf(
+		return decimal.Zero, domains.FuelUsageCost{}, fmt.Errorf(
 			"kmBeforeUse should be > kmAfterUse, kmBeforeUse: [%d], kmAfterUse: [%d]",
 			kmBeforeUse,
 			kmAfterUse,
 		)
 	}
 	kmUsed := decimal.NewFromInt(kmBeforeUse - kmAfterUse)
-	return kmUsed.Mul(fuelPrice), nil
-}
 
-func calculatePayEach(totalMoney decimal.Decimal, numberOfUser int) decimal.Decimal {
-	return totalMoney.DivRound(decimal.NewFromInt(int64(numberOfUser)), 2)
+	cost := domains.FuelUsageCost{
+		FuelCost:    kmUsed.Mul(fuelPrice),
+		DistanceFee: kmUsed.Mul(pricingPolicy.ExtraPerKm),
+		TripFee:     pricingPolicy.TripFee,
+	}
+	totalMoney := cost.FuelCost.Add(cost.DistanceFee).Add(cost.TripFee)
+
+	if totalMoney.LessThan(pricingPolicy.MinimumCharge) {
+		cost.MinimumChargeTopUp = pricingPolicy.MinimumCharge.Sub(totalMoney)
+		totalMoney = pricingPolicy.MinimumCharge
+	}
+
+	if pricingPolicy.IsRoundedUp {
+		cost.RoundUp = totalMoney.Ceil().Sub(totalMoney)
+		totalMoney = totalMoney.Ceil()
+	}
+
+	return totalMoney, cost, nil
 }
 
 func (s *Service) GetFuelRefills(ctx context.Context, req models.GetFuelRefillRequest) (*models.GetFuelRefillResponse, error) {
@@ -357,7 +523,11 @@ func (s *Service) GetFuelRefills(ctx context.Context, req models.GetFuelRefillRe
 			TotalMoney:            fr.TotalMoney,
 			FuelPriceCalculated:   fr.FuelPriceCalculated,
 			IsPaid:                fr.IsPaid,
+			PaidAmount:            fr.PaidAmount,
+			ClaimedAmount:         fr.ClaimedAmount,
+			PaymentStatus:         domains.PaymentStatusOf(fr.TotalMoney, fr.PaidAmount, fr.ClaimedAmount),
 			RefillBy:              fr.RefillBy,
+			IsPaidFromWallet:      fr.IsPaidFromWallet,
 		})
 	}
 
@@ -370,6 +540,16 @@ func (s *Service) CreateFuelRefill(ctx context.Context, req models.CreateFuelRef
 		return errs.ErrValidateFailed
 	}
 
+	car, err := s.getActiveCarByID(ctx, req.CurrentCarID)
+	if err != nil {
+		return err
+	}
+
+	currentUserID, err := actingUserID(ctx)
+	if err != nil {
+		return err
+	}
+
 	fuelPrice, err := calculateFuelPrice(
 		req.TotalMoney,
 		req.KilometerBeforeRefill,
@@ -390,19 +570,42 @@ func (s *Service) CreateFuelRefill(ctx context.Context, req models.CreateFuelRef
 		KilometerAfterRefill:  req.KilometerAfterRefill,
 		FuelPriceCalculated:   fuelPrice,
 		IsPaid:                req.IsPaid,
+		PaidAmount:            decimal.Zero,
 		RefillBy:              req.RefillBy,
-		UpdateBy:              req.CurrentUserID,
-		CreateBy:              req.CurrentUserID,
+		UpdateBy:              currentUserID,
+		CreateBy:              currentUserID,
 		CreateTime:            now,
 		UpdateTime:            now,
 	}
-
-	if err := s.db.CreateFuelRefill(ctx, fuelRefill); err != nil {
-		slog.ErrorContext(ctx, err.Error())
-		return err
+	if car.IsWalletEnabled {
+		fuelRefill.IsPaidFromWallet = true
+		fuelRefill.IsPaid = true
+	}
+	if fuelRefill.IsPaid {
+		fuelRefill.PaidAmount = fuelRefill.TotalMoney
 	}
 
-	return nil
+	return s.db.Transaction(ctx, func(ctxTx context.Context) error {
+		fuelRefillID, err := s.db.CreateFuelRefill(ctxTx, fuelRefill)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		fuelRefill.ID = fuelRefillID
+		if err := s.createLedgerEntries(ctxTx, FuelRefillLedgerEntries(fuelRefill, now)); err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		err = s.createWalletTransactions(ctxTx, FuelRefillWalletTransactions(fuelRefill, now))
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		return nil
+	})
 }
 
 func (s *Service) GetFuelRefillByID(ctx context.Context, req models.GetFuelRefillByIDRequest) (*models.GetFuelRefillByIDResponse, error) {
@@ -424,20 +627,46 @@ func (s *Service) GetFuelRefillByID(ctx context.Context, req models.GetFuelRefil
 		TotalMoney:            fuelRefill.TotalMoney,
 		FuelPriceCalculated:   fuelRefill.FuelPriceCalculated,
 		IsPaid:                fuelRefill.IsPaid,
+		PaidAmount:            fuelRefill.PaidAmount,
+		ClaimedAmount:         fuelRefill.ClaimedAmount,
+		PaymentStatus:         domains.PaymentStatusOf(fuelRefill.TotalMoney, fuelRefill.PaidAmount, fuelRefill.ClaimedAmount),
 		RefillBy:              fuelRefill.RefillBy,
+		IsPaidFromWallet:      fuelRefill.IsPaidFromWallet,
 	}, nil
 }
 
-func (s *Service) UpdateFuelRefillByID(ctx context.Context, req models.PutFuelRefillByIDRequest) error {
+// UpdateFuelRefillByID can recalculate the fuel usages which took their fuel
+// price from the refill, see recalculateFuelUsages.
+func (s *Service) UpdateFuelRefillByID(ctx context.Context, req models.PutFuelRefillByIDRequest) (*models.PutFuelRefillByIDResponse, error) {
 	if err := req.Validate(); err != nil {
 		slog.ErrorContext(ctx, err.Error())
-		return errs.ErrValidateFailed
+		return nil, errs.ErrValidateFailed
+	}
+
+	currentUserID, err := actingUserID(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	oldFuelRefill, err := s.db.GetFuelRefillByID(ctx, req.FuelRefillID)
 	if err != nil {
 		slog.ErrorContext(ctx, err.Error())
-		return err
+		return nil, err
+	}
+	if oldFuelRefill.ClaimedAmount.IsPositive() {
+		slog.WarnContext(ctx, "a payment of the fuel refill waits for confirmation",
+			"fuelRefillId", req.FuelRefillID,
+		)
+		return nil, errs.ErrConflict
+	}
+	if err := s.shouldNotBeLockedFuelRefill(ctx, *oldFuelRefill); err != nil {
+		return nil, err
+	}
+
+	if req.CurrentCarID != oldFuelRefill.CarID {
+		if err := s.shouldBeActiveCar(ctx, req.CurrentCarID); err != nil {
+			return nil, err
+		}
 	}
 
 	newFuelPrice, err := calculateFuelPrice(
@@ -447,9 +676,11 @@ func (s *Service) UpdateFuelRefillByID(ctx context.Context, req models.PutFuelRe
 	)
 	if err != nil {
 		slog.ErrorContext(ctx, err.Error())
-		return err
+		return nil, err
 	}
 
+	now := time.Now()
+
 	newFuelRefill := domains.FuelRefill{
 		ID:                    req.FuelRefillID,
 		CarID:                 req.CurrentCarID,
@@ -459,19 +690,84 @@ func (s *Service) UpdateFuelRefillByID(ctx context.Context, req models.PutFuelRe
 		KilometerAfterRefill:  req.KilometerAfterRefill,
 		FuelPriceCalculated:   newFuelPrice,
 		IsPaid:                req.IsPaid,
+		PaidAmount:            decimal.Zero,
+		ClaimedAmount:         oldFuelRefill.ClaimedAmount,
 		RefillBy:              req.RefillBy,
+		IsPaidFromWallet:      oldFuelRefill.IsPaidFromWallet,
 		CreateBy:              oldFuelRefill.CreateBy,
 		CreateTime:            oldFuelRefill.CreateTime,
-		UpdateBy:              req.CurrentUserID,
-		UpdateTime:            time.Now(),
+		UpdateBy:              currentUserID,
+		UpdateTime:            now,
+	}
+	if newFuelRefill.IsPaidFromWallet {
+		newFuelRefill.IsPaid = true
+	}
+	if newFuelRefill.IsPaid {
+		newFuelRefill.PaidAmount = newFuelRefill.TotalMoney
 	}
 
-	if err := s.db.UpdateFuelRefill(ctx, newFuelRefill); err != nil {
-		slog.ErrorContext(ctx, err.Error())
-		return err
+	response := models.PutFuelRefillByIDResponse{
+		RecalculatedFuelUsages: []models.RecalculatedFuelUsage{},
+		SkippedFuelUsages:      []models.SkippedFuelUsage{},
 	}
 
-	return nil
+	recalculateFuelUsages := cmp.Or(req.RecalculateFuelUsages, models.RecalculateFuelUsagesNone)
+	isFuelPriceChanged := !newFuelPrice.Equal(oldFuelRefill.FuelPriceCalculated)
+
+	err = s.db.Transaction(ctx, func(ctxTx context.Context) error {
+		if err := s.db.UpdateFuelRefill(ctxTx, newFuelRefill); err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		err := s.reverseLedgerEntries(ctxTx, domains.LedgerReferenceTypeFuelRefill, req.FuelRefillID, now)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		if err := s.createLedgerEntries(ctxTx, FuelRefillLedgerEntries(newFuelRefill, now)); err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		if newFuelRefill.IsPaidFromWallet {
+			err := s.reverseWalletTransactions(ctxTx, domains.WalletReferenceTypeFuelRefill, req.FuelRefillID, now)
+			if err != nil {
+				slog.ErrorContext(ctxTx, err.Error())
+				return err
+			}
+
+			err = s.createWalletTransactions(ctxTx, FuelRefillWalletTransactions(newFuelRefill, now))
+			if err != nil {
+				slog.ErrorContext(ctxTx, err.Error())
+				return err
+			}
+		}
+
+		if recalculateFuelUsages == models.RecalculateFuelUsagesNone || !isFuelPriceChanged {
+			return nil
+		}
+
+		recalculated, err := s.recalculateFuelUsages(
+			ctxTx,
+			newFuelRefill,
+			recalculateFuelUsages == models.RecalculateFuelUsagesAll,
+			now,
+		)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+		response = *recalculated
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
 }
 
 func calculateFuelPrice(
@@ -493,12 +789,51 @@ func (s *Service) DeleteFuelRefillByID(ctx context.Context, req models.DeleteFue
 		return errs.ErrValidateFailed
 	}
 
-	if err := s.db.DeleteFuelRefillByID(ctx, req.FuelRefillID); err != nil {
+	fuelRefill, err := s.db.GetFuelRefillByID(ctx, req.FuelRefillID)
+	if err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		return err
 	}
+	if fuelRefill.ClaimedAmount.IsPositive() {
+		slog.WarnContext(ctx, "a payment of the fuel refill waits for confirmation",
+			"fuelRefillId", req.FuelRefillID,
+		)
+		return errs.ErrConflict
+	}
+	if err := s.shouldNotBeLockedFuelRefill(ctx, *fuelRefill); err != nil {
+		return err
+	}
 
-	return nil
+	return s.db.Transaction(ctx, func(ctxTx context.Context) error {
+		if err := s.db.DeleteFuelRefillByID(ctxTx, req.FuelRefillID); err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		// the fuel usages keep the fuel price as if it was given by hand
+		if err := s.db.UnsetFuelUsagesFuelRefillID(ctxTx, req.FuelRefillID); err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		now := time.Now()
+
+		err := s.reverseLedgerEntries(ctxTx, domains.LedgerReferenceTypeFuelRefill, req.FuelRefillID, now)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		if fuelRefill.IsPaidFromWallet {
+			err := s.reverseWalletTransactions(ctxTx, domains.WalletReferenceTypeFuelRefill, req.FuelRefillID, now)
+			if err != nil {
+				slog.ErrorContext(ctxTx, err.Error())
+				return err
+			}
+		}
+
+		return nil
+	})
 }
 
 func (s *Service) GetLatestFuelInfoResponse(ctx context.Context, req models.GetLatestFuelInfoRequest) (*models.GetLatestFuelInfoResponse, error) {
@@ -519,13 +854,11 @@ func (s *Service) GetLatestFuelInfoResponse(ctx context.Context, req models.GetL
 		return nil, err
 	}
 
-	var latestKmAfterUse = latestFuelUsage.KilometerAfterUse
-	if latestFuelRefill.RefillTime.After(latestFuelUsage.FuelUseTime) {
-		latestKmAfterUse = latestFuelRefill.KilometerAfterRefill
-	}
+	latestKmAfterUse, _ := latestKilometerAfterUse(latestFuelUsage, latestFuelRefill)
 
 	return &models.GetLatestFuelInfoResponse{
 		LatestFuelPrice:         latestFuelRefill.FuelPriceCalculated,
+		LatestFuelRefillID:      latestFuelRefill.ID,
 		LatestKilometerAfterUse: latestKmAfterUse,
 	}, nil
 }
@@ -600,9 +933,15 @@ func (s *Service) GetUserFuelUsages(ctx context.Context, req models.GetUserFuelU
 			FuelUsageID:     u.FuelUsageID,
 			FuelUsageUserID: u.ID,
 			FuelUseTime:     u.FuelUseTime.Format("_2 Jan 15:04"),
-			PayEach:         u.PayEach,
+			PayEach:         u.Amount,
+			PaidAmount:      u.PaidAmount,
+			ClaimedAmount:   u.ClaimedAmount,
+			PaymentStatus:   domains.PaymentStatusOf(u.Amount, u.PaidAmount, u.ClaimedAmount),
 			Description:     u.Description,
 			FuelUsers:       fuelUsers,
+			DriverUserID:    u.DriverUserID,
+			FareRule:        u.FareRule,
+			FareWeight:      u.FareWeight,
 		})
 	}
 
@@ -626,14 +965,41 @@ func (s *Service) GetUserCarUnpaidActivities(ctx context.Context, req models.Get
 		return nil, errs.ErrValidateFailed
 	}
 
-	var unpaidFuelUsages = []models.FuelUsage{}
-
 	userFuelUsages, err := s.db.GetUserFuelUsagesByPaidStatus(ctx, req.UserID, false, req.CarID)
 	if err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		return nil, err
 	}
 
+	unpaidFuelUsages, err := s.toUserFuelUsageModels(ctx, userFuelUsages)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	userUnpaidFuelRefills, err := s.db.GetUserUnpaidFuelRefills(ctx, req.UserID, req.CarID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	response := models.GetUserCarUnpaidActivitiesResponse{
+		FuelUsages:  unpaidFuelUsages,
+		FuelRefills: toUserFuelRefillModels(userUnpaidFuelRefills),
+	}
+
+	if req.IsPromptPayIncluded {
+		response.PromptPays, err = s.getPromptPays(ctx, req.UserID, userFuelUsages)
+		if err != nil {
+			slog.ErrorContext(ctx, err.Error())
+			return nil, err
+		}
+	}
+
+	return &response, nil
+}
+
+func (s *Service) toUserFuelUsageModels(ctx context.Context, userFuelUsages []FuelUsageUserWithFuelUsage) ([]models.FuelUsage, error) {
 	fuelUsageIDs := []int64{}
 	for _, userFuelUsage := range userFuelUsages {
 		fuelUsageIDs = append(fuelUsageIDs, userFuelUsage.FuelUsageID)
@@ -646,54 +1012,65 @@ func (s *Service) GetUserCarUnpaidActivities(ctx context.Context, req models.Get
 
 	fuelUsageIDToFuelUsers := getMapFuelUsageIDToFuelUsers(fuelUsageUsers)
 
+	var fuelUsages = []models.FuelUsage{}
 	for _, u := range userFuelUsages {
 		fuelUsers, foundFuelUsageID := fuelUsageIDToFuelUsers[u.FuelUsageID]
 		if !foundFuelUsageID {
 			return nil, fmt.Errorf("not found fuelUsageId: '%d'", u.FuelUsageID)
 		}
-		unpaidFuelUsages = append(unpaidFuelUsages, models.FuelUsage{
+		fuelUsages = append(fuelUsages, models.FuelUsage{
 			FuelUsageID:     u.FuelUsageID,
 			FuelUsageUserID: u.ID,
 			FuelUseTime:     u.FuelUseTime.Format("_2 Jan 15:04"),
-			PayEach:         u.PayEach,
+			PayEach:         u.Amount,
+			PaidAmount:      u.PaidAmount,
+			ClaimedAmount:   u.ClaimedAmount,
+			PaymentStatus:   domains.PaymentStatusOf(u.Amount, u.PaidAmount, u.ClaimedAmount),
 			Description:     u.Description,
 			FuelUsers:       fuelUsers,
+			DriverUserID:    u.DriverUserID,
+			FareRule:        u.FareRule,
+			FareWeight:      u.FareWeight,
 		})
 	}
 
-	var unpaidFuelRefills = []models.FuelRefill{}
-
-	userUnpaidFuelRefills, err := s.db.GetUserUnpaidFuelRefills(ctx, req.UserID, req.CarID)
-	if err != nil {
-		slog.ErrorContext(ctx, err.Error())
-		return nil, err
-	}
+	return fuelUsages, nil
+}
 
-	for _, fr := range userUnpaidFuelRefills {
+func toUserFuelRefillModels(fuelRefills []domains.FuelRefill) []models.FuelRefill {
+	var userFuelRefills = []models.FuelRefill{}
+	for _, fr := range fuelRefills {
 		isPaid := "❌"
 		if fr.IsPaid {
 			isPaid = "✅"
 		}
-		unpaidFuelRefills = append(unpaidFuelRefills, models.FuelRefill{
-			FuelRefillID: fr.ID,
-			RefillTime:   fr.RefillTime.Format("_2 Jan 15:04"),
-			IsPaid:       isPaid,
-			TotalMoney:   fr.TotalMoney,
+		userFuelRefills = append(userFuelRefills, models.FuelRefill{
+			FuelRefillID:  fr.ID,
+			RefillTime:    fr.RefillTime.Format("_2 Jan 15:04"),
+			IsPaid:        isPaid,
+			TotalMoney:    fr.TotalMoney,
+			PaidAmount:    fr.PaidAmount,
+			ClaimedAmount: fr.ClaimedAmount,
+			PaymentStatus: domains.PaymentStatusOf(fr.TotalMoney, fr.PaidAmount, fr.ClaimedAmount),
 		})
 	}
-
-	return &models.GetUserCarUnpaidActivitiesResponse{
-		FuelUsages:  unpaidFuelUsages,
-		FuelRefills: unpaidFuelRefills,
-	}, nil
+	return userFuelRefills
 }
 
+// BulkUpdateUserFuelUsagePaymentStatus sets the shares of the user unpaid at
+// once, while a share set paid is only claimed paid until its creditor
+// confirms the payment. A paid share stays paid, see
+// shouldNotUnpayLockedFuelUsages.
 func (s *Service) BulkUpdateUserFuelUsagePaymentStatus(ctx context.Context, req models.BulkUpdateUserFuelUsagePaymentStatusRequest) error {
 	if err := req.Validate(); err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		return errs.ErrValidateFailed
 	}
 
+	if err := shouldActAsUser(ctx, req.UserID); err != nil {
+		return err
+	}
+
 	actualUserFuelUsages, err := s.db.GetUserFuelUsageByUserID(ctx, req.UserID)
 	if err != nil {
 		slog.ErrorContext(ctx, err.Error())
@@ -712,74 +1089,152 @@ func (s *Service) BulkUpdateUserFuelUsagePaymentStatus(ctx context.Context, req
 				userFuelUsage.ID,
 			)
 			slog.ErrorContext(ctx, err.Error())
-			return errs.ErrValidateFailed
+			return errs.ErrForbidden
 		}
 	}
 
-	var userFuelUsages []domains.FuelUsageUser
+	var unpaidUserFuelUsages []domains.FuelUsageUser
+	var paidFuelUsageUserIDs []int64
 	for _, userFuelUsage := range req.UserFuelUsages {
-		userFuelUsages = append(userFuelUsages, domains.FuelUsageUser{
+		if userFuelUsage.IsPaid {
+			paidFuelUsageUserIDs = append(paidFuelUsageUserIDs, userFuelUsage.ID)
+			continue
+		}
+		unpaidUserFuelUsages = append(unpaidUserFuelUsages, domains.FuelUsageUser{
 			ID:     userFuelUsage.ID,
-			IsPaid: userFuelUsage.IsPaid,
+			IsPaid: false,
 		})
 	}
 
+	if err := s.shouldNotBePaidFromWallet(ctx, unpaidUserFuelUsages); err != nil {
+		return err
+	}
+
+	if err := s.shouldNotUnpayLockedFuelUsages(ctx, unpaidUserFuelUsages); err != nil {
+		return err
+	}
+
+	now := time.Now()
+
 	return s.db.Transaction(ctx, func(ctxTx context.Context) error {
-		for _, userFuelUsage := range userFuelUsages {
+		for _, userFuelUsage := range unpaidUserFuelUsages {
+			err := s.postFuelUsageUserPaymentStatus(ctxTx, []int64{userFuelUsage.ID}, userFuelUsage.IsPaid, now)
+			if err != nil {
+				slog.ErrorContext(ctx, err.Error())
+				return err
+			}
 			if err := s.db.UpdateUserFuelUsagePaymentStatus(ctxTx, userFuelUsage); err != nil {
 				slog.ErrorContext(ctx, err.Error())
 				return err
 			}
 		}
+
+		if len(paidFuelUsageUserIDs) == 0 {
+			return nil
+		}
+
+		// the user only claims the shares are paid, their creditors confirm
+		fuelUsageUsers, err := s.db.GetFuelUsageUsersWithFuelUsageByIDs(ctxTx, paidFuelUsageUserIDs)
+		if err != nil {
+			slog.ErrorContext(ctx, err.Error())
+			return err
+		}
+		items := fuelUsageUserPaymentItems(fuelUsageUsers)
+		_, err = s.payFuelUsageUserItems(ctxTx, req.UserID, items, domains.PaymentMethodCash, now)
+		if err != nil {
+			slog.ErrorContext(ctx, err.Error())
+			return err
+		}
 		return nil
 	})
 }
 
-func (s *Service) PayUserCarUnpaidActivities(ctx context.Context, req models.PayUserCarUnpaidActivitiesRequest) error {
+// PayUserCarUnpaidActivities pays the picked shares and refills in full, with
+// a payment of the user to each creditor of the shares and a payment of the
+// car to the user for the refills. A share payment waits for its creditor to
+// confirm it.
+func (s *Service) PayUserCarUnpaidActivities(ctx context.Context, req models.PayUserCarUnpaidActivitiesRequest) (*models.PayUserCarUnpaidActivitiesResponse, error) {
 	if err := req.Validate(); err != nil {
 		slog.ErrorContext(ctx, err.Error())
-		return errs.ErrValidateFailed
+		return nil, errs.ErrValidateFailed
 	}
 
-	IsUserOwnAllFuelUsageUser, err := s.db.IsUserOwnAllFuelUsageUser(ctx, req.UserID, req.FuelUsageUserIDs)
+	err := s.authorizePayment(ctx, paymentScope{
+		UserID:           req.UserID,
+		CarID:            req.CarID,
+		FuelUsageUserIDs: req.FuelUsageUserIDs,
+		FuelRefillIDs:    req.FuelRefillIDs,
+	})
 	if err != nil {
-		slog.ErrorContext(ctx, err.Error())
-		return err
+		return nil, err
 	}
 
-	if !IsUserOwnAllFuelUsageUser {
-		slog.ErrorContext(ctx, errors.New("there is fuel usage user that user not own").Error(),
-			"userId", req.UserID,
-			"fuelUsageUserIds", req.FuelUsageUserIDs,
-		)
-		return errs.ErrValidateFailed
-	}
+	now := time.Now()
+	method := cmp.Or(req.Method, domains.PaymentMethodCash)
 
-	IsUserOwnAllFuelRefills, err := s.db.IsUserOwnAllFuelRefills(ctx, req.UserID, req.FuelRefillIDs)
-	if err != nil {
-		slog.ErrorContext(ctx, err.Error())
-		return err
+	response := models.PayUserCarUnpaidActivitiesResponse{
+		Payments: []models.Payment{},
 	}
 
-	if !IsUserOwnAllFuelRefills {
-		slog.ErrorContext(ctx, errors.New("there is fuel refill that user not own").Error(),
-			"userId", req.UserID,
-			"fuelRefills", req.FuelRefillIDs,
-		)
-		return errs.ErrValidateFailed
-	}
+	// the items are locked as they are read, so a payment made meanwhile
+	// cannot pay them again
+	err = s.db.Transaction(ctx, func(ctxTx context.Context) error {
+		fuelUsageUserItems, err := s.getPickedPaymentItems(ctxTx, req.FuelUsageUserIDs, nil)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
 
-	return s.db.Transaction(ctx, func(ctxTx context.Context) error {
-		if err := s.db.PayFuelUsageUsers(ctxTx, req.FuelUsageUserIDs); err != nil {
-			slog.ErrorContext(ctx, err.Error())
+		fuelRefillItems, err := s.getPickedPaymentItems(ctxTx, nil, req.FuelRefillIDs)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
 			return err
 		}
 
-		if err := s.db.PayFuelRefills(ctxTx, req.FuelRefillIDs); err != nil {
-			slog.ErrorContext(ctx, err.Error())
+		payments, err := s.payFuelUsageUserItems(ctxTx, req.UserID, fuelUsageUserItems, method, now)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+		response.Payments = append(response.Payments, payments...)
+
+		amount := decimal.Zero
+		for _, item := range fuelRefillItems {
+			amount = amount.Add(item.Outstanding)
+		}
+		if amount.IsZero() {
+			return nil
+		}
+
+		allocations, err := allocatePayment(amount, fuelRefillItems)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		// the user is reimbursed, so the user confirms it
+		payment, err := s.createPayment(ctxTx, domains.Payment{
+			CarID:         req.CarID,
+			PayeeUserID:   req.UserID,
+			Amount:        amount,
+			Method:        method,
+			PayTime:       now,
+			Status:        domains.PaymentConfirmStatusConfirmed,
+			ConfirmUserID: req.UserID,
+			CreateBy:      req.UserID,
+			CreateTime:    now,
+		}, allocations, fuelRefillItems, now)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
 			return err
 		}
 
+		response.Payments = append(response.Payments, toPaymentModel(*payment, allocations))
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
 }
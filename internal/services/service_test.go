@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/shopspring/decimal"
 )
 
 func Test_getMapFuelUsageIDToFuelUsers(t *testing.T) {
@@ -99,3 +100,66 @@ func Test_getMapFuelUsageIDToFuelUsers(t *testing.T) {
 		})
 	}
 }
+
+func Test_calculateTotalMoney(t *testing.T) {
+	tests := []struct {
+		name           string
+		pricingPolicy  domains.CarPricingPolicy
+		wantTotalMoney string
+		wantCost       domains.FuelUsageCost
+	}{
+		{
+			name:           "fuel cost only",
+			wantTotalMoney: "35.5",
+			wantCost:       domains.FuelUsageCost{FuelCost: decimal.RequireFromString("35.5")},
+		},
+		{
+			name: "extra per km and trip fee",
+			pricingPolicy: domains.CarPricingPolicy{
+				ExtraPerKm: decimal.RequireFromString("0.5"),
+				TripFee:    decimal.NewFromInt(10),
+			},
+			wantTotalMoney: "50.5",
+			wantCost: domains.FuelUsageCost{
+				FuelCost:    decimal.RequireFromString("35.5"),
+				DistanceFee: decimal.NewFromInt(5),
+				TripFee:     decimal.NewFromInt(10),
+			},
+		},
+		{
+			name: "minimum charge then round up",
+			pricingPolicy: domains.CarPricingPolicy{
+				MinimumCharge: decimal.RequireFromString("40.2"),
+				IsRoundedUp:   true,
+			},
+			wantTotalMoney: "41",
+			wantCost: domains.FuelUsageCost{
+				FuelCost:           decimal.RequireFromString("35.5"),
+				MinimumChargeTopUp: decimal.RequireFromString("4.7"),
+				RoundUp:            decimal.RequireFromString("0.8"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			totalMoney, cost, err := calculateTotalMoney(110, 100, decimal.RequireFromString("3.55"), tt.pricingPolicy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !totalMoney.Equal(decimal.RequireFromString(tt.wantTotalMoney)) {
+				t.Errorf("totalMoney = %s, want %s", totalMoney, tt.wantTotalMoney)
+			}
+			if !cost.FuelCost.Equal(tt.wantCost.FuelCost) ||
+				!cost.DistanceFee.Equal(tt.wantCost.DistanceFee) ||
+				!cost.TripFee.Equal(tt.wantCost.TripFee) ||
+				!cost.MinimumChargeTopUp.Equal(tt.wantCost.MinimumChargeTopUp) ||
+				!cost.RoundUp.Equal(tt.wantCost.RoundUp) {
+				t.Errorf("cost = %+v, want %+v", cost, tt.wantCost)
+			}
+		})
+	}
+
+	if _, _, err := calculateTotalMoney(100, 110, decimal.NewFromInt(1), domains.CarPricingPolicy{}); err == nil {
+		t.Error("kmBeforeUse < kmAfterUse should fail")
+	}
+}
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/shopspring/decimal"
+)
+
+// settlementProposal nets what is left to pay on the fuel usage shares of a
+// user against what is left to reimburse on the refills the same user
+// fronted for a car.
+type settlementProposal struct {
+	FuelUsageAmount    decimal.Decimal
+	FuelRefillAmount   decimal.Decimal
+	OffsetFuelUsages   []FuelUsageUserWithFuelUsage
+	OffsetFuelRefills  []domains.FuelRefill
+	RemainingAmount    decimal.Decimal
+	RemainingDirection string
+}
+
+func (p settlementProposal) isEmpty() bool {
+	return len(p.OffsetFuelUsages) == 0 && len(p.OffsetFuelRefills) == 0
+}
+
+// proposeSettlement offsets every item of the smaller side and the oldest
+// items of the larger side until they cover it. The part of the last covering
+// item that is left over becomes the remaining amount, which stays
+// outstanding on that item, and every item after it stays unpaid. Both
+// inputs should be sorted from oldest to newest.
+func proposeSettlement(fuelUsages []FuelUsageUserWithFuelUsage, fuelRefills []domains.FuelRefill) settlementProposal {
+	proposal := settlementProposal{
+		FuelUsageAmount:    decimal.Zero,
+		FuelRefillAmount:   decimal.Zero,
+		OffsetFuelUsages:   []FuelUsageUserWithFuelUsage{},
+		OffsetFuelRefills:  []domains.FuelRefill{},
+		RemainingAmount:    decimal.Zero,
+		RemainingDirection: domains.SettlementDirectionNone,
+	}
+	for _, fu := range fuelUsages {
+		proposal.FuelUsageAmount = proposal.FuelUsageAmount.Add(fu.Outstanding())
+	}
+	for _, fr := range fuelRefills {
+		proposal.FuelRefillAmount = proposal.FuelRefillAmount.Add(fr.Outstanding())
+	}
+
+	if proposal.FuelUsageAmount.IsZero() || proposal.FuelRefillAmount.IsZero() {
+		return proposal
+	}
+
+	if proposal.FuelUsageAmount.GreaterThanOrEqual(proposal.FuelRefillAmount) {
+		proposal.OffsetFuelRefills = fuelRefills
+		covered := decimal.Zero
+		for _, fu := range fuelUsages {
+			if covered.GreaterThanOrEqual(proposal.FuelRefillAmount) {
+				break
+			}
+			covered = covered.Add(fu.Outstanding())
+			proposal.OffsetFuelUsages = append(proposal.OffsetFuelUsages, fu)
+		}
+		proposal.RemainingAmount = covered.Sub(proposal.FuelRefillAmount)
+		if proposal.RemainingAmount.IsPositive() {
+			proposal.RemainingDirection = domains.SettlementDirectionUserPays
+		}
+		return proposal
+	}
+
+	proposal.OffsetFuelUsages = fuelUsages
+	covered := decimal.Zero
+	for _, fr := range fuelRefills {
+		if covered.GreaterThanOrEqual(proposal.FuelUsageAmount) {
+			break
+		}
+		covered = covered.Add(fr.Outstanding())
+		proposal.OffsetFuelRefills = append(proposal.OffsetFuelRefills, fr)
+	}
+	proposal.RemainingAmount = covered.Sub(proposal.FuelUsageAmount)
+	if proposal.RemainingAmount.IsPositive() {
+		proposal.RemainingDirection = domains.SettlementDirectionUserReceives
+	}
+	return proposal
+}
+
+// paymentItemQueue hands out what is left to pay on the items in order, so
+// the items a settlement takes are not taken again by the next payment.
+type paymentItemQueue []paymentItem
+
+// take allocates the amount to the first items and keeps the rest of them
+// in the queue.
+func (q *paymentItemQueue) take(amount decimal.Decimal) ([]domains.PaymentAllocation, []paymentItem, error) {
+	items := *q
+	allocations, err := allocatePayment(amount, items)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var left []paymentItem
+	for i, item := range items {
+		if i < len(allocations) {
+			item.Outstanding = item.Outstanding.Sub(allocations[i].Amount)
+		}
+		if item.Outstanding.IsPositive() {
+			left = append(left, item)
+		}
+	}
+	*q = left
+
+	return allocations, items[:len(allocations)], nil
+}
+
+// createSettlementPayments pays the amount of the shares taken from the
+// shares and reimburses the same amount of the refills taken from the
+// refills, with a pending payment for each car and payee which claims them
+// until the payee confirms. The payee is the one of the given payment, or
+// the creditor of each share when it has none.
+func (s *Service) createSettlementPayments(
+	ctx context.Context,
+	payment domains.Payment,
+	amount decimal.Decimal,
+	shares *paymentItemQueue,
+	refills *paymentItemQueue,
+	now time.Time,
+) ([]models.Payment, error) {
+	shareAllocations, shareItems, err := shares.take(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	type paymentKey struct {
+		carID       int64
+		payeeUserID int64
+	}
+	var keys []paymentKey
+	keyToAllocations := make(map[paymentKey][]domains.PaymentAllocation)
+	keyToItems := make(map[paymentKey][]paymentItem)
+	for i, allocation := range shareAllocations {
+		key := paymentKey{shareItems[i].CarID, cmp.Or(payment.PayeeUserID, shareItems[i].CreditorUserID)}
+		if _, found := keyToAllocations[key]; !found {
+			keys = append(keys, key)
+		}
+		keyToAllocations[key] = append(keyToAllocations[key], allocation)
+		keyToItems[key] = append(keyToItems[key], shareItems[i])
+	}
+
+	payments := []models.Payment{}
+	for _, key := range keys {
+		keyAmount := decimal.Zero
+		for _, allocation := range keyToAllocations[key] {
+			keyAmount = keyAmount.Add(allocation.Amount)
+		}
+
+		refillAllocations, refillItems, err := refills.take(keyAmount)
+		if err != nil {
+			return nil, err
+		}
+
+		status, err := confirmStatusOf(payment.CreateBy, key.payeeUserID)
+		if err != nil {
+			slog.ErrorContext(ctx, err.Error(), "carId", key.carID)
+			return nil, errs.ErrValidateFailed
+		}
+
+		keyPayment := payment
+		keyPayment.CarID = key.carID
+		keyPayment.PayeeUserID = key.payeeUserID
+		if keyPayment.PayeeUserID == keyPayment.PayerUserID {
+			keyPayment.PayeeUserID = 0
+		}
+		keyPayment.Amount = keyAmount
+		keyPayment.Status = status
+		keyPayment.ConfirmUserID = key.payeeUserID
+
+		allocations := append(keyToAllocations[key], refillAllocations...)
+		items := append(keyToItems[key], refillItems...)
+
+		created, err := s.createPayment(ctx, keyPayment, allocations, items, now)
+		if err != nil {
+			return nil, err
+		}
+
+		payments = append(payments, toPaymentModel(*created, allocations))
+	}
+	return payments, nil
+}
+
+func (s *Service) getSettlementProposal(ctx context.Context, userID, carID int64) (*settlementProposal, error) {
+	userFuelUsages, err := s.db.GetUserFuelUsagesByPaidStatus(ctx, userID, false, carID)
+	if err != nil {
+		return nil, err
+	}
+
+	userUnpaidFuelRefills, err := s.db.GetUserUnpaidFuelRefills(ctx, userID, carID)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposeSettlement(userFuelUsages, userUnpaidFuelRefills)
+	return &proposal, nil
+}
+
+func (s *Service) GetUserCarSettlement(ctx context.Context, req models.GetUserCarSettlementRequest) (*models.GetUserCarSettlementResponse, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	proposal, err := s.getSettlementProposal(ctx, req.UserID, req.CarID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	offsetFuelUsages, err := s.toUserFuelUsageModels(ctx, proposal.OffsetFuelUsages)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	return &models.GetUserCarSettlementResponse{
+		FuelUsageAmount:    proposal.FuelUsageAmount,
+		FuelRefillAmount:   proposal.FuelRefillAmount,
+		NetAmount:          proposal.FuelRefillAmount.Sub(proposal.FuelUsageAmount),
+		OffsetFuelUsages:   offsetFuelUsages,
+		OffsetFuelRefills:  toUserFuelRefillModels(proposal.OffsetFuelRefills),
+		RemainingAmount:    proposal.RemainingAmount,
+		RemainingDirection: proposal.RemainingDirection,
+	}, nil
+}
+
+func (s *Service) CreateUserCarSettlement(ctx context.Context, req models.PostUserCarSettlementRequest) (*models.PostUserCarSettlementResponse, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	if err := shouldActAsUser(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+
+	var response models.PostUserCarSettlementResponse
+
+	now := time.Now()
+
+	err := s.db.Transaction(ctx, func(ctxTx context.Context) error {
+		proposal, err := s.getSettlementProposal(ctxTx, req.UserID, req.CarID)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		if proposal.isEmpty() {
+			slog.WarnContext(ctxTx, errors.New("nothing to settle").Error(),
+				"userId", req.UserID,
+				"carId", req.CarID,
+			)
+			return errs.ErrValidateFailed
+		}
+
+		settlementID, err := s.db.CreateSettlement(ctxTx, domains.Settlement{
+			UserID:             req.UserID,
+			CarID:              req.CarID,
+			FuelUsageAmount:    proposal.FuelUsageAmount,
+			FuelRefillAmount:   proposal.FuelRefillAmount,
+			RemainingAmount:    proposal.RemainingAmount,
+			RemainingDirection: proposal.RemainingDirection,
+			CreateBy:           req.UserID,
+			CreateTime:         now,
+		})
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		shares := paymentItemQueue(fuelUsageUserPaymentItems(proposal.OffsetFuelUsages))
+		refills := paymentItemQueue(fuelRefillPaymentItems(proposal.OffsetFuelRefills))
+
+		// the rest of the last covering item stays outstanding on it
+		payments, err := s.createSettlementPayments(ctxTx, domains.Payment{
+			PayerUserID: req.UserID,
+			Method:      domains.PaymentMethodSettlement,
+			PayTime:     now,
+			CreateBy:    req.UserID,
+			CreateTime:  now,
+		}, decimal.Min(proposal.FuelUsageAmount, proposal.FuelRefillAmount), &shares, &refills, now)
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		var settlementItems []domains.SettlementItem
+		for _, payment := range payments {
+			for _, allocation := range payment.Allocations {
+				itemType := domains.SettlementItemTypeFuelUsageUser
+				if allocation.ItemType == domains.PaymentItemTypeFuelRefill {
+					itemType = domains.SettlementItemTypeFuelRefill
+				}
+				settlementItems = append(settlementItems, domains.SettlementItem{
+					SettlementID: settlementID,
+					ItemType:     itemType,
+					ItemID:       allocation.ItemID,
+					Amount:       allocation.Amount,
+				})
+			}
+		}
+
+		if err := s.db.CreateSettlementItems(ctxTx, settlementItems); err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		response = models.PostUserCarSettlementResponse{
+			SettlementID:       settlementID,
+			RemainingAmount:    proposal.RemainingAmount,
+			RemainingDirection: proposal.RemainingDirection,
+			Payments:           payments,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
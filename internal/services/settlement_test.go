@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/shopspring/decimal"
+)
+
+func (stub *stubDatabaseAdaptor) CreateSettlement(ctx context.Context, settlement domains.Settlement) (int64, error) {
+	settlement.ID = int64(len(stub.settlements) + 1)
+	stub.settlements = append(stub.settlements, settlement)
+	return settlement.ID, nil
+}
+
+func (stub *stubDatabaseAdaptor) CreateSettlementItems(ctx context.Context, settlementItems []domains.SettlementItem) error {
+	stub.settlementItems = append(stub.settlementItems, settlementItems...)
+	return nil
+}
+
+func Test_proposeSettlement(t *testing.T) {
+	usage := func(id int64, amount float64) FuelUsageUserWithFuelUsage {
+		return FuelUsageUserWithFuelUsage{
+			FuelUsageUser: domains.FuelUsageUser{ID: id, Amount: decimal.NewFromFloat(amount)},
+		}
+	}
+	refill := func(id int64, totalMoney float64) domains.FuelRefill {
+		return domains.FuelRefill{ID: id, TotalMoney: decimal.NewFromFloat(totalMoney)}
+	}
+	tests := []struct {
+		name                   string
+		fuelUsages             []FuelUsageUserWithFuelUsage
+		fuelRefills            []domains.FuelRefill
+		wantFuelUsageUserIDs   []int64
+		wantFuelRefillIDs      []int64
+		wantRemainingAmount    decimal.Decimal
+		wantRemainingDirection string
+	}{
+		{
+			name:                   "no refill to offset",
+			fuelUsages:             []FuelUsageUserWithFuelUsage{usage(1, 50)},
+			wantRemainingAmount:    decimal.Zero,
+			wantRemainingDirection: domains.SettlementDirectionNone,
+		},
+		{
+			name:                   "exactly cancel out",
+			fuelUsages:             []FuelUsageUserWithFuelUsage{usage(1, 50), usage(2, 50)},
+			fuelRefills:            []domains.FuelRefill{refill(1, 100)},
+			wantFuelUsageUserIDs:   []int64{1, 2},
+			wantFuelRefillIDs:      []int64{1},
+			wantRemainingAmount:    decimal.Zero,
+			wantRemainingDirection: domains.SettlementDirectionNone,
+		},
+		{
+			name:                   "user still pays the rest of the last covering usage",
+			fuelUsages:             []FuelUsageUserWithFuelUsage{usage(1, 100), usage(2, 50), usage(3, 40)},
+			fuelRefills:            []domains.FuelRefill{refill(1, 120)},
+			wantFuelUsageUserIDs:   []int64{1, 2},
+			wantFuelRefillIDs:      []int64{1},
+			wantRemainingAmount:    decimal.NewFromInt(30),
+			wantRemainingDirection: domains.SettlementDirectionUserPays,
+		},
+		{
+			name:                   "user receives the rest of the last covering refill",
+			fuelUsages:             []FuelUsageUserWithFuelUsage{usage(1, 30.5)},
+			fuelRefills:            []domains.FuelRefill{refill(1, 20), refill(2, 500), refill(3, 100)},
+			wantFuelUsageUserIDs:   []int64{1},
+			wantFuelRefillIDs:      []int64{1, 2},
+			wantRemainingAmount:    decimal.NewFromFloat(489.5),
+			wantRemainingDirection: domains.SettlementDirectionUserReceives,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := proposeSettlement(tt.fuelUsages, tt.fuelRefills)
+			var gotFuelUsageUserIDs []int64
+			for _, fu := range got.OffsetFuelUsages {
+				gotFuelUsageUserIDs = append(gotFuelUsageUserIDs, fu.ID)
+			}
+			var gotFuelRefillIDs []int64
+			for _, fr := range got.OffsetFuelRefills {
+				gotFuelRefillIDs = append(gotFuelRefillIDs, fr.ID)
+			}
+			if !slices.Equal(gotFuelUsageUserIDs, tt.wantFuelUsageUserIDs) {
+				t.Errorf("offset fuel usage users = %v, want %v", gotFuelUsageUserIDs, tt.wantFuelUsageUserIDs)
+			}
+			if !slices.Equal(gotFuelRefillIDs, tt.wantFuelRefillIDs) {
+				t.Errorf("offset fuel refills = %v, want %v", gotFuelRefillIDs, tt.wantFuelRefillIDs)
+			}
+			if !got.RemainingAmount.Equal(tt.wantRemainingAmount) {
+				t.Errorf("remaining amount = %v, want %v", got.RemainingAmount, tt.wantRemainingAmount)
+			}
+			if got.RemainingDirection != tt.wantRemainingDirection {
+				t.Errorf("remaining direction = %v, want %v", got.RemainingDirection, tt.wantRemainingDirection)
+			}
+		})
+	}
+}
+
+func TestService_CreateUserCarSettlement_partialOffset(t *testing.T) {
+	db := &stubDatabaseAdaptor{
+		cars:       []domains.Car{{ID: 1, OwnerUserID: 2}},
+		fuelUsages: []domains.FuelUsage{{ID: 1, CarID: 1}},
+		fuelUsageUsers: []FuelUsageUser{
+			{FuelUsageUser: domains.FuelUsageUser{ID: 1, FuelUsageID: 1, UserID: 1, Amount: decimal.NewFromInt(300)}},
+		},
+		fuelRefills: []domains.FuelRefill{
+			{ID: 1, CarID: 1, RefillBy: 1, TotalMoney: decimal.NewFromInt(100)},
+		},
+	}
+	s := New(nil, db, nil, nil)
+
+	response, err := s.CreateUserCarSettlement(contextWithUser(1), models.PostUserCarSettlementRequest{UserID: 1, CarID: 1})
+	if err != nil {
+		t.Fatalf("CreateUserCarSettlement() error = %v", err)
+	}
+	if !response.RemainingAmount.Equal(decimal.NewFromInt(200)) {
+		t.Errorf("remaining amount = %s, want 200", response.RemainingAmount)
+	}
+	if len(response.Payments) != 1 || response.Payments[0].Status != domains.PaymentConfirmStatusPending {
+		t.Fatalf("payments = %+v, want one pending payment", response.Payments)
+	}
+	if payment := response.Payments[0]; payment.PayeeUserID != 2 || payment.ConfirmUserID != 2 {
+		t.Errorf("payment = %+v, want the owner, who is owed the share, to confirm", payment)
+	}
+	if item := db.settlementItems[0]; !item.Amount.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("settlement item = %+v, want 100 offset", item)
+	}
+
+	// nothing is paid until the creditor confirms
+	if share := db.fuelUsageUsers[0]; !share.PaidAmount.IsZero() || !share.ClaimedAmount.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("share = %+v, want 100 claimed", share)
+	}
+	if len(db.ledgerEntries) != 0 {
+		t.Errorf("ledger entries = %+v, want none before the confirmation", db.ledgerEntries)
+	}
+
+	_, err = s.ConfirmUserPayment(contextWithUser(2), models.PatchUserPaymentConfirmRequest{
+		UserID:    2,
+		PaymentID: response.Payments[0].ID,
+	})
+	if err != nil {
+		t.Fatalf("ConfirmUserPayment() error = %v", err)
+	}
+
+	// the 200 not offset is still owed on the share
+	share := db.fuelUsageUsers[0]
+	if share.IsPaid || !share.PaidAmount.Equal(decimal.NewFromInt(100)) || !share.Outstanding().Equal(decimal.NewFromInt(200)) {
+		t.Errorf("share = %+v, want 200 left to pay", share)
+	}
+	if refill := db.fuelRefills[0]; !refill.IsPaid || !refill.Outstanding().IsZero() {
+		t.Errorf("refill = %+v, want reimbursed in full", refill)
+	}
+	if balance := ledgerBalances(db.ledgerEntries)[1]; !balance.IsZero() {
+		t.Errorf("balance = %s, want the offset to net to 0", balance)
+	}
+}
@@ -0,0 +1,370 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/allocations"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	SplitTieBreakFirstUser = "first_user"
+	SplitTieBreakLastUser  = "last_user"
+	SplitTieBreakDriver    = "driver"
+)
+
+var (
+	oneHundred = decimal.NewFromInt(100)
+	fullFare   = decimal.NewFromInt(1)
+	halfFare   = decimal.RequireFromString("0.5")
+)
+
+// RecomputedFuelUsageUser is an amount which RecomputeFuelUsageAmounts
+// changed.
+type RecomputedFuelUsageUser struct {
+	FuelUsageID     int64
+	FuelUsageUserID int64
+	UserID          int64
+	OldAmount       decimal.Decimal
+	NewAmount       decimal.Decimal
+}
+
+// RecomputeFuelUsageAmounts splits every fuel usage again with the current
+// rounding, then rewrites the amounts and the ledger entries of the usages
+// which changed. A fuel usage is skipped like recalculateFuelUsages skips it
+// when it is claimed, adjusted or paid, the wallet pays the new amounts of a
+// fuel usage paid from it. A dry run only reports the changes.
+func (s *Service) RecomputeFuelUsageAmounts(ctx context.Context, dryRun bool) ([]RecomputedFuelUsageUser, []models.SkippedFuelUsage, error) {
+	fuelUsages, err := s.db.GetAllFuelUsages(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, nil, err
+	}
+	if len(fuelUsages) == 0 {
+		return nil, nil, nil
+	}
+
+	var fuelUsageIDs []int64
+	for _, fuelUsage := range fuelUsages {
+		fuelUsageIDs = append(fuelUsageIDs, fuelUsage.ID)
+	}
+
+	fuelUsageUsers, err := s.db.GetFuelUsageUsersByFuelUsageIDs(ctx, fuelUsageIDs)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, nil, err
+	}
+
+	fuelUsageIDToFuelUsageUsers := make(map[int64][]domains.FuelUsageUser)
+	for _, fuelUsageUser := range fuelUsageUsers {
+		fuelUsageIDToFuelUsageUsers[fuelUsageUser.FuelUsageID] = append(
+			fuelUsageIDToFuelUsageUsers[fuelUsageUser.FuelUsageID],
+			fuelUsageUser.FuelUsageUser,
+		)
+	}
+
+	now := time.Now()
+
+	var recomputed []RecomputedFuelUsageUser
+	var skipped []models.SkippedFuelUsage
+	err = s.db.Transaction(ctx, func(ctxTx context.Context) error {
+		for _, fuelUsage := range fuelUsages {
+			fuelUsageUsers := fuelUsageIDToFuelUsageUsers[fuelUsage.ID]
+			if len(fuelUsageUsers) == 0 {
+				continue
+			}
+
+			// the users are created in the order of the request
+			slices.SortFunc(fuelUsageUsers, func(a, b domains.FuelUsageUser) int {
+				return cmp.Compare(a.ID, b.ID)
+			})
+
+			isClaimed := slices.ContainsFunc(fuelUsageUsers, func(fuelUsageUser domains.FuelUsageUser) bool {
+				return fuelUsageUser.ClaimedAmount.IsPositive()
+			})
+			if isClaimed {
+				skipped = append(skipped, models.SkippedFuelUsage{
+					FuelUsageID: fuelUsage.ID,
+					Reason:      models.SkippedFuelUsageReasonClaimed,
+				})
+				continue
+			}
+
+			adjustments, err := s.db.GetAdjustmentsByReference(ctxTx, domains.AdjustmentReferenceTypeFuelUsage, fuelUsage.ID)
+			if err != nil {
+				return err
+			}
+			if len(adjustments) > 0 {
+				skipped = append(skipped, models.SkippedFuelUsage{
+					FuelUsageID: fuelUsage.ID,
+					Reason:      models.SkippedFuelUsageReasonAdjusted,
+				})
+				continue
+			}
+
+			isPaid := slices.ContainsFunc(fuelUsageUsers, func(fuelUsageUser domains.FuelUsageUser) bool {
+				return fuelUsageUser.IsPaid || fuelUsageUser.PaidAmount.IsPositive()
+			})
+			if isPaid && !fuelUsage.IsPaidFromWallet {
+				skipped = append(skipped, models.SkippedFuelUsage{
+					FuelUsageID: fuelUsage.ID,
+					Reason:      models.SkippedFuelUsageReasonPaid,
+				})
+				continue
+			}
+
+			oldAmounts := make([]decimal.Decimal, len(fuelUsageUsers))
+			for i, fuelUsageUser := range fuelUsageUsers {
+				oldAmounts[i] = fuelUsageUser.Amount
+			}
+
+			// the fare weights were set by the fare rule of the car at that time
+			if err := splitFuelUsage(fuelUsage, fuelUsageUsers, s.splitTieBreak()); err != nil {
+				return fmt.Errorf("fuelUsageId: [%d]: %w", fuelUsage.ID, err)
+			}
+			if fuelUsage.IsPaidFromWallet {
+				payFuelUsageUsersFromWallet(fuelUsageUsers)
+			}
+
+			isChanged := false
+			for i, fuelUsageUser := range fuelUsageUsers {
+				if fuelUsageUser.Amount.Equal(oldAmounts[i]) {
+					continue
+				}
+				isChanged = true
+				recomputed = append(recomputed, RecomputedFuelUsageUser{
+					FuelUsageID:     fuelUsage.ID,
+					FuelUsageUserID: fuelUsageUser.ID,
+					UserID:          fuelUsageUser.UserID,
+					OldAmount:       oldAmounts[i],
+					NewAmount:       fuelUsageUser.Amount,
+				})
+				if dryRun {
+					continue
+				}
+				if err := s.db.UpdateFuelUsageUserAmount(ctxTx, fuelUsageUser.ID, fuelUsageUser.Amount); err != nil {
+					return err
+				}
+				if fuelUsage.IsPaidFromWallet {
+					isAdded, err := s.db.AddFuelUsageUserPaidAmount(ctxTx, fuelUsageUser.ID, fuelUsageUser.Amount.Sub(oldAmounts[i]))
+					if err != nil {
+						return err
+					}
+					if !isAdded {
+						slog.WarnContext(ctxTx, "the share would be paid more than it owes", "fuelUsageUserId", fuelUsageUser.ID)
+						return errs.ErrConflict
+					}
+				}
+			}
+			if !isChanged || dryRun {
+				continue
+			}
+
+			if err := s.replaceFuelUsageLedgerEntries(ctxTx, fuelUsage, fuelUsageUsers, now); err != nil {
+				return err
+			}
+			if fuelUsage.IsPaidFromWallet {
+				if err := s.replaceFuelUsageWalletTransactions(ctxTx, fuelUsage, fuelUsageUsers, now); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, nil, err
+	}
+
+	return recomputed, skipped, nil
+}
+
+// splitTieBreak defaults to the first user when it is not configured.
+func (s *Service) splitTieBreak() string {
+	if s.cfg == nil || s.cfg.FuelUsage.SplitTieBreak == "" {
+		return SplitTieBreakFirstUser
+	}
+	return s.cfg.FuelUsage.SplitTieBreak
+}
+
+// newFuelUsageUsers keeps the order of the request, which the split and
+// its tie break depend on.
+func newFuelUsageUsers(splitMode string, fuelUsers []models.FuelUser) []domains.FuelUsageUser {
+	var fuelUsageUsers []domains.FuelUsageUser
+	for _, fuelUser := range fuelUsers {
+		splitValue := fuelUser.SplitValue
+		if splitMode == domains.FuelUsageSplitModeEqual {
+			splitValue = decimal.Zero
+		}
+		fuelUsageUsers = append(fuelUsageUsers, domains.FuelUsageUser{
+			UserID:     fuelUser.UserID,
+			IsPaid:     fuelUser.IsPaid,
+			SplitValue: splitValue,
+			FareWeight: fullFare,
+		})
+	}
+	return fuelUsageUsers
+}
+
+// applyFareRule sets the fare rule of the car on the fuel usage and the fare
+// weight of its users. Percentage and amount splits are given by hand, so
+// the rule applies to equal and share splits only, and never when it would
+// leave nobody paying.
+func applyFareRule(fuelUsage *domains.FuelUsage, car domains.Car, fuelUsageUsers []domains.FuelUsageUser) {
+	fuelUsage.FareRule = domains.CarFareRuleNone
+	for i := range fuelUsageUsers {
+		fuelUsageUsers[i].FareWeight = fullFare
+	}
+
+	if fuelUsage.SplitMode != domains.FuelUsageSplitModeEqual &&
+		fuelUsage.SplitMode != domains.FuelUsageSplitModeShare {
+		return
+	}
+
+	var userID int64
+	var fareWeight decimal.Decimal
+	switch car.FareRule {
+	case domains.CarFareRuleDriverFree:
+		userID, fareWeight = fuelUsage.DriverUserID, decimal.Zero
+	case domains.CarFareRuleDriverHalf:
+		userID, fareWeight = fuelUsage.DriverUserID, halfFare
+	case domains.CarFareRuleOwnerFree:
+		userID, fareWeight = car.OwnerUserID, decimal.Zero
+	default:
+		return
+	}
+	if userID == 0 {
+		return
+	}
+
+	index := slices.IndexFunc(fuelUsageUsers, func(fuelUsageUser domains.FuelUsageUser) bool {
+		return fuelUsageUser.UserID == userID
+	})
+	if index < 0 {
+		return
+	}
+	if fareWeight.IsZero() && len(fuelUsageUsers) == 1 {
+		return
+	}
+
+	fuelUsageUsers[index].FareWeight = fareWeight
+	fuelUsage.FareRule = car.FareRule
+}
+
+// splitFuelUsage sets the amount of every fuel usage user by the split mode
+// and the fare weights, the amounts always sum to the total money.
+func splitFuelUsage(fuelUsage domains.FuelUsage, fuelUsageUsers []domains.FuelUsageUser, tieBreak string) error {
+	if len(fuelUsageUsers) == 0 {
+		return fmt.Errorf("fuelUsageUsers should not be empty")
+	}
+
+	order, err := tieBreakOrder(tieBreak, fuelUsage.DriverUserID, fuelUsageUsers)
+	if err != nil {
+		return err
+	}
+
+	weights := make([]decimal.Decimal, len(fuelUsageUsers))
+	switch fuelUsage.SplitMode {
+	case domains.FuelUsageSplitModeEqual:
+		for i, fuelUsageUser := range fuelUsageUsers {
+			weights[i] = fuelUsageUser.FareWeight
+		}
+
+	case domains.FuelUsageSplitModePercentage:
+		sum, err := sumSplitValues(fuelUsageUsers)
+		if err != nil {
+			return err
+		}
+		if !sum.Equal(oneHundred) {
+			return fmt.Errorf("percentages should sum to 100, got: [%s]", sum)
+		}
+		for i, fuelUsageUser := range fuelUsageUsers {
+			weights[i] = fuelUsageUser.SplitValue
+		}
+
+	case domains.FuelUsageSplitModeAmount:
+		sum, err := sumSplitValues(fuelUsageUsers)
+		if err != nil {
+			return err
+		}
+		if !sum.Equal(fuelUsage.TotalMoney) {
+			return fmt.Errorf("amounts should sum to totalMoney [%s], got: [%s]", fuelUsage.TotalMoney, sum)
+		}
+		for i := range fuelUsageUsers {
+			fuelUsageUsers[i].Amount = fuelUsageUsers[i].SplitValue
+		}
+		return nil
+
+	case domains.FuelUsageSplitModeShare:
+		if _, err := sumSplitValues(fuelUsageUsers); err != nil {
+			return err
+		}
+		for i, fuelUsageUser := range fuelUsageUsers {
+			weights[i] = fuelUsageUser.SplitValue.Mul(fuelUsageUser.FareWeight)
+		}
+
+	default:
+		return fmt.Errorf("unknown splitMode: [%s]", fuelUsage.SplitMode)
+	}
+
+	amounts := allocations.ByWeights(fuelUsage.TotalMoney, weights, order)
+	for i := range fuelUsageUsers {
+		fuelUsageUsers[i].Amount = amounts[i]
+	}
+	return nil
+}
+
+// sumSplitValues returns the sum of the split values, which should all be
+// positive.
+func sumSplitValues(fuelUsageUsers []domains.FuelUsageUser) (decimal.Decimal, error) {
+	sum := decimal.Zero
+	for _, fuelUsageUser := range fuelUsageUsers {
+		if !fuelUsageUser.SplitValue.IsPositive() {
+			return decimal.Zero, fmt.Errorf(
+				"splitValue should be > 0, userId: [%d], splitValue: [%s]",
+				fuelUsageUser.UserID,
+				fuelUsageUser.SplitValue,
+			)
+		}
+		sum = sum.Add(fuelUsageUser.SplitValue)
+	}
+	return sum, nil
+}
+
+// tieBreakOrder lists the indexes of fuelUsageUsers by who gets a leftover
+// satang first when the remainders are equal. The driver tie break falls
+// back to the first user when the driver is not one of the users.
+func tieBreakOrder(tieBreak string, driverUserID int64, fuelUsageUsers []domains.FuelUsageUser) ([]int, error) {
+	order := make([]int, len(fuelUsageUsers))
+	for i := range order {
+		order[i] = i
+	}
+	switch tieBreak {
+	case SplitTieBreakFirstUser:
+	case SplitTieBreakLastUser:
+		slices.Reverse(order)
+	case SplitTieBreakDriver:
+		slices.SortStableFunc(order, func(a, b int) int {
+			isDriverA := fuelUsageUsers[a].UserID == driverUserID
+			isDriverB := fuelUsageUsers[b].UserID == driverUserID
+			if isDriverA == isDriverB {
+				return 0
+			}
+			if isDriverA {
+				return -1
+			}
+			return 1
+		})
+	default:
+		return nil, fmt.Errorf("unknown split tie break: [%s]", tieBreak)
+	}
+	return order, nil
+}
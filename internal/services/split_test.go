@@ -0,0 +1,506 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/allocations"
+	"github.com/shopspring/decimal"
+)
+
+func Test_splitFuelUsage(t *testing.T) {
+	fuelUsageUsers := func(splitValues ...string) []domains.FuelUsageUser {
+		var fuelUsageUsers []domains.FuelUsageUser
+		for i, splitValue := range splitValues {
+			fuelUsageUsers = append(fuelUsageUsers, domains.FuelUsageUser{
+				UserID:     int64(i + 1),
+				SplitValue: decimal.RequireFromString(splitValue),
+				FareWeight: fullFare,
+			})
+		}
+		return fuelUsageUsers
+	}
+
+	tests := []struct {
+		name           string
+		totalMoney     string
+		splitMode      string
+		fuelUsageUsers []domains.FuelUsageUser
+		wantAmounts    []string
+		wantErr        bool
+	}{
+		{
+			name:           "equal split ignores split values",
+			totalMoney:     "90",
+			splitMode:      domains.FuelUsageSplitModeEqual,
+			fuelUsageUsers: fuelUsageUsers("0", "5", "0"),
+			wantAmounts:    []string{"30", "30", "30"},
+		},
+		{
+			name:           "equal split sums to total money",
+			totalMoney:     "100",
+			splitMode:      domains.FuelUsageSplitModeEqual,
+			fuelUsageUsers: fuelUsageUsers("0", "0", "0"),
+			wantAmounts:    []string{"33.34", "33.33", "33.33"},
+		},
+		{
+			name:           "by percentage",
+			totalMoney:     "200",
+			splitMode:      domains.FuelUsageSplitModePercentage,
+			fuelUsageUsers: fuelUsageUsers("50", "30", "20"),
+			wantAmounts:    []string{"100", "60", "40"},
+		},
+		{
+			name:           "percentages not summing to 100",
+			totalMoney:     "200",
+			splitMode:      domains.FuelUsageSplitModePercentage,
+			fuelUsageUsers: fuelUsageUsers("50", "30"),
+			wantErr:        true,
+		},
+		{
+			name:           "by fixed amounts",
+			totalMoney:     "150.5",
+			splitMode:      domains.FuelUsageSplitModeAmount,
+			fuelUsageUsers: fuelUsageUsers("100", "50.5"),
+			wantAmounts:    []string{"100", "50.5"},
+		},
+		{
+			name:           "fixed amounts not summing to total money",
+			totalMoney:     "150.5",
+			splitMode:      domains.FuelUsageSplitModeAmount,
+			fuelUsageUsers: fuelUsageUsers("100", "50.49"),
+			wantErr:        true,
+		},
+		{
+			name:           "by shares with a driver counting as half",
+			totalMoney:     "250",
+			splitMode:      domains.FuelUsageSplitModeShare,
+			fuelUsageUsers: fuelUsageUsers("0.5", "1", "1"),
+			wantAmounts:    []string{"50", "100", "100"},
+		},
+		{
+			name:           "zero share",
+			totalMoney:     "250",
+			splitMode:      domains.FuelUsageSplitModeShare,
+			fuelUsageUsers: fuelUsageUsers("0", "1"),
+			wantErr:        true,
+		},
+		{
+			name:           "unknown split mode",
+			totalMoney:     "250",
+			splitMode:      "RANDOM",
+			fuelUsageUsers: fuelUsageUsers("1"),
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fuelUsage := domains.FuelUsage{
+				TotalMoney: decimal.RequireFromString(tt.totalMoney),
+				SplitMode:  tt.splitMode,
+			}
+			err := splitFuelUsage(fuelUsage, tt.fuelUsageUsers, SplitTieBreakFirstUser)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			assertAmounts(t, tt.fuelUsageUsers, tt.wantAmounts)
+		})
+	}
+}
+
+func Test_applyFareRule(t *testing.T) {
+	const (
+		ownerUserID  = 1
+		driverUserID = 2
+	)
+	fuelUsageUsers := func(splitValues ...string) []domains.FuelUsageUser {
+		var fuelUsageUsers []domains.FuelUsageUser
+		for i, splitValue := range splitValues {
+			fuelUsageUsers = append(fuelUsageUsers, domains.FuelUsageUser{
+				UserID:     int64(i + 1),
+				SplitValue: decimal.RequireFromString(splitValue),
+			})
+		}
+		return fuelUsageUsers
+	}
+
+	tests := []struct {
+		name           string
+		fareRule       string
+		splitMode      string
+		driverUserID   int64
+		fuelUsageUsers []domains.FuelUsageUser
+		wantFareRule   string
+		wantAmounts    []string
+	}{
+		{
+			name:           "no fare rule",
+			fareRule:       domains.CarFareRuleNone,
+			splitMode:      domains.FuelUsageSplitModeEqual,
+			driverUserID:   driverUserID,
+			fuelUsageUsers: fuelUsageUsers("0", "0", "0"),
+			wantFareRule:   domains.CarFareRuleNone,
+			wantAmounts:    []string{"100", "100", "100"},
+		},
+		{
+			name:           "driver rides free",
+			fareRule:       domains.CarFareRuleDriverFree,
+			splitMode:      domains.FuelUsageSplitModeEqual,
+			driverUserID:   driverUserID,
+			fuelUsageUsers: fuelUsageUsers("0", "0", "0"),
+			wantFareRule:   domains.CarFareRuleDriverFree,
+			wantAmounts:    []string{"150", "0", "150"},
+		},
+		{
+			name:           "driver pays half",
+			fareRule:       domains.CarFareRuleDriverHalf,
+			splitMode:      domains.FuelUsageSplitModeEqual,
+			driverUserID:   driverUserID,
+			fuelUsageUsers: fuelUsageUsers("0", "0", "0", "0"),
+			// 300 / 3.5 shares
+			wantAmounts:  []string{"85.72", "42.86", "85.71", "85.71"},
+			wantFareRule: domains.CarFareRuleDriverHalf,
+		},
+		{
+			name:           "driver pays half of the shares",
+			fareRule:       domains.CarFareRuleDriverHalf,
+			splitMode:      domains.FuelUsageSplitModeShare,
+			driverUserID:   driverUserID,
+			fuelUsageUsers: fuelUsageUsers("1", "2", "1"),
+			wantFareRule:   domains.CarFareRuleDriverHalf,
+			wantAmounts:    []string{"100", "100", "100"},
+		},
+		{
+			name:           "owner rides free",
+			fareRule:       domains.CarFareRuleOwnerFree,
+			splitMode:      domains.FuelUsageSplitModeEqual,
+			driverUserID:   driverUserID,
+			fuelUsageUsers: fuelUsageUsers("0", "0", "0"),
+			wantFareRule:   domains.CarFareRuleOwnerFree,
+			wantAmounts:    []string{"0", "150", "150"},
+		},
+		{
+			name:           "driver not given",
+			fareRule:       domains.CarFareRuleDriverFree,
+			splitMode:      domains.FuelUsageSplitModeEqual,
+			fuelUsageUsers: fuelUsageUsers("0", "0", "0"),
+			wantFareRule:   domains.CarFareRuleNone,
+			wantAmounts:    []string{"100", "100", "100"},
+		},
+		{
+			name:           "percentage split is given by hand",
+			fareRule:       domains.CarFareRuleDriverFree,
+			splitMode:      domains.FuelUsageSplitModePercentage,
+			driverUserID:   driverUserID,
+			fuelUsageUsers: fuelUsageUsers("50", "25", "25"),
+			wantFareRule:   domains.CarFareRuleNone,
+			wantAmounts:    []string{"150", "75", "75"},
+		},
+		{
+			name:           "the only user still pays",
+			fareRule:       domains.CarFareRuleOwnerFree,
+			splitMode:      domains.FuelUsageSplitModeEqual,
+			fuelUsageUsers: fuelUsageUsers("0"),
+			wantFareRule:   domains.CarFareRuleNone,
+			wantAmounts:    []string{"300"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fuelUsage := domains.FuelUsage{
+				TotalMoney:   decimal.NewFromInt(300),
+				SplitMode:    tt.splitMode,
+				DriverUserID: tt.driverUserID,
+			}
+			car := domains.Car{OwnerUserID: ownerUserID, FareRule: tt.fareRule}
+			applyFareRule(&fuelUsage, car, tt.fuelUsageUsers)
+			if fuelUsage.FareRule != tt.wantFareRule {
+				t.Errorf("fareRule = %s, want %s", fuelUsage.FareRule, tt.wantFareRule)
+			}
+			if err := splitFuelUsage(fuelUsage, tt.fuelUsageUsers, SplitTieBreakFirstUser); err != nil {
+				t.Fatal(err)
+			}
+			assertAmounts(t, tt.fuelUsageUsers, tt.wantAmounts)
+		})
+	}
+}
+
+func assertAmounts(t *testing.T, fuelUsageUsers []domains.FuelUsageUser, wantAmounts []string) {
+	t.Helper()
+	if len(fuelUsageUsers) != len(wantAmounts) {
+		t.Fatalf("got %d amounts, want %d", len(fuelUsageUsers), len(wantAmounts))
+	}
+	for i, fuelUsageUser := range fuelUsageUsers {
+		if !fuelUsageUser.Amount.Equal(decimal.RequireFromString(wantAmounts[i])) {
+			t.Errorf("amounts[%d] = %s, want %s", i, fuelUsageUser.Amount, wantAmounts[i])
+		}
+	}
+}
+
+func Test_tieBreakOrder(t *testing.T) {
+	const driverUserID = 3
+
+	tests := []struct {
+		name       string
+		totalMoney string
+		weights    []string
+		tieBreak   string
+		wantParts  []string
+	}{
+		{
+			name:       "leftover satang to the first user",
+			totalMoney: "100",
+			weights:    []string{"1", "1", "1"},
+			tieBreak:   SplitTieBreakFirstUser,
+			wantParts:  []string{"33.34", "33.33", "33.33"},
+		},
+		{
+			name:       "leftover satang to the last user",
+			totalMoney: "100",
+			weights:    []string{"1", "1", "1"},
+			tieBreak:   SplitTieBreakLastUser,
+			wantParts:  []string{"33.33", "33.33", "33.34"},
+		},
+		{
+			name:       "leftover satang to the driver",
+			totalMoney: "100",
+			weights:    []string{"1", "1", "1"},
+			tieBreak:   SplitTieBreakDriver,
+			wantParts:  []string{"33.33", "33.33", "33.34"},
+		},
+		{
+			name:       "leftover satang after the driver",
+			totalMoney: "100.01",
+			weights:    []string{"1", "1", "1"},
+			tieBreak:   SplitTieBreakDriver,
+			wantParts:  []string{"33.34", "33.33", "33.34"},
+		},
+		{
+			name:       "largest remainder wins over the tie break",
+			totalMoney: "10",
+			weights:    []string{"1", "1", "1", "3"},
+			tieBreak:   SplitTieBreakLastUser,
+			// exact parts are 1.6666.., 1.6666.., 1.6666.. and 5
+			wantParts: []string{"1.66", "1.67", "1.67", "5"},
+		},
+		{
+			name:       "total money finer than satang",
+			totalMoney: "10.001",
+			weights:    []string{"1", "1"},
+			tieBreak:   SplitTieBreakFirstUser,
+			wantParts:  []string{"5.001", "5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var weights []decimal.Decimal
+			var fuelUsageUsers []domains.FuelUsageUser
+			for i, weight := range tt.weights {
+				weights = append(weights, decimal.RequireFromString(weight))
+				fuelUsageUsers = append(fuelUsageUsers, domains.FuelUsageUser{UserID: int64(i + 1)})
+			}
+			order, err := tieBreakOrder(tt.tieBreak, driverUserID, fuelUsageUsers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts := allocations.ByWeights(decimal.RequireFromString(tt.totalMoney), weights, order)
+			for i, part := range parts {
+				if !part.Equal(decimal.RequireFromString(tt.wantParts[i])) {
+					t.Errorf("parts = %v, want %v", parts, tt.wantParts)
+					break
+				}
+			}
+		})
+	}
+
+	if _, err := tieBreakOrder("random", driverUserID, []domains.FuelUsageUser{{UserID: 1}}); err == nil {
+		t.Error("unknown tie break should fail")
+	}
+}
+
+func (stub *stubDatabaseAdaptor) GetAllFuelUsages(ctx context.Context) ([]domains.FuelUsage, error) {
+	return stub.fuelUsages, nil
+}
+
+func (stub *stubDatabaseAdaptor) GetFuelUsageUsersByFuelUsageIDs(ctx context.Context, fuelUsageIDs []int64) ([]FuelUsageUser, error) {
+	var fuelUsageUsers []FuelUsageUser
+	for _, fuelUsageUser := range stub.fuelUsageUsers {
+		if slices.Contains(fuelUsageIDs, fuelUsageUser.FuelUsageID) {
+			fuelUsageUsers = append(fuelUsageUsers, fuelUsageUser)
+		}
+	}
+	return fuelUsageUsers, nil
+}
+
+func (stub *stubDatabaseAdaptor) UpdateFuelUsageUserAmount(ctx context.Context, fuelUsageUserID int64, amount decimal.Decimal) error {
+	for i := range stub.fuelUsageUsers {
+		if stub.fuelUsageUsers[i].ID == fuelUsageUserID {
+			stub.fuelUsageUsers[i].Amount = amount
+			stub.fuelUsageUsers[i].IsPaid = stub.fuelUsageUsers[i].PaidAmount.GreaterThanOrEqual(amount)
+		}
+	}
+	return nil
+}
+
+func (stub *stubDatabaseAdaptor) GetLedgerNetAmountsByReference(ctx context.Context, referenceType string, referenceID int64) ([]LedgerNetAmount, error) {
+	userIDToNetAmount := make(map[int64]LedgerNetAmount)
+	for _, e := range stub.ledgerEntries {
+		if e.ReferenceType != referenceType || e.ReferenceID != referenceID {
+			continue
+		}
+		netAmount := userIDToNetAmount[e.UserID]
+		netAmount.UserID, netAmount.CarID = e.UserID, e.CarID
+		netAmount.NetAmount = netAmount.NetAmount.Add(e.Credit).Sub(e.Debit)
+		userIDToNetAmount[e.UserID] = netAmount
+	}
+	var netAmounts []LedgerNetAmount
+	for _, netAmount := range userIDToNetAmount {
+		netAmounts = append(netAmounts, netAmount)
+	}
+	return netAmounts, nil
+}
+
+func (stub *stubDatabaseAdaptor) CreateLedgerEntries(ctx context.Context, ledgerEntries []domains.LedgerEntry) error {
+	stub.ledgerEntries = append(stub.ledgerEntries, ledgerEntries...)
+	return nil
+}
+
+func TestService_RecomputeFuelUsageAmounts(t *testing.T) {
+	fuelUsageUser := func(id, userID int64, amount string) FuelUsageUser {
+		return FuelUsageUser{FuelUsageUser: domains.FuelUsageUser{
+			ID:          id,
+			FuelUsageID: 1,
+			UserID:      userID,
+			FareWeight:  fullFare,
+			Amount:      decimal.RequireFromString(amount),
+		}}
+	}
+	newStub := func() *stubDatabaseAdaptor {
+		stub := &stubDatabaseAdaptor{
+			fuelUsages: []domains.FuelUsage{
+				{ID: 1, CarID: 1, TotalMoney: decimal.NewFromInt(100), SplitMode: domains.FuelUsageSplitModeEqual},
+			},
+			// rounded by the former DivRound, one satang is missing
+			fuelUsageUsers: []FuelUsageUser{
+				fuelUsageUser(3, 3, "33.33"),
+				fuelUsageUser(1, 1, "33.33"),
+				fuelUsageUser(2, 2, "33.33"),
+			},
+		}
+		for _, u := range stub.fuelUsageUsers {
+			stub.ledgerEntries = append(stub.ledgerEntries, FuelUsageLedgerEntries(stub.fuelUsages[0], []domains.FuelUsageUser{u.FuelUsageUser}, time.Now())...)
+		}
+		return stub
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		stub := newStub()
+		s := New(nil, stub, nil, nil)
+		recomputed, _, err := s.RecomputeFuelUsageAmounts(context.Background(), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recomputed) != 1 || recomputed[0].FuelUsageUserID != 1 || !recomputed[0].NewAmount.Equal(decimal.RequireFromString("33.34")) {
+			t.Errorf("recomputed = %+v, want the first user to pay 33.34", recomputed)
+		}
+		if !stub.fuelUsageUsers[1].Amount.Equal(decimal.RequireFromString("33.33")) {
+			t.Errorf("dry run should not update the amount, got %s", stub.fuelUsageUsers[1].Amount)
+		}
+		if len(stub.ledgerEntries) != 3 {
+			t.Errorf("dry run should not post ledger entries, got %d entries", len(stub.ledgerEntries))
+		}
+	})
+
+	t.Run("recompute", func(t *testing.T) {
+		stub := newStub()
+		s := New(nil, stub, nil, nil)
+		if _, _, err := s.RecomputeFuelUsageAmounts(context.Background(), false); err != nil {
+			t.Fatal(err)
+		}
+		if !stub.fuelUsageUsers[1].Amount.Equal(decimal.RequireFromString("33.34")) {
+			t.Errorf("first user amount = %s, want 33.34", stub.fuelUsageUsers[1].Amount)
+		}
+		balances := ledgerBalances(stub.ledgerEntries)
+		if !balances[1].Equal(decimal.RequireFromString("-33.34")) {
+			t.Errorf("first user balance = %s, want -33.34", balances[1])
+		}
+
+		recomputed, _, err := s.RecomputeFuelUsageAmounts(context.Background(), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recomputed) != 0 {
+			t.Errorf("recomputing again = %+v, want no change", recomputed)
+		}
+	})
+
+	t.Run("skips locked fuel usages", func(t *testing.T) {
+		stub := &stubDatabaseAdaptor{
+			adjustments: []domains.Adjustment{
+				{ID: 1, CarID: 1, ReferenceType: domains.AdjustmentReferenceTypeFuelUsage, ReferenceID: 3, UserID: 1},
+			},
+		}
+		for fuelUsageID := int64(1); fuelUsageID <= 4; fuelUsageID++ {
+			fuelUsage := domains.FuelUsage{ID: fuelUsageID, CarID: 1, TotalMoney: decimal.NewFromInt(100), SplitMode: domains.FuelUsageSplitModeEqual}
+			var fuelUsageUsers []domains.FuelUsageUser
+			for userID := int64(1); userID <= 3; userID++ {
+				u := fuelUsageUser(int64(len(stub.fuelUsageUsers)+1), userID, "33.33")
+				u.FuelUsageID = fuelUsageID
+				switch fuelUsageID {
+				case 1:
+					u.PaidAmount = u.Amount
+					u.IsPaid = true
+				case 2:
+					u.ClaimedAmount = u.Amount
+				case 4:
+					fuelUsage.IsPaidFromWallet = true
+					u.PaidAmount = u.Amount
+					u.IsPaid = true
+				}
+				stub.fuelUsageUsers = append(stub.fuelUsageUsers, u)
+				fuelUsageUsers = append(fuelUsageUsers, u.FuelUsageUser)
+			}
+			stub.fuelUsages = append(stub.fuelUsages, fuelUsage)
+			stub.walletTransactions = append(stub.walletTransactions, FuelUsageWalletTransactions(fuelUsage, fuelUsageUsers, time.Now())...)
+		}
+		s := New(nil, stub, nil, nil)
+
+		recomputed, skipped, err := s.RecomputeFuelUsageAmounts(context.Background(), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantSkipped := []models.SkippedFuelUsage{
+			{FuelUsageID: 1, Reason: models.SkippedFuelUsageReasonPaid},
+			{FuelUsageID: 2, Reason: models.SkippedFuelUsageReasonClaimed},
+			{FuelUsageID: 3, Reason: models.SkippedFuelUsageReasonAdjusted},
+		}
+		if !slices.Equal(skipped, wantSkipped) {
+			t.Errorf("skipped = %+v, want %+v", skipped, wantSkipped)
+		}
+		if len(recomputed) != 1 || recomputed[0].FuelUsageID != 4 {
+			t.Fatalf("recomputed = %+v, want the fuel usage paid from the wallet", recomputed)
+		}
+
+		// the wallet pays the new amount
+		walletFuelUsageUser := stub.fuelUsageUsers[9]
+		if !walletFuelUsageUser.Amount.Equal(decimal.RequireFromString("33.34")) || !walletFuelUsageUser.PaidAmount.Equal(walletFuelUsageUser.Amount) || !walletFuelUsageUser.IsPaid {
+			t.Errorf("fuel usage user = %+v, want 33.34 paid from the wallet", walletFuelUsageUser)
+		}
+		deducted := decimal.Zero
+		for _, walletTransaction := range stub.walletTransactions {
+			if walletTransaction.ReferenceID == 4 && walletTransaction.UserID == 1 {
+				deducted = deducted.Add(walletTransaction.Amount)
+			}
+		}
+		if !deducted.Equal(decimal.RequireFromString("33.34")) {
+			t.Errorf("deducted from the wallet = %s, want 33.34", deducted)
+		}
+	})
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/bosskrub9992/fuel-management-backend/library/images"
+	"github.com/bosskrub9992/fuel-management-backend/library/masks"
+	"github.com/bosskrub9992/fuel-management-backend/library/promptpay"
+	"gorm.io/gorm"
+)
+
+func (s *Service) GetUsers(ctx context.Context, req models.GetUsersRequest) (*models.GetUserData, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	users, err := s.db.GetAllUsers(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	userData := []models.GetUserDatum{}
+	for _, user := range users {
+		if user.IsDeactivated && !req.IncludeDeactivated {
+			continue
+		}
+		userData = append(userData, toUserDatum(user))
+	}
+
+	return &models.GetUserData{
+		Data: userData,
+	}, nil
+}
+
+func (s *Service) GetCarUsers(ctx context.Context, req models.GetCarUsersRequest) (*models.GetUserData, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	if _, err := s.getCarByID(ctx, req.CarID); err != nil {
+		return nil, err
+	}
+
+	users, err := s.db.GetUsersByDefaultCarID(ctx, req.CarID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	userData := []models.GetUserDatum{}
+	for _, user := range users {
+		userData = append(userData, toUserDatum(user))
+	}
+
+	return &models.GetUserData{
+		Data: userData,
+	}, nil
+}
+
+// CreateUser registers a user together with the credential to login.
+func (s *Service) CreateUser(ctx context.Context, req models.PostUserRequest) (*models.PostUserResponse, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	if err := s.shouldBeActiveCar(ctx, req.DefaultCarID); err != nil {
+		return nil, err
+	}
+
+	_, err := s.db.GetUserCredentialByUsername(ctx, req.Username)
+	if err == nil {
+		slog.WarnContext(ctx, "username is already taken", "username", req.Username)
+		return nil, errs.ErrConflict
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	passwordHash, err := HashPassword(req.Password)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	now := time.Now()
+
+	var userID int64
+	err = s.db.Transaction(ctx, func(ctxTx context.Context) error {
+		var err error
+		userID, err = s.db.CreateUser(ctxTx, domains.User{
+			DefaultCarID: req.DefaultCarID,
+			Nickname:     req.Nickname,
+			PromptPayID:  normalizePromptPayID(req.PromptPayID),
+			CreateTime:   now,
+			UpdateTime:   now,
+		})
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		err = s.db.CreateUserCredential(ctxTx, domains.UserCredential{
+			UserID:       userID,
+			Username:     req.Username,
+			PasswordHash: passwordHash,
+			CreateTime:   now,
+			UpdateTime:   now,
+		})
+		if err != nil {
+			slog.ErrorContext(ctxTx, err.Error())
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PostUserResponse{
+		ID: userID,
+	}, nil
+}
+
+func (s *Service) UpdateUserByID(ctx context.Context, req models.PutUserByIDRequest) error {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return errs.ErrValidateFailed
+	}
+
+	if err := shouldActAsUser(ctx, req.UserID); err != nil {
+		return err
+	}
+
+	user, err := s.getUserByID(ctx, req.UserID)
+	if err != nil {
+		return err
+	}
+
+	user.Nickname = req.Nickname
+	user.PromptPayID = normalizePromptPayID(req.PromptPayID)
+	user.UpdateTime = time.Now()
+
+	if err := s.db.UpdateUser(ctx, *user); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) UpdateUserDefaultCar(ctx context.Context, req models.PatchUserDefaultCarRequest) error {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return errs.ErrValidateFailed
+	}
+
+	if err := shouldActAsUser(ctx, req.UserID); err != nil {
+		return err
+	}
+
+	if err := s.shouldBeActiveCar(ctx, req.CarID); err != nil {
+		return err
+	}
+
+	user, err := s.getUserByID(ctx, req.UserID)
+	if err != nil {
+		return err
+	}
+
+	user.DefaultCarID = req.CarID
+	user.UpdateTime = time.Now()
+
+	if err := s.db.UpdateUser(ctx, *user); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeactivateUserByID deactivates instead of deleting, so fuel usages of the
+// user keep resolving the nickname.
+func (s *Service) DeactivateUserByID(ctx context.Context, req models.DeleteUserByIDRequest) error {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return errs.ErrValidateFailed
+	}
+
+	if err := shouldActAsUser(ctx, req.UserID); err != nil {
+		return err
+	}
+
+	user, err := s.getUserByID(ctx, req.UserID)
+	if err != nil {
+		return err
+	}
+
+	if user.IsDeactivated {
+		return nil
+	}
+
+	user.IsDeactivated = true
+	user.UpdateTime = time.Now()
+
+	if err := s.db.UpdateUser(ctx, *user); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) getUserByID(ctx context.Context, userID int64) (*domains.User, error) {
+	user, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.WarnContext(ctx, "not found user", "userId", userID)
+			return nil, errs.ErrNotFound
+		}
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+	return user, nil
+}
+
+// normalizePromptPayID keeps an empty PromptPay id empty, the id itself is
+// validated with the request.
+func normalizePromptPayID(id string) string {
+	if id == "" {
+		return ""
+	}
+	normalized, _ := promptpay.NormalizeID(id)
+	return normalized
+}
+
+// toUserDatum falls back to an initials avatar for a user without image.
+func toUserDatum(user domains.User) models.GetUserDatum {
+	profileImageURL := user.ProfileImageURL
+	if profileImageURL == "" {
+		profileImageURL = images.InitialsDataURL(user.Nickname, slices.Max(avatarSizes))
+	}
+	return models.GetUserDatum{
+		ID:              user.ID,
+		DefaultCarID:    user.DefaultCarID,
+		Nickname:        user.Nickname,
+		ProfileImageURL: profileImageURL,
+		PromptPayID:     masks.Left(user.PromptPayID, 4),
+		IsDeactivated:   user.IsDeactivated,
+	}
+}
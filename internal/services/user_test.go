@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"gorm.io/gorm"
+)
+
+func (stub *stubDatabaseAdaptor) GetAllUsers(ctx context.Context) ([]domains.User, error) {
+	return stub.users, nil
+}
+
+func (stub *stubDatabaseAdaptor) GetUserByID(ctx context.Context, userID int64) (*domains.User, error) {
+	for _, user := range stub.users {
+		if user.ID == userID {
+			return &user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (stub *stubDatabaseAdaptor) GetUserCredentialByUsername(ctx context.Context, username string) (*domains.UserCredential, error) {
+	for _, userCredential := range stub.userCredentials {
+		if userCredential.Username == username {
+			return &userCredential, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (stub *stubDatabaseAdaptor) UpdateUser(ctx context.Context, user domains.User) error {
+	stub.updatedUsers = append(stub.updatedUsers, user)
+	return nil
+}
+
+func TestService_GetUsers(t *testing.T) {
+	s := New(nil, &stubDatabaseAdaptor{
+		users: []domains.User{
+			{ID: 1, Nickname: "Best"},
+			{ID: 2, Nickname: "Boss", IsDeactivated: true},
+		},
+	}, nil, nil)
+
+	tests := []struct {
+		name               string
+		includeDeactivated bool
+		wantCount          int
+	}{
+		{
+			name:      "hide deactivated users",
+			wantCount: 1,
+		},
+		{
+			name:               "include deactivated users",
+			includeDeactivated: true,
+			wantCount:          2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetUsers(context.Background(), models.GetUsersRequest{
+				IncludeDeactivated: tt.includeDeactivated,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Data) != tt.wantCount {
+				t.Errorf("GetUsers() got %d users, want %d", len(got.Data), tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestService_CreateUser_UsernameTaken(t *testing.T) {
+	s := New(nil, &stubDatabaseAdaptor{
+		cars:            []domains.Car{{ID: 1, Name: "Mazda 2"}},
+		userCredentials: []domains.UserCredential{{UserID: 1, Username: "boss"}},
+	}, nil, nil)
+
+	_, err := s.CreateUser(contextWithUser(1), models.PostUserRequest{
+		Nickname:     "Boss",
+		DefaultCarID: 1,
+		Username:     "boss",
+		Password:     "password",
+	})
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("CreateUser() err = %v, want %v", err, errs.ErrConflict)
+	}
+}
+
+func TestService_DeactivateUserByID(t *testing.T) {
+	tests := []struct {
+		name        string
+		actingUser  int64
+		wantErr     error
+		wantUpdated bool
+	}{
+		{
+			name:        "deactivate self",
+			actingUser:  1,
+			wantUpdated: true,
+		},
+		{
+			name:       "deactivate another user",
+			actingUser: 2,
+			wantErr:    errs.ErrForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubDatabaseAdaptor{
+				users: []domains.User{{ID: 1, Nickname: "Boss"}, {ID: 2, Nickname: "Best"}},
+			}
+			s := New(nil, stub, nil, nil)
+
+			err := s.DeactivateUserByID(contextWithUser(tt.actingUser), models.DeleteUserByIDRequest{
+				UserID: 1,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeactivateUserByID() err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantUpdated != (len(stub.updatedUsers) == 1 && stub.updatedUsers[0].IsDeactivated) {
+				t.Errorf("updated users = %+v", stub.updatedUsers)
+			}
+		})
+	}
+}
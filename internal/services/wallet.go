@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/shopspring/decimal"
+)
+
+// payFuelUsageUsersFromWallet pays every share in full, the wallet
+// transactions of the shares are what the users owe instead.
+func payFuelUsageUsersFromWallet(fuelUsageUsers []domains.FuelUsageUser) {
+	for i := range fuelUsageUsers {
+		fuelUsageUsers[i].IsPaid = true
+		fuelUsageUsers[i].PaidAmount = fuelUsageUsers[i].Amount
+	}
+}
+
+// FuelUsageWalletTransactions deducts the share of every fuel user from the
+// wallet, a fuel usage not paid from the wallet has none.
+func FuelUsageWalletTransactions(fuelUsage domains.FuelUsage, fuelUsageUsers []domains.FuelUsageUser, now time.Time) []domains.WalletTransaction {
+	if !fuelUsage.IsPaidFromWallet {
+		return nil
+	}
+	var walletTransactions []domains.WalletTransaction
+	for _, fuelUsageUser := range fuelUsageUsers {
+		if fuelUsageUser.Amount.IsZero() {
+			continue
+		}
+		walletTransactions = append(walletTransactions, domains.WalletTransaction{
+			CarID:           fuelUsage.CarID,
+			UserID:          fuelUsageUser.UserID,
+			TransactionType: domains.WalletTransactionTypeFuelUsageShare,
+			ReferenceType:   domains.WalletReferenceTypeFuelUsage,
+			ReferenceID:     fuelUsage.ID,
+			Amount:          fuelUsageUser.Amount,
+			CreateTime:      now,
+		})
+	}
+	return walletTransactions
+}
+
+// FuelRefillWalletTransactions takes the money of the refill out of the
+// wallet, a refill not paid from the wallet has none.
+func FuelRefillWalletTransactions(fuelRefill domains.FuelRefill, now time.Time) []domains.WalletTransaction {
+	if !fuelRefill.IsPaidFromWallet {
+		return nil
+	}
+	return []domains.WalletTransaction{
+		{
+			CarID:           fuelRefill.CarID,
+			UserID:          fuelRefill.RefillBy,
+			TransactionType: domains.WalletTransactionTypeFuelRefill,
+			ReferenceType:   domains.WalletReferenceTypeFuelRefill,
+			ReferenceID:     fuelRefill.ID,
+			Amount:          fuelRefill.TotalMoney,
+			CreateTime:      now,
+		},
+	}
+}
+
+func (s *Service) createWalletTransactions(ctx context.Context, walletTransactions []domains.WalletTransaction) error {
+	if len(walletTransactions) == 0 {
+		return nil
+	}
+	return s.db.CreateWalletTransactions(ctx, walletTransactions)
+}
+
+// reverseWalletTransactions takes back what a fuel usage or refill holds in
+// the wallet, the transactions themselves are never updated or deleted.
+func (s *Service) reverseWalletTransactions(ctx context.Context, referenceType string, referenceID int64, now time.Time) error {
+	netAmounts, err := s.db.GetWalletNetAmountsByReference(ctx, referenceType, referenceID)
+	if err != nil {
+		return err
+	}
+
+	var walletTransactions []domains.WalletTransaction
+	for _, netAmount := range netAmounts {
+		if netAmount.NetAmount.IsZero() {
+			continue
+		}
+		walletTransactions = append(walletTransactions, domains.WalletTransaction{
+			CarID:           netAmount.CarID,
+			UserID:          netAmount.UserID,
+			TransactionType: netAmount.TransactionType,
+			ReferenceType:   referenceType,
+			ReferenceID:     referenceID,
+			Amount:          netAmount.NetAmount.Neg(),
+			CreateTime:      now,
+		})
+	}
+
+	return s.createWalletTransactions(ctx, walletTransactions)
+}
+
+// replaceFuelUsageWalletTransactions takes back the deductions of the fuel
+// usage and deducts the current amounts of its users.
+func (s *Service) replaceFuelUsageWalletTransactions(ctx context.Context, fuelUsage domains.FuelUsage, fuelUsageUsers []domains.FuelUsageUser, now time.Time) error {
+	err := s.reverseWalletTransactions(ctx, domains.WalletReferenceTypeFuelUsage, fuelUsage.ID, now)
+	if err != nil {
+		return err
+	}
+	return s.createWalletTransactions(ctx, FuelUsageWalletTransactions(fuelUsage, fuelUsageUsers, now))
+}
+
+// shouldNotBePaidFromWallet keeps the shares deducted from the wallet paid,
+// they are taken back by editing or deleting their fuel usage.
+func (s *Service) shouldNotBePaidFromWallet(ctx context.Context, fuelUsageUsers []domains.FuelUsageUser) error {
+	if len(fuelUsageUsers) == 0 {
+		return nil
+	}
+
+	var fuelUsageUserIDs []int64
+	for _, fuelUsageUser := range fuelUsageUsers {
+		fuelUsageUserIDs = append(fuelUsageUserIDs, fuelUsageUser.ID)
+	}
+
+	fuelUsageUsersWithFuelUsage, err := s.db.GetFuelUsageUsersWithFuelUsageByIDs(ctx, fuelUsageUserIDs)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+	for _, fuelUsageUser := range fuelUsageUsersWithFuelUsage {
+		if fuelUsageUser.IsPaidFromWallet {
+			slog.WarnContext(ctx, "the share is paid from the wallet",
+				"fuelUsageUserId", fuelUsageUser.ID,
+			)
+			return errs.ErrConflict
+		}
+	}
+	return nil
+}
+
+func (s *Service) TopUpUserCarWallet(ctx context.Context, req models.PostUserCarWalletTopUpRequest) (*models.WalletTransaction, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	if err := shouldActAsUser(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+
+	car, err := s.getActiveCarByID(ctx, req.CarID)
+	if err != nil {
+		return nil, err
+	}
+	if !car.IsWalletEnabled {
+		slog.WarnContext(ctx, "the car has no wallet", "carId", req.CarID)
+		return nil, errs.ErrConflict
+	}
+
+	walletTransaction := domains.WalletTransaction{
+		CarID:           req.CarID,
+		UserID:          req.UserID,
+		TransactionType: domains.WalletTransactionTypeTopUp,
+		Amount:          req.Amount,
+		Description:     req.Description,
+		CreateTime:      time.Now(),
+	}
+
+	walletTransactions := []domains.WalletTransaction{walletTransaction}
+	if err := s.db.CreateWalletTransactions(ctx, walletTransactions); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	response := toWalletTransactionModel(walletTransactions[0])
+	return &response, nil
+}
+
+// GetCarWalletStatement sums the wallet of the car from its transactions,
+// with what every member put in and consumed.
+func (s *Service) GetCarWalletStatement(ctx context.Context, req models.GetCarWalletStatementRequest) (*models.GetCarWalletStatementResponse, error) {
+	if err := req.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, errs.ErrValidateFailed
+	}
+
+	car, err := s.getCarByID(ctx, req.CarID)
+	if err != nil {
+		return nil, err
+	}
+
+	walletTransactions, err := s.db.GetCarWalletTransactions(ctx, req.CarID)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+
+	users, err := s.db.GetAllUsers(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return nil, err
+	}
+	userIDToNickname := make(map[int64]string)
+	for _, user := range users {
+		userIDToNickname[user.ID] = user.Nickname
+	}
+
+	response := models.GetCarWalletStatementResponse{
+		CarID:            car.ID,
+		IsWalletEnabled:  car.IsWalletEnabled,
+		Balance:          decimal.Zero,
+		TotalTopUp:       decimal.Zero,
+		TotalRefill:      decimal.Zero,
+		TotalConsumption: decimal.Zero,
+		Members:          []models.WalletMember{},
+		Transactions:     []models.WalletTransaction{},
+	}
+
+	userIDToMemberIndex := make(map[int64]int)
+	member := func(userID int64) *models.WalletMember {
+		index, found := userIDToMemberIndex[userID]
+		if !found {
+			index = len(response.Members)
+			userIDToMemberIndex[userID] = index
+			response.Members = append(response.Members, models.WalletMember{
+				UserID:       userID,
+				Nickname:     userIDToNickname[userID],
+				Contribution: decimal.Zero,
+				Consumption:  decimal.Zero,
+			})
+		}
+		return &response.Members[index]
+	}
+
+	for _, walletTransaction := range walletTransactions {
+		switch walletTransaction.TransactionType {
+		case domains.WalletTransactionTypeTopUp:
+			response.TotalTopUp = response.TotalTopUp.Add(walletTransaction.Amount)
+			m := member(walletTransaction.UserID)
+			m.Contribution = m.Contribution.Add(walletTransaction.Amount)
+		case domains.WalletTransactionTypeFuelUsageShare:
+			response.TotalConsumption = response.TotalConsumption.Add(walletTransaction.Amount)
+			m := member(walletTransaction.UserID)
+			m.Consumption = m.Consumption.Add(walletTransaction.Amount)
+		case domains.WalletTransactionTypeFuelRefill:
+			response.TotalRefill = response.TotalRefill.Add(walletTransaction.Amount)
+		}
+		response.Transactions = append(response.Transactions, toWalletTransactionModel(walletTransaction))
+	}
+
+	response.Balance = response.TotalTopUp.Sub(response.TotalRefill)
+	for i := range response.Members {
+		response.Members[i].Balance = response.Members[i].Contribution.Sub(response.Members[i].Consumption)
+	}
+
+	return &response, nil
+}
+
+func toWalletTransactionModel(walletTransaction domains.WalletTransaction) models.WalletTransaction {
+	return models.WalletTransaction{
+		ID:              walletTransaction.ID,
+		UserID:          walletTransaction.UserID,
+		TransactionType: walletTransaction.TransactionType,
+		ReferenceType:   walletTransaction.ReferenceType,
+		ReferenceID:     walletTransaction.ReferenceID,
+		Amount:          walletTransaction.Amount,
+		Description:     walletTransaction.Description,
+		CreateTime:      walletTransaction.CreateTime,
+	}
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/domains"
+	"github.com/bosskrub9992/fuel-management-backend/internal/entities/models"
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/shopspring/decimal"
+)
+
+func (stub *stubDatabaseAdaptor) CreateFuelUsage(ctx context.Context, fuelUsage domains.FuelUsage) (int64, error) {
+	fuelUsage.ID = int64(len(stub.fuelUsages) + 1)
+	stub.fuelUsages = append(stub.fuelUsages, fuelUsage)
+	return fuelUsage.ID, nil
+}
+
+func (stub *stubDatabaseAdaptor) CreateFuelUsageUsers(ctx context.Context, fuelUsageUsers []domains.FuelUsageUser) error {
+	for _, fuelUsageUser := range fuelUsageUsers {
+		fuelUsageUser.ID = int64(len(stub.fuelUsageUsers) + 1)
+		stub.fuelUsageUsers = append(stub.fuelUsageUsers, FuelUsageUser{FuelUsageUser: fuelUsageUser})
+	}
+	return nil
+}
+
+func (stub *stubDatabaseAdaptor) CreateFuelRefill(ctx context.Context, fuelRefill domains.FuelRefill) (int64, error) {
+	fuelRefill.ID = int64(len(stub.fuelRefills) + 1)
+	stub.fuelRefills = append(stub.fuelRefills, fuelRefill)
+	return fuelRefill.ID, nil
+}
+
+func (stub *stubDatabaseAdaptor) CreateWalletTransactions(ctx context.Context, walletTransactions []domains.WalletTransaction) error {
+	for i := range walletTransactions {
+		walletTransactions[i].ID = int64(len(stub.walletTransactions) + 1)
+		stub.walletTransactions = append(stub.walletTransactions, walletTransactions[i])
+	}
+	return nil
+}
+
+func (stub *stubDatabaseAdaptor) GetWalletNetAmountsByReference(ctx context.Context, referenceType string, referenceID int64) ([]WalletNetAmount, error) {
+	var netAmounts []WalletNetAmount
+	for _, walletTransaction := range stub.walletTransactions {
+		if walletTransaction.ReferenceType != referenceType || walletTransaction.ReferenceID != referenceID {
+			continue
+		}
+		netAmounts = append(netAmounts, WalletNetAmount{
+			UserID:          walletTransaction.UserID,
+			CarID:           walletTransaction.CarID,
+			TransactionType: walletTransaction.TransactionType,
+			NetAmount:       walletTransaction.Amount,
+		})
+	}
+	return netAmounts, nil
+}
+
+func (stub *stubDatabaseAdaptor) GetCarWalletTransactions(ctx context.Context, carID int64) ([]domains.WalletTransaction, error) {
+	var walletTransactions []domains.WalletTransaction
+	for _, walletTransaction := range stub.walletTransactions {
+		if walletTransaction.CarID == carID {
+			walletTransactions = append(walletTransactions, walletTransaction)
+		}
+	}
+	return walletTransactions, nil
+}
+
+// walletBalances sums the wallet transactions of every user by their type.
+func walletBalances(walletTransactions []domains.WalletTransaction) map[string]map[int64]decimal.Decimal {
+	balances := make(map[string]map[int64]decimal.Decimal)
+	for _, walletTransaction := range walletTransactions {
+		if balances[walletTransaction.TransactionType] == nil {
+			balances[walletTransaction.TransactionType] = make(map[int64]decimal.Decimal)
+		}
+		userIDToAmount := balances[walletTransaction.TransactionType]
+		userIDToAmount[walletTransaction.UserID] = userIDToAmount[walletTransaction.UserID].Add(walletTransaction.Amount)
+	}
+	return balances
+}
+
+func TestService_CreateFuelUsage_paidFromWallet(t *testing.T) {
+	stub := &stubDatabaseAdaptor{
+		cars: []domains.Car{{ID: 1, IsWalletEnabled: true}},
+	}
+	s := New(nil, stub, nil, nil)
+
+	_, err := s.CreateFuelUsage(context.Background(), models.CreateFuelUsageRequest{
+		CurrentCarID: 1,
+		FuelUseTime:  time.Now(),
+		FuelPrice:    decimal.NewFromInt(2),
+		FuelUsers: []models.FuelUser{
+			{UserID: 1, IsPaid: true},
+			{UserID: 2, IsPaid: true},
+		},
+		KilometerBeforeUse: 850,
+		KilometerAfterUse:  800,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stub.fuelUsages) != 1 || !stub.fuelUsages[0].IsPaidFromWallet {
+		t.Fatalf("fuel usages = %+v, want one paid from the wallet", stub.fuelUsages)
+	}
+	for _, fuelUsageUser := range stub.fuelUsageUsers {
+		if !fuelUsageUser.IsPaid || !fuelUsageUser.PaidAmount.Equal(fuelUsageUser.Amount) {
+			t.Errorf("share of user %d = %+v, want paid in full", fuelUsageUser.UserID, fuelUsageUser.FuelUsageUser)
+		}
+	}
+	for userID, balance := range ledgerBalances(stub.ledgerEntries) {
+		if !balance.IsZero() {
+			t.Errorf("ledger balance of user %d = %s, want 0", userID, balance)
+		}
+	}
+
+	consumptions := walletBalances(stub.walletTransactions)[domains.WalletTransactionTypeFuelUsageShare]
+	for _, userID := range []int64{1, 2} {
+		if !consumptions[userID].Equal(decimal.NewFromInt(50)) {
+			t.Errorf("consumption of user %d = %s, want 50", userID, consumptions[userID])
+		}
+	}
+}
+
+func TestService_CreateFuelRefill_paidFromWallet(t *testing.T) {
+	stub := &stubDatabaseAdaptor{
+		cars: []domains.Car{{ID: 1, IsWalletEnabled: true}},
+	}
+	s := New(nil, stub, nil, nil)
+
+	err := s.CreateFuelRefill(contextWithUser(1), models.CreateFuelRefillRequest{
+		CurrentCarID:          1,
+		RefillTime:            time.Now(),
+		KilometerBeforeRefill: 100,
+		KilometerAfterRefill:  500,
+		TotalMoney:            decimal.NewFromInt(1000),
+		RefillBy:              2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fuelRefill := stub.fuelRefills[0]
+	if !fuelRefill.IsPaidFromWallet || !fuelRefill.IsPaid || !fuelRefill.PaidAmount.Equal(fuelRefill.TotalMoney) {
+		t.Errorf("fuel refill = %+v, want paid from the wallet", fuelRefill)
+	}
+	if balance := ledgerBalances(stub.ledgerEntries)[2]; !balance.IsZero() {
+		t.Errorf("ledger balance of the refiller = %s, want 0", balance)
+	}
+	refills := walletBalances(stub.walletTransactions)[domains.WalletTransactionTypeFuelRefill]
+	if !refills[2].Equal(decimal.NewFromInt(1000)) {
+		t.Errorf("refills from the wallet = %v, want 1000 by user 2", refills)
+	}
+}
+
+func TestService_replaceFuelUsageWalletTransactions(t *testing.T) {
+	stub := &stubDatabaseAdaptor{}
+	s := New(nil, stub, nil, nil)
+	ctx := context.Background()
+	now := time.Now()
+
+	fuelUsage := domains.FuelUsage{ID: 1, CarID: 1, IsPaidFromWallet: true}
+	share := func(userID int64, amount int64) domains.FuelUsageUser {
+		return domains.FuelUsageUser{UserID: userID, Amount: decimal.NewFromInt(amount)}
+	}
+
+	err := s.createWalletTransactions(ctx, FuelUsageWalletTransactions(fuelUsage, []domains.FuelUsageUser{share(1, 60), share(2, 40)}, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.replaceFuelUsageWalletTransactions(ctx, fuelUsage, []domains.FuelUsageUser{share(1, 70), share(3, 30)}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	consumptions := walletBalances(stub.walletTransactions)[domains.WalletTransactionTypeFuelUsageShare]
+	want := map[int64]int64{1: 70, 2: 0, 3: 30}
+	for userID, amount := range want {
+		if !consumptions[userID].Equal(decimal.NewFromInt(amount)) {
+			t.Errorf("consumption of user %d = %s, want %d", userID, consumptions[userID], amount)
+		}
+	}
+}
+
+func TestService_BulkUpdateUserFuelUsagePaymentStatus_paidFromWallet(t *testing.T) {
+	s := New(nil, &stubDatabaseAdaptor{
+		cars:       []domains.Car{{ID: 1, IsWalletEnabled: true}},
+		fuelUsages: []domains.FuelUsage{{ID: 1, CarID: 1, IsPaidFromWallet: true}},
+		fuelUsageUsers: []FuelUsageUser{
+			{FuelUsageUser: domains.FuelUsageUser{ID: 10, FuelUsageID: 1, UserID: 1, IsPaid: true, Amount: decimal.NewFromInt(50), PaidAmount: decimal.NewFromInt(50)}},
+		},
+	}, nil, nil)
+
+	req := models.BulkUpdateUserFuelUsagePaymentStatusRequest{UserID: 1}
+	body := `{"userFuelUsages": [{"id": 10, "isPaid": false}]}`
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatal(err)
+	}
+
+	err := s.BulkUpdateUserFuelUsagePaymentStatus(contextWithUser(1), req)
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("BulkUpdateUserFuelUsagePaymentStatus() err = %v, want %v", err, errs.ErrConflict)
+	}
+}
+
+func TestService_TopUpUserCarWallet(t *testing.T) {
+	stub := &stubDatabaseAdaptor{
+		cars: []domains.Car{
+			{ID: 1, IsWalletEnabled: true},
+			{ID: 2},
+		},
+	}
+	s := New(nil, stub, nil, nil)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		carID   int64
+		wantErr error
+	}{
+		{
+			name:  "top up",
+			ctx:   contextWithUser(1),
+			carID: 1,
+		},
+		{
+			name:    "top up for another user",
+			ctx:     contextWithUser(2),
+			carID:   1,
+			wantErr: errs.ErrForbidden,
+		},
+		{
+			name:    "car without wallet",
+			ctx:     contextWithUser(1),
+			carID:   2,
+			wantErr: errs.ErrConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.TopUpUserCarWallet(tt.ctx, models.PostUserCarWalletTopUpRequest{
+				UserID: 1,
+				CarID:  tt.carID,
+				Amount: decimal.NewFromInt(500),
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("TopUpUserCarWallet() err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (got.ID == 0 || got.TransactionType != domains.WalletTransactionTypeTopUp) {
+				t.Errorf("TopUpUserCarWallet() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestService_GetCarWalletStatement(t *testing.T) {
+	transaction := func(userID int64, transactionType string, amount int64) domains.WalletTransaction {
+		return domains.WalletTransaction{
+			CarID:           1,
+			UserID:          userID,
+			TransactionType: transactionType,
+			Amount:          decimal.NewFromInt(amount),
+		}
+	}
+	s := New(nil, &stubDatabaseAdaptor{
+		cars: []domains.Car{{ID: 1, IsWalletEnabled: true}},
+		users: []domains.User{
+			{ID: 1, Nickname: "Best"},
+			{ID: 2, Nickname: "Boss"},
+		},
+		walletTransactions: []domains.WalletTransaction{
+			transaction(1, domains.WalletTransactionTypeTopUp, 1000),
+			transaction(2, domains.WalletTransactionTypeTopUp, 500),
+			transaction(1, domains.WalletTransactionTypeFuelRefill, 1200),
+			transaction(1, domains.WalletTransactionTypeFuelUsageShare, 300),
+			transaction(2, domains.WalletTransactionTypeFuelUsageShare, 700),
+			// a deleted fuel usage
+			transaction(2, domains.WalletTransactionTypeFuelUsageShare, 100),
+			transaction(2, domains.WalletTransactionTypeFuelUsageShare, -100),
+		},
+	}, nil, nil)
+
+	got, err := s.GetCarWalletStatement(context.Background(), models.GetCarWalletStatementRequest{CarID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !got.Balance.Equal(decimal.NewFromInt(300)) || !got.TotalConsumption.Equal(decimal.NewFromInt(1000)) {
+		t.Errorf("balance = %s, consumption = %s, want 300 and 1000", got.Balance, got.TotalConsumption)
+	}
+	if len(got.Transactions) != 7 {
+		t.Errorf("got %d transactions, want 7", len(got.Transactions))
+	}
+
+	want := []models.WalletMember{
+		{UserID: 1, Nickname: "Best", Contribution: decimal.NewFromInt(1000), Consumption: decimal.NewFromInt(300), Balance: decimal.NewFromInt(700)},
+		{UserID: 2, Nickname: "Boss", Contribution: decimal.NewFromInt(500), Consumption: decimal.NewFromInt(700), Balance: decimal.NewFromInt(-200)},
+	}
+	if len(got.Members) != len(want) {
+		t.Fatalf("members = %+v, want %+v", got.Members, want)
+	}
+	for i, member := range got.Members {
+		if member.UserID != want[i].UserID || member.Nickname != want[i].Nickname ||
+			!member.Contribution.Equal(want[i].Contribution) ||
+			!member.Consumption.Equal(want[i].Consumption) ||
+			!member.Balance.Equal(want[i].Balance) {
+			t.Errorf("members[%d] = %+v, want %+v", i, member, want[i])
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package allocations
+
+import (
+	"slices"
+
+	"github.com/shopspring/decimal"
+)
+
+// ByWeights splits totalMoney by the largest remainder method: every
+// part is rounded down to satang, then the satang left over go one each to
+// the parts which lost the most by rounding, ties go by tieBreakOrder.
+func ByWeights(totalMoney decimal.Decimal, weights []decimal.Decimal, tieBreakOrder []int) []decimal.Decimal {
+	// a total money finer than satang is allocated in its own unit
+	places := int32(2)
+	for !totalMoney.Equal(totalMoney.Truncate(places)) {
+		places++
+	}
+	unit := decimal.New(1, -places)
+
+	sumWeight := decimal.Zero
+	for _, weight := range weights {
+		sumWeight = sumWeight.Add(weight)
+	}
+
+	parts := make([]decimal.Decimal, len(weights))
+	remainders := make([]decimal.Decimal, len(weights))
+	allocated := decimal.Zero
+	for i, weight := range weights {
+		exact := totalMoney.Mul(weight).Div(sumWeight)
+		parts[i] = exact.RoundFloor(places)
+		remainders[i] = exact.Sub(parts[i])
+		allocated = allocated.Add(parts[i])
+	}
+
+	order := slices.Clone(tieBreakOrder)
+	slices.SortStableFunc(order, func(a, b int) int {
+		return remainders[b].Cmp(remainders[a])
+	})
+	leftoverUnits := int(totalMoney.Sub(allocated).Div(unit).IntPart())
+	for _, i := range order[:leftoverUnits] {
+		parts[i] = parts[i].Add(unit)
+	}
+	return parts
+}
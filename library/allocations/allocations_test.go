@@ -0,0 +1,59 @@
+package allocations
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestByWeights(t *testing.T) {
+	tests := []struct {
+		name          string
+		totalMoney    string
+		weights       []string
+		tieBreakOrder []int
+		wantParts     []string
+	}{
+		{
+			name:          "leftover satang by the tie break order",
+			totalMoney:    "100",
+			weights:       []string{"1", "1", "1"},
+			tieBreakOrder: []int{2, 1, 0},
+			wantParts:     []string{"33.33", "33.33", "33.34"},
+		},
+		{
+			name:          "largest remainder wins over the tie break order",
+			totalMoney:    "10",
+			weights:       []string{"1", "1", "1", "3"},
+			tieBreakOrder: []int{3, 2, 1, 0},
+			wantParts:     []string{"1.66", "1.67", "1.67", "5"},
+		},
+		{
+			name:          "total money finer than satang",
+			totalMoney:    "10.001",
+			weights:       []string{"1", "1"},
+			tieBreakOrder: []int{0, 1},
+			wantParts:     []string{"5.001", "5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var weights []decimal.Decimal
+			for _, weight := range tt.weights {
+				weights = append(weights, decimal.RequireFromString(weight))
+			}
+			parts := ByWeights(decimal.RequireFromString(tt.totalMoney), weights, tt.tieBreakOrder)
+			sum := decimal.Zero
+			for i, part := range parts {
+				sum = sum.Add(part)
+				if !part.Equal(decimal.RequireFromString(tt.wantParts[i])) {
+					t.Errorf("parts = %v, want %v", parts, tt.wantParts)
+					break
+				}
+			}
+			if !sum.Equal(decimal.RequireFromString(tt.totalMoney)) {
+				t.Errorf("parts sum to %s, want %s", sum, tt.totalMoney)
+			}
+		})
+	}
+}
@@ -7,15 +7,30 @@ import (
 type Code int
 
 const (
-	CodeAPIFailed      Code = 1000
-	CodeBadRequest     Code = 1001
-	CodeValidateFailed Code = 1002
+	CodeAPIFailed              Code = 1000
+	CodeBadRequest             Code = 1001
+	CodeValidateFailed         Code = 1002
+	CodeUnauthorized           Code = 1003
+	CodeForbidden              Code = 1004
+	CodeNotFound               Code = 1005
+	CodeConflict               Code = 1006
+	CodeKilometerDiscontinuity Code = 1007
+	CodePaidRecordLocked       Code = 1008
 )
 
 var (
 	ErrAPIFailed      Err = New(http.StatusInternalServerError, CodeAPIFailed, "api failed", nil)
 	ErrBadRequest     Err = New(http.StatusBadRequest, CodeBadRequest, "bad request", nil)
 	ErrValidateFailed Err = New(http.StatusUnprocessableEntity, CodeValidateFailed, "validate failed", nil)
+	ErrUnauthorized   Err = New(http.StatusUnauthorized, CodeUnauthorized, "unauthorized", nil)
+	ErrForbidden      Err = New(http.StatusForbidden, CodeForbidden, "forbidden", nil)
+	ErrNotFound       Err = New(http.StatusNotFound, CodeNotFound, "not found", nil)
+	ErrConflict       Err = New(http.StatusConflict, CodeConflict, "conflict", nil)
+	// ErrKilometerDiscontinuity carries the warnings which rejected the request
+	ErrKilometerDiscontinuity Err = New(http.StatusUnprocessableEntity, CodeKilometerDiscontinuity, "kilometer discontinuity", nil)
+	// ErrPaidRecordLocked is an edit or a delete of a paid fuel usage or
+	// refill, which is corrected by an adjustment instead
+	ErrPaidRecordLocked Err = New(http.StatusConflict, CodePaidRecordLocked, "paid record is locked", nil)
 )
 
 type Err struct {
@@ -0,0 +1,66 @@
+package images
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestThumbnail(t *testing.T) {
+	// 4x2, left half red and right half blue, the center crop is 2x2
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	got := Thumbnail(src, 1)
+	if got.Bounds() != image.Rect(0, 0, 1, 1) {
+		t.Fatalf("Thumbnail() bounds = %v", got.Bounds())
+	}
+	want := color.RGBA{R: 127, B: 127, A: 255}
+	if c := got.RGBAAt(0, 0); c != want {
+		t.Errorf("Thumbnail() pixel = %v, want %v", c, want)
+	}
+
+	upscaled := Thumbnail(src, 4)
+	if c := upscaled.RGBAAt(0, 0); c != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("upscaled left pixel = %v", c)
+	}
+	if c := upscaled.RGBAAt(3, 3); c != (color.RGBA{B: 255, A: 255}) {
+		t.Errorf("upscaled right pixel = %v", c)
+	}
+}
+
+func TestInitials(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "boss", want: "B"},
+		{name: "john doe smith", want: "JD"},
+		{name: "  (nut) ", want: "N"},
+		{name: "บอส", want: "บ"},
+		{name: "", want: "?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Initials(tt.name); got != tt.want {
+				t.Errorf("Initials(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitialsSVG(t *testing.T) {
+	svg := string(InitialsSVG("<b>", 64))
+	if !strings.Contains(svg, ">B</text>") {
+		t.Errorf("InitialsSVG() should escape and render initials, got %s", svg)
+	}
+}
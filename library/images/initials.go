@@ -0,0 +1,59 @@
+package images
+
+import (
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"strings"
+	"unicode"
+)
+
+var initialsBackgrounds = []string{
+	"#F44336", "#E91E63", "#9C27B0", "#3F51B5", "#2196F3",
+	"#009688", "#4CAF50", "#FF9800", "#795548", "#607D8B",
+}
+
+// Initials returns the uppercase first letter of up to two words of name.
+func Initials(name string) string {
+	var initials []rune
+	for _, word := range strings.Fields(name) {
+		for _, r := range word {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				initials = append(initials, unicode.ToUpper(r))
+				break
+			}
+		}
+		if len(initials) == 2 {
+			break
+		}
+	}
+	if len(initials) == 0 {
+		return "?"
+	}
+	return string(initials)
+}
+
+// InitialsSVG renders the initials of name on a background color picked
+// from name, so a user keeps the same color.
+func InitialsSVG(name string, size int) []byte {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(name))
+	background := initialsBackgrounds[hash.Sum32()%uint32(len(initialsBackgrounds))]
+
+	return []byte(fmt.Sprintf(
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="%[1]d" viewBox="0 0 %[1]d %[1]d">`+
+			`<rect width="100%%" height="100%%" fill="%[2]s"/>`+
+			`<text x="50%%" y="50%%" dy=".35em" fill="#FFFFFF" font-family="sans-serif" font-size="%[3]d" text-anchor="middle">%[4]s</text>`+
+			`</svg>`,
+		size,
+		background,
+		size*2/5,
+		html.EscapeString(Initials(name)),
+	))
+}
+
+// InitialsDataURL is InitialsSVG as a data URL usable as an image src.
+func InitialsDataURL(name string, size int) string {
+	return "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString(InitialsSVG(name, size))
+}
@@ -0,0 +1,94 @@
+package jwts
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type Config struct {
+	SecretKey      string        `mapstructure:"secret_key"`
+	Issuer         string        `mapstructure:"issuer"`
+	ExpireDuration time.Duration `mapstructure:"expire_duration"`
+}
+
+// defaultSecretKey is the secret key once shipped in config.yml, tokens
+// signed with it can be forged by anyone who read the repository.
+const defaultSecretKey = "local-secret-key"
+
+var (
+	ErrEmptySecretKey   = errors.New("jwt secret key should be set, e.g. by env AUTH_JWT_SECRET_KEY")
+	ErrDefaultSecretKey = errors.New("jwt secret key should not be the default one")
+)
+
+// Validate refuses a secret key which is empty or the default, the server
+// should not start with either.
+func (cfg Config) Validate() error {
+	switch cfg.SecretKey {
+	case "":
+		return ErrEmptySecretKey
+	case defaultSecretKey:
+		return ErrDefaultSecretKey
+	}
+	return nil
+}
+
+type JWT struct {
+	secretKey      []byte
+	issuer         string
+	expireDuration time.Duration
+}
+
+func New(cfg *Config) *JWT {
+	return &JWT{
+		secretKey:      []byte(cfg.SecretKey),
+		issuer:         cfg.Issuer,
+		expireDuration: cfg.ExpireDuration,
+	}
+}
+
+// Sign returns a HS256 signed token carrying userID as the subject
+// together with the time the token expires.
+func (j *JWT) Sign(userID int64, now time.Time) (string, time.Time, error) {
+	expireTime := now.Add(j.expireDuration)
+	claims := jwt.RegisteredClaims{
+		Issuer:    j.issuer,
+		Subject:   strconv.FormatInt(userID, 10),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expireTime),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(j.secretKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expireTime, nil
+}
+
+// Parse verifies the signature, issuer and expiry of tokenString
+// and returns the user id stored in its subject.
+func (j *JWT) Parse(tokenString string) (int64, error) {
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(tokenString, &claims,
+		func(token *jwt.Token) (any, error) {
+			return j.secretKey, nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(j.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid subject %q: %w", claims.Subject, err)
+	}
+	if userID <= 0 {
+		return 0, errors.New("subject should be a positive user id")
+	}
+	return userID, nil
+}
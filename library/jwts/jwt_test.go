@@ -0,0 +1,87 @@
+package jwts
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJWT_SignAndParse(t *testing.T) {
+	j := New(&Config{
+		SecretKey:      "secret",
+		Issuer:         "test",
+		ExpireDuration: time.Hour,
+	})
+	tests := []struct {
+		name       string
+		parser     *JWT
+		signTime   time.Time
+		wantUserID int64
+		wantErr    bool
+	}{
+		{
+			name:       "valid token",
+			parser:     j,
+			signTime:   time.Now(),
+			wantUserID: 7,
+		},
+		{
+			name:     "expired token",
+			parser:   j,
+			signTime: time.Now().Add(-2 * time.Hour),
+			wantErr:  true,
+		},
+		{
+			name: "signed with another secret",
+			parser: New(&Config{
+				SecretKey:      "another secret",
+				Issuer:         "test",
+				ExpireDuration: time.Hour,
+			}),
+			signTime: time.Now(),
+			wantErr:  true,
+		},
+		{
+			name: "signed by another issuer",
+			parser: New(&Config{
+				SecretKey:      "secret",
+				Issuer:         "another issuer",
+				ExpireDuration: time.Hour,
+			}),
+			signTime: time.Now(),
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, _, err := j.Sign(7, tt.signTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+			userID, err := tt.parser.Parse(token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if userID != tt.wantUserID {
+				t.Errorf("Parse() = %v, want %v", userID, tt.wantUserID)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		secretKey string
+		wantErr   error
+	}{
+		{secretKey: "", wantErr: ErrEmptySecretKey},
+		{secretKey: "local-secret-key", wantErr: ErrDefaultSecretKey},
+		{secretKey: "a secret of this deployment"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.secretKey, func(t *testing.T) {
+			if err := (Config{SecretKey: tt.secretKey}).Validate(); err != tt.wantErr {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
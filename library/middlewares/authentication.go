@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/bosskrub9992/fuel-management-backend/library/jwts"
+	"github.com/labstack/echo/v4"
+)
+
+const bearerPrefix = "Bearer "
+
+// Authentication rejects requests without a valid bearer token and puts
+// the authenticated user id into the request context.
+func Authentication(j *jwts.JWT) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := req.Context()
+
+			authorization := req.Header.Get(echo.HeaderAuthorization)
+			if !strings.HasPrefix(authorization, bearerPrefix) {
+				slog.WarnContext(ctx, errors.New("missing bearer token").Error())
+				resp := errs.ErrUnauthorized
+				return c.JSON(resp.Status, resp)
+			}
+
+			userID, err := j.Parse(strings.TrimPrefix(authorization, bearerPrefix))
+			if err != nil {
+				slog.WarnContext(ctx, err.Error())
+				resp := errs.ErrUnauthorized
+				return c.JSON(resp.Status, resp)
+			}
+
+			ctx = context.WithValue(ctx, ContextKeyUserID, userID)
+			c.SetRequest(req.WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
+
+// UserIDFromContext returns the user id put by Authentication.
+func UserIDFromContext(ctx context.Context) (int64, bool) {
+	userID, ok := ctx.Value(ContextKeyUserID).(int64)
+	return userID, ok
+}
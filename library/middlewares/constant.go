@@ -8,8 +8,10 @@ type (
 const (
 	ContextKeyRequestID ContextKey = "request_id"
 	ContextKeyHTMXData  ContextKey = "htmx_data"
+	ContextKeyUserID    ContextKey = "user_id"
 )
 
 const (
-	LogFieldKeyReqID LogFieldKey = "reqId"
+	LogFieldKeyReqID  LogFieldKey = "reqId"
+	LogFieldKeyUserID LogFieldKey = "userId"
 )
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/bosskrub9992/fuel-management-backend/library/errs"
+	"github.com/bosskrub9992/fuel-management-backend/library/masks"
 	"github.com/labstack/echo/v4"
 )
 
@@ -39,6 +40,7 @@ func Logger() echo.MiddlewareFunc {
 					resp := errs.ErrAPIFailed
 					return c.JSON(resp.Status, resp)
 				}
+				maskBody(reqBody)
 				// Set the body back to the request.
 				c.Request().Body = io.NopCloser(bytes.NewBuffer(rawReqBody))
 			}
@@ -72,6 +74,7 @@ func Logger() echo.MiddlewareFunc {
 					resp := errs.ErrAPIFailed
 					return c.JSON(resp.Status, resp)
 				}
+				maskBody(resBody)
 			}
 
 			var level slog.Level
@@ -109,6 +112,17 @@ func Logger() echo.MiddlewareFunc {
 	}
 }
 
+// maskingBodyFields are credentials that must never reach the log.
+var maskingBodyFields = []string{"password", "newPassword", "accessToken"}
+
+func maskBody(body map[string]any) {
+	for _, field := range maskingBodyFields {
+		if _, found := body[field]; found {
+			body[field] = masks.All(field)
+		}
+	}
+}
+
 type MsgResponseWriter struct {
 	io.Writer
 	http.ResponseWriter
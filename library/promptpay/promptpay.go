@@ -0,0 +1,114 @@
+package promptpay
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+// the PromptPay application id and the kinds of account of the merchant
+// account information
+const (
+	applicationID         = "A000000677010111"
+	accountTypePhone      = "01"
+	accountTypeNationalID = "02"
+	accountTypeEWallet    = "03"
+)
+
+var ErrInvalidID = errors.New("promptpay id should be a phone number of 10 digits, a national id of 13 digits or an e-wallet id of 15 digits")
+
+// NormalizeID keeps the digits of a PromptPay id, a phone number with the
+// country code 66 becomes a local phone number.
+func NormalizeID(id string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		if r == ' ' || r == '-' || r == '+' {
+			return -1
+		}
+		return 'x'
+	}, id)
+	if strings.Contains(digits, "x") {
+		return "", ErrInvalidID
+	}
+
+	if len(digits) == 11 && strings.HasPrefix(digits, "66") {
+		digits = "0" + digits[2:]
+	}
+
+	switch len(digits) {
+	case 10, 13, 15:
+		return digits, nil
+	default:
+		return "", ErrInvalidID
+	}
+}
+
+// Payload is the EMVCo QR payload which transfers the amount to the
+// PromptPay id, an amount of 0 leaves the payer to fill it in.
+func Payload(id string, amount decimal.Decimal) (string, error) {
+	digits, err := NormalizeID(id)
+	if err != nil {
+		return "", err
+	}
+	if amount.IsNegative() {
+		return "", fmt.Errorf("amount should >= 0, got: [%s]", amount)
+	}
+
+	var account string
+	switch len(digits) {
+	case 10:
+		// 0812345678 is sent as 0066812345678
+		account = field(accountTypePhone, "0066"+digits[1:])
+	case 13:
+		account = field(accountTypeNationalID, digits)
+	case 15:
+		account = field(accountTypeEWallet, digits)
+	}
+
+	pointOfInitiation := "11"
+	if amount.IsPositive() {
+		pointOfInitiation = "12"
+	}
+
+	var payload strings.Builder
+	payload.WriteString(field("00", "01"))
+	payload.WriteString(field("01", pointOfInitiation))
+	payload.WriteString(field("29", field("00", applicationID)+account))
+	payload.WriteString(field("53", "764"))
+	if amount.IsPositive() {
+		payload.WriteString(field("54", amount.StringFixed(2)))
+	}
+	payload.WriteString(field("58", "TH"))
+
+	// the checksum covers its own id and length
+	payload.WriteString("6304")
+	payload.WriteString(fmt.Sprintf("%04X", crc16([]byte(payload.String()))))
+
+	return payload.String(), nil
+}
+
+// field is the id, the length and the value of an EMVCo data object.
+func field(id, value string) string {
+	return fmt.Sprintf("%s%02d%s", id, len(value), value)
+}
+
+// crc16 is CRC-16/CCITT-FALSE, the checksum of the EMVCo QR payload.
+func crc16(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,82 @@
+package promptpay
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func Test_crc16(t *testing.T) {
+	// the check value of CRC-16/CCITT-FALSE
+	if got := crc16([]byte("123456789")); got != 0x29B1 {
+		t.Errorf("crc16() = %04X, want 29B1", got)
+	}
+}
+
+func TestNormalizeID(t *testing.T) {
+	tests := []struct {
+		id      string
+		want    string
+		wantErr bool
+	}{
+		{id: "081-234-5678", want: "0812345678"},
+		{id: "+66 81 234 5678", want: "0812345678"},
+		{id: "1234567890123", want: "1234567890123"},
+		{id: "123456789012345", want: "123456789012345"},
+		{id: "08123", wantErr: true},
+		{id: "08l2345678", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			got, err := NormalizeID(tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeID() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPayload(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string
+		amount decimal.Decimal
+		want   string
+	}{
+		{
+			name:   "phone number without amount",
+			id:     "0812345678",
+			amount: decimal.Zero,
+			want:   "000201010211" + "29370016A000000677010111011300668123456785303764" + "5802TH6304",
+		},
+		{
+			name:   "national id with amount",
+			id:     "1234567890123",
+			amount: decimal.RequireFromString("420.5"),
+			want:   "000201010212" + "29370016A000000677010111021312345678901235303764" + "5406420.505802TH6304",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Payload(tt.id, tt.amount)
+			if err != nil {
+				t.Fatalf("Payload() error = %v", err)
+			}
+			if !strings.HasPrefix(got, tt.want) || len(got) != len(tt.want)+4 {
+				t.Fatalf("Payload() = %s, want %s and the checksum", got, tt.want)
+			}
+			if checksum := fmt.Sprintf("%04X", crc16([]byte(tt.want))); !strings.HasSuffix(got, checksum) {
+				t.Errorf("Payload() = %s, want the checksum %s", got, checksum)
+			}
+		})
+	}
+
+	if _, err := Payload("0812345678", decimal.NewFromInt(-1)); err == nil {
+		t.Error("Payload() of a negative amount should fail")
+	}
+}
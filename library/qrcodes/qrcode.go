@@ -0,0 +1,498 @@
+package qrcodes
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// quietZone is the light border around the symbol readers need, in modules.
+const quietZone = 4
+
+// ErrTooLong is returned for data which does not fit the largest version
+// supported.
+var ErrTooLong = errors.New("data is too long for a qr code up to version 10")
+
+// version is the size and the error correction blocks of a symbol at the
+// medium error correction level.
+type version struct {
+	number int
+	// eccPerBlock is the error correction codewords of every block
+	eccPerBlock int
+	// shortBlocks have shortDataLength data codewords, the rest of the
+	// blocks one more
+	shortBlocks     int
+	longBlocks      int
+	shortDataLength int
+	alignments      []int
+}
+
+// versions are the versions 1 to 10 at the medium error correction level.
+var versions = []version{
+	{number: 1, eccPerBlock: 10, shortBlocks: 1, shortDataLength: 16},
+	{number: 2, eccPerBlock: 16, shortBlocks: 1, shortDataLength: 28, alignments: []int{6, 18}},
+	{number: 3, eccPerBlock: 26, shortBlocks: 1, shortDataLength: 44, alignments: []int{6, 22}},
+	{number: 4, eccPerBlock: 18, shortBlocks: 2, shortDataLength: 32, alignments: []int{6, 26}},
+	{number: 5, eccPerBlock: 24, shortBlocks: 2, shortDataLength: 43, alignments: []int{6, 30}},
+	{number: 6, eccPerBlock: 16, shortBlocks: 4, shortDataLength: 27, alignments: []int{6, 34}},
+	{number: 7, eccPerBlock: 18, shortBlocks: 4, shortDataLength: 31, alignments: []int{6, 22, 38}},
+	{number: 8, eccPerBlock: 22, shortBlocks: 2, longBlocks: 2, shortDataLength: 38, alignments: []int{6, 24, 42}},
+	{number: 9, eccPerBlock: 22, shortBlocks: 3, longBlocks: 2, shortDataLength: 36, alignments: []int{6, 26, 46}},
+	{number: 10, eccPerBlock: 26, shortBlocks: 4, longBlocks: 1, shortDataLength: 43, alignments: []int{6, 28, 50}},
+}
+
+func (v version) size() int {
+	return 17 + 4*v.number
+}
+
+func (v version) dataLength() int {
+	return v.shortBlocks*v.shortDataLength + v.longBlocks*(v.shortDataLength+1)
+}
+
+// countBits is the length of the character count of the byte mode.
+func (v version) countBits() int {
+	if v.number < 10 {
+		return 8
+	}
+	return 16
+}
+
+// Code is a QR code symbol, a true module is dark.
+type Code struct {
+	Size       int
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+// Dark reports whether the module at column x and row y is dark.
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// Encode encodes data in the byte mode at the medium error correction level,
+// in the smallest version it fits.
+func Encode(data []byte) (*Code, error) {
+	var v version
+	isFound := false
+	for _, candidate := range versions {
+		if 4+candidate.countBits()+8*len(data) <= 8*candidate.dataLength() {
+			v = candidate
+			isFound = true
+			break
+		}
+	}
+	if !isFound {
+		return nil, ErrTooLong
+	}
+
+	code := newCode(v.size())
+	code.drawFunctionPatterns(v)
+	code.drawCodewords(interleave(v, encodeData(v, data)))
+
+	bestMask, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		code.applyMask(mask)
+		code.drawFormatBits(mask)
+		if penalty := code.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+		// masking twice takes the mask back off
+		code.applyMask(mask)
+	}
+	code.applyMask(bestMask)
+	code.drawFormatBits(bestMask)
+
+	return code, nil
+}
+
+func newCode(size int) *Code {
+	code := &Code{
+		Size:       size,
+		modules:    make([][]bool, size),
+		isFunction: make([][]bool, size),
+	}
+	for y := 0; y < size; y++ {
+		code.modules[y] = make([]bool, size)
+		code.isFunction[y] = make([]bool, size)
+	}
+	return code
+}
+
+// encodeData is the mode, the count and the data padded to the data
+// codewords of the version.
+func encodeData(v version, data []byte) []byte {
+	var bits bitBuffer
+	bits.append(0b0100, 4)
+	bits.append(len(data), v.countBits())
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	capacity := 8 * v.dataLength()
+	bits.append(0, min(4, capacity-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	return bits.bytes()
+}
+
+// interleave splits the data into the blocks of the version, adds the error
+// correction of every block, then takes a codeword of every block in turn.
+func interleave(v version, data []byte) []byte {
+	divisor := reedSolomonDivisor(v.eccPerBlock)
+
+	var dataBlocks, eccBlocks [][]byte
+	offset := 0
+	for i := 0; i < v.shortBlocks+v.longBlocks; i++ {
+		length := v.shortDataLength
+		if i >= v.shortBlocks {
+			length++
+		}
+		block := data[offset : offset+length]
+		offset += length
+		dataBlocks = append(dataBlocks, block)
+		eccBlocks = append(eccBlocks, reedSolomonRemainder(block, divisor))
+	}
+
+	var result []byte
+	for i := 0; i <= v.shortDataLength; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < v.eccPerBlock; i++ {
+		for _, block := range eccBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+func (c *Code) setFunction(x, y int, isDark bool) {
+	c.modules[y][x] = isDark
+	c.isFunction[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns(v version) {
+	for i := 0; i < c.Size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	c.drawFinderPattern(3, 3)
+	c.drawFinderPattern(c.Size-4, 3)
+	c.drawFinderPattern(3, c.Size-4)
+
+	last := len(v.alignments) - 1
+	for i, x := range v.alignments {
+		for j, y := range v.alignments {
+			isOnFinder := (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0)
+			if !isOnFinder {
+				c.drawAlignmentPattern(x, y)
+			}
+		}
+	}
+
+	// reserve the format bits before the data is drawn
+	c.drawFormatBits(0)
+	c.drawVersionBits(v)
+}
+
+// drawFinderPattern draws the finder pattern and its separator around the
+// center at column x and row y.
+func (c *Code) drawFinderPattern(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= c.Size || yy < 0 || yy >= c.Size {
+				continue
+			}
+			distance := max(abs(dx), abs(dy))
+			c.setFunction(xx, yy, distance != 2 && distance != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignmentPattern(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// formatBits is the medium error correction level and the mask protected by
+// the BCH code.
+func formatBits(mask int) int {
+	const eccLevelMedium = 0b00
+	data := eccLevelMedium<<3 | mask
+	remainder := data
+	for i := 0; i < 10; i++ {
+		remainder = remainder<<1 ^ (remainder>>9)*0x537
+	}
+	return (data<<10 | remainder) ^ 0x5412
+}
+
+func (c *Code) drawFormatBits(mask int) {
+	bits := formatBits(mask)
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(bits, i))
+	}
+	c.setFunction(8, 7, bit(bits, 6))
+	c.setFunction(8, 8, bit(bits, 7))
+	c.setFunction(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(bits, i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(bits, i))
+	}
+	c.setFunction(8, c.Size-8, true)
+}
+
+// versionBits is the version protected by the BCH code.
+func versionBits(number int) int {
+	remainder := number
+	for i := 0; i < 12; i++ {
+		remainder = remainder<<1 ^ (remainder>>11)*0x1F25
+	}
+	return number<<12 | remainder
+}
+
+// drawVersionBits draws the version of 7 and above next to the top right
+// and the bottom left finder patterns.
+func (c *Code) drawVersionBits(v version) {
+	if v.number < 7 {
+		return
+	}
+	bits := versionBits(v.number)
+	for i := 0; i < 18; i++ {
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, bit(bits, i))
+		c.setFunction(b, a, bit(bits, i))
+	}
+}
+
+// drawCodewords places the codewords in the zigzag of two module columns
+// from the bottom right, skipping the function patterns.
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		isUpward := (right+1)&2 == 0
+		for vertical := 0; vertical < c.Size; vertical++ {
+			y := vertical
+			if isUpward {
+				y = c.Size - 1 - vertical
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if c.isFunction[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				c.modules[y][x] = bit(int(codewords[i/8]), 7-i%8)
+				i++
+			}
+		}
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.isFunction[y][x] {
+				continue
+			}
+			var isInverted bool
+			switch mask {
+			case 0:
+				isInverted = (x+y)%2 == 0
+			case 1:
+				isInverted = y%2 == 0
+			case 2:
+				isInverted = x%3 == 0
+			case 3:
+				isInverted = (x+y)%3 == 0
+			case 4:
+				isInverted = (x/3+y/2)%2 == 0
+			case 5:
+				isInverted = x*y%2+x*y%3 == 0
+			case 6:
+				isInverted = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				isInverted = ((x+y)%2+x*y%3)%2 == 0
+			}
+			c.modules[y][x] = c.modules[y][x] != isInverted
+		}
+	}
+}
+
+// penalty scores how hard the symbol is to read, the mask with the lowest
+// score is used.
+func (c *Code) penalty() int {
+	penalty := 0
+
+	// runs of five or more modules of the same color, and patterns which
+	// look like a finder pattern, in the rows then the columns
+	for _, isRow := range []bool{true, false} {
+		for i := 0; i < c.Size; i++ {
+			line := make([]bool, c.Size)
+			for j := 0; j < c.Size; j++ {
+				if isRow {
+					line[j] = c.modules[i][j]
+				} else {
+					line[j] = c.modules[j][i]
+				}
+			}
+			penalty += linePenalty(line)
+		}
+	}
+
+	// blocks of 2x2 modules of the same color
+	for y := 0; y < c.Size-1; y++ {
+		for x := 0; x < c.Size-1; x++ {
+			color := c.modules[y][x]
+			if color == c.modules[y][x+1] && color == c.modules[y+1][x] && color == c.modules[y+1][x+1] {
+				penalty += 3
+			}
+		}
+	}
+
+	// dark modules far from half of the symbol
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+		}
+	}
+	percent := dark * 100 / (c.Size * c.Size)
+	penalty += abs(percent-50) / 5 * 10
+
+	return penalty
+}
+
+var finderLikePatterns = [][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+func linePenalty(line []bool) int {
+	penalty := 0
+
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			penalty += 3 + run - 5
+		}
+		run = 1
+	}
+
+	for _, pattern := range finderLikePatterns {
+		for i := 0; i+len(pattern) <= len(line); i++ {
+			isMatched := true
+			for j, isDark := range pattern {
+				if line[i+j] != isDark {
+					isMatched = false
+					break
+				}
+			}
+			if isMatched {
+				penalty += 40
+			}
+		}
+	}
+
+	return penalty
+}
+
+// Image renders the symbol in black on white, scale pixels a module, with
+// the quiet zone around it.
+func (c *Code) Image(scale int) *image.Gray {
+	scale = max(scale, 1)
+	size := (c.Size + 2*quietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			moduleX, moduleY := x/scale-quietZone, y/scale-quietZone
+			isDark := moduleX >= 0 && moduleX < c.Size && moduleY >= 0 && moduleY < c.Size &&
+				c.modules[moduleY][moduleX]
+			if isDark {
+				img.SetGray(x, y, color.Gray{Y: 0})
+			} else {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return img
+}
+
+// PNG encodes the data and renders it as a PNG image.
+func PNG(data []byte, scale int) ([]byte, error) {
+	code, err := Encode(data)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, code.Image(scale)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// PNGDataURL is PNG as a data URL usable as an image src.
+func PNGDataURL(data []byte, scale int) (string, error) {
+	b, err := PNG(data, scale)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(b), nil
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, bit(value, i))
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	result := make([]byte, len(b)/8)
+	for i, isSet := range b {
+		if isSet {
+			result[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return result
+}
+
+func bit(value, i int) bool {
+	return value>>i&1 != 0
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
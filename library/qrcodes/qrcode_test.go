@@ -0,0 +1,154 @@
+package qrcodes
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func Test_formatBits(t *testing.T) {
+	// from the format information table of ISO/IEC 18004
+	tests := map[int]int{
+		0: 0b101010000010010,
+		1: 0b101000100100101,
+		5: 0b100000011001110,
+		7: 0b100101010100000,
+	}
+	for mask, want := range tests {
+		if got := formatBits(mask); got != want {
+			t.Errorf("formatBits(%d) = %015b, want %015b", mask, got, want)
+		}
+	}
+}
+
+func Test_versionBits(t *testing.T) {
+	tests := map[int]int{
+		7:  0x07C94,
+		10: 0x0A4D3,
+	}
+	for number, want := range tests {
+		if got := versionBits(number); got != want {
+			t.Errorf("versionBits(%d) = %05X, want %05X", number, got, want)
+		}
+	}
+}
+
+func Test_reedSolomonRemainder(t *testing.T) {
+	// the data codewords of "01234567" in version 1-M from the ISO/IEC
+	// 18004 example, with their error correction codewords
+	data := []byte{
+		0x10, 0x20, 0x0C, 0x56, 0x61, 0x80, 0xEC, 0x11,
+		0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11,
+	}
+	want := []byte{0xA5, 0x24, 0xD4, 0xC1, 0xED, 0x36, 0xC7, 0x87, 0x2C, 0x55}
+
+	got := reedSolomonRemainder(data, reedSolomonDivisor(len(want)))
+	if !bytes.Equal(got, want) {
+		t.Errorf("reedSolomonRemainder() = % X, want % X", got, want)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name     string
+		length   int
+		wantSize int
+	}{
+		{name: "version 1", length: 14, wantSize: 21},
+		{name: "version 5", length: 84, wantSize: 37},
+		{name: "version 7 with the version bits", length: 122, wantSize: 45},
+		{name: "version 10 with more blocks", length: 213, wantSize: 57},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(strings.Repeat("a", tt.length))
+			code, err := Encode(data)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if code.Size != tt.wantSize {
+				t.Fatalf("Encode() size = %d, want %d", code.Size, tt.wantSize)
+			}
+			for _, corner := range [][2]int{{0, 0}, {code.Size - 7, 0}, {0, code.Size - 7}} {
+				if !code.Dark(corner[0], corner[1]) || !code.Dark(corner[0]+6, corner[1]+6) || code.Dark(corner[0]+1, corner[1]+1) {
+					t.Errorf("no finder pattern at %v", corner)
+				}
+			}
+
+			// unmasking and reading the zigzag back gives the codewords
+			var v version
+			for _, candidate := range versions {
+				if candidate.size() == code.Size {
+					v = candidate
+				}
+			}
+			want := interleave(v, encodeData(v, data))
+			code.applyMask(code.mask())
+			if got := code.readCodewords(len(want)); !bytes.Equal(got, want) {
+				t.Errorf("codewords = % X, want % X", got, want)
+			}
+		})
+	}
+
+	if _, err := Encode(make([]byte, 214)); err != ErrTooLong {
+		t.Errorf("Encode() error = %v, want %v", err, ErrTooLong)
+	}
+}
+
+// mask reads the mask back from the first copy of the format bits.
+func (c *Code) mask() int {
+	for mask := 0; mask < 8; mask++ {
+		bits := formatBits(mask)
+		isMatched := c.Dark(8, 0) == bit(bits, 0) &&
+			c.Dark(8, 1) == bit(bits, 1) &&
+			c.Dark(8, 2) == bit(bits, 2) &&
+			c.Dark(8, 3) == bit(bits, 3) &&
+			c.Dark(8, 4) == bit(bits, 4) &&
+			c.Dark(8, 5) == bit(bits, 5) &&
+			c.Dark(8, 7) == bit(bits, 6) &&
+			c.Dark(8, 8) == bit(bits, 7) &&
+			c.Dark(7, 8) == bit(bits, 8)
+		if isMatched {
+			return mask
+		}
+	}
+	return -1
+}
+
+func (c *Code) readCodewords(length int) []byte {
+	var bits bitBuffer
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		isUpward := (right+1)&2 == 0
+		for vertical := 0; vertical < c.Size; vertical++ {
+			y := vertical
+			if isUpward {
+				y = c.Size - 1 - vertical
+			}
+			for j := 0; j < 2; j++ {
+				if x := right - j; !c.isFunction[y][x] && len(bits) < length*8 {
+					bits = append(bits, c.Dark(x, y))
+				}
+			}
+		}
+	}
+	return bits.bytes()
+}
+
+func TestPNG(t *testing.T) {
+	b, err := PNG([]byte("hello"), 2)
+	if err != nil {
+		t.Fatalf("PNG() error = %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("png.Decode() error = %v", err)
+	}
+	// version 1 is 21 modules and 4 of quiet zone on each side
+	if size := img.Bounds().Dx(); size != (21+8)*2 {
+		t.Errorf("PNG() width = %d, want %d", size, (21+8)*2)
+	}
+}
@@ -42,5 +42,11 @@ func (h ContextHandler) addTraceFromContext(ctx context.Context) []slog.Attr {
 			string(middlewares.LogFieldKeyReqID), reqID),
 		)
 	}
+	userID, ok := middlewares.UserIDFromContext(ctx)
+	if ok {
+		attrs = append(attrs, slog.Int64(
+			string(middlewares.LogFieldKeyUserID), userID),
+		)
+	}
 	return attrs
 }
@@ -0,0 +1,63 @@
+package storages
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocal_Put(t *testing.T) {
+	dir := t.TempDir()
+	storage, err := NewLocal(&LocalConfig{
+		Dir:     dir,
+		BaseURL: "http://localhost:8080/public/uploads/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		wantURL string
+		wantErr bool
+	}{
+		{
+			name:    "nested key",
+			key:     "avatars/1/256.png",
+			wantURL: "http://localhost:8080/public/uploads/avatars/1/256.png",
+		},
+		{
+			name:    "key outside the directory",
+			key:     "../secret.png",
+			wantErr: true,
+		},
+		{
+			name:    "absolute key",
+			key:     "/etc/passwd",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, err := storage.Put(context.Background(), tt.key, []byte("data"), "image/png")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Put() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if url != tt.wantURL {
+				t.Errorf("Put() url = %q, want %q", url, tt.wantURL)
+			}
+			data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(tt.key)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "data" {
+				t.Errorf("stored %q, want %q", data, "data")
+			}
+		})
+	}
+}
@@ -10,14 +10,14 @@ import (
 	"time"
 
 	"github.com/bosskrub9992/fuel-management-backend/config"
-	"github.com/bosskrub9992/fuel-management-backend/internal/adaptors/pgadaptor"
+	"github.com/bosskrub9992/fuel-management-backend/internal/bootstraps"
 	"github.com/bosskrub9992/fuel-management-backend/internal/handlers/resthandler"
+	"github.com/bosskrub9992/fuel-management-backend/internal/migrations"
 	"github.com/bosskrub9992/fuel-management-backend/internal/routers"
 	"github.com/bosskrub9992/fuel-management-backend/internal/services"
-	"github.com/bosskrub9992/fuel-management-backend/library/databases"
+	"github.com/bosskrub9992/fuel-management-backend/library/jwts"
 	"github.com/bosskrub9992/fuel-management-backend/library/slogger"
 	"github.com/labstack/echo/v4"
-	"gorm.io/gorm"
 )
 
 func main() {
@@ -27,27 +27,46 @@ func main() {
 		MaskingFields:   cfg.Logger.MaskingFields,
 		RemovingFields:  cfg.Logger.RemovingFields,
 	}))
-	sqlDB, err := databases.NewPostgres(&cfg.Database.Postgres)
+	// refuse to start on a weak secret before touching the database
+	if err := cfg.Auth.JWT.Validate(); err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	database, err := bootstraps.NewDatabase(cfg)
 	if err != nil {
 		slog.Error(err.Error())
 		return
 	}
 	defer func() {
-		if err := sqlDB.Close(); err != nil {
+		if err := database.Close(); err != nil {
 			slog.Error(err.Error())
 		}
 	}()
-	gormDB, err := databases.NewGormDBPostgres(sqlDB, gorm.Config{})
+	if cfg.Database.MigrateOnStartup {
+		migrator, err := migrations.NewMigrator(database.DB, database.Migrations, false, nil)
+		if err != nil {
+			slog.Error(err.Error())
+			return
+		}
+		// refuse to serve on a schema which failed to migrate or verify
+		migratedCount, err := migrator.UpWithLock(context.Background())
+		if err != nil {
+			slog.Error(err.Error())
+			return
+		}
+		slog.Info(fmt.Sprintf("migrated up %d pending migrations on startup", migratedCount))
+	}
+	storage, err := bootstraps.NewStorage(cfg)
 	if err != nil {
 		slog.Error(err.Error())
 		return
 	}
-	db := pgadaptor.NewPostgresAdaptor(gormDB)
-	service := services.New(cfg, db)
+	jwt := jwts.New(&cfg.Auth.JWT)
+	service := services.New(cfg, database.Adaptor, jwt, storage)
 	restHandler := resthandler.New(service, time.Now())
 
 	e := echo.New()
-	router := routers.New(e, restHandler)
+	router := routers.New(e, restHandler, jwt)
 	e = router.Init()
 
 	// run server